@@ -14,7 +14,41 @@ type NodeConfigSpec struct {
 
 type MetricsCollector struct {
 	InletTemp EndpointTerm `json:"inletTemp"`
-	DeltaP    EndpointTerm `json:"deltaP"`
+	// InletTempFallback specifies additional sources of inlet temperature used together with InletTemp.
+	// +optional
+	InletTempFallback *FallbackTerm `json:"inletTempFallback,omitempty"`
+	DeltaP            EndpointTerm  `json:"deltaP"`
+	// DeltaPFallback specifies additional sources of differential pressure used together with DeltaP.
+	// +optional
+	DeltaPFallback *FallbackTerm `json:"deltaPFallback,omitempty"`
+	// PowerConsumption specifies the source of measured power consumption in Watts.
+	// +optional
+	PowerConsumption *EndpointTerm `json:"powerConsumption,omitempty"`
+	// PowerConsumptionFallback specifies additional sources of measured power consumption used together with PowerConsumption.
+	// +optional
+	PowerConsumptionFallback *FallbackTerm `json:"powerConsumptionFallback,omitempty"`
+}
+
+// FallbackTerm specifies additional sources of a metric.
+// The EndpointTerm of the metric is the first source, followed by Sources in order.
+type FallbackTerm struct {
+	// Policy specifies how the value is chosen from the sources. Default is FirstHealthy.
+	//   - FirstHealthy: The value of the first source that returns a value.
+	//   - Median: The median of the values of all sources that return a value.
+	//   - WeightedAverage: The average of the values of all sources that return a value, weighted by EndpointTerm.Weight.
+	// +kubebuilder:validation:Enum=FirstHealthy;Median;WeightedAverage
+	// +optional
+	Policy string `json:"policy,omitempty"`
+	// Sources specifies the sources following the EndpointTerm of the metric.
+	// FetchInterval of the sources is ignored and that of the metric is used. Type Push is not supported.
+	Sources []EndpointTerm `json:"sources"`
+	// FailureThreshold specifies the number of consecutive failures after which a source is skipped for OpenDuration. Default is 3.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	FailureThreshold *int32 `json:"failureThreshold,omitempty"`
+	// OpenDuration specifies how long a failing source is skipped before it is tried again. Default is 1m.
+	// +optional
+	OpenDuration *metav1.Duration `json:"openDuration,omitempty"`
 }
 
 type Predictor struct {
@@ -22,6 +56,37 @@ type Predictor struct {
 	PowerConsumption *EndpointTerm `json:"powerConsumption,omitempty"`
 	// +optional
 	PowerConsumptionEndpointProvider *EndpointTerm `json:"powerConsumptionEndpointProvider,omitempty"`
+	// +optional
+	ResponseTime *EndpointTerm `json:"responseTime,omitempty"`
+	// +optional
+	ResponseTimeEndpointProvider *EndpointTerm `json:"responseTimeEndpointProvider,omitempty"`
+	// PowerConsumptionFallbacks specifies power consumption predictors tried in order when PowerConsumption fails
+	// or its prediction is rejected by PowerConsumptionSanityCheck,
+	// e.g., a remote model, then a LinearModel, then a StaticPower.
+	// +optional
+	PowerConsumptionFallbacks []EndpointTerm `json:"powerConsumptionFallbacks,omitempty"`
+	// PowerConsumptionSanityCheck specifies checks of predicted power consumption.
+	// Non-finite predictions are always rejected. Default is to reject negative predictions.
+	// +optional
+	PowerConsumptionSanityCheck *SanityCheckTerm `json:"powerConsumptionSanityCheck,omitempty"`
+}
+
+type SanityCheckTerm struct {
+	// IdleWatts specifies the min valid watts as a decimal number, e.g., "80". Default is "0".
+	// +optional
+	IdleWatts string `json:"idleWatts,omitempty"`
+	// MaxWatts specifies the max valid watts as a decimal number, e.g., "450". Default is no upper bound.
+	// +optional
+	MaxWatts string `json:"maxWatts,omitempty"`
+	// Monotonic requires predictions to be non-decreasing in cpu_usage among inputs with the same other features.
+	// +optional
+	Monotonic bool `json:"monotonic,omitempty"`
+	// Action specifies what to do with a prediction that fails the checks. Default is Reject.
+	//   - Reject: The prediction is discarded and the next predictor is tried.
+	//   - Clamp: The prediction is clamped to [IdleWatts, MaxWatts] and made monotonic. Non-finite predictions are still rejected.
+	// +kubebuilder:validation:Enum=Reject;Clamp
+	// +optional
+	Action string `json:"action,omitempty"`
 }
 
 type EndpointTerm struct {
@@ -35,13 +100,223 @@ type EndpointTerm struct {
 	// FetchInterval specifies the data retrieval interval. Some Types require this value, and behavior depends on the client.
 	// +optional
 	FetchInterval *metav1.Duration `json:"fetchInterval,omitempty"`
+	// Prometheus specifies options for the Prometheus client. Required if Type is Prometheus.
+	// +optional
+	Prometheus *PrometheusTerm `json:"prometheus,omitempty"`
+	// HTTPJSON specifies options for the HTTPJSON client. Required if Type is HTTPJSON.
+	// +optional
+	HTTPJSON *HTTPJSONTerm `json:"httpJSON,omitempty"`
+	// Modbus specifies options for the ModbusTCP client. Required if Type is ModbusTCP.
+	// +optional
+	Modbus *ModbusTerm `json:"modbus,omitempty"`
+	// SNMP specifies options for the SNMP client. Required if Type is SNMP.
+	// +optional
+	SNMP *SNMPTerm `json:"snmp,omitempty"`
+	// Weight specifies the weight of the source used by the WeightedAverage policy of FallbackTerm. Default is "1".
+	// E.g., "0.5"
+	// +optional
+	Weight string `json:"weight,omitempty"`
+	// ModelSchema specifies the input and output tensors of the model. Used by V2InferenceProtocol and V2InferenceProtocolGRPC predictors.
+	// Default is a FP32 [N,3] tensor named "predict-prob" with cpu_usage, inlet_temp and delta_p, and the first column of the first output.
+	// +optional
+	ModelSchema *ModelSchemaTerm `json:"modelSchema,omitempty"`
+	// LinearModel specifies the in-process linear model. Required if Type is LinearModel.
+	// +optional
+	LinearModel *LinearModelTerm `json:"linearModel,omitempty"`
+	// StaticPower specifies the in-process static power model. Required if Type is StaticPower.
+	// +optional
+	StaticPower *StaticPowerTerm `json:"staticPower,omitempty"`
+}
+
+// LinearModelTerm specifies a model that predicts Intercept + sum(Coefficient * Metric).
+type LinearModelTerm struct {
+	// Intercept specifies a decimal number, e.g., "85.5". Default is "0".
+	// +optional
+	Intercept string `json:"intercept,omitempty"`
+	// Coefficients specifies the terms of features.
+	Coefficients []LinearCoefficientTerm `json:"coefficients"`
+}
+
+type LinearCoefficientTerm struct {
+	// Metric specifies the feature: cpu_usage, inlet_temp, delta_p, or an extra feature supplied by the consumer, e.g., memory_usage.
+	Metric string `json:"metric"`
+	// Coefficient specifies a decimal number multiplied to the metric, e.g., "1.8".
+	Coefficient string `json:"coefficient"`
+}
+
+// StaticPowerTerm specifies a model that predicts IdleWatts + WattsPerCore * cpu_usage.
+type StaticPowerTerm struct {
+	// IdleWatts specifies the watts at no CPU usage as a decimal number, e.g., "80".
+	IdleWatts string `json:"idleWatts"`
+	// WattsPerCore specifies the watts per unit of cpu_usage as a decimal number, e.g., "10".
+	// This is per core if the consumer sends cpu_usage in cores, and per percent if in percent.
+	WattsPerCore string `json:"wattsPerCore"`
+}
+
+type ModelSchemaTerm struct {
+	// InputName specifies the name of the input tensor. Default is "predict-prob".
+	// +optional
+	InputName string `json:"inputName,omitempty"`
+	// InputDatatype specifies the datatype of the input tensor. Default is FP32.
+	// +kubebuilder:validation:Enum=FP32;FP64
+	// +optional
+	InputDatatype string `json:"inputDatatype,omitempty"`
+	// Features specifies the columns of the input tensor in order, i.e., the shape is [N, len(Features)].
+	// Default is cpu_usage, inlet_temp and delta_p.
+	// +optional
+	Features []ModelFeatureTerm `json:"features,omitempty"`
+	// OutputName specifies the name of the output tensor. Default is the first output.
+	// +optional
+	OutputName string `json:"outputName,omitempty"`
+	// OutputIndex specifies the 0-based column of the value in the output tensor. Default is 0.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	OutputIndex int32 `json:"outputIndex,omitempty"`
+	// OutputScale specifies a decimal number multiplied to the output value, e.g., to denormalize it. Default is "1".
+	// +optional
+	OutputScale string `json:"outputScale,omitempty"`
+	// OutputOffset specifies a decimal number added to the output value after scaling. Default is "0".
+	// +optional
+	OutputOffset string `json:"outputOffset,omitempty"`
+}
+
+type ModelFeatureTerm struct {
+	// Metric specifies the feature fed to the column: cpu_usage, inlet_temp, delta_p, or an extra feature supplied by the consumer, e.g., memory_usage.
+	Metric string `json:"metric"`
+	// Scale specifies a decimal number multiplied to the metric, e.g., to normalize it. Default is "1".
+	// +optional
+	Scale string `json:"scale,omitempty"`
+	// Offset specifies a decimal number added to the metric after scaling. Default is "0".
+	// +optional
+	Offset string `json:"offset,omitempty"`
+}
+
+type PrometheusTerm struct {
+	// Query specifies a PromQL instant query that returns a single value.
+	// E.g., `avg(ipmi_temperature_celsius{instance="{{ .Hostname }}",name="Inlet_Temp"})`
+	Query string `json:"query"`
+}
+
+type HTTPJSONTerm struct {
+	// Method specifies the HTTP method. Default is GET.
+	// +optional
+	Method string `json:"method,omitempty"`
+	// Body specifies the request body. Sent as application/json.
+	// +optional
+	Body string `json:"body,omitempty"`
+	// JSONPath specifies a JSONPath expression to extract a single number (or numeric string) from the response.
+	// E.g., `{.sensors[?(@.host=="{{ .Hostname }}")].temperature}`
+	JSONPath string `json:"jsonPath"`
+	// Unit specifies the unit of the extracted value, which is converted to the unit of the metric
+	// (Celsius for inlet temperature, Pascal for differential pressure). Default is no conversion.
+	// +kubebuilder:validation:Enum=Celsius;Fahrenheit;Kelvin;Pascal;Hectopascal;Kilopascal;InchOfWater
+	// +optional
+	Unit string `json:"unit,omitempty"`
+	// Scale specifies a decimal number multiplied to the extracted value before unit conversion. Default is "1".
+	// +optional
+	Scale string `json:"scale,omitempty"`
+	// Offset specifies a decimal number added to the extracted value after scaling. Default is "0".
+	// +optional
+	Offset string `json:"offset,omitempty"`
+}
+
+type ModbusTerm struct {
+	// UnitID specifies the unit (slave) ID. Default is 1.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=255
+	// +optional
+	UnitID *int32 `json:"unitID,omitempty"`
+	// Address specifies the 0-based register address.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=65535
+	Address int32 `json:"address"`
+	// RegisterType specifies the register type. Default is Holding.
+	// +kubebuilder:validation:Enum=Holding;Input
+	// +optional
+	RegisterType string `json:"registerType,omitempty"`
+	// DataType specifies the data type. 32-bit types use two registers. Default is Int16.
+	// +kubebuilder:validation:Enum=Int16;Uint16;Int32;Uint32;Float32
+	// +optional
+	DataType string `json:"dataType,omitempty"`
+	// ByteOrder specifies the byte order of a value ABCD where registers are [AB, CD].
+	// ABCD (big-endian), DCBA (little-endian), CDAB (big-endian word-swapped) or BADC (little-endian word-swapped).
+	// Default is ABCD.
+	// +kubebuilder:validation:Enum=ABCD;DCBA;CDAB;BADC
+	// +optional
+	ByteOrder string `json:"byteOrder,omitempty"`
+	// Scale specifies a decimal number multiplied to the decoded value. Default is "1".
+	// +optional
+	Scale string `json:"scale,omitempty"`
+	// Offset specifies a decimal number added to the decoded value after scaling. Default is "0".
+	// +optional
+	Offset string `json:"offset,omitempty"`
+}
+
+type SNMPTerm struct {
+	// Version specifies the SNMP version. Default is v2c.
+	// For v2c, the password in BasicAuthSecret is used as the community (default is "public").
+	// For v3, the username and password in BasicAuthSecret are used as the security name and the authentication passphrase.
+	// Privacy (encryption) is not supported.
+	// +kubebuilder:validation:Enum=v2c;v3
+	// +optional
+	Version string `json:"version,omitempty"`
+	// SecurityLevel specifies the security level for v3. Default is authNoPriv if the password in BasicAuthSecret is set, otherwise noAuthNoPriv.
+	// authPriv is not supported as privacy (encryption) is not implemented.
+	// +kubebuilder:validation:Enum=noAuthNoPriv;authNoPriv
+	// +optional
+	SecurityLevel string `json:"securityLevel,omitempty"`
+	// AuthProtocol specifies the authentication protocol for v3. Default is SHA.
+	// +kubebuilder:validation:Enum=MD5;SHA
+	// +optional
+	AuthProtocol string `json:"authProtocol,omitempty"`
+	// OID specifies the numeric OID of a single value.
+	// E.g., `1.3.6.1.4.1.318.1.1.26.9.4.3.1.7.{{ index .Labels "pdu.example.com/outlet" }}`
+	OID string `json:"oid"`
+	// Scale specifies a decimal number multiplied to the value. Default is "1".
+	// +optional
+	Scale string `json:"scale,omitempty"`
+	// Offset specifies a decimal number added to the value after scaling. Default is "0".
+	// +optional
+	Offset string `json:"offset,omitempty"`
 }
 
 const (
-	TypeFake                = "Fake"
-	TypeRedfish             = "Redfish"
-	TypeDPAPI               = "DifferentialPressureAPI"
-	TypeV2InferenceProtocol = "V2InferenceProtocol"
+	PolicyFirstHealthy    = "FirstHealthy"
+	PolicyMedian          = "Median"
+	PolicyWeightedAverage = "WeightedAverage"
+)
+
+const (
+	TypeFake                    = "Fake"
+	TypeRedfish                 = "Redfish"
+	TypeRedfishSSE              = "RedfishSSE"
+	TypeDPAPI                   = "DifferentialPressureAPI"
+	TypeV2InferenceProtocol     = "V2InferenceProtocol"
+	TypeV2InferenceProtocolGRPC = "V2InferenceProtocolGRPC"
+	TypePrometheus              = "Prometheus"
+	TypeHTTPJSON                = "HTTPJSON"
+	TypePush                    = "Push"
+	TypeModbusTCP               = "ModbusTCP"
+	TypeSNMP                    = "SNMP"
+	TypeNodeAgent               = "NodeAgent"
+	TypeReplay                  = "Replay"
+	TypeLinearModel             = "LinearModel"
+	TypeStaticPower             = "StaticPower"
+)
+
+const (
+	SanityCheckActionReject = "Reject"
+	SanityCheckActionClamp  = "Clamp"
+)
+
+// Condition types of NodeConfig.
+const (
+	// ConditionModelDrift is True if the error of the power consumption predictor against the measured power
+	// exceeds the threshold. It is maintained by wao-metrics-adapter.
+	ConditionModelDrift = "ModelDrift"
+	// ConditionPredictorSchemaValid is True if the modelSchema of the power consumption predictors matches the model metadata.
+	// It is maintained by wao-metrics-adapter, and only set if any power consumption predictor has modelSchema.
+	ConditionPredictorSchemaValid = "PredictorSchemaValid"
 )
 
 // NodeConfigStatus defines the observed state of NodeConfig
@@ -49,7 +324,11 @@ type NodeConfigStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
-	// TODO: implement status
+	// Conditions are the latest observations of the NodeConfig, e.g., ModelDrift.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
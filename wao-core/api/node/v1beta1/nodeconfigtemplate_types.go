@@ -17,6 +17,8 @@ type TemplateData struct {
 	Hostname string
 	// IPv4 contains address value of the first `InternalIP` in `status.addresses`.
 	IPv4 TemplateDataIPv4
+	// Labels contains the labels of the node, e.g., `{{ index .Labels "pdu.example.com/outlet" }}`.
+	Labels map[string]string
 }
 
 func NewTemplateDataFromNode(node corev1.Node) TemplateData {
@@ -48,6 +50,7 @@ func NewTemplateDataFromNode(node corev1.Node) TemplateData {
 			Octet3:  octets[2],
 			Octet4:  octets[3],
 		},
+		Labels: node.Labels,
 	}
 }
 
@@ -116,15 +119,70 @@ func TemplateParseEndpointTerm(in *EndpointTerm, data TemplateData) *EndpointTer
 		// Templating is not supported as FetchInterval is a Duration type.
 	}
 
+	// Prometheus
+	{
+		if in.Prometheus != nil {
+			v, err := TemplateParseString(in.Prometheus.Query, data)
+			if err == nil {
+				out.Prometheus.Query = v
+			}
+		}
+	}
+
+	// HTTPJSON
+	{
+		if in.HTTPJSON != nil {
+			if v, err := TemplateParseString(in.HTTPJSON.Body, data); err == nil {
+				out.HTTPJSON.Body = v
+			}
+			if v, err := TemplateParseString(in.HTTPJSON.JSONPath, data); err == nil {
+				out.HTTPJSON.JSONPath = v
+			}
+		}
+	}
+
+	// SNMP
+	{
+		if in.SNMP != nil {
+			if v, err := TemplateParseString(in.SNMP.OID, data); err == nil {
+				out.SNMP.OID = v
+			}
+		}
+	}
+
+	return out
+}
+
+// TemplateParseFallbackTerm parses each source like TemplateParseEndpointTerm.
+func TemplateParseFallbackTerm(in *FallbackTerm, data TemplateData) *FallbackTerm {
+	out := in.DeepCopy()
+
+	if out == nil {
+		return nil
+	}
+
+	for i := range in.Sources {
+		out.Sources[i] = *TemplateParseEndpointTerm(&in.Sources[i], data)
+	}
+
 	return out
 }
 
 func TemplateParseNodeConfig(nc *NodeConfig, data TemplateData) {
 	nc.Spec.MetricsCollector.InletTemp = *TemplateParseEndpointTerm(&nc.Spec.MetricsCollector.InletTemp, data)
 	nc.Spec.MetricsCollector.DeltaP = *TemplateParseEndpointTerm(&nc.Spec.MetricsCollector.DeltaP, data)
+	nc.Spec.MetricsCollector.PowerConsumption = TemplateParseEndpointTerm(nc.Spec.MetricsCollector.PowerConsumption, data)
+	nc.Spec.MetricsCollector.InletTempFallback = TemplateParseFallbackTerm(nc.Spec.MetricsCollector.InletTempFallback, data)
+	nc.Spec.MetricsCollector.DeltaPFallback = TemplateParseFallbackTerm(nc.Spec.MetricsCollector.DeltaPFallback, data)
+	nc.Spec.MetricsCollector.PowerConsumptionFallback = TemplateParseFallbackTerm(nc.Spec.MetricsCollector.PowerConsumptionFallback, data)
 
 	nc.Spec.Predictor.PowerConsumption = TemplateParseEndpointTerm(nc.Spec.Predictor.PowerConsumption, data)
 	nc.Spec.Predictor.PowerConsumptionEndpointProvider = TemplateParseEndpointTerm(nc.Spec.Predictor.PowerConsumptionEndpointProvider, data)
+	nc.Spec.Predictor.ResponseTime = TemplateParseEndpointTerm(nc.Spec.Predictor.ResponseTime, data)
+	nc.Spec.Predictor.ResponseTimeEndpointProvider = TemplateParseEndpointTerm(nc.Spec.Predictor.ResponseTimeEndpointProvider, data)
+	for i := range nc.Spec.Predictor.PowerConsumptionFallbacks {
+		nc.Spec.Predictor.PowerConsumptionFallbacks[i] = *TemplateParseEndpointTerm(&nc.Spec.Predictor.PowerConsumptionFallbacks[i], data)
+	}
 }
 
 // NodeConfigTemplateSpec defines the desired state of NodeConfigTemplate
@@ -54,6 +54,34 @@ var (
 		BasicAuthSecret: &corev1.LocalObjectReference{Name: "redfish-basicauth-worker-0"},
 		FetchInterval:   &metav1.Duration{Duration: 10 * time.Second},
 	}
+
+	iet3 = EndpointTerm{
+		Type:       "Prometheus",
+		Endpoint:   "http://prometheus:9090",
+		Prometheus: &PrometheusTerm{Query: `avg(ipmi_temperature_celsius{instance="{{.Hostname}}"})`},
+	}
+	td3  = td0
+	wet3 = EndpointTerm{
+		Type:       "Prometheus",
+		Endpoint:   "http://prometheus:9090",
+		Prometheus: &PrometheusTerm{Query: `avg(ipmi_temperature_celsius{instance="worker-0"})`},
+	}
+
+	iet4 = EndpointTerm{
+		Type:     "SNMP",
+		Endpoint: "udp://10.0.200.1:161",
+		SNMP:     &SNMPTerm{OID: `1.3.6.1.4.1.318.1.1.26.9.4.3.1.7.{{ index .Labels "pdu.example.com/outlet" }}`},
+	}
+	td4 = TemplateData{
+		Hostname: "worker-0",
+		IPv4:     TemplateDataIPv4{Address: "10.0.0.1", Octet1: "10", Octet2: "0", Octet3: "0", Octet4: "1"},
+		Labels:   map[string]string{"pdu.example.com/outlet": "12"},
+	}
+	wet4 = EndpointTerm{
+		Type:     "SNMP",
+		Endpoint: "udp://10.0.200.1:161",
+		SNMP:     &SNMPTerm{OID: "1.3.6.1.4.1.318.1.1.26.9.4.3.1.7.12"},
+	}
 )
 
 func TestTemplateParseEndpointTerm(t *testing.T) {
@@ -70,6 +98,8 @@ func TestTemplateParseEndpointTerm(t *testing.T) {
 		{"ok", args{in: &iet0, data: td0}, &wet0},
 		{"partially_fail", args{in: &iet1, data: td1}, &wet1},
 		{"add", args{in: &iet2, data: td2}, &wet2},
+		{"prometheus", args{in: &iet3, data: td3}, &wet3},
+		{"snmp_label", args{in: &iet4, data: td4}, &wet4},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -107,10 +137,12 @@ var (
 	testTemplateData0 = TemplateData{
 		Hostname: testNode0Name,
 		IPv4:     TemplateDataIPv4{Address: testNode0Addr, Octet1: "10", Octet2: "0", Octet3: "0", Octet4: "100"},
+		Labels:   map[string]string{labelHostname: testNode0Name, testLabel: testLabelValue},
 	}
 	testTemplateData1 = TemplateData{
 		Hostname: testNode1Name,
 		IPv4:     TemplateDataIPv4{Address: testNode1Addr, Octet1: "10", Octet2: "0", Octet3: "0", Octet4: "101"},
+		Labels:   map[string]string{labelHostname: testNode1Name, testLabel: testLabelValue},
 	}
 )
 
@@ -23,6 +23,41 @@ func (in *EndpointTerm) DeepCopyInto(out *EndpointTerm) {
 		*out = new(metav1.Duration)
 		**out = **in
 	}
+	if in.Prometheus != nil {
+		in, out := &in.Prometheus, &out.Prometheus
+		*out = new(PrometheusTerm)
+		**out = **in
+	}
+	if in.HTTPJSON != nil {
+		in, out := &in.HTTPJSON, &out.HTTPJSON
+		*out = new(HTTPJSONTerm)
+		**out = **in
+	}
+	if in.Modbus != nil {
+		in, out := &in.Modbus, &out.Modbus
+		*out = new(ModbusTerm)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SNMP != nil {
+		in, out := &in.SNMP, &out.SNMP
+		*out = new(SNMPTerm)
+		**out = **in
+	}
+	if in.ModelSchema != nil {
+		in, out := &in.ModelSchema, &out.ModelSchema
+		*out = new(ModelSchemaTerm)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LinearModel != nil {
+		in, out := &in.LinearModel, &out.LinearModel
+		*out = new(LinearModelTerm)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StaticPower != nil {
+		in, out := &in.StaticPower, &out.StaticPower
+		*out = new(StaticPowerTerm)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EndpointTerm.
@@ -35,11 +70,113 @@ func (in *EndpointTerm) DeepCopy() *EndpointTerm {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FallbackTerm) DeepCopyInto(out *FallbackTerm) {
+	*out = *in
+	if in.Sources != nil {
+		in, out := &in.Sources, &out.Sources
+		*out = make([]EndpointTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.FailureThreshold != nil {
+		in, out := &in.FailureThreshold, &out.FailureThreshold
+		*out = new(int32)
+		**out = **in
+	}
+	if in.OpenDuration != nil {
+		in, out := &in.OpenDuration, &out.OpenDuration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FallbackTerm.
+func (in *FallbackTerm) DeepCopy() *FallbackTerm {
+	if in == nil {
+		return nil
+	}
+	out := new(FallbackTerm)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPJSONTerm) DeepCopyInto(out *HTTPJSONTerm) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPJSONTerm.
+func (in *HTTPJSONTerm) DeepCopy() *HTTPJSONTerm {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPJSONTerm)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LinearCoefficientTerm) DeepCopyInto(out *LinearCoefficientTerm) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LinearCoefficientTerm.
+func (in *LinearCoefficientTerm) DeepCopy() *LinearCoefficientTerm {
+	if in == nil {
+		return nil
+	}
+	out := new(LinearCoefficientTerm)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LinearModelTerm) DeepCopyInto(out *LinearModelTerm) {
+	*out = *in
+	if in.Coefficients != nil {
+		in, out := &in.Coefficients, &out.Coefficients
+		*out = make([]LinearCoefficientTerm, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LinearModelTerm.
+func (in *LinearModelTerm) DeepCopy() *LinearModelTerm {
+	if in == nil {
+		return nil
+	}
+	out := new(LinearModelTerm)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MetricsCollector) DeepCopyInto(out *MetricsCollector) {
 	*out = *in
 	in.InletTemp.DeepCopyInto(&out.InletTemp)
+	if in.InletTempFallback != nil {
+		in, out := &in.InletTempFallback, &out.InletTempFallback
+		*out = new(FallbackTerm)
+		(*in).DeepCopyInto(*out)
+	}
 	in.DeltaP.DeepCopyInto(&out.DeltaP)
+	if in.DeltaPFallback != nil {
+		in, out := &in.DeltaPFallback, &out.DeltaPFallback
+		*out = new(FallbackTerm)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PowerConsumption != nil {
+		in, out := &in.PowerConsumption, &out.PowerConsumption
+		*out = new(EndpointTerm)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PowerConsumptionFallback != nil {
+		in, out := &in.PowerConsumptionFallback, &out.PowerConsumptionFallback
+		*out = new(FallbackTerm)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricsCollector.
@@ -52,13 +189,68 @@ func (in *MetricsCollector) DeepCopy() *MetricsCollector {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ModbusTerm) DeepCopyInto(out *ModbusTerm) {
+	*out = *in
+	if in.UnitID != nil {
+		in, out := &in.UnitID, &out.UnitID
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModbusTerm.
+func (in *ModbusTerm) DeepCopy() *ModbusTerm {
+	if in == nil {
+		return nil
+	}
+	out := new(ModbusTerm)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ModelFeatureTerm) DeepCopyInto(out *ModelFeatureTerm) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModelFeatureTerm.
+func (in *ModelFeatureTerm) DeepCopy() *ModelFeatureTerm {
+	if in == nil {
+		return nil
+	}
+	out := new(ModelFeatureTerm)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ModelSchemaTerm) DeepCopyInto(out *ModelSchemaTerm) {
+	*out = *in
+	if in.Features != nil {
+		in, out := &in.Features, &out.Features
+		*out = make([]ModelFeatureTerm, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModelSchemaTerm.
+func (in *ModelSchemaTerm) DeepCopy() *ModelSchemaTerm {
+	if in == nil {
+		return nil
+	}
+	out := new(ModelSchemaTerm)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeConfig) DeepCopyInto(out *NodeConfig) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeConfig.
@@ -131,6 +323,13 @@ func (in *NodeConfigSpec) DeepCopy() *NodeConfigSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeConfigStatus) DeepCopyInto(out *NodeConfigStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeConfigStatus.
@@ -247,6 +446,28 @@ func (in *Predictor) DeepCopyInto(out *Predictor) {
 		*out = new(EndpointTerm)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ResponseTime != nil {
+		in, out := &in.ResponseTime, &out.ResponseTime
+		*out = new(EndpointTerm)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ResponseTimeEndpointProvider != nil {
+		in, out := &in.ResponseTimeEndpointProvider, &out.ResponseTimeEndpointProvider
+		*out = new(EndpointTerm)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PowerConsumptionFallbacks != nil {
+		in, out := &in.PowerConsumptionFallbacks, &out.PowerConsumptionFallbacks
+		*out = make([]EndpointTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PowerConsumptionSanityCheck != nil {
+		in, out := &in.PowerConsumptionSanityCheck, &out.PowerConsumptionSanityCheck
+		*out = new(SanityCheckTerm)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Predictor.
@@ -259,9 +480,76 @@ func (in *Predictor) DeepCopy() *Predictor {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusTerm) DeepCopyInto(out *PrometheusTerm) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusTerm.
+func (in *PrometheusTerm) DeepCopy() *PrometheusTerm {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusTerm)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SNMPTerm) DeepCopyInto(out *SNMPTerm) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SNMPTerm.
+func (in *SNMPTerm) DeepCopy() *SNMPTerm {
+	if in == nil {
+		return nil
+	}
+	out := new(SNMPTerm)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SanityCheckTerm) DeepCopyInto(out *SanityCheckTerm) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SanityCheckTerm.
+func (in *SanityCheckTerm) DeepCopy() *SanityCheckTerm {
+	if in == nil {
+		return nil
+	}
+	out := new(SanityCheckTerm)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StaticPowerTerm) DeepCopyInto(out *StaticPowerTerm) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StaticPowerTerm.
+func (in *StaticPowerTerm) DeepCopy() *StaticPowerTerm {
+	if in == nil {
+		return nil
+	}
+	out := new(StaticPowerTerm)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TemplateData) DeepCopyInto(out *TemplateData) {
 	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	out.IPv4 = in.IPv4
 }
 
@@ -289,7 +289,17 @@ func NewProxier(ctx context.Context,
 
 	// WAO
 	klog.V(2).InfoS("WAO: NewProxier", "ipFamily", ipFamily)
-	waoLB, err := NewWAOLB(WAOLBOptions{IPFamily: proxier.ipFamily})
+	featureQuantization, err := FeatureQuantizationFromEnv()
+	if err != nil {
+		klog.ErrorS(err, "Failed to initialize WAO Load Balancer")
+		return nil, err
+	}
+	batchWindow, err := BatchWindowFromEnv()
+	if err != nil {
+		klog.ErrorS(err, "Failed to initialize WAO Load Balancer")
+		return nil, err
+	}
+	waoLB, err := NewWAOLB(WAOLBOptions{IPFamily: proxier.ipFamily, ExtraFeatures: ExtraFeaturesFromEnv(), FeatureQuantization: featureQuantization, BatchWindow: batchWindow})
 	if err != nil {
 		klog.ErrorS(err, "Failed to initialize WAO Load Balancer")
 		return nil, err
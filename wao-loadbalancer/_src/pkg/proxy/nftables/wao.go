@@ -3,7 +3,10 @@ package nftables
 import (
 	"context"
 	"fmt"
+	"maps"
 	"math"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -53,6 +56,13 @@ const (
 
 	AnnotationCPUPerRequest = "waok8s.github.io/cpu-per-request"
 
+	// EnvVarExtraFeatures is a comma-separated list of WAOLBOptions.ExtraFeatures, e.g., "memory_usage,power_consumption".
+	EnvVarExtraFeatures = "WAO_PREDICTOR_EXTRA_FEATURES"
+	// EnvVarFeatureQuantization is a comma-separated list of WAOLBOptions.FeatureQuantization, e.g., "cpu_usage=1,inlet_temp=0.5".
+	EnvVarFeatureQuantization = "WAO_PREDICTOR_FEATURE_QUANTIZATION"
+	// EnvVarBatchWindow is WAOLBOptions.BatchWindow in time.ParseDuration format, e.g., "5ms".
+	EnvVarBatchWindow = "WAO_PREDICTOR_BATCH_WINDOW"
+
 	DefaultCPUPerRequest = "100m"
 
 	// Parallelism is the number of goroutines to use for parallelizing work.
@@ -66,6 +76,9 @@ const (
 	DefaultMetricsCacheTTL   = 30 * time.Second
 	DefaultPredictorCacheTTL = 30 * time.Minute
 
+	DefaultMetricsCacheSize   = waoclient.DefaultMetricsCacheSize
+	DefaultPredictorCacheSize = waoclient.DefaultPredictorCacheSize
+
 	DefaultCPUUsageFormat = CPUUsageFormatPercent
 )
 
@@ -85,7 +98,72 @@ type WAOLBOptions struct {
 	MetricsCacheTTL   time.Duration
 	PredictorCacheTTL time.Duration
 
+	// MetricsCacheSize and PredictorCacheSize are the max number of entries of each cache.
+	MetricsCacheSize   int
+	PredictorCacheSize int
+
 	CPUUsageFormat string
+
+	// ExtraFeatures are features supplied to the predictor in addition to cpu_usage, inlet_temp and delta_p.
+	// "memory_usage" is from metrics-server, and other names are custom metrics of the node.
+	ExtraFeatures []string
+
+	// FeatureQuantization is the step to round each feature to before predicting, so that close inputs share a cache entry.
+	// Nil means waoclient.DefaultQuantization, and a step of 0 disables quantization of the feature.
+	FeatureQuantization map[string]float64
+
+	// BatchWindow is the time to wait for concurrent power consumption predictions to the same endpoint
+	// to coalesce into a single request. Zero disables coalescing.
+	BatchWindow time.Duration
+}
+
+// ExtraFeaturesFromEnv returns WAOLBOptions.ExtraFeatures from the environment variable EnvVarExtraFeatures.
+func ExtraFeaturesFromEnv() []string {
+	var features []string
+	for _, s := range strings.Split(os.Getenv(EnvVarExtraFeatures), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			features = append(features, s)
+		}
+	}
+	return features
+}
+
+// FeatureQuantizationFromEnv returns WAOLBOptions.FeatureQuantization from the environment variable EnvVarFeatureQuantization.
+// It returns nil if the variable is not set.
+func FeatureQuantizationFromEnv() (map[string]float64, error) {
+	var steps map[string]float64
+	for _, s := range strings.Split(os.Getenv(EnvVarFeatureQuantization), ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(s, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s: %q is not in feature=step format", EnvVarFeatureQuantization, s)
+		}
+		step, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid step of %s: %w", EnvVarFeatureQuantization, k, err)
+		}
+		if steps == nil {
+			steps = map[string]float64{}
+		}
+		steps[strings.TrimSpace(k)] = step
+	}
+	return steps, nil
+}
+
+// BatchWindowFromEnv returns WAOLBOptions.BatchWindow from the environment variable EnvVarBatchWindow.
+// It returns 0 if the variable is not set.
+func BatchWindowFromEnv() (time.Duration, error) {
+	s := strings.TrimSpace(os.Getenv(EnvVarBatchWindow))
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", EnvVarBatchWindow, err)
+	}
+	return d, nil
 }
 
 func DefaultingWAOLBOptions(opts WAOLBOptions) WAOLBOptions {
@@ -99,9 +177,18 @@ func DefaultingWAOLBOptions(opts WAOLBOptions) WAOLBOptions {
 	if opts.PredictorCacheTTL == 0 {
 		opts.PredictorCacheTTL = DefaultPredictorCacheTTL
 	}
+	if opts.MetricsCacheSize == 0 {
+		opts.MetricsCacheSize = DefaultMetricsCacheSize
+	}
+	if opts.PredictorCacheSize == 0 {
+		opts.PredictorCacheSize = DefaultPredictorCacheSize
+	}
 	if opts.CPUUsageFormat == "" {
 		opts.CPUUsageFormat = DefaultCPUUsageFormat
 	}
+	if opts.FeatureQuantization == nil {
+		opts.FeatureQuantization = waoclient.DefaultQuantization()
+	}
 	return opts
 }
 
@@ -115,9 +202,26 @@ func ValidatingWAOLBOptions(opts WAOLBOptions) error {
 	if opts.PredictorCacheTTL <= 0 {
 		return fmt.Errorf("ValidatingWAOLBOptions: PredictorCacheTTL must be positive")
 	}
+	if opts.MetricsCacheSize <= 0 {
+		return fmt.Errorf("ValidatingWAOLBOptions: MetricsCacheSize must be positive")
+	}
+	if opts.PredictorCacheSize <= 0 {
+		return fmt.Errorf("ValidatingWAOLBOptions: PredictorCacheSize must be positive")
+	}
 	if opts.CPUUsageFormat != CPUUsageFormatRaw && opts.CPUUsageFormat != CPUUsageFormatPercent {
 		return fmt.Errorf("ValidatingWAOLBOptions: CPUUsageFormat must be either `Raw` or `Percent`")
 	}
+	if err := predictor.ValidateExtraFeatures(opts.ExtraFeatures); err != nil {
+		return fmt.Errorf("ValidatingWAOLBOptions: ExtraFeatures: %w", err)
+	}
+	for k, v := range opts.FeatureQuantization {
+		if v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("ValidatingWAOLBOptions: FeatureQuantization of %s must be a non-negative number", k)
+		}
+	}
+	if opts.BatchWindow < 0 {
+		return fmt.Errorf("ValidatingWAOLBOptions: BatchWindow must not be negative")
+	}
 	return nil
 }
 
@@ -186,12 +290,16 @@ func NewWAOLB(opts WAOLBOptions) (*WAOLB, error) {
 		return nil, err
 	}
 
+	predictorclient := waoclient.NewCachedPredictorClient(clientSet, opts.PredictorCacheTTL, opts.PredictorCacheSize)
+	predictorclient.Quantization = opts.FeatureQuantization
+	predictorclient.BatchWindow = opts.BatchWindow
+
 	return &WAOLB{
 		opts: opts,
 
 		ctrlclient:      c,
-		metricsclient:   waoclient.NewCachedMetricsClient(mc, cmc, opts.MetricsCacheTTL),
-		predictorclient: waoclient.NewCachedPredictorClient(clientSet, opts.PredictorCacheTTL),
+		metricsclient:   waoclient.NewCachedMetricsClient(mc, cmc, opts.MetricsCacheTTL, opts.MetricsCacheSize),
+		predictorclient: predictorclient,
 	}, nil
 }
 
@@ -388,6 +496,16 @@ func (w *WAOLB) ScoreNode(ctx context.Context, nodeName string, cpuUsage resourc
 	}
 	klog.V(5).InfoS("WAO: ScoreNode metrics", "ipFamily", w.opts.IPFamily, "node", nodeName, "inlet_temp", inletTemp.Value.AsApproximateFloat64(), "delta_p", deltaP.Value.AsApproximateFloat64())
 
+	// get extra features
+	extraFeatures, err := w.metricsclient.GetNodeFeatures(ctx, &node, w.opts.ExtraFeatures)
+	if err != nil {
+		klog.ErrorS(err, "WAO: ScoreNode GetNodeFeatures", "ipFamily", w.opts.IPFamily, "node", nodeName, "features", w.opts.ExtraFeatures)
+		return 0, err
+	}
+	if len(extraFeatures) > 0 {
+		klog.V(5).InfoS("WAO: ScoreNode extra features", "ipFamily", w.opts.IPFamily, "node", nodeName, "features", extraFeatures)
+	}
+
 	// get NodeConfig
 	var nc *waov1beta1.NodeConfig
 	var ncs waov1beta1.NodeConfigList
@@ -407,38 +525,17 @@ func (w *WAOLB) ScoreNode(ctx context.Context, nodeName string, cpuUsage resourc
 		return 0, nil
 	}
 
-	// init predictor endpoint
-	var ep *waov1beta1.EndpointTerm
-	if nc.Spec.Predictor.PowerConsumption != nil {
-		ep = nc.Spec.Predictor.PowerConsumption
-	} else {
-		ep = &waov1beta1.EndpointTerm{}
-	}
-	if nc.Spec.Predictor.PowerConsumptionEndpointProvider != nil {
-		ep2, err := w.predictorclient.GetPredictorEndpoint(ctx, nc.Namespace, nc.Spec.Predictor.PowerConsumptionEndpointProvider, predictor.TypePowerConsumption)
-		if err != nil {
-			klog.ErrorS(err, "WAO: ScoreNode GetPredictorEndpoint", "ipFamily", w.opts.IPFamily, "node", nodeName)
-			return 0, err
-		}
-		if ep2 == nil { // FIXME: this is a workaround for some weird cases
-			klog.ErrorS(fmt.Errorf("ep2 == nil"), "WAO: ScoreNode unexpected error", "ipFamily", w.opts.IPFamily, "node", nodeName)
-			return 0, err
-		}
-		ep.Type = ep2.Type
-		ep.Endpoint = ep2.Endpoint
-	}
-
-	// do predict
-	beforeWatt, err := w.predictorclient.PredictPowerConsumption(ctx, nc.Namespace, ep, beforeUsage, inletTemp.Value.AsApproximateFloat64(), deltaP.Value.AsApproximateFloat64())
-	if err != nil {
-		klog.ErrorS(err, "WAO: ScoreNode failed to predict power consumption", "ipFamily", w.opts.IPFamily, "node", nodeName)
-		return 0, err
-	}
-	afterWatt, err := w.predictorclient.PredictPowerConsumption(ctx, nc.Namespace, ep, afterUsage, inletTemp.Value.AsApproximateFloat64(), deltaP.Value.AsApproximateFloat64())
+	// do predict (before and after in a single request), falling back to PowerConsumptionFallbacks
+	beforeFeatures := predictor.PowerConsumptionInput{CPUUsage: beforeUsage, InletTemp: inletTemp.Value.AsApproximateFloat64(), DeltaP: deltaP.Value.AsApproximateFloat64()}.Features()
+	afterFeatures := predictor.PowerConsumptionInput{CPUUsage: afterUsage, InletTemp: inletTemp.Value.AsApproximateFloat64(), DeltaP: deltaP.Value.AsApproximateFloat64()}.Features()
+	maps.Copy(beforeFeatures, extraFeatures)
+	maps.Copy(afterFeatures, extraFeatures)
+	watts, err := w.predictorclient.PredictNodePowerConsumptionFeaturesBatch(ctx, nc, []predictor.Features{beforeFeatures, afterFeatures})
 	if err != nil {
 		klog.ErrorS(err, "WAO: ScoreNode failed to predict power consumption", "ipFamily", w.opts.IPFamily, "node", nodeName)
 		return 0, err
 	}
+	beforeWatt, afterWatt := watts[0], watts[1]
 	klog.V(5).InfoS("WAO: ScoreNode prediction", "ipFamily", w.opts.IPFamily, "node", nodeName, "watt_before", beforeWatt, "watt_after", afterWatt)
 
 	powerConsumption := int(afterWatt - beforeWatt)
@@ -2,6 +2,7 @@ package nftables
 
 import (
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -68,3 +69,54 @@ func Test_normalizeScores(t *testing.T) {
 		})
 	}
 }
+
+func TestFeatureQuantizationFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		want    map[string]float64
+		wantErr bool
+	}{
+		{"unset", "", nil, false},
+		{"steps", "cpu_usage=1, inlet_temp=0.5,delta_p=0", map[string]float64{"cpu_usage": 1, "inlet_temp": 0.5, "delta_p": 0}, false},
+		{"no_step", "cpu_usage", nil, true},
+		{"invalid_step", "cpu_usage=x", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvVarFeatureQuantization, tt.env)
+			got, err := FeatureQuantizationFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FeatureQuantizationFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("FeatureQuantizationFromEnv() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBatchWindowFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"unset", "", 0, false},
+		{"window", "5ms", 5 * time.Millisecond, false},
+		{"invalid", "5", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvVarBatchWindow, tt.env)
+			got, err := BatchWindowFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BatchWindowFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("BatchWindowFromEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
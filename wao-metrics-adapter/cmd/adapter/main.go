@@ -2,20 +2,30 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"net"
+	"net/http"
 	"os"
+	"sync"
+	"time"
 
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/component-base/logs"
 	"k8s.io/klog/v2"
+	metricsclientv1beta1 "k8s.io/metrics/pkg/client/clientset/versioned/typed/metrics/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	basecmd "sigs.k8s.io/custom-metrics-apiserver/pkg/cmd"
 
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/accuracy"
+	waoclient "github.com/waok8s/waok8s/wao-metrics-adapter/pkg/client"
 	waocontroller "github.com/waok8s/waok8s/wao-metrics-adapter/pkg/controller"
 	waometrics "github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics/push"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
 	waoprovider "github.com/waok8s/waok8s/wao-metrics-adapter/pkg/provider"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/sharding"
 )
 
 type Adapter struct {
@@ -23,6 +33,52 @@ type Adapter struct {
 
 	// the message printed on startup
 	Message string
+
+	// ProviderOptions configures the custom metrics provider.
+	ProviderOptions waoprovider.Options
+
+	// LeaderElect enables leader election so that only one replica collects metrics.
+	LeaderElect bool
+	// LeaderElectionNamespace is the namespace to create the leader election Lease in.
+	LeaderElectionNamespace string
+
+	// Sharding enables sharding so that each replica collects metrics of a subset of NodeConfigs.
+	Sharding bool
+	// ShardingOptions configures the sharder. Namespace is shared with LeaderElectionNamespace.
+	ShardingOptions sharding.Options
+	// PeerBindAddress is the address the peer endpoint binds to.
+	PeerBindAddress string
+	// PeerTokenFile is the ServiceAccount token file used to authenticate to peers.
+	PeerTokenFile string
+	// PeerSyncInterval is the interval to pull values from peers.
+	PeerSyncInterval time.Duration
+
+	// SnapshotFile is the path to save Store snapshots to.
+	SnapshotFile string
+	// SnapshotConfigMap is the name of the ConfigMap to save Store snapshots to.
+	SnapshotConfigMap string
+	// SnapshotInterval is the interval to save Store snapshots.
+	SnapshotInterval time.Duration
+
+	// IngestBindAddress is the address the push ingestion endpoint binds to. Empty disables it.
+	IngestBindAddress string
+	// IngestTLSCertFile and IngestTLSKeyFile enable TLS on the push ingestion endpoint.
+	IngestTLSCertFile string
+	IngestTLSKeyFile  string
+
+	// RecordFile is the path to record accepted samples to in JSONL. Empty disables recording.
+	RecordFile string
+	// RecordMaxSizeMB is the size in MiB to rotate the recording file at.
+	RecordMaxSizeMB int
+	// RecordMaxBackups is the number of rotated recording files to keep.
+	RecordMaxBackups int
+
+	// Accuracy enables prediction accuracy tracking against measured power consumption.
+	Accuracy bool
+	// AccuracyOptions configures the accuracy tracker.
+	AccuracyOptions accuracy.Options
+	// AccuracyCPUUsageFormat is the format of cpu_usage supplied to predictors, "Raw" or "Percent".
+	AccuracyCPUUsageFormat string
 }
 
 func main() {
@@ -38,11 +94,77 @@ func main() {
 		Message: "WAO Metrics Adapter",
 	}
 	// init flags
+	podName, _ := os.Hostname()
+	podNamespace := os.Getenv("POD_NAMESPACE")
+	if podNamespace == "" {
+		podNamespace = "custom-metrics"
+	}
 	cmd.Flags().StringVar(&cmd.Message, "msg", "starting adapter...", "startup message")
+	cmd.Flags().BoolVar(&cmd.ProviderOptions.PartialList, "partial-list", waoprovider.DefaultPartialList, "skip objects without fresh data in list queries instead of failing the whole list")
+	cmd.Flags().BoolVar(&cmd.LeaderElect, "leader-elect", false, "enable leader election so that only one replica collects metrics (cannot be used with --sharding)")
+	cmd.Flags().StringVar(&cmd.LeaderElectionNamespace, "leader-election-namespace", podNamespace, "namespace to create Leases in (for both --leader-elect and --sharding)")
+	cmd.Flags().BoolVar(&cmd.Sharding, "sharding", false, "enable sharding so that each replica collects metrics of a subset of NodeConfigs (cannot be used with --leader-elect)")
+	cmd.Flags().StringVar(&cmd.ShardingOptions.Group, "shard-group", "wao-metrics-adapter", "name of the shard group")
+	cmd.Flags().StringVar(&cmd.ShardingOptions.Identity, "shard-identity", podName, "unique name of this replica in the shard group, also used to name snapshots with --leader-elect")
+	cmd.Flags().StringVar(&cmd.ShardingOptions.Address, "peer-advertise-address", "", "peer endpoint address advertised to other replicas (default: derived from $POD_IP and --peer-bind-address)")
+	cmd.Flags().DurationVar(&cmd.ShardingOptions.LeaseDuration, "shard-lease-duration", sharding.DefaultLeaseDuration, "duration a replica is considered alive after its last renewal")
+	cmd.Flags().DurationVar(&cmd.ShardingOptions.RenewInterval, "shard-renew-interval", sharding.DefaultRenewInterval, "interval to renew the Lease of this replica")
+	cmd.Flags().StringVar(&cmd.PeerBindAddress, "peer-bind-address", ":8081", "address the peer endpoint binds to")
+	cmd.Flags().StringVar(&cmd.PeerTokenFile, "peer-token-file", sharding.DefaultServiceAccountTokenFile, "ServiceAccount token file to authenticate to peers (peers must run with the same ServiceAccount)")
+	cmd.Flags().DurationVar(&cmd.PeerSyncInterval, "peer-sync-interval", sharding.DefaultPeerSyncInterval, "interval to pull values from peers")
+	cmd.Flags().StringVar(&cmd.SnapshotFile, "snapshot-file", "", "path to save Store snapshots to and restore from at startup (cannot be used with --snapshot-configmap)")
+	cmd.Flags().StringVar(&cmd.SnapshotConfigMap, "snapshot-configmap", "", "name of the ConfigMap in --leader-election-namespace to save Store snapshots to and restore from at startup (cannot be used with --snapshot-file)")
+	cmd.Flags().DurationVar(&cmd.SnapshotInterval, "snapshot-interval", 30*time.Second, "interval to save Store snapshots")
+	cmd.Flags().StringVar(&cmd.IngestBindAddress, "ingest-bind-address", "", "address the push ingestion endpoint binds to, e.g., \":8082\" (empty to disable)")
+	cmd.Flags().StringVar(&cmd.IngestTLSCertFile, "ingest-tls-cert-file", "", "TLS certificate file for the push ingestion endpoint")
+	cmd.Flags().StringVar(&cmd.IngestTLSKeyFile, "ingest-tls-key-file", "", "TLS key file for the push ingestion endpoint")
+	cmd.Flags().StringVar(&cmd.RecordFile, "record-file", "", "path to record accepted samples to in JSONL for the Replay agent type (empty to disable)")
+	cmd.Flags().IntVar(&cmd.RecordMaxSizeMB, "record-max-size-mb", 100, "size in MiB to rotate the recording file at (0 to disable rotation)")
+	cmd.Flags().IntVar(&cmd.RecordMaxBackups, "record-max-backups", 5, "number of rotated recording files to keep (0 to keep all)")
+	cmd.Flags().BoolVar(&cmd.Accuracy, "accuracy", false, "enable prediction accuracy tracking against measured power consumption")
+	cmd.Flags().DurationVar(&cmd.AccuracyOptions.Interval, "accuracy-interval", accuracy.DefaultInterval, "interval to compare predictions with measured power consumption")
+	cmd.Flags().IntVar(&cmd.AccuracyOptions.WindowSize, "accuracy-window-size", accuracy.DefaultWindowSize, "number of latest samples to compute MAE and MAPE over")
+	cmd.Flags().IntVar(&cmd.AccuracyOptions.MinSamples, "accuracy-min-samples", accuracy.DefaultMinSamples, "number of samples required to evaluate the ModelDrift condition")
+	cmd.Flags().Float64Var(&cmd.AccuracyOptions.MAPEThreshold, "accuracy-mape-threshold", accuracy.DefaultMAPEThreshold, "MAPE in percent above which ModelDrift is set to True")
+	cmd.Flags().Float64Var(&cmd.AccuracyOptions.MAEThreshold, "accuracy-mae-threshold", 0, "MAE in watts above which ModelDrift is set to True (0 to disable)")
+	cmd.Flags().StringVar(&cmd.AccuracyCPUUsageFormat, "accuracy-cpu-usage-format", "Raw", "format of cpu_usage supplied to predictors, Raw (cores) or Percent (must match the models, e.g., WAO Load Balancer supplies Percent by default)")
+	cmd.Flags().StringSliceVar(&cmd.AccuracyOptions.ExtraFeatures, "accuracy-extra-features", nil, "features supplied to predictors in addition to cpu_usage, inlet_temp and delta_p, e.g., memory_usage,power_consumption")
 	logs.AddGoFlags(flag.CommandLine)          // register klog flags
 	cmd.Flags().AddGoFlagSet(flag.CommandLine) // register adapter flags
 	cmd.Flags().Parse(os.Args)
 
+	if cmd.LeaderElect && cmd.Sharding {
+		klog.Fatalf("--leader-elect and --sharding cannot be used together")
+	}
+	if cmd.SnapshotFile != "" && cmd.SnapshotConfigMap != "" {
+		klog.Fatalf("--snapshot-file and --snapshot-configmap cannot be used together")
+	}
+	switch cmd.AccuracyCPUUsageFormat {
+	case "Raw":
+	case "Percent":
+		cmd.AccuracyOptions.CPUUsagePercent = true
+	default:
+		klog.Fatalf("--accuracy-cpu-usage-format must be Raw or Percent")
+	}
+	if err := predictor.ValidateExtraFeatures(cmd.AccuracyOptions.ExtraFeatures); err != nil {
+		klog.Fatalf("invalid --accuracy-extra-features: %v", err)
+	}
+	cmd.AccuracyOptions.MaxAge = waoprovider.MetricTTL
+	cmd.ShardingOptions.Namespace = cmd.LeaderElectionNamespace
+	if podIP := os.Getenv("POD_IP"); cmd.ShardingOptions.Address == "" && podIP != "" {
+		if _, port, err := net.SplitHostPort(cmd.PeerBindAddress); err == nil {
+			cmd.ShardingOptions.Address = "http://" + net.JoinHostPort(podIP, port)
+		}
+	}
+
+	// use klog for controller-runtime to merge logs
+	ctrl.SetLogger(klog.NewKlogr())
+	setupLog := ctrl.Log.WithName("setup")
+
+	ctx, cancel := context.WithCancel(ctrl.SetupSignalHandler())
+	defer cancel()
+	var wg sync.WaitGroup
+
 	// init provider
 	client, err := cmd.DynamicClient()
 	if err != nil {
@@ -52,47 +174,187 @@ func main() {
 	if err != nil {
 		klog.Fatalf("unable to construct discovery REST mapper: %v", err)
 	}
-	provider := waoprovider.New(client, mapper, metricsStore)
+	provider := waoprovider.New(client, mapper, metricsStore, cmd.ProviderOptions)
 	cmd.WithCustomMetrics(provider)
 	// cmd.WithExternalMetrics(provider) // waoprovider.Provider don't support external metrics
 
+	cfg, err := cmd.ClientConfig()
+	if err != nil {
+		klog.Fatalf("unable to construct client config: %v", err)
+	}
+	clientset := kubernetes.NewForConfigOrDie(cfg)
+
+	// init recorder
+	if cmd.RecordFile != "" {
+		recorder, err := waometrics.NewFileRecorder(cmd.RecordFile, int64(cmd.RecordMaxSizeMB)<<20, cmd.RecordMaxBackups)
+		if err != nil {
+			klog.Fatalf("unable to init recorder: %v", err)
+		}
+		defer recorder.Close()
+		metricsStore.Recorder = recorder
+	}
+
+	// restore snapshot
+	var snapshotBackend waometrics.SnapshotBackend
+	switch {
+	case cmd.SnapshotFile != "":
+		snapshotBackend = &waometrics.FileSnapshotBackend{Path: cmd.SnapshotFile}
+	case cmd.SnapshotConfigMap != "":
+		backend := &waometrics.ConfigMapSnapshotBackend{Client: clientset, Namespace: cmd.LeaderElectionNamespace, Name: cmd.SnapshotConfigMap}
+		if cmd.Sharding || cmd.LeaderElect {
+			// each replica saves its own snapshot so that replicas do not overwrite each other,
+			// e.g., a follower with an empty store overwriting the snapshot of the leader
+			backend.Identity = cmd.ShardingOptions.Identity
+		}
+		snapshotBackend = backend
+	}
+	if snapshotBackend != nil {
+		checkpointer := waometrics.NewCheckpointer(metricsStore, snapshotBackend, cmd.SnapshotInterval)
+		if err := checkpointer.Restore(ctx); err != nil {
+			klog.Errorf("unable to restore snapshot, so start with an empty store: %v", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkpointer.Run(ctx)
+		}()
+	}
+
+	// init sharding
+	// Replicas find each other with Leases and pull values from each other for both --sharding and --leader-elect,
+	// so that standbys can answer queries with values collected by the leader.
+	var sharder *sharding.Sharder
+	if cmd.Sharding || cmd.LeaderElect {
+		sharder = sharding.NewSharder(clientset, cmd.ShardingOptions)
+		if err := sharder.Sync(ctx); err != nil {
+			klog.Fatalf("unable to join the shard group: %v", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sharder.Run(ctx)
+		}()
+
+		peerAuth := sharding.NewPeerAuthenticator(clientset, cmd.PeerTokenFile)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sharding.NewPeerSyncer(sharder, metricsStore, cmd.PeerSyncInterval, peerAuth).Run(ctx)
+		}()
+
+		srv := &http.Server{Addr: cmd.PeerBindAddress, Handler: sharding.NewPeerHandler(metricsStore, peerAuth)}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				klog.Errorf("unable to run peer endpoint: %v", err)
+				cancel()
+			}
+		}()
+		go func() {
+			<-ctx.Done()
+			ctx2, cancel2 := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel2()
+			srv.Shutdown(ctx2)
+		}()
+	}
+
+	// init push ingestion
+	var pushReceiver *push.Receiver
+	if cmd.IngestBindAddress != "" {
+		pushReceiver = push.NewReceiver(metricsStore, waoprovider.MetricTTL, push.DefaultMaxClockSkew)
+		srv := &http.Server{Addr: cmd.IngestBindAddress, Handler: push.NewHandler(pushReceiver)}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			if cmd.IngestTLSCertFile != "" {
+				err = srv.ListenAndServeTLS(cmd.IngestTLSCertFile, cmd.IngestTLSKeyFile)
+			} else {
+				err = srv.ListenAndServe()
+			}
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				klog.Errorf("unable to run push ingestion endpoint: %v", err)
+				cancel()
+			}
+		}()
+		go func() {
+			<-ctx.Done()
+			ctx2, cancel2 := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel2()
+			srv.Shutdown(ctx2)
+		}()
+	}
+
 	klog.Infof(cmd.Message)
+	wg.Add(1)
 	go func() {
-		// TODO: handle graceful shutdown
-		if err := cmd.Run(context.Background()); err != nil {
-			klog.Fatalf("unable to run custom metrics adapter: %v", err)
+		defer wg.Done()
+		if err := cmd.Run(ctx); err != nil {
+			klog.Errorf("unable to run custom metrics adapter: %v", err)
+			cancel()
 		}
 	}()
 
 	// init controller
-	// TODO: merge flags, merge logs, use LeaderElection
-	setupLog := ctrl.Log.WithName("setup")
-	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&zap.Options{Development: true})))
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
 		Scheme: waocontroller.Scheme,
 		Metrics: metricsserver.Options{
 			BindAddress: "0", // disable metrics server to avoid port conflict
 		},
-		HealthProbeBindAddress: "",
-		LeaderElection:         false,
+		HealthProbeBindAddress:  "",
+		LeaderElection:          cmd.LeaderElect,
+		LeaderElectionID:        "wao-metrics-adapter.waok8s.github.io",
+		LeaderElectionNamespace: cmd.LeaderElectionNamespace,
+		// release the Lease on shutdown so that a standby replica takes over immediately
+		LeaderElectionReleaseOnCancel: true,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
-	if err := (&waocontroller.NodeConfigReconciler{
+	reconciler := &waocontroller.NodeConfigReconciler{
 		Client:           mgr.GetClient(),
 		Scheme:           mgr.GetScheme(),
-		SecretClient:     kubernetes.NewForConfigOrDie(mgr.GetConfig()),
+		SecretClient:     clientset,
 		MetricsCollector: metricsCollector,
 		MetricsStore:     metricsStore,
-	}).SetupWithManager(mgr); err != nil {
+	}
+	if cmd.Sharding {
+		reconciler.Sharder = sharder
+	}
+	if pushReceiver != nil {
+		reconciler.PushReceiver = pushReceiver
+	}
+	if err := reconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Operator")
 		os.Exit(1)
 	}
+	if cmd.Accuracy {
+		mc, err := metricsclientv1beta1.NewForConfig(cfg)
+		if err != nil {
+			klog.Fatalf("unable to construct metrics client: %v", err)
+		}
+		pc := waoclient.NewCachedPredictorClient(clientset, 30*time.Minute, waoclient.DefaultPredictorCacheSize)
+		tracker := accuracy.NewTracker(mgr.GetClient(), mc, pc, metricsStore, cmd.AccuracyOptions)
+		if cmd.Sharding {
+			tracker.Sharder = sharder
+		}
+		if err := mgr.Add(tracker); err != nil {
+			setupLog.Error(err, "unable to add accuracy tracker")
+			os.Exit(1)
+		}
+	}
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
-		os.Exit(1)
+		cancel()
 	}
+
+	// graceful shutdown
+	setupLog.Info("shutting down")
+	cancel()
+	metricsCollector.UnregisterAll()
+	wg.Wait()
+	setupLog.Info("stopped")
 }
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/nodeagent"
+)
+
+func main() {
+	var nodeName string
+	flag.StringVar(&nodeName, "node-name", os.Getenv("NODE_NAME"), "name of the node the agent runs on (default $NODE_NAME)")
+	var sysfsRoot string
+	flag.StringVar(&sysfsRoot, "sysfs-root", "/sys", "sysfs mount point, e.g., /host/sys in a container")
+	var interval time.Duration
+	flag.DurationVar(&interval, "interval", 5*time.Second, "sampling interval")
+	var bindAddress string
+	flag.StringVar(&bindAddress, "bind-address", ":9102", "address the readings endpoint binds to (empty to disable)")
+	var tempSensor string
+	flag.StringVar(&tempSensor, "temp-sensor", `(?i)inlet|ambient`, "regexp selecting the hwmon sensor used as inlet temperature by {name}-{label} (empty to disable)")
+	var pushAddress string
+	flag.StringVar(&pushAddress, "push-address", "", "base URL of the push ingestion endpoint of the metrics adapter (empty to disable pushing)")
+	var pushCredentialsDir string
+	flag.StringVar(&pushCredentialsDir, "push-credentials-dir", "", "directory containing username and password files for pushing, e.g., a mounted basic auth Secret")
+	var pushMetrics string
+	flag.StringVar(&pushMetrics, "push-metrics", string(metrics.ValuePowerConsumption), "comma-separated metrics to push (empty to push all)")
+	var logLevel int
+	flag.IntVar(&logLevel, "v", 2, "klog-style log level")
+	flag.Parse()
+
+	var slogLevel slog.Level
+	switch {
+	case logLevel < 0:
+		slogLevel = 100 // silent
+	case logLevel == 0:
+		slogLevel = slog.LevelError
+	case logLevel == 1:
+		slogLevel = slog.LevelWarn
+	case logLevel == 2:
+		slogLevel = slog.LevelInfo
+	case logLevel == 3:
+		slogLevel = slog.LevelDebug
+	case logLevel > 3:
+		slogLevel = -100 // verbose
+	}
+
+	lg := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		AddSource: true,
+		Level:     slogLevel,
+	}))
+	slog.SetDefault(lg.With("component", "NodeAgent"))
+
+	if nodeName == "" {
+		log.Fatal("--node-name or $NODE_NAME is required")
+	}
+	var sensor *regexp.Regexp
+	if tempSensor != "" {
+		var err error
+		if sensor, err = regexp.Compile(tempSensor); err != nil {
+			log.Fatalf("invalid --temp-sensor: %v", err)
+		}
+	}
+
+	var pusher *nodeagent.Pusher
+	if pushAddress != "" {
+		pusher = &nodeagent.Pusher{Address: pushAddress, Client: &http.Client{Timeout: interval}}
+		if pushCredentialsDir != "" {
+			username, err := os.ReadFile(filepath.Join(pushCredentialsDir, "username"))
+			if err != nil {
+				log.Fatalf("unable to read username: %v", err)
+			}
+			password, err := os.ReadFile(filepath.Join(pushCredentialsDir, "password"))
+			if err != nil {
+				log.Fatalf("unable to read password: %v", err)
+			}
+			pusher.Username = strings.TrimSpace(string(username))
+			pusher.Password = strings.TrimSpace(string(password))
+		}
+		for _, m := range strings.Split(pushMetrics, ",") {
+			if m = strings.TrimSpace(m); m != "" {
+				pusher.Metrics = append(pusher.Metrics, metrics.ValueType(m))
+			}
+		}
+	}
+
+	agent := nodeagent.NewAgent(nodeName, sysfsRoot, sensor, interval)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		agent.Run(ctx, pusher)
+	}()
+
+	if bindAddress != "" {
+		srv := &http.Server{Addr: bindAddress, Handler: agent.Handler()}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slog.Info("starting readings endpoint", "addr", bindAddress)
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("readings endpoint stopped", "err", err)
+				stop()
+			}
+		}()
+		go func() {
+			<-ctx.Done()
+			ctx2, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			srv.Shutdown(ctx2)
+		}()
+	}
+
+	slog.Info("node agent started", "node", nodeName, "sysfsRoot", sysfsRoot, "interval", interval, "push", pushAddress != "")
+	wg.Wait()
+}
@@ -0,0 +1,411 @@
+package accuracy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	metricsclientv1beta1 "k8s.io/metrics/pkg/client/clientset/versioned/typed/metrics/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	waov1beta1 "github.com/waok8s/waok8s/wao-core/api/node/v1beta1"
+
+	waoclient "github.com/waok8s/waok8s/wao-metrics-adapter/pkg/client"
+	waometrics "github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
+)
+
+const (
+	DefaultInterval      = time.Minute
+	DefaultWindowSize    = 60
+	DefaultMinSamples    = 10
+	DefaultMAPEThreshold = 10.0
+	DefaultMaxAge        = time.Minute
+)
+
+// Reasons of the ModelDrift condition.
+const (
+	ReasonErrorAboveThreshold  = "ErrorAboveThreshold"
+	ReasonErrorWithinThreshold = "ErrorWithinThreshold"
+)
+
+var (
+	accuracyMAE = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      "wao",
+			Subsystem:      "prediction_accuracy",
+			Name:           "mae_watts",
+			Help:           "Rolling mean absolute error of predicted power consumption against measured power consumption",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"node", "model"},
+	)
+	accuracyMAPE = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      "wao",
+			Subsystem:      "prediction_accuracy",
+			Name:           "mape_percent",
+			Help:           "Rolling mean absolute percentage error of predicted power consumption against measured power consumption",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"node", "model"},
+	)
+	accuracySamples = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      "wao",
+			Subsystem:      "prediction_accuracy",
+			Name:           "samples",
+			Help:           "Number of samples in the rolling window",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"node", "model"},
+	)
+	accuracyModelDrift = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      "wao",
+			Subsystem:      "prediction_accuracy",
+			Name:           "model_drift",
+			Help:           "1 if the rolling error exceeds the threshold, 0 otherwise",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"node", "model"},
+	)
+)
+
+var registerMetricsOnce sync.Once
+
+// RegisterMetrics registers accuracy metrics to the legacy registry, which is served by the custom metrics apiserver.
+// NewTracker calls this.
+func RegisterMetrics() {
+	registerMetricsOnce.Do(func() {
+		legacyregistry.MustRegister(accuracyMAE)
+		legacyregistry.MustRegister(accuracyMAPE)
+		legacyregistry.MustRegister(accuracySamples)
+		legacyregistry.MustRegister(accuracyModelDrift)
+	})
+}
+
+// Options configures Tracker. Zero values are replaced with defaults.
+type Options struct {
+	// Interval is the interval to compare predictions with measured power consumption.
+	Interval time.Duration
+	// WindowSize is the number of latest samples to compute the errors over.
+	WindowSize int
+	// MinSamples is the number of samples required to evaluate the ModelDrift condition.
+	MinSamples int
+	// MAPEThreshold is the mean absolute percentage error in percent above which the model is considered drifted.
+	MAPEThreshold float64
+	// MAEThreshold is the mean absolute error in watts above which the model is considered drifted. Zero disables it.
+	MAEThreshold float64
+	// MaxAge is the max age of measured values to be compared.
+	MaxAge time.Duration
+	// CPUUsagePercent supplies cpu_usage in percent of the capacity instead of cores, which must match the models.
+	// Note that consumers differ in the default, e.g., WAO Scheduler supplies cores and WAO Load Balancer supplies percent.
+	CPUUsagePercent bool
+	// ExtraFeatures are the features supplied in addition to cpu_usage, inlet_temp and delta_p, which must match the models.
+	// predictor.FeatureMemoryUsage is the memory usage in percent of the capacity, and other names are values in the Store,
+	// e.g., predictor.FeaturePowerConsumption. See predictor.ValidateExtraFeatures.
+	ExtraFeatures []string
+}
+
+func (o *Options) defaults() {
+	if o.Interval <= 0 {
+		o.Interval = DefaultInterval
+	}
+	if o.WindowSize <= 0 {
+		o.WindowSize = DefaultWindowSize
+	}
+	if o.MinSamples <= 0 {
+		o.MinSamples = DefaultMinSamples
+	}
+	if o.MinSamples > o.WindowSize {
+		o.MinSamples = o.WindowSize
+	}
+	if o.MAPEThreshold <= 0 {
+		o.MAPEThreshold = DefaultMAPEThreshold
+	}
+	if o.MaxAge <= 0 {
+		o.MaxAge = DefaultMaxAge
+	}
+}
+
+// Sharder decides which NodeConfigs this replica is responsible for.
+// See: sharding.Sharder
+type Sharder interface {
+	// Owns returns true if this replica owns the object with the given key.
+	Owns(key string) bool
+}
+
+// Tracker periodically compares the power consumption predicted for the current CPU usage, inlet temperature and
+// delta pressure of each node with the measured power consumption, and tracks rolling errors per node and model.
+// It exports the errors as metrics and maintains the ModelDrift condition of NodeConfigs.
+// NodeConfigs without a power consumption predictor or measured power consumption are skipped.
+type Tracker struct {
+	client           client.Client
+	metricsclientset metricsclientv1beta1.MetricsV1beta1Interface
+	predictorclient  *waoclient.CachedPredictorClient
+	store            *waometrics.Store
+	opts             Options
+
+	// Sharder is used to run multiple replicas. If set, only NodeConfigs owned by this replica are tracked.
+	// +optional
+	Sharder Sharder
+
+	mu sync.Mutex
+	// nodes holds the state of each node being tracked.
+	nodes map[string]*nodeState
+}
+
+// nodeState is the errors of the current model of a node. The window is reset when the model changes.
+type nodeState struct {
+	model  string
+	window *window
+	// reported is true if the ModelDrift condition of the current model has been written.
+	reported bool
+}
+
+func NewTracker(c client.Client, metricsclientset metricsclientv1beta1.MetricsV1beta1Interface, predictorclient *waoclient.CachedPredictorClient, store *waometrics.Store, opts Options) *Tracker {
+	RegisterMetrics()
+	opts.defaults()
+	return &Tracker{
+		client:           c,
+		metricsclientset: metricsclientset,
+		predictorclient:  predictorclient,
+		store:            store,
+		opts:             opts,
+		nodes:            map[string]*nodeState{},
+	}
+}
+
+// Start runs the tracker until ctx is done. It implements manager.Runnable.
+func (t *Tracker) Start(ctx context.Context) error {
+	ticker := time.NewTicker(t.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			t.Check(ctx)
+		}
+	}
+}
+
+// Check compares predictions with measured power consumption once for all NodeConfigs.
+func (t *Tracker) Check(ctx context.Context) {
+	lg := slog.With("func", "Tracker.Check")
+
+	var ncs waov1beta1.NodeConfigList
+	if err := t.client.List(ctx, &ncs); err != nil {
+		lg.Error("unable to list NodeConfigs", "err", err)
+		return
+	}
+	seen := map[string]struct{}{}
+	for i := range ncs.Items {
+		nc := &ncs.Items[i]
+		if nc.Spec.NodeName == "" || !nc.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if t.Sharder != nil && !t.Sharder.Owns(types.NamespacedName{Namespace: nc.Namespace, Name: nc.Name}.String()) {
+			continue
+		}
+		if nc.Spec.Predictor.PowerConsumption == nil && nc.Spec.Predictor.PowerConsumptionEndpointProvider == nil {
+			continue
+		}
+		seen[nc.Spec.NodeName] = struct{}{}
+		if err := t.check(ctx, nc); err != nil {
+			lg.Warn("unable to check prediction accuracy", "node", nc.Spec.NodeName, "err", err)
+		}
+	}
+
+	// forget nodes that are no longer tracked
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for node, st := range t.nodes {
+		if _, ok := seen[node]; !ok {
+			deleteSeries(node, st.model)
+			delete(t.nodes, node)
+		}
+	}
+}
+
+// check compares a prediction with the measured power consumption of the node, and updates the errors.
+func (t *Tracker) check(ctx context.Context, nc *waov1beta1.NodeConfig) error {
+	lg := slog.With("func", "Tracker.check", "node", nc.Spec.NodeName)
+
+	md, ok := t.store.Get(waometrics.StoreKeyForNode(nc.Spec.NodeName))
+	if !ok {
+		return nil
+	}
+	measured, ok, err := t.freshValue(md, waometrics.ValuePowerConsumption)
+	if !ok {
+		return nil // nodes without measured power consumption are skipped
+	}
+	if err != nil {
+		return err
+	}
+	if measured <= 0 {
+		return fmt.Errorf("measured power consumption is not positive: %v", measured)
+	}
+	features, err := t.features(ctx, nc.Spec.NodeName, md)
+	if err != nil {
+		return err
+	}
+
+	ep, err := t.predictorclient.PowerConsumptionEndpoint(ctx, nc)
+	if err != nil {
+		return fmt.Errorf("unable to get endpoint: %w", err)
+	}
+	predicted, err := t.predictorclient.PredictPowerConsumptionFeatures(ctx, nc.Namespace, ep, features)
+	if err != nil {
+		return fmt.Errorf("unable to predict: %w", err)
+	}
+	model := waoclient.ModelName(ep)
+	lg.Debug("prediction compared", "model", model, "features", features, "predicted", predicted, "measured", measured)
+
+	cond, report := t.record(nc.Spec.NodeName, model, predicted, measured, nc.Generation)
+	if cond == nil {
+		return nil
+	}
+	if existing := meta.FindStatusCondition(nc.Status.Conditions, cond.Type); !report && existing != nil &&
+		existing.Status == cond.Status && existing.Reason == cond.Reason && existing.ObservedGeneration == cond.ObservedGeneration {
+		return nil
+	}
+	patch := client.MergeFrom(nc.DeepCopy())
+	meta.SetStatusCondition(&nc.Status.Conditions, *cond)
+	if err := t.client.Status().Patch(ctx, nc, patch); err != nil {
+		t.unreport(nc.Spec.NodeName, model)
+		return fmt.Errorf("unable to update ModelDrift condition: %w", err)
+	}
+	lg.Info("ModelDrift condition updated", "status", cond.Status, "message", cond.Message)
+	return nil
+}
+
+// freshValue returns the value in md. ok is false if the value has never been stored.
+// err is not nil if the value is older than MaxAge.
+func (t *Tracker) freshValue(md waometrics.MetricData, vt waometrics.ValueType) (v float64, ok bool, err error) {
+	v, timestamp, _, ok := md.Value(vt)
+	if !ok {
+		return 0.0, false, fmt.Errorf("%s is not found", vt)
+	}
+	if timestamp.Add(t.opts.MaxAge).Before(time.Now()) {
+		return 0.0, true, fmt.Errorf("%s is stale: timestamp=%s", vt, timestamp)
+	}
+	return v, true, nil
+}
+
+// features returns cpu_usage, inlet_temp, delta_p and ExtraFeatures of the node.
+// cpu_usage is in cores, or in percent if CPUUsagePercent is set.
+func (t *Tracker) features(ctx context.Context, nodeName string, md waometrics.MetricData) (predictor.Features, error) {
+	features := predictor.Features{}
+	for _, name := range append([]string{predictor.FeatureInletTemp, predictor.FeatureDeltaP}, t.opts.ExtraFeatures...) {
+		if name == predictor.FeatureMemoryUsage {
+			continue // from NodeMetrics
+		}
+		v, _, err := t.freshValue(md, waometrics.ValueType(name))
+		if err != nil {
+			return nil, err
+		}
+		features[name] = v
+	}
+
+	nm, err := t.metricsclientset.NodeMetricses().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get NodeMetrics: %w", err)
+	}
+	features[predictor.FeatureCPUUsage] = nm.Usage.Cpu().AsApproximateFloat64()
+	memoryUsage := slices.Contains(t.opts.ExtraFeatures, predictor.FeatureMemoryUsage)
+	if !t.opts.CPUUsagePercent && !memoryUsage {
+		return features, nil
+	}
+
+	var node corev1.Node
+	if err := t.client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
+		return nil, fmt.Errorf("unable to get Node: %w", err)
+	}
+	if t.opts.CPUUsagePercent {
+		capacity := node.Status.Capacity.Cpu().AsApproximateFloat64()
+		if capacity <= 0 {
+			return nil, fmt.Errorf("CPU capacity of node=%s is unknown", nodeName)
+		}
+		features[predictor.FeatureCPUUsage] = features[predictor.FeatureCPUUsage] / capacity * 100
+	}
+	if memoryUsage {
+		capacity := node.Status.Capacity.Memory().AsApproximateFloat64()
+		if capacity <= 0 {
+			return nil, fmt.Errorf("memory capacity of node=%s is unknown", nodeName)
+		}
+		features[predictor.FeatureMemoryUsage] = nm.Usage.Memory().AsApproximateFloat64() / capacity * 100
+	}
+	return features, nil
+}
+
+// record adds a sample to the window of the node and the model, and updates the metrics.
+// It returns the ModelDrift condition to set, or nil if there are not enough samples yet.
+// report is true if the condition of the model has not been written yet.
+func (t *Tracker) record(node, model string, predicted, measured float64, generation int64) (cond *metav1.Condition, report bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	st, ok := t.nodes[node]
+	if ok && st.model != model {
+		deleteSeries(node, st.model)
+		ok = false
+	}
+	if !ok {
+		st = &nodeState{model: model, window: newWindow(t.opts.WindowSize)}
+		t.nodes[node] = st
+	}
+	st.window.add(predicted, measured)
+
+	mae, mape, n := st.window.mae(), st.window.mape(), st.window.len()
+	accuracyMAE.WithLabelValues(node, model).Set(mae)
+	accuracyMAPE.WithLabelValues(node, model).Set(mape)
+	accuracySamples.WithLabelValues(node, model).Set(float64(n))
+	if n < t.opts.MinSamples {
+		return nil, false
+	}
+
+	drifted := mape > t.opts.MAPEThreshold || (t.opts.MAEThreshold > 0 && mae > t.opts.MAEThreshold)
+	cond = &metav1.Condition{
+		Type:               waov1beta1.ConditionModelDrift,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             ReasonErrorWithinThreshold,
+		Message:            fmt.Sprintf("model=%s MAE=%.1fW MAPE=%.1f%% samples=%d", model, mae, mape, n),
+	}
+	accuracyModelDrift.WithLabelValues(node, model).Set(0)
+	if drifted {
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = ReasonErrorAboveThreshold
+		accuracyModelDrift.WithLabelValues(node, model).Set(1)
+	}
+	report = !st.reported
+	st.reported = true
+	return cond, report
+}
+
+// unreport marks the condition of the model as not written, so that the next check retries.
+func (t *Tracker) unreport(node, model string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if st, ok := t.nodes[node]; ok && st.model == model {
+		st.reported = false
+	}
+}
+
+func deleteSeries(node, model string) {
+	accuracyMAE.DeleteLabelValues(node, model)
+	accuracyMAPE.DeleteLabelValues(node, model)
+	accuracySamples.DeleteLabelValues(node, model)
+	accuracyModelDrift.DeleteLabelValues(node, model)
+}
@@ -0,0 +1,238 @@
+package accuracy
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/component-base/metrics/legacyregistry"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	waov1beta1 "github.com/waok8s/waok8s/wao-core/api/node/v1beta1"
+
+	waoclient "github.com/waok8s/waok8s/wao-metrics-adapter/pkg/client"
+	waometrics "github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
+)
+
+func TestWindow(t *testing.T) {
+	type sample struct{ predicted, measured float64 }
+	tests := []struct {
+		name     string
+		size     int
+		samples  []sample
+		wantLen  int
+		wantMAE  float64
+		wantMAPE float64
+	}{
+		{"empty", 3, nil, 0, math.NaN(), math.NaN()},
+		{"exact", 3, []sample{{100, 100}, {200, 200}}, 2, 0, 0},
+		{"partial", 3, []sample{{110, 100}, {180, 200}}, 2, 15, 10},
+		{"rolled", 2, []sample{{500, 100}, {110, 100}, {90, 100}}, 2, 10, 10},
+		{"rolled_twice", 2, []sample{{500, 100}, {400, 100}, {110, 100}, {130, 100}}, 2, 20, 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newWindow(tt.size)
+			for _, s := range tt.samples {
+				w.add(s.predicted, s.measured)
+			}
+			if got := w.len(); got != tt.wantLen {
+				t.Errorf("len() = %v, want %v", got, tt.wantLen)
+			}
+			if got := w.mae(); !almostEqual(got, tt.wantMAE) {
+				t.Errorf("mae() = %v, want %v", got, tt.wantMAE)
+			}
+			if got := w.mape(); !almostEqual(got, tt.wantMAPE) {
+				t.Errorf("mape() = %v, want %v", got, tt.wantMAPE)
+			}
+		})
+	}
+}
+
+func almostEqual(a, b float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestTracker_record(t *testing.T) {
+	tr := NewTracker(nil, nil, nil, nil, Options{WindowSize: 3, MinSamples: 2, MAPEThreshold: 10})
+
+	// not enough samples
+	if cond, _ := tr.record("node-a", "v1", 100, 100, 1); cond != nil {
+		t.Fatalf("got condition %+v with 1 sample", cond)
+	}
+	// within threshold, reported for the first time
+	cond, report := tr.record("node-a", "v1", 105, 100, 1)
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != ReasonErrorWithinThreshold || !report {
+		t.Fatalf("got (%+v, %v), want ModelDrift=False to be reported", cond, report)
+	}
+	// above threshold: MAPE = (5 + 50) / 3 > 10
+	cond, report = tr.record("node-a", "v1", 150, 100, 1)
+	if cond == nil || cond.Status != metav1.ConditionTrue || cond.Reason != ReasonErrorAboveThreshold || report {
+		t.Fatalf("got (%+v, %v), want ModelDrift=True", cond, report)
+	}
+	if cond.ObservedGeneration != 1 {
+		t.Errorf("ObservedGeneration = %v, want 1", cond.ObservedGeneration)
+	}
+
+	// a new model resets the window
+	if cond, _ := tr.record("node-a", "v2", 100, 100, 2); cond != nil {
+		t.Fatalf("got condition %+v with 1 sample of the new model", cond)
+	}
+	cond, report = tr.record("node-a", "v2", 100, 100, 2)
+	if cond == nil || cond.Status != metav1.ConditionFalse || !report {
+		t.Fatalf("got (%+v, %v), want ModelDrift=False of the new model to be reported", cond, report)
+	}
+
+	// MAEThreshold
+	tr = NewTracker(nil, nil, nil, nil, Options{WindowSize: 1, MinSamples: 1, MAPEThreshold: 10, MAEThreshold: 20})
+	cond, _ = tr.record("node-b", "v1", 1050, 1000, 1) // MAPE = 5%, MAE = 50W
+	if cond == nil || cond.Status != metav1.ConditionTrue {
+		t.Fatalf("got %+v, want ModelDrift=True by MAE", cond)
+	}
+}
+
+// countSeries returns the number of accuracy series of the node.
+func countSeries(t *testing.T, node string) int {
+	mfs, err := legacyregistry.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			for _, lp := range m.GetLabel() {
+				if lp.GetName() == "node" && lp.GetValue() == node && (mf.GetName() == "wao_prediction_accuracy_samples" || mf.GetName() == "wao_prediction_accuracy_mape_percent") {
+					n++
+				}
+			}
+		}
+	}
+	return n
+}
+
+func TestTracker_Check(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	clientgoscheme.AddToScheme(scheme)
+	waov1beta1.AddToScheme(scheme)
+	// predicted = 100 + 10 * cpu_usage + memory_usage = 100 + 10 * 2 + 50 = 170
+	nc := &waov1beta1.NodeConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nc-c", Generation: 1},
+		Spec: waov1beta1.NodeConfigSpec{
+			NodeName: "node-c",
+			Predictor: waov1beta1.Predictor{
+				PowerConsumption: &waov1beta1.EndpointTerm{
+					Type: waov1beta1.TypeLinearModel,
+					LinearModel: &waov1beta1.LinearModelTerm{Intercept: "100", Coefficients: []waov1beta1.LinearCoefficientTerm{
+						{Metric: predictor.FeatureCPUUsage, Coefficient: "10"},
+						{Metric: predictor.FeatureMemoryUsage, Coefficient: "1"},
+					}},
+				},
+			},
+		},
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-c"},
+		Status:     corev1.NodeStatus{Capacity: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4"), corev1.ResourceMemory: resource.MustParse("1000Mi")}},
+	}
+	var patches int
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(nc, node).WithStatusSubresource(nc).
+		WithInterceptorFuncs(interceptor.Funcs{
+			SubResourcePatch: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+				patches++
+				return c.SubResource(subResourceName).Patch(ctx, obj, patch, opts...)
+			},
+		}).Build()
+
+	mc := metricsfake.NewSimpleClientset()
+	mc.PrependReactor("get", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &metricsv1beta1.NodeMetrics{
+			ObjectMeta: metav1.ObjectMeta{Name: action.(k8stesting.GetAction).GetName()},
+			Usage:      corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2"), corev1.ResourceMemory: resource.MustParse("500Mi")},
+		}, nil
+	})
+
+	store := &waometrics.Store{}
+	setMeasured := func(watts float64) {
+		now := time.Now()
+		store.Set(waometrics.StoreKeyForNode("node-c"), waometrics.MetricData{}.
+			WithValue(waometrics.ValueInletTemperature, 22.5, now, nil).
+			WithValue(waometrics.ValueDeltaPressure, 7.5, now, nil).
+			WithValue(waometrics.ValuePowerConsumption, watts, now, nil))
+	}
+	getCondition := func() *metav1.Condition {
+		var got waov1beta1.NodeConfig
+		if err := c.Get(ctx, client.ObjectKeyFromObject(nc), &got); err != nil {
+			t.Fatal(err)
+		}
+		return meta.FindStatusCondition(got.Status.Conditions, waov1beta1.ConditionModelDrift)
+	}
+
+	pc := waoclient.NewCachedPredictorClient(kubefake.NewSimpleClientset(), time.Minute, waoclient.DefaultPredictorCacheSize)
+	tr := NewTracker(c, mc.MetricsV1beta1(), pc, store, Options{WindowSize: 3, MinSamples: 2, MAPEThreshold: 10, ExtraFeatures: []string{predictor.FeatureMemoryUsage}})
+
+	// not enough samples
+	setMeasured(170)
+	tr.Check(ctx)
+	if cond := getCondition(); cond != nil {
+		t.Fatalf("got condition %+v with 1 sample", cond)
+	}
+
+	// within threshold
+	tr.Check(ctx)
+	if cond := getCondition(); cond == nil || cond.Status != metav1.ConditionFalse {
+		t.Fatalf("got condition %+v, want ModelDrift=False", cond)
+	}
+	if patches != 1 {
+		t.Errorf("patches = %d, want 1", patches)
+	}
+
+	// unchanged conditions are not patched again
+	tr.Check(ctx)
+	if patches != 1 {
+		t.Errorf("patches = %d after an unchanged check, want 1", patches)
+	}
+
+	// above threshold: MAPE = (0 + 0 + 50) / 3 > 10
+	setMeasured(340)
+	tr.Check(ctx)
+	if cond := getCondition(); cond == nil || cond.Status != metav1.ConditionTrue {
+		t.Fatalf("got condition %+v, want ModelDrift=True", cond)
+	}
+	if patches != 2 {
+		t.Errorf("patches = %d, want 2", patches)
+	}
+	if n := countSeries(t, "node-c"); n != 2 {
+		t.Fatalf("series = %d, want 2", n)
+	}
+
+	// series of removed NodeConfigs are deleted
+	if err := c.Delete(ctx, nc); err != nil {
+		t.Fatal(err)
+	}
+	tr.Check(ctx)
+	if n := countSeries(t, "node-c"); n != 0 {
+		t.Errorf("series = %d after the NodeConfig is removed, want 0", n)
+	}
+	if len(tr.nodes) != 0 {
+		t.Errorf("nodes = %v after the NodeConfig is removed, want empty", tr.nodes)
+	}
+}
@@ -0,0 +1,51 @@
+package accuracy
+
+import "math"
+
+// window is a rolling window of the latest prediction errors.
+type window struct {
+	absErrs    []float64 // |predicted - measured| in watts
+	absPctErrs []float64 // |predicted - measured| / measured * 100
+	next       int
+}
+
+func newWindow(size int) *window {
+	return &window{
+		absErrs:    make([]float64, 0, size),
+		absPctErrs: make([]float64, 0, size),
+	}
+}
+
+// add records a sample, and drops the oldest one if the window is full. measured must be positive.
+func (w *window) add(predicted, measured float64) {
+	absErr := math.Abs(predicted - measured)
+	absPctErr := absErr / measured * 100
+	if len(w.absErrs) < cap(w.absErrs) {
+		w.absErrs = append(w.absErrs, absErr)
+		w.absPctErrs = append(w.absPctErrs, absPctErr)
+		return
+	}
+	w.absErrs[w.next] = absErr
+	w.absPctErrs[w.next] = absPctErr
+	w.next = (w.next + 1) % len(w.absErrs)
+}
+
+// len returns the number of samples in the window.
+func (w *window) len() int { return len(w.absErrs) }
+
+// mae returns the mean absolute error in watts, or NaN if the window is empty.
+func (w *window) mae() float64 { return mean(w.absErrs) }
+
+// mape returns the mean absolute percentage error in percent, or NaN if the window is empty.
+func (w *window) mape() float64 { return mean(w.absPctErrs) }
+
+func mean(vs []float64) float64 {
+	if len(vs) == 0 {
+		return math.NaN()
+	}
+	sum := 0.0
+	for _, v := range vs {
+		sum += v
+	}
+	return sum / float64(len(vs))
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
+)
+
+const (
+	// DefaultBatchWindow is the default time to wait for concurrent predictions to coalesce into a batch.
+	// Zero disables coalescing, as the window adds latency to every prediction. Consumers opt in with a positive window.
+	DefaultBatchWindow = time.Duration(0)
+	// DefaultMaxBatchSize is the default max number of inputs in a batch.
+	DefaultMaxBatchSize = 64
+
+	// maxBatchers is the max number of endpoints CachedPredictorClient keeps batchers for.
+	// The least recently used batcher is dropped, and its pending batch is still sent.
+	// Batchers are also dropped once they have no pending batch, so only endpoints being predicted have one.
+	maxBatchers = 1024
+
+	// batchPredictTimeout limits a batch request, which is shared by callers and so does not use their contexts.
+	batchPredictTimeout = 10 * time.Second
+)
+
+// powerConsumptionBatcher coalesces concurrent predictions to the same predictor into batches.
+// Predictors that implement predictor.FeaturePowerConsumptionPredictor or predictor.BatchPowerConsumptionPredictor
+// get a single request per batch, and others get a request per input.
+type powerConsumptionBatcher struct {
+	// newPredictor is called for each batch, so that Secret updates are applied.
+	newPredictor func() (predictor.PowerConsumptionPredictor, error)
+	window       time.Duration
+	maxSize      int
+	// onIdle is called after a batch is sent if no inputs are pending, e.g., to drop the batcher. Optional.
+	onIdle func()
+
+	mu      sync.Mutex
+	pending []*batchCall
+	timer   *time.Timer
+}
+
+type batchCall struct {
+	input predictor.Features
+	watt  float64
+	err   error
+	done  chan struct{}
+}
+
+func newPowerConsumptionBatcher(newPredictor func() (predictor.PowerConsumptionPredictor, error), window time.Duration, maxSize int) *powerConsumptionBatcher {
+	return &powerConsumptionBatcher{
+		newPredictor: newPredictor,
+		window:       window,
+		maxSize:      max(maxSize, 1),
+	}
+}
+
+// Predict adds the input to the pending batch and waits for the result.
+// The batch is sent when the window has passed since the first input, or when it reaches maxSize.
+func (b *powerConsumptionBatcher) Predict(ctx context.Context, input predictor.Features) (watt float64, err error) {
+	call := &batchCall{input: input, done: make(chan struct{})}
+
+	b.mu.Lock()
+	b.pending = append(b.pending, call)
+	switch {
+	case len(b.pending) >= b.maxSize:
+		go b.flush(b.take())
+	case len(b.pending) == 1:
+		b.timer = time.AfterFunc(b.window, func() {
+			b.mu.Lock()
+			calls := b.take()
+			b.mu.Unlock()
+			b.flush(calls)
+		})
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return 0.0, ctx.Err()
+	case <-call.done:
+		return call.watt, call.err
+	}
+}
+
+// take removes and returns the pending calls. b.mu must be held.
+func (b *powerConsumptionBatcher) take() []*batchCall {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	calls := b.pending
+	b.pending = nil
+	return calls
+}
+
+func (b *powerConsumptionBatcher) flush(calls []*batchCall) {
+	if len(calls) == 0 {
+		return
+	}
+	lg := slog.With("func", "powerConsumptionBatcher.flush", "size", len(calls))
+
+	inputs := make([]predictor.Features, len(calls))
+	for i, c := range calls {
+		inputs[i] = c.input
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), batchPredictTimeout)
+	defer cancel()
+	watts, err := b.predict(ctx, inputs)
+	if err != nil {
+		lg.Error("unable to predict batch", "err", err)
+	} else {
+		lg.Debug("predicted batch")
+	}
+
+	// drop the batcher before returning results, so that callers see no batcher left after the batch
+	b.mu.Lock()
+	idle := len(b.pending) == 0
+	b.mu.Unlock()
+	if idle && b.onIdle != nil {
+		b.onIdle()
+	}
+
+	for i, c := range calls {
+		if err != nil {
+			c.err = err
+		} else {
+			c.watt = watts[i]
+		}
+		close(c.done)
+	}
+
+}
+
+func (b *powerConsumptionBatcher) predict(ctx context.Context, inputs []predictor.Features) ([]float64, error) {
+	pred, err := b.newPredictor()
+	if err != nil {
+		return nil, err
+	}
+	watts, err := predictor.AsFeaturePowerConsumptionPredictor(pred).PredictFeatures(ctx, inputs)
+	if err != nil {
+		return nil, err
+	}
+	if len(watts) != len(inputs) {
+		return nil, fmt.Errorf("got %d predictions for %d inputs", len(watts), len(inputs))
+	}
+	return watts, nil
+}
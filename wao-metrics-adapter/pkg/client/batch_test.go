@@ -0,0 +1,165 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	waov1beta1 "github.com/waok8s/waok8s/wao-core/api/node/v1beta1"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor/fake"
+)
+
+type testBatchPredictor struct {
+	*fake.FakePowerConsumptionPredictor
+	requests atomic.Int32
+	err      error
+}
+
+func (p *testBatchPredictor) PredictFeatures(ctx context.Context, features []predictor.Features) ([]float64, error) {
+	p.requests.Add(1)
+	if p.err != nil {
+		return nil, p.err
+	}
+	watts := make([]float64, len(features))
+	for i, f := range features {
+		watts[i] = f[predictor.FeatureCPUUsage] * 2
+	}
+	return watts, nil
+}
+
+func TestPowerConsumptionBatcher(t *testing.T) {
+	tests := []struct {
+		name         string
+		n            int
+		maxSize      int
+		err          error
+		wantRequests int32
+	}{
+		{name: "coalesce", n: 10, maxSize: 64, wantRequests: 1},
+		{name: "max_size", n: 10, maxSize: 5, wantRequests: 2},
+		{name: "error", n: 3, maxSize: 64, err: errors.New("down"), wantRequests: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pred := &testBatchPredictor{err: tt.err}
+			b := newPowerConsumptionBatcher(func() (predictor.PowerConsumptionPredictor, error) { return pred, nil }, 50*time.Millisecond, tt.maxSize)
+
+			var wg sync.WaitGroup
+			for i := range tt.n {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					watt, err := b.Predict(context.Background(), predictor.Features{predictor.FeatureCPUUsage: float64(i)})
+					if (err != nil) != (tt.err != nil) {
+						t.Errorf("Predict(%d) error = %v, want %v", i, err, tt.err)
+					}
+					if err == nil && watt != float64(i)*2 {
+						t.Errorf("Predict(%d) = %v, want %v", i, watt, float64(i)*2)
+					}
+				}()
+			}
+			wg.Wait()
+			if got := pred.requests.Load(); got != tt.wantRequests {
+				t.Errorf("got %d requests, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+// singlePredictor hides PredictBatch and PredictFeatures of the embedded predictor.
+type singlePredictor struct {
+	predictor.PowerConsumptionPredictor
+}
+
+func TestPowerConsumptionBatcher_nonBatchPredictor(t *testing.T) {
+	pred := fake.NewPowerConsumptionPredictor("", nil, 3.14, nil, 0)
+	b := newPowerConsumptionBatcher(func() (predictor.PowerConsumptionPredictor, error) { return singlePredictor{pred}, nil }, time.Millisecond, 64)
+	watt, err := b.Predict(context.Background(), predictor.PowerConsumptionInput{CPUUsage: 1}.Features())
+	if err != nil || watt != 3.14 {
+		t.Errorf("Predict() = %v, %v, want 3.14", watt, err)
+	}
+	if _, err := b.Predict(context.Background(), predictor.Features{predictor.FeatureCPUUsage: 1}); err == nil {
+		t.Error("Predict() without inlet_temp and delta_p got no error")
+	}
+}
+
+func TestCachedPredictorClient_batchers(t *testing.T) {
+	ep := &waov1beta1.EndpointTerm{Type: waov1beta1.TypeFake, Endpoint: "formula?expr=100+cpu_usage"}
+	features := predictor.Features{predictor.FeatureCPUUsage: 10, predictor.FeatureInletTemp: 20, predictor.FeatureDeltaP: 5}
+
+	// disabled by default
+	c := NewCachedPredictorClient(nil, time.Minute, DefaultPredictorCacheSize)
+	if watt, err := c.predictPowerConsumption(context.Background(), "default", ep, features); err != nil || watt != 110 {
+		t.Fatalf("predictPowerConsumption() = %v, %v, want 110", watt, err)
+	}
+	if n := c.batchers.Len(); n != 0 {
+		t.Errorf("got %d batchers without BatchWindow, want 0", n)
+	}
+
+	// batchers are dropped once their batches are sent
+	c.BatchWindow = time.Millisecond
+	if watt, err := c.predictPowerConsumption(context.Background(), "default", ep, features); err != nil || watt != 110 {
+		t.Fatalf("predictPowerConsumption() = %v, %v, want 110", watt, err)
+	}
+	if n := c.batchers.Len(); n != 0 {
+		t.Errorf("got %d batchers after the batch is sent, want 0", n)
+	}
+}
+
+func TestCachedPredictorClient_PredictPowerConsumptionBatch(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"inputs": [{"name": "predict-prob", "datatype": "FP32", "shape": [-1, 3]}], "outputs": [{"name": "predict", "datatype": "FP64", "shape": [-1, 1]}]}`))
+			return
+		}
+		requests.Add(1)
+		var req struct {
+			Inputs []struct {
+				Data [][]float64 `json:"data"`
+			} `json:"inputs"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Inputs) != 1 {
+			http.Error(w, "unexpected inputs", http.StatusBadRequest)
+			return
+		}
+		var watts []float64
+		for _, row := range req.Inputs[0].Data {
+			watts = append(watts, 100+row[0])
+		}
+		json.NewEncoder(w).Encode(map[string]any{"outputs": []map[string]any{{"name": "predict", "data": watts}}})
+	}))
+	defer srv.Close()
+
+	ep := &waov1beta1.EndpointTerm{Type: waov1beta1.TypeV2InferenceProtocol, Endpoint: srv.URL + "/v2/models/power/infer"}
+	inputs := []predictor.PowerConsumptionInput{{CPUUsage: 10}, {CPUUsage: 20}, {CPUUsage: 10}}
+
+	// default config, so the batch is not coalesced with others but still sent in a single request
+	c := NewCachedPredictorClient(nil, time.Minute, DefaultPredictorCacheSize)
+	for _, tt := range []struct {
+		inputs       []predictor.PowerConsumptionInput
+		want         []float64
+		wantRequests int32
+	}{
+		{inputs: inputs, want: []float64{110, 120, 110}, wantRequests: 1},
+		{inputs: inputs, want: []float64{110, 120, 110}, wantRequests: 1}, // cached
+		{inputs: append(inputs, predictor.PowerConsumptionInput{CPUUsage: 30}, predictor.PowerConsumptionInput{CPUUsage: 40}), want: []float64{110, 120, 110, 130, 140}, wantRequests: 2},
+	} {
+		got, err := c.PredictPowerConsumptionBatch(context.Background(), "default", ep, tt.inputs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, tt.want) || requests.Load() != tt.wantRequests {
+			t.Errorf("PredictPowerConsumptionBatch() = %v with %d requests in total, want %v with %d", got, requests.Load(), tt.want, tt.wantRequests)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package client
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
+)
+
+const (
+	// DefaultMetricsCacheSize is the default max number of entries of CachedMetricsClient.
+	DefaultMetricsCacheSize = 10000
+	// DefaultPredictorCacheSize is the default max number of entries of CachedPredictorClient.
+	DefaultPredictorCacheSize = 10000
+)
+
+const (
+	cacheNameMetrics   = "metrics"
+	cacheNamePredictor = "predictor"
+
+	// cacheLoadTimeout limits a load shared by callers, which does not use their deadlines.
+	cacheLoadTimeout = 30 * time.Second
+)
+
+var (
+	cacheHitsTotal = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      "wao",
+			Subsystem:      "client_cache",
+			Name:           "hits_total",
+			Help:           "Cumulative number of cache hits",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"cache"},
+	)
+	cacheMissesTotal = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      "wao",
+			Subsystem:      "client_cache",
+			Name:           "misses_total",
+			Help:           "Cumulative number of cache misses, including expired entries",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"cache"},
+	)
+	cacheEvictionsTotal = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      "wao",
+			Subsystem:      "client_cache",
+			Name:           "evictions_total",
+			Help:           "Cumulative number of entries evicted as the cache is full",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"cache"},
+	)
+	cacheEntries = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      "wao",
+			Subsystem:      "client_cache",
+			Name:           "entries",
+			Help:           "Current number of cache entries",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"cache"},
+	)
+)
+
+var registerMetricsOnce sync.Once
+
+// RegisterMetrics registers cache, prediction and calibration metrics to the legacy registry, which is served by kube-scheduler and kube-proxy.
+// Clients call this on creation.
+func RegisterMetrics() {
+	registerMetricsOnce.Do(func() {
+		legacyregistry.MustRegister(cacheHitsTotal)
+		legacyregistry.MustRegister(cacheMissesTotal)
+		legacyregistry.MustRegister(cacheEvictionsTotal)
+		legacyregistry.MustRegister(cacheEntries)
+		legacyregistry.MustRegister(predictionSanityCheckFailuresTotal)
+		legacyregistry.MustRegister(predictionFallbacksTotal)
+		legacyregistry.MustRegister(calibrationScale)
+		legacyregistry.MustRegister(calibrationOffset)
+		legacyregistry.MustRegister(calibrationActive)
+	})
+}
+
+// lruCache is a size-bounded LRU cache with TTL. Concurrent loads of the same key are de-duplicated.
+type lruCache[V any] struct {
+	// name is the label of metrics.
+	name       string
+	ttl        time.Duration
+	maxEntries int
+
+	mu    sync.Mutex
+	ll    *list.List // front is the most recently used
+	items map[string]*list.Element
+
+	group singleflight.Group
+}
+
+type lruEntry[V any] struct {
+	key       string
+	value     V
+	expiredAt time.Time
+}
+
+// newLRUCache inits a cache. maxEntries <= 0 means unbounded.
+func newLRUCache[V any](name string, ttl time.Duration, maxEntries int) *lruCache[V] {
+	return &lruCache[V]{
+		name:       name,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+// get returns the value if it exists and has not expired. Expired entries are removed.
+func (c *lruCache[V]) get(key string) (v V, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return v, false
+	}
+	ent := e.Value.(*lruEntry[V])
+	if !ent.expiredAt.After(time.Now()) {
+		c.removeElement(e)
+		return v, false
+	}
+	c.ll.MoveToFront(e)
+	return ent.value, true
+}
+
+// add adds or replaces the value, and evicts the least recently used entries if the cache is full.
+func (c *lruCache[V]) add(key string, v V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ent := &lruEntry[V]{key: key, value: v, expiredAt: time.Now().Add(c.ttl)}
+	if e, ok := c.items[key]; ok {
+		e.Value = ent
+		c.ll.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.ll.PushFront(ent)
+	for c.maxEntries > 0 && c.ll.Len() > c.maxEntries {
+		c.removeElement(c.ll.Back())
+		cacheEvictionsTotal.WithLabelValues(c.name).Inc()
+	}
+	cacheEntries.WithLabelValues(c.name).Set(float64(c.ll.Len()))
+}
+
+// removeElement removes the entry. c.mu must be held.
+func (c *lruCache[V]) removeElement(e *list.Element) {
+	c.ll.Remove(e)
+	delete(c.items, e.Value.(*lruEntry[V]).key)
+	cacheEntries.WithLabelValues(c.name).Set(float64(c.ll.Len()))
+}
+
+// len returns the number of entries including expired ones.
+func (c *lruCache[V]) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// getOrLoad returns the cached value, or calls load and caches the result.
+// Concurrent calls for the same key share a single load, which runs with the values of the first caller's context
+// but is not canceled with it, so that a canceled caller does not fail the others. It is limited by cacheLoadTimeout instead.
+// Errors are not cached.
+func (c *lruCache[V]) getOrLoad(ctx context.Context, key string, load func(ctx context.Context) (V, error)) (V, error) {
+	if v, ok := c.get(key); ok {
+		cacheHitsTotal.WithLabelValues(c.name).Inc()
+		return v, nil
+	}
+	cacheMissesTotal.WithLabelValues(c.name).Inc()
+
+	ch := c.group.DoChan(key, func() (any, error) {
+		// The previous load may have finished between get and DoChan.
+		if v, ok := c.get(key); ok {
+			return v, nil
+		}
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cacheLoadTimeout)
+		defer cancel()
+		v, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.add(key, v)
+		return v, nil
+	})
+	select {
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			var zero V
+			return zero, res.Err
+		}
+		return res.Val.(V), nil
+	}
+}
+
+// DefaultQuantization returns steps to quantize cpu_usage and inlet_temp to 0.1 (i.e., 0.1 core or 0.1 percent, and 0.1 °C).
+func DefaultQuantization() map[string]float64 {
+	return map[string]float64{
+		predictor.FeatureCPUUsage:  0.1,
+		predictor.FeatureInletTemp: 0.1,
+	}
+}
+
+// quantize returns a copy of features with each value rounded to the nearest multiple of its step.
+// Features without a positive step are kept as is.
+func quantize(features predictor.Features, steps map[string]float64) predictor.Features {
+	if features == nil || len(steps) == 0 {
+		return features
+	}
+	out := make(predictor.Features, len(features))
+	for k, v := range features {
+		if step := steps[k]; step > 0 {
+			v = math.Round(v/step) * step
+		}
+		out[k] = v
+	}
+	return out
+}
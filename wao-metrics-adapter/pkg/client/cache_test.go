@@ -0,0 +1,148 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
+)
+
+func TestLRUCache_Evict(t *testing.T) {
+	c := newLRUCache[int]("test", time.Minute, 2)
+	c.add("a", 1)
+	c.add("b", 2)
+	if _, ok := c.get("a"); !ok { // a is the most recently used
+		t.Fatalf("a not found")
+	}
+	c.add("c", 3) // evicts b
+	if _, ok := c.get("b"); ok {
+		t.Errorf("b not evicted")
+	}
+	for k, want := range map[string]int{"a": 1, "c": 3} {
+		if got, ok := c.get(k); !ok || got != want {
+			t.Errorf("get(%s) = (%v, %v), want (%v, true)", k, got, ok, want)
+		}
+	}
+	if got := c.len(); got != 2 {
+		t.Errorf("len() = %v, want 2", got)
+	}
+}
+
+func TestLRUCache_Expire(t *testing.T) {
+	c := newLRUCache[int]("test", 10*time.Millisecond, 0)
+	c.add("a", 1)
+	if _, ok := c.get("a"); !ok {
+		t.Fatalf("a not found")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := c.get("a"); ok {
+		t.Errorf("a not expired")
+	}
+	if got := c.len(); got != 0 {
+		t.Errorf("len() = %v, want 0", got)
+	}
+}
+
+func TestLRUCache_GetOrLoad(t *testing.T) {
+	c := newLRUCache[int]("test", time.Minute, 0)
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	load := func(context.Context) (int, error) {
+		calls.Add(1)
+		<-release
+		return 42, nil
+	}
+	const n = 10
+	var wg sync.WaitGroup
+	vs := make([]int, n)
+	errs := make([]error, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			vs[i], errs[i] = c.getOrLoad(context.Background(), "a", load)
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	for i := range n {
+		if errs[i] != nil || vs[i] != 42 {
+			t.Errorf("[%d] got (%v, %v), want (42, nil)", i, vs[i], errs[i])
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("load called %d times, want 1", got)
+	}
+
+	// errors are not cached
+	errLoad := errors.New("load error")
+	if _, err := c.getOrLoad(context.Background(), "b", func(context.Context) (int, error) { return 0, errLoad }); !errors.Is(err, errLoad) {
+		t.Errorf("err = %v, want %v", err, errLoad)
+	}
+	if v, err := c.getOrLoad(context.Background(), "b", func(context.Context) (int, error) { return 1, nil }); err != nil || v != 1 {
+		t.Errorf("got (%v, %v), want (1, nil)", v, err)
+	}
+
+	// the first caller being canceled does not cancel the shared load
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	release = make(chan struct{})
+	loadCtx := func(ctx context.Context) (int, error) {
+		close(started)
+		<-release
+		return 7, ctx.Err()
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := c.getOrLoad(ctx, "c", loadCtx)
+		errCh <- err
+	}()
+	<-started
+	vCh := make(chan int, 1)
+	go func() {
+		v, _ := c.getOrLoad(context.Background(), "c", loadCtx)
+		vCh <- v
+	}()
+	cancel()
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Errorf("err of the canceled caller = %v, want %v", err, context.Canceled)
+	}
+	time.Sleep(10 * time.Millisecond) // the second caller joins the load
+	close(release)
+	if v := <-vCh; v != 7 {
+		t.Errorf("got %v from the shared load, want 7", v)
+	}
+}
+
+func TestQuantize(t *testing.T) {
+	tests := []struct {
+		name     string
+		features predictor.Features
+		steps    map[string]float64
+		want     predictor.Features
+	}{
+		{"nil", nil, DefaultQuantization(), nil},
+		{"no_steps", predictor.Features{"cpu_usage": 1.234}, nil, predictor.Features{"cpu_usage": 1.234}},
+		{"default",
+			predictor.Features{"cpu_usage": 1.26, "inlet_temp": 22.44, "delta_p": 7.55},
+			DefaultQuantization(),
+			predictor.Features{"cpu_usage": 1.3, "inlet_temp": 22.400000000000002, "delta_p": 7.55}},
+		{"zero_step", predictor.Features{"cpu_usage": 1.26}, map[string]float64{"cpu_usage": 0}, predictor.Features{"cpu_usage": 1.26}},
+		{"large_step", predictor.Features{"cpu_usage": 37}, map[string]float64{"cpu_usage": 5}, predictor.Features{"cpu_usage": 35}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := quantize(tt.features, tt.steps)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("quantize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
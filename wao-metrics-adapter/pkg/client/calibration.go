@@ -0,0 +1,409 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/component-base/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	waov1beta1 "github.com/waok8s/waok8s/wao-core/api/node/v1beta1"
+
+	waometrics "github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
+)
+
+const (
+	DefaultCalibrationInterval         = time.Minute
+	DefaultCalibrationForgettingFactor = 0.98
+	DefaultCalibrationMinScale         = 0.5
+	DefaultCalibrationMaxScale         = 2.0
+	DefaultCalibrationMaxOffset        = 100.0
+	DefaultCalibrationMinSamples       = 5
+	DefaultCalibrationMaxAge           = 5 * time.Minute
+
+	// calibrationInitialScaleVariance and calibrationInitialOffsetVariance are the initial covariance of the RLS,
+	// i.e., how far the scale and the offset (in watts) may move from 1 and 0 at first.
+	// The covariance is kept below them so that it does not wind up while the input is not exciting.
+	calibrationInitialScaleVariance  = 0.01
+	calibrationInitialOffsetVariance = 100.0
+)
+
+var (
+	calibrationScale = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      "wao",
+			Subsystem:      "client_calibration",
+			Name:           "scale",
+			Help:           "Scale applied to predicted power consumption of the node",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"node"},
+	)
+	calibrationOffset = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      "wao",
+			Subsystem:      "client_calibration",
+			Name:           "offset_watts",
+			Help:           "Offset added to predicted power consumption of the node",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"node"},
+	)
+	calibrationActive = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      "wao",
+			Subsystem:      "client_calibration",
+			Name:           "active",
+			Help:           "1 if predictions of the node are calibrated, 0 if calibration is disabled as there are not enough or only stale measurements",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"node"},
+	)
+)
+
+// CalibrationState is the online fit of measured = Scale * predicted + Offset of a node and its model,
+// which is updated by recursive least squares (RLS).
+type CalibrationState struct {
+	// Model is the model the fit is for. See ModelName.
+	Model  string  `json:"model"`
+	Scale  float64 `json:"scale"`
+	Offset float64 `json:"offset"`
+	// P is the covariance of (Scale, Offset).
+	P [2][2]float64 `json:"p"`
+	// Samples is the number of observations.
+	Samples int `json:"samples"`
+	// MeasuredAt is the timestamp of the last observed measurement.
+	MeasuredAt time.Time `json:"measuredAt"`
+}
+
+func newCalibrationState(model string) *CalibrationState {
+	return &CalibrationState{
+		Model: model,
+		Scale: 1.0,
+		P:     [2][2]float64{{calibrationInitialScaleVariance, 0}, {0, calibrationInitialOffsetVariance}},
+	}
+}
+
+// update updates the fit with an observation by RLS with forgetting factor lambda in (0, 1].
+func (s *CalibrationState) update(predicted, measured, lambda float64) {
+	x := [2]float64{predicted, 1}
+	// px = P x
+	px := [2]float64{s.P[0][0]*x[0] + s.P[0][1]*x[1], s.P[1][0]*x[0] + s.P[1][1]*x[1]}
+	denom := lambda + x[0]*px[0] + x[1]*px[1]
+	k := [2]float64{px[0] / denom, px[1] / denom}
+	e := measured - (s.Scale*x[0] + s.Offset*x[1])
+	s.Scale += k[0] * e
+	s.Offset += k[1] * e
+	// P = (P - k (P x)^T) / lambda, as P is symmetric
+	for i := range 2 {
+		for j := range 2 {
+			s.P[i][j] = (s.P[i][j] - k[i]*px[j]) / lambda
+		}
+	}
+	// limit the covariance to the initial one to avoid wind-up
+	if f := max(s.P[0][0]/calibrationInitialScaleVariance, s.P[1][1]/calibrationInitialOffsetVariance); f > 1 {
+		for i := range 2 {
+			for j := range 2 {
+				s.P[i][j] /= f
+			}
+		}
+	}
+	s.Samples++
+}
+
+type CalibrationOptions struct {
+	// Interval is the interval to observe measured power consumption of all NodeConfigs.
+	Interval time.Duration
+	// ForgettingFactor in (0, 1] weighs recent observations, e.g., 0.98 halves the weight of an observation in ~34 observations.
+	ForgettingFactor float64
+	// MinScale and MaxScale bound the applied scale.
+	MinScale float64
+	MaxScale float64
+	// MaxOffset bounds the absolute value of the applied offset in watts.
+	MaxOffset float64
+	// MinSamples is the number of observations required to apply calibration.
+	MinSamples int
+	// MaxAge disables calibration of a node if its last observed measurement is older than this.
+	MaxAge time.Duration
+	// CPUUsagePercent makes cpu_usage [0.0, 100.0] instead of [0.0, NumLogicalCores].
+	CPUUsagePercent bool
+	// ExtraFeatures are the features supplied in addition to cpu_usage, inlet_temp and delta_p. See CachedMetricsClient.GetNodeFeatures.
+	ExtraFeatures []string
+	// Backend persists the states across restarts. Nil disables persistence.
+	Backend waometrics.SnapshotBackend
+}
+
+// Calibrator corrects power consumption predictions of each node with a fit between predicted and measured power consumption,
+// so that absolute watts follow the measurement even if the model has drifted.
+// Nodes without measured power consumption (i.e., the power_consumption custom metric) are not calibrated.
+type Calibrator struct {
+	reader          client.Reader
+	metricsclient   *CachedMetricsClient
+	predictorclient *CachedPredictorClient
+	opts            CalibrationOptions
+
+	mu sync.Mutex
+	// states holds the state of each node name. A state is reset when the model of the node changes.
+	states map[string]*CalibrationState
+}
+
+// NewCalibrator inits the calibrator. reader is used to get NodeConfigs and Nodes.
+// Set it to CachedPredictorClient.Calibrator to apply, and call Run to observe periodically.
+func NewCalibrator(reader client.Reader, metricsclient *CachedMetricsClient, predictorclient *CachedPredictorClient, opts CalibrationOptions) *Calibrator {
+	RegisterMetrics()
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultCalibrationInterval
+	}
+	if opts.ForgettingFactor <= 0 || opts.ForgettingFactor > 1 {
+		opts.ForgettingFactor = DefaultCalibrationForgettingFactor
+	}
+	if opts.MinScale <= 0 {
+		opts.MinScale = DefaultCalibrationMinScale
+	}
+	if opts.MaxScale <= 0 {
+		opts.MaxScale = DefaultCalibrationMaxScale
+	}
+	if opts.MaxOffset <= 0 {
+		opts.MaxOffset = DefaultCalibrationMaxOffset
+	}
+	if opts.MinSamples <= 0 {
+		opts.MinSamples = DefaultCalibrationMinSamples
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = DefaultCalibrationMaxAge
+	}
+	return &Calibrator{
+		reader:          reader,
+		metricsclient:   metricsclient,
+		predictorclient: predictorclient,
+		opts:            opts,
+		states:          map[string]*CalibrationState{},
+	}
+}
+
+// Run restores the states, and then observes all NodeConfigs and saves the states every Interval until ctx is done.
+func (c *Calibrator) Run(ctx context.Context) {
+	lg := slog.With("func", "Calibrator.Run")
+
+	if err := c.Restore(ctx); err != nil {
+		lg.Error("unable to restore calibration states, so start from scratch", "err", err)
+	}
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if err := c.ObserveAll(ctx); err != nil {
+			lg.Error("unable to observe", "err", err)
+		}
+		if err := c.Save(ctx); err != nil {
+			lg.Error("unable to save calibration states", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ObserveAll observes all NodeConfigs. Errors of each node are logged and skipped.
+// States and metrics of nodes without NodeConfigs are removed.
+func (c *Calibrator) ObserveAll(ctx context.Context) error {
+	lg := slog.With("func", "Calibrator.ObserveAll")
+
+	var ncs waov1beta1.NodeConfigList
+	if err := c.reader.List(ctx, &ncs); err != nil {
+		return fmt.Errorf("unable to list NodeConfigs: %w", err)
+	}
+	seen := map[string]struct{}{}
+	for i := range ncs.Items {
+		nc := &ncs.Items[i]
+		if nc.Spec.Predictor.PowerConsumption == nil && nc.Spec.Predictor.PowerConsumptionEndpointProvider == nil {
+			continue
+		}
+		seen[nc.Spec.NodeName] = struct{}{}
+		if err := c.observe(ctx, nc); err != nil {
+			lg.Debug("unable to observe", "nodeconfig", client.ObjectKeyFromObject(nc), "node", nc.Spec.NodeName, "err", err)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for nodeName := range c.states {
+		if _, ok := seen[nodeName]; !ok {
+			delete(c.states, nodeName)
+			deleteCalibrationSeries(nodeName)
+		}
+	}
+	return nil
+}
+
+// observe predicts power consumption of the node at the current features without calibration,
+// and updates the state with the measured power consumption.
+func (c *Calibrator) observe(ctx context.Context, nc *waov1beta1.NodeConfig) error {
+	lg := slog.With("func", "Calibrator.observe", "node", nc.Spec.NodeName)
+
+	mv, err := c.metricsclient.GetCustomMetricForNode(ctx, nc.Spec.NodeName, waometrics.ValuePowerConsumption)
+	if err != nil {
+		return fmt.Errorf("unable to get measured power consumption: %w", err)
+	}
+	if mv == nil {
+		return fmt.Errorf("measured power consumption not found")
+	}
+	measured, measuredAt := mv.Value.AsApproximateFloat64(), mv.Timestamp.Time
+	ep, err := c.predictorclient.PowerConsumptionEndpoint(ctx, nc)
+	if err != nil {
+		return fmt.Errorf("unable to get endpoint: %w", err)
+	}
+	model := ModelName(ep)
+	if s, ok := c.State(nc.Spec.NodeName); ok && s.Model == model && !measuredAt.After(s.MeasuredAt) {
+		return nil // already observed
+	}
+	if measured <= 0 || math.IsNaN(measured) || math.IsInf(measured, 0) {
+		return fmt.Errorf("invalid measured power consumption: %v", measured)
+	}
+
+	var node corev1.Node
+	if err := c.reader.Get(ctx, types.NamespacedName{Name: nc.Spec.NodeName}, &node); err != nil {
+		return fmt.Errorf("unable to get Node: %w", err)
+	}
+	nodeMetrics, err := c.metricsclient.GetNodeMetrics(ctx, node.Name)
+	if err != nil {
+		return fmt.Errorf("unable to get NodeMetrics: %w", err)
+	}
+	cpuUsage := nodeMetrics.Usage.Cpu().AsApproximateFloat64()
+	if c.opts.CPUUsagePercent {
+		capacity := node.Status.Capacity.Cpu().AsApproximateFloat64()
+		if capacity <= 0 {
+			return fmt.Errorf("CPU capacity of node=%s is unknown", node.Name)
+		}
+		cpuUsage = cpuUsage / capacity * 100
+	}
+	env, err := c.metricsclient.GetNodeFeatures(ctx, &node, append([]string{predictor.FeatureInletTemp, predictor.FeatureDeltaP}, c.opts.ExtraFeatures...))
+	if err != nil {
+		return fmt.Errorf("unable to get features: %w", err)
+	}
+	features := predictor.Features{predictor.FeatureCPUUsage: cpuUsage}
+	maps.Copy(features, env)
+
+	predicted, err := c.predictorclient.PredictPowerConsumptionFeatures(ctx, nc.Namespace, ep, features)
+	if err != nil {
+		return fmt.Errorf("unable to predict: %w", err)
+	}
+
+	s := c.Observe(nc.Spec.NodeName, model, predicted, measured, measuredAt)
+	lg.Debug("calibration updated", "model", model, "predicted", predicted, "measured", measured, "scale", s.Scale, "offset", s.Offset, "samples", s.Samples)
+	return nil
+}
+
+// Observe updates the state of the node with a prediction of the model and the measurement at the same features,
+// and returns a copy of the state. The state is reset if it is for another model.
+func (c *Calibrator) Observe(nodeName, model string, predicted, measured float64, measuredAt time.Time) CalibrationState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.states[nodeName]
+	if !ok || s.Model != model {
+		s = newCalibrationState(model)
+		c.states[nodeName] = s
+	}
+	s.update(predicted, measured, c.opts.ForgettingFactor)
+	s.MeasuredAt = measuredAt
+	return *s
+}
+
+// State returns a copy of the state of the node.
+func (c *Calibrator) State(nodeName string) (CalibrationState, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.states[nodeName]
+	if !ok {
+		return CalibrationState{}, false
+	}
+	return *s, true
+}
+
+// Active reports whether predictions of the node are calibrated, i.e., the node has at least MinSamples observations
+// and the last one is not older than MaxAge.
+func (c *Calibrator) Active(nodeName string) bool {
+	s, ok := c.State(nodeName)
+	return ok && c.active(s)
+}
+
+func (c *Calibrator) active(s CalibrationState) bool {
+	return s.Samples >= c.opts.MinSamples && !s.MeasuredAt.Add(c.opts.MaxAge).Before(time.Now())
+}
+
+// Apply returns a copy of watts of the model corrected with the bounded fit of the node.
+// Watts are returned as is if the calibration is not active or the fit is for another model.
+func (c *Calibrator) Apply(nodeName, model string, watts []float64) []float64 {
+	s, ok := c.State(nodeName)
+	if !ok || s.Model != model || !c.active(s) {
+		calibrationActive.WithLabelValues(nodeName).Set(0)
+		return watts
+	}
+	scale := min(max(s.Scale, c.opts.MinScale), c.opts.MaxScale)
+	offset := min(max(s.Offset, -c.opts.MaxOffset), c.opts.MaxOffset)
+	calibrationActive.WithLabelValues(nodeName).Set(1)
+	calibrationScale.WithLabelValues(nodeName).Set(scale)
+	calibrationOffset.WithLabelValues(nodeName).Set(offset)
+
+	out := make([]float64, len(watts))
+	for i, w := range watts {
+		out[i] = scale*w + offset
+	}
+	return out
+}
+
+func deleteCalibrationSeries(nodeName string) {
+	calibrationScale.DeleteLabelValues(nodeName)
+	calibrationOffset.DeleteLabelValues(nodeName)
+	calibrationActive.DeleteLabelValues(nodeName)
+}
+
+// Save saves the states to the Backend if set.
+func (c *Calibrator) Save(ctx context.Context) error {
+	if c.opts.Backend == nil {
+		return nil
+	}
+	c.mu.Lock()
+	data, err := json.Marshal(c.states)
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("unable to marshal calibration states: %w", err)
+	}
+	return c.opts.Backend.Save(ctx, data)
+}
+
+// Restore loads the states from the Backend if set. States of nodes that have been observed since start are kept.
+func (c *Calibrator) Restore(ctx context.Context) error {
+	if c.opts.Backend == nil {
+		return nil
+	}
+	data, err := c.opts.Backend.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to load calibration states: %w", err)
+	}
+	if data == nil {
+		return nil
+	}
+	var states map[string]*CalibrationState
+	if err := json.Unmarshal(data, &states); err != nil {
+		return fmt.Errorf("unable to unmarshal calibration states: %w", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, s := range states {
+		if _, ok := c.states[k]; !ok && s != nil {
+			c.states[k] = s
+		}
+	}
+	return nil
+}
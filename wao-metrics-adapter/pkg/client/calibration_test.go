@@ -0,0 +1,181 @@
+package client
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	k8stesting "k8s.io/client-go/testing"
+	custommetricsv1beta2 "k8s.io/metrics/pkg/apis/custom_metrics/v1beta2"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+	custommetricsfake "k8s.io/metrics/pkg/client/custom_metrics/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	waov1beta1 "github.com/waok8s/waok8s/wao-core/api/node/v1beta1"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
+)
+
+type testSnapshotBackend struct{ data []byte }
+
+func (b *testSnapshotBackend) Save(_ context.Context, data []byte) error { b.data = data; return nil }
+func (b *testSnapshotBackend) Load(_ context.Context) ([]byte, error)    { return b.data, nil }
+
+func TestCalibrator_Apply(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       CalibrationOptions
+		scale      float64
+		offset     float64
+		n          int
+		measuredAt time.Time
+		want       float64 // calibrated 200W
+	}{
+		{"not_enough_samples", CalibrationOptions{MinSamples: 5}, 1.2, 10, 4, time.Now(), 200},
+		{"converged", CalibrationOptions{}, 1.2, 10, 200, time.Now(), 250},
+		{"stale", CalibrationOptions{MaxAge: time.Minute}, 1.2, 10, 200, time.Now().Add(-2 * time.Minute), 200},
+		{"scale_bounded", CalibrationOptions{MaxScale: 1.1}, 1.5, 0, 200, time.Now(), 220},
+		{"offset_bounded", CalibrationOptions{MaxOffset: 20}, 1.0, 50, 200, time.Now(), 220},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCalibrator(nil, nil, nil, tt.opts)
+			// measured = scale * predicted + offset with predicted varying in [100, 300)
+			for i := range tt.n {
+				predicted := 100 + float64(i*37%200)
+				c.Observe("node-a", "model-1", predicted, tt.scale*predicted+tt.offset, tt.measuredAt)
+			}
+			got := c.Apply("node-a", "model-1", []float64{200})
+			if math.Abs(got[0]-tt.want) > 1 {
+				t.Errorf("Apply() = %v, want %v", got[0], tt.want)
+			}
+			if got := c.Apply("node-b", "model-1", []float64{200}); got[0] != 200 {
+				t.Errorf("Apply() of unknown node = %v, want 200", got[0])
+			}
+			if got := c.Apply("node-a", "model-2", []float64{200}); got[0] != 200 {
+				t.Errorf("Apply() of another model = %v, want 200", got[0])
+			}
+		})
+	}
+}
+
+func TestCalibrator_SaveRestore(t *testing.T) {
+	backend := &testSnapshotBackend{}
+	c := NewCalibrator(nil, nil, nil, CalibrationOptions{Backend: backend})
+	now := time.Now().Truncate(time.Second)
+	for i := range 10 {
+		c.Observe("node-a", "model-1", 100+float64(i*10), 120+float64(i*10), now)
+	}
+	if err := c.Save(context.Background()); err != nil {
+		t.Fatalf("Save() err = %v", err)
+	}
+	want, _ := c.State("node-a")
+
+	c2 := NewCalibrator(nil, nil, nil, CalibrationOptions{Backend: backend})
+	if err := c2.Restore(context.Background()); err != nil {
+		t.Fatalf("Restore() err = %v", err)
+	}
+	got, ok := c2.State("node-a")
+	if !ok {
+		t.Fatalf("state of node-a not restored")
+	}
+	if !got.MeasuredAt.Equal(want.MeasuredAt) {
+		t.Errorf("MeasuredAt = %v, want %v", got.MeasuredAt, want.MeasuredAt)
+	}
+	got.MeasuredAt = want.MeasuredAt
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("State() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCalibrator_ObserveAll(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	clientgoscheme.AddToScheme(scheme)
+	waov1beta1.AddToScheme(scheme)
+	// predicted = 100 + 10 * cpu_usage = 120
+	nc := &waov1beta1.NodeConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nc-a"},
+		Spec: waov1beta1.NodeConfigSpec{
+			NodeName: "node-a",
+			Predictor: waov1beta1.Predictor{
+				PowerConsumption: &waov1beta1.EndpointTerm{
+					Type:        waov1beta1.TypeLinearModel,
+					LinearModel: &waov1beta1.LinearModelTerm{Intercept: "100", Coefficients: []waov1beta1.LinearCoefficientTerm{{Metric: predictor.FeatureCPUUsage, Coefficient: "10"}}},
+				},
+			},
+		},
+	}
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}}
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(nc, node).Build()
+
+	mc := metricsfake.NewSimpleClientset()
+	mc.PrependReactor("get", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &metricsv1beta1.NodeMetrics{Usage: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")}}, nil
+	})
+	// each measurement is newer than the previous one
+	measuredAt := time.Now().Add(-time.Minute)
+	cmc := &custommetricsfake.FakeCustomMetricsClient{}
+	cmc.AddReactor("get", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		measuredAt = measuredAt.Add(time.Second)
+		v := resource.MustParse("20")
+		if action.(custommetricsfake.GetForAction).GetMetricName() == predictor.FeaturePowerConsumption {
+			v = resource.MustParse("150")
+		}
+		return true, &custommetricsv1beta2.MetricValueList{Items: []custommetricsv1beta2.MetricValue{{Value: v, Timestamp: metav1.NewTime(measuredAt)}}}, nil
+	})
+
+	pc := NewCachedPredictorClient(kubefake.NewSimpleClientset(), time.Minute, DefaultPredictorCacheSize)
+	c := NewCalibrator(reader, NewCachedMetricsClient(mc.MetricsV1beta1(), cmc, time.Nanosecond, DefaultMetricsCacheSize), pc, CalibrationOptions{MinSamples: 3})
+
+	for range 3 {
+		if err := c.ObserveAll(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s, ok := c.State("node-a"); !ok || s.Model != waov1beta1.TypeLinearModel || s.Samples != 3 {
+		t.Fatalf("State() = %+v, %v, want 3 samples of %s", s, ok, waov1beta1.TypeLinearModel)
+	}
+	if !c.Active("node-a") {
+		t.Errorf("calibration of node-a not active")
+	}
+
+	// the state is reset when the model changes
+	nc.Spec.Predictor.PowerConsumption = &waov1beta1.EndpointTerm{
+		Type:        waov1beta1.TypeStaticPower,
+		StaticPower: &waov1beta1.StaticPowerTerm{IdleWatts: "100", WattsPerCore: "10"},
+	}
+	if err := reader.Update(ctx, nc); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ObserveAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if s, ok := c.State("node-a"); !ok || s.Model != waov1beta1.TypeStaticPower || s.Samples != 1 {
+		t.Fatalf("State() = %+v, %v, want 1 sample of %s", s, ok, waov1beta1.TypeStaticPower)
+	}
+	if c.Active("node-a") {
+		t.Errorf("calibration of node-a active right after the model changed")
+	}
+
+	// states of removed NodeConfigs are deleted
+	if err := reader.Delete(ctx, nc); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ObserveAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if s, ok := c.State("node-a"); ok {
+		t.Errorf("State() = %+v after the NodeConfig is removed, want none", s)
+	}
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"k8s.io/component-base/metrics"
+
+	waov1beta1 "github.com/waok8s/waok8s/wao-core/api/node/v1beta1"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor/fromnodeconfig"
+)
+
+const (
+	sanityCheckActionRejected = "Rejected"
+	sanityCheckActionClamped  = "Clamped"
+)
+
+var (
+	predictionSanityCheckFailuresTotal = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      "wao",
+			Subsystem:      "client_prediction",
+			Name:           "sanity_check_failures_total",
+			Help:           "Cumulative number of power consumption predictions that failed the sanity check",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"reason", "action"},
+	)
+	predictionFallbacksTotal = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      "wao",
+			Subsystem:      "client_prediction",
+			Name:           "fallbacks_total",
+			Help:           "Cumulative number of power consumption predictions served by a fallback predictor",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"type"},
+	)
+)
+
+// PredictNodePowerConsumptionFeaturesBatch predicts with the power consumption predictor of the NodeConfig,
+// followed by PowerConsumptionFallbacks in order, and returns the watts of the first predictor that succeeds
+// and passes PowerConsumptionSanityCheck. Watts are returned in the same order as features.
+// Watts of the power consumption predictor are corrected by the Calibrator if set, before the sanity check.
+func (c *CachedPredictorClient) PredictNodePowerConsumptionFeaturesBatch(ctx context.Context, nc *waov1beta1.NodeConfig, features []predictor.Features) (watts []float64, err error) {
+	lg := slog.With("func", "CachedPredictorClient.PredictNodePowerConsumptionFeaturesBatch", "node", nc.Spec.NodeName)
+
+	check, err := fromnodeconfig.NewPowerConsumptionSanityCheck(nc.Spec.Predictor.PowerConsumptionSanityCheck)
+	if err != nil {
+		return nil, fmt.Errorf("invalid powerConsumptionSanityCheck: %w", err)
+	}
+
+	var errs []error
+	for i := 0; i <= len(nc.Spec.Predictor.PowerConsumptionFallbacks); i++ {
+		var ep *waov1beta1.EndpointTerm
+		if i == 0 {
+			if nc.Spec.Predictor.PowerConsumption == nil && nc.Spec.Predictor.PowerConsumptionEndpointProvider == nil {
+				continue
+			}
+			if ep, err = c.PowerConsumptionEndpoint(ctx, nc); err != nil {
+				errs = append(errs, fmt.Errorf("predictor[%d]: unable to get endpoint: %w", i, err))
+				continue
+			}
+		} else {
+			ep = &nc.Spec.Predictor.PowerConsumptionFallbacks[i-1]
+		}
+
+		ws, err := c.PredictPowerConsumptionFeaturesBatch(ctx, nc.Namespace, ep, features)
+		if err != nil {
+			lg.Warn("prediction failed", "predictor", i, "type", ep.Type, "err", err)
+			errs = append(errs, fmt.Errorf("predictor[%d] type=%s: %w", i, ep.Type, err))
+			continue
+		}
+		if i == 0 && c.Calibrator != nil {
+			ws = c.Calibrator.Apply(nc.Spec.NodeName, ModelName(ep), ws)
+		}
+		ws, reasons, err := check.Check(features, ws)
+		action := sanityCheckActionClamped
+		if err != nil {
+			action = sanityCheckActionRejected
+		}
+		for _, reason := range reasons {
+			predictionSanityCheckFailuresTotal.WithLabelValues(reason, action).Inc()
+		}
+		if err != nil {
+			lg.Warn("prediction rejected", "predictor", i, "type", ep.Type, "reasons", reasons)
+			errs = append(errs, fmt.Errorf("predictor[%d] type=%s: %w", i, ep.Type, err))
+			continue
+		}
+		if len(reasons) > 0 {
+			lg.Warn("prediction clamped", "predictor", i, "type", ep.Type, "reasons", reasons)
+		}
+		if i > 0 {
+			lg.Info("prediction served by fallback", "predictor", i, "type", ep.Type)
+			predictionFallbacksTotal.WithLabelValues(ep.Type).Inc()
+		}
+		return ws, nil
+	}
+
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no power consumption predictor is configured for node=%s", nc.Spec.NodeName)
+	}
+	return nil, errors.Join(errs...)
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	waov1beta1 "github.com/waok8s/waok8s/wao-core/api/node/v1beta1"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
+)
+
+func TestCachedPredictorClient_PredictNodePowerConsumptionFeaturesBatch(t *testing.T) {
+	var (
+		ok           = &waov1beta1.EndpointTerm{Type: waov1beta1.TypeFake, Endpoint: "formula?expr=100+cpu_usage"}
+		negative     = &waov1beta1.EndpointTerm{Type: waov1beta1.TypeFake, Endpoint: "formula?expr=cpu_usage-1000"}
+		decreasing   = &waov1beta1.EndpointTerm{Type: waov1beta1.TypeFake, Endpoint: "formula?expr=500-cpu_usage"}
+		unreachable  = &waov1beta1.EndpointTerm{Type: waov1beta1.TypeV2InferenceProtocol, Endpoint: "http://127.0.0.1:1/v2/models/m/infer"}
+		linearModel  = waov1beta1.EndpointTerm{Type: waov1beta1.TypeLinearModel, LinearModel: &waov1beta1.LinearModelTerm{Intercept: "80", Coefficients: []waov1beta1.LinearCoefficientTerm{{Metric: "cpu_usage", Coefficient: "2"}}}}
+		linearBroken = waov1beta1.EndpointTerm{Type: waov1beta1.TypeLinearModel, LinearModel: &waov1beta1.LinearModelTerm{Coefficients: []waov1beta1.LinearCoefficientTerm{{Metric: predictor.FeatureMemoryUsage, Coefficient: "1"}}}}
+		staticPower  = waov1beta1.EndpointTerm{Type: waov1beta1.TypeStaticPower, StaticPower: &waov1beta1.StaticPowerTerm{IdleWatts: "50", WattsPerCore: "10"}}
+	)
+	features := []predictor.Features{
+		{predictor.FeatureCPUUsage: 10, predictor.FeatureInletTemp: 20, predictor.FeatureDeltaP: 5},
+		{predictor.FeatureCPUUsage: 20, predictor.FeatureInletTemp: 20, predictor.FeatureDeltaP: 5},
+	}
+	tests := []struct {
+		name    string
+		pred    waov1beta1.Predictor
+		want    []float64
+		wantErr bool
+	}{
+		{"primary", waov1beta1.Predictor{PowerConsumption: ok, PowerConsumptionFallbacks: []waov1beta1.EndpointTerm{linearModel}}, []float64{110, 120}, false},
+		{"primary_rejected", waov1beta1.Predictor{PowerConsumption: negative, PowerConsumptionFallbacks: []waov1beta1.EndpointTerm{linearModel}}, []float64{100, 120}, false},
+		{"primary_down", waov1beta1.Predictor{PowerConsumption: unreachable, PowerConsumptionFallbacks: []waov1beta1.EndpointTerm{linearBroken, staticPower}}, []float64{150, 250}, false},
+		{"fallbacks_only", waov1beta1.Predictor{PowerConsumptionFallbacks: []waov1beta1.EndpointTerm{staticPower}}, []float64{150, 250}, false},
+		{"clamp",
+			waov1beta1.Predictor{PowerConsumption: ok, PowerConsumptionSanityCheck: &waov1beta1.SanityCheckTerm{MaxWatts: "115", Action: waov1beta1.SanityCheckActionClamp}},
+			[]float64{110, 115}, false},
+		{"not_monotonic",
+			waov1beta1.Predictor{PowerConsumption: decreasing, PowerConsumptionFallbacks: []waov1beta1.EndpointTerm{staticPower}, PowerConsumptionSanityCheck: &waov1beta1.SanityCheckTerm{Monotonic: true}},
+			[]float64{150, 250}, false},
+		{"all_failed", waov1beta1.Predictor{PowerConsumption: negative, PowerConsumptionFallbacks: []waov1beta1.EndpointTerm{linearBroken}}, nil, true},
+		{"no_predictors", waov1beta1.Predictor{}, nil, true},
+		{"invalid_sanity_check", waov1beta1.Predictor{PowerConsumption: ok, PowerConsumptionSanityCheck: &waov1beta1.SanityCheckTerm{IdleWatts: "100", MaxWatts: "50"}}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCachedPredictorClient(nil, time.Minute, DefaultPredictorCacheSize)
+			c.BatchWindow = 0
+			nc := &waov1beta1.NodeConfig{Spec: waov1beta1.NodeConfigSpec{NodeName: "node-0", Predictor: *tt.pred.DeepCopy()}}
+			got, err := c.PredictNodePowerConsumptionFeaturesBatch(context.Background(), nc, features)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PredictNodePowerConsumptionFeaturesBatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PredictNodePowerConsumptionFeaturesBatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,9 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"sync"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -17,21 +17,23 @@ import (
 	custommetricsclient "k8s.io/metrics/pkg/client/custom_metrics"
 
 	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
 )
 
 type CachedMetricsClient struct {
 	metricsclientset    metricsclientv1beta1.MetricsV1beta1Interface
 	custommetricsclient custommetricsclient.CustomMetricsClient
 
-	ttl   time.Duration
-	cache sync.Map
+	cache *lruCache[*metricsCache]
 }
 
-func NewCachedMetricsClient(metricsclientset metricsclientv1beta1.MetricsV1beta1Interface, custommetricsclient custommetricsclient.CustomMetricsClient, ttl time.Duration) *CachedMetricsClient {
+// NewCachedMetricsClient inits the client. The cache holds up to size entries (size <= 0 means unbounded) for ttl.
+func NewCachedMetricsClient(metricsclientset metricsclientv1beta1.MetricsV1beta1Interface, custommetricsclient custommetricsclient.CustomMetricsClient, ttl time.Duration, size int) *CachedMetricsClient {
+	RegisterMetrics()
 	return &CachedMetricsClient{
 		metricsclientset:    metricsclientset,
 		custommetricsclient: custommetricsclient,
-		ttl:                 ttl,
+		cache:               newLRUCache[*metricsCache](cacheNameMetrics, ttl, size),
 	}
 }
 
@@ -52,11 +54,6 @@ type metricsCache struct {
 	NodeMetrics   *metricsv1beta1.NodeMetrics
 	PodMetrics    *metricsv1beta1.PodMetrics
 	CustomMetrics map[string]*custommetricsv1beta2.MetricValue
-
-	ExpiredAt time.Time
-
-	// mu is used to avoid concurrent requests for the same key (which would result in multiple requests to origin servers)
-	mu sync.Mutex
 }
 
 func (c *CachedMetricsClient) get(ctx context.Context, obj types.NamespacedName, metricType string, metricName string) (*metricsCache, error) {
@@ -64,80 +61,44 @@ func (c *CachedMetricsClient) get(ctx context.Context, obj types.NamespacedName,
 	key := metricsCacheKey(obj, metricType, metricName)
 	lg := slog.With("func", "CachedMetricsClient.get", "key", key)
 
-	if v, ok1 := c.cache.Load(key); ok1 {
-		if cv, ok2 := v.(*metricsCache); ok2 {
+	return c.cache.getOrLoad(ctx, key, func(ctx context.Context) (*metricsCache, error) {
+		lg.Debug("metrics cache missed")
 
-			// Wait until the cache is ready
-			cv.mu.Lock()
-			lg.Debug("metrics cache is available")
-			cv.mu.Unlock() // NOTE: any better way to do this?
-
-			// Check if the cache is expired
-			if cv.ExpiredAt.After(time.Now()) {
-				lg.Debug("metrics cache hit")
-				return cv, nil
-			}
+		cv := &metricsCache{
+			CustomMetrics: make(map[string]*custommetricsv1beta2.MetricValue),
 		}
-	}
-	lg.Debug("metrics cache missed")
-
-	// Push an empty cache and lock it to avoid concurrent requests
-	cv := &metricsCache{
-		CustomMetrics: make(map[string]*custommetricsv1beta2.MetricValue),
-		ExpiredAt:     time.Now().Add(c.ttl),
-	}
-	cv.mu.Lock()
-	c.cache.Store(key, cv)
-
-	switch metricType {
-	case metricTypeNode:
-		switch metricName {
-		case metricNameMetrics:
-			nodeMetrics, err := c.metricsclientset.NodeMetricses().Get(ctx, obj.Name, metav1.GetOptions{})
-			if err != nil {
-				cv.mu.Unlock()
-				c.cache.Delete(key)
-				return nil, fmt.Errorf("unable to get metrics for obj=%s metricType=%s metricName=%s: %w", obj, metricType, metricName, err)
+		switch metricType {
+		case metricTypeNode:
+			switch metricName {
+			case metricNameMetrics:
+				nodeMetrics, err := c.metricsclientset.NodeMetricses().Get(ctx, obj.Name, metav1.GetOptions{})
+				if err != nil {
+					return nil, fmt.Errorf("unable to get metrics for obj=%s metricType=%s metricName=%s: %w", obj, metricType, metricName, err)
+				}
+				cv.NodeMetrics = nodeMetrics
+			default: // custom metrics, e.g., metricNameInletTemp, metricNameDeltaP
+				metricValue, err := c.custommetricsclient.RootScopedMetrics().GetForObject(schema.GroupKind{Group: "", Kind: "node"}, obj.Name, metricName, labels.NewSelector())
+				if err != nil {
+					return nil, fmt.Errorf("unable to get metrics for obj=%s metricType=%s metricName=%s: %w", obj, metricType, metricName, err)
+				}
+				cv.CustomMetrics[metricName] = metricValue
 			}
-			cv.NodeMetrics = nodeMetrics
-		case metricNameInletTemp, metricNameDeltaP:
-			metricValue, err := c.custommetricsclient.RootScopedMetrics().GetForObject(schema.GroupKind{Group: "", Kind: "node"}, obj.Name, metricName, labels.NewSelector())
-			if err != nil {
-				cv.mu.Unlock()
-				c.cache.Delete(key)
-				return nil, fmt.Errorf("unable to get metrics for obj=%s metricType=%s metricName=%s: %w", obj, metricType, metricName, err)
+		case metricTypePod:
+			switch metricName {
+			case metricNameMetrics:
+				podMetrics, err := c.metricsclientset.PodMetricses(obj.Namespace).Get(ctx, obj.Name, metav1.GetOptions{})
+				if err != nil {
+					return nil, fmt.Errorf("unable to get metrics for obj=%s metricType=%s metricName=%s: %w", obj, metricType, metricName, err)
+				}
+				cv.PodMetrics = podMetrics
+			default:
+				return nil, fmt.Errorf("unknown metricName=%s for metricType=%s", metricName, metricType)
 			}
-			cv.CustomMetrics[metricName] = metricValue
 		default:
-			cv.mu.Unlock()
-			c.cache.Delete(key)
-			return nil, fmt.Errorf("unknown metricName=%s for metricType=%s", metricName, metricType)
+			return nil, fmt.Errorf("unknown metricType=%s", metricType)
 		}
-	case metricTypePod:
-		switch metricName {
-		case metricNameMetrics:
-			podMetrics, err := c.metricsclientset.PodMetricses(obj.Namespace).Get(ctx, obj.Name, metav1.GetOptions{})
-			if err != nil {
-				cv.mu.Unlock()
-				c.cache.Delete(key)
-				return nil, fmt.Errorf("unable to get metrics for obj=%s metricType=%s metricName=%s: %w", obj, metricType, metricName, err)
-			}
-			cv.PodMetrics = podMetrics
-		default:
-			cv.mu.Unlock()
-			c.cache.Delete(key)
-			return nil, fmt.Errorf("unknown metricName=%s for metricType=%s", metricName, metricType)
-		}
-	default:
-		cv.mu.Unlock()
-		c.cache.Delete(key)
-		return nil, fmt.Errorf("unknown metricType=%s", metricType)
-	}
-
-	c.cache.Store(key, cv)
-	cv.mu.Unlock()
-
-	return cv, nil
+		return cv, nil
+	})
 }
 
 func (c *CachedMetricsClient) GetNodeMetrics(ctx context.Context, name string) (*metricsv1beta1.NodeMetrics, error) {
@@ -163,3 +124,34 @@ func (c *CachedMetricsClient) GetCustomMetricForNode(ctx context.Context, name s
 	}
 	return cv.CustomMetrics[metricName], nil
 }
+
+// GetNodeFeatures returns the named features of the node for predictor.FeaturePowerConsumptionPredictor.
+// predictor.FeatureMemoryUsage is the memory usage from metrics-server in percent of the capacity,
+// and other names are custom metrics of the node, e.g., predictor.FeaturePowerConsumption. See predictor.ValidateExtraFeatures.
+func (c *CachedMetricsClient) GetNodeFeatures(ctx context.Context, node *corev1.Node, names []string) (predictor.Features, error) {
+	features := make(predictor.Features, len(names))
+	for _, name := range names {
+		switch name {
+		case predictor.FeatureMemoryUsage:
+			nodeMetrics, err := c.GetNodeMetrics(ctx, node.Name)
+			if err != nil {
+				return nil, err
+			}
+			capacity := node.Status.Capacity.Memory().AsApproximateFloat64()
+			if capacity == 0 {
+				return nil, fmt.Errorf("memory capacity of node=%s is unknown", node.Name)
+			}
+			features[name] = nodeMetrics.Usage.Memory().AsApproximateFloat64() / capacity * 100
+		default:
+			metricValue, err := c.GetCustomMetricForNode(ctx, node.Name, name)
+			if err != nil {
+				return nil, err
+			}
+			if metricValue == nil {
+				return nil, fmt.Errorf("custom metric %s of node=%s not found", name, node.Name)
+			}
+			features[name] = metricValue.Value.AsApproximateFloat64()
+		}
+	}
+	return features, nil
+}
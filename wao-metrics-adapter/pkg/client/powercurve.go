@@ -0,0 +1,266 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	waov1beta1 "github.com/waok8s/waok8s/wao-core/api/node/v1beta1"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
+)
+
+const (
+	DefaultPowerCurvePoints          = 11
+	DefaultPowerCurveRefreshInterval = 5 * time.Minute
+	DefaultPowerCurveDriftThreshold  = 0.05
+	DefaultPowerCurveDriftFloor      = 0.5
+
+	// powerCurveRefreshTimeout limits a refresh of a node, which runs in background.
+	powerCurveRefreshTimeout = 30 * time.Second
+)
+
+// PowerCurve is power consumption predicted at CPU usage points under fixed environmental features.
+type PowerCurve struct {
+	// Environment holds the features except cpu_usage, e.g., inlet_temp and delta_p.
+	Environment predictor.Features
+	// CPUUsages are the points in ascending order.
+	CPUUsages []float64
+	// Watts are the predictions at CPUUsages.
+	Watts []float64
+	// Calibrated is true if the Calibrator was active for the node when the curve was created.
+	Calibrated bool
+	CreatedAt  time.Time
+}
+
+// Interpolate returns the watt at cpuUsage by linear interpolation between the nearest points.
+// Outside the points, the first or last segment is extrapolated.
+func (c *PowerCurve) Interpolate(cpuUsage float64) float64 {
+	n := len(c.CPUUsages)
+	if n == 1 {
+		return c.Watts[0]
+	}
+	i := min(max(sort.SearchFloat64s(c.CPUUsages, cpuUsage), 1), n-1)
+	x0, x1 := c.CPUUsages[i-1], c.CPUUsages[i]
+	y0, y1 := c.Watts[i-1], c.Watts[i]
+	return y0 + (y1-y0)*(cpuUsage-x0)/(x1-x0)
+}
+
+// Drifted reports whether env has different features from the curve's environment,
+// or any of them differs by more than threshold relative to the larger absolute value and by more than floor.
+// floor keeps features near 0 (e.g., delta_p) from drifting on tiny fluctuations. cpu_usage in env is ignored.
+func (c *PowerCurve) Drifted(env predictor.Features, threshold, floor float64) bool {
+	n := 0
+	for k, v := range env {
+		if k == predictor.FeatureCPUUsage {
+			continue
+		}
+		n++
+		v0, ok := c.Environment[k]
+		if !ok || math.Abs(v-v0) > max(threshold*max(math.Abs(v), math.Abs(v0)), floor) {
+			return true
+		}
+	}
+	return n != len(c.Environment)
+}
+
+type PowerCurveOptions struct {
+	// Points is the number of CPU usage points from 0 to the max, i.e., 100 or the CPU capacity of the node.
+	Points int
+	// RefreshInterval is the interval to refresh curves of all NodeConfigs.
+	RefreshInterval time.Duration
+	// DriftThreshold is the relative difference of environmental features to refresh the curve, e.g., 0.05 for 5%.
+	DriftThreshold float64
+	// DriftFloor is the absolute difference of environmental features below which the curve is never refreshed.
+	DriftFloor float64
+	// CPUUsagePercent makes cpu_usage [0.0, 100.0] instead of [0.0, NumLogicalCores].
+	CPUUsagePercent bool
+	// ExtraFeatures are the features supplied in addition to cpu_usage, inlet_temp and delta_p. See CachedMetricsClient.GetNodeFeatures.
+	ExtraFeatures []string
+}
+
+// PowerCurveClient serves power consumption predictions by interpolating a PowerCurve of each node,
+// which is computed in background so that predictions do not wait for the predictor.
+type PowerCurveClient struct {
+	reader          client.Reader
+	metricsclient   *CachedMetricsClient
+	predictorclient *CachedPredictorClient
+	opts            PowerCurveOptions
+
+	// curves holds a *PowerCurve for each node name.
+	curves sync.Map
+	// refreshing holds node names that are being refreshed.
+	refreshing sync.Map
+}
+
+// NewPowerCurveClient inits the client. reader is used to get NodeConfigs and Nodes.
+// Call Run to refresh curves periodically.
+func NewPowerCurveClient(reader client.Reader, metricsclient *CachedMetricsClient, predictorclient *CachedPredictorClient, opts PowerCurveOptions) *PowerCurveClient {
+	if opts.Points < 2 {
+		opts.Points = DefaultPowerCurvePoints
+	}
+	if opts.RefreshInterval <= 0 {
+		opts.RefreshInterval = DefaultPowerCurveRefreshInterval
+	}
+	if opts.DriftThreshold <= 0 {
+		opts.DriftThreshold = DefaultPowerCurveDriftThreshold
+	}
+	if opts.DriftFloor <= 0 {
+		opts.DriftFloor = DefaultPowerCurveDriftFloor
+	}
+	return &PowerCurveClient{
+		reader:          reader,
+		metricsclient:   metricsclient,
+		predictorclient: predictorclient,
+		opts:            opts,
+	}
+}
+
+// Run refreshes curves of all NodeConfigs every RefreshInterval until ctx is done.
+func (c *PowerCurveClient) Run(ctx context.Context) {
+	lg := slog.With("func", "PowerCurveClient.Run")
+
+	ticker := time.NewTicker(c.opts.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		if err := c.RefreshAll(ctx); err != nil {
+			lg.Error("unable to refresh power curves", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RefreshAll refreshes curves of all NodeConfigs. Errors of each node are logged and skipped.
+// Curves of nodes without NodeConfigs are removed.
+func (c *PowerCurveClient) RefreshAll(ctx context.Context) error {
+	lg := slog.With("func", "PowerCurveClient.RefreshAll")
+
+	var ncs waov1beta1.NodeConfigList
+	if err := c.reader.List(ctx, &ncs); err != nil {
+		return fmt.Errorf("unable to list NodeConfigs: %w", err)
+	}
+	seen := map[string]struct{}{}
+	for i := range ncs.Items {
+		nc := &ncs.Items[i]
+		seen[nc.Spec.NodeName] = struct{}{}
+		if err := c.refresh(ctx, nc); err != nil {
+			lg.Error("unable to refresh power curve", "nodeconfig", client.ObjectKeyFromObject(nc), "node", nc.Spec.NodeName, "err", err)
+		}
+	}
+
+	c.curves.Range(func(k, _ any) bool {
+		if _, ok := seen[k.(string)]; !ok {
+			c.curves.Delete(k)
+			lg.Debug("power curve removed", "node", k)
+		}
+		return true
+	})
+	return nil
+}
+
+// PredictPowerConsumption returns the watt at features by interpolating the curve of the node.
+// ok is false if the node has no curve, the environment has drifted from the curve,
+// or the calibration of the node has been activated or deactivated since, and the curve is refreshed in background.
+func (c *PowerCurveClient) PredictPowerConsumption(nodeName string, features predictor.Features) (watt float64, ok bool) {
+	cpuUsage, hasCPUUsage := features[predictor.FeatureCPUUsage]
+	if !hasCPUUsage {
+		return 0.0, false
+	}
+	if v, found := c.curves.Load(nodeName); found {
+		if curve := v.(*PowerCurve); !curve.Drifted(features, c.opts.DriftThreshold, c.opts.DriftFloor) && curve.Calibrated == c.calibrated(nodeName) {
+			return curve.Interpolate(cpuUsage), true
+		}
+	}
+	c.refreshAsync(nodeName)
+	return 0.0, false
+}
+
+// calibrated reports whether predictions of the node are corrected by the Calibrator.
+func (c *PowerCurveClient) calibrated(nodeName string) bool {
+	return c.predictorclient.Calibrator != nil && c.predictorclient.Calibrator.Active(nodeName)
+}
+
+// refreshAsync refreshes the curve of the node in background unless it is being refreshed.
+func (c *PowerCurveClient) refreshAsync(nodeName string) {
+	if _, loaded := c.refreshing.LoadOrStore(nodeName, struct{}{}); loaded {
+		return
+	}
+	go func() {
+		defer c.refreshing.Delete(nodeName)
+		lg := slog.With("func", "PowerCurveClient.refreshAsync", "node", nodeName)
+
+		ctx, cancel := context.WithTimeout(context.Background(), powerCurveRefreshTimeout)
+		defer cancel()
+		var ncs waov1beta1.NodeConfigList
+		if err := c.reader.List(ctx, &ncs); err != nil {
+			lg.Error("unable to list NodeConfigs", "err", err)
+			return
+		}
+		for i := range ncs.Items {
+			// TODO: handle node with multiple NodeConfig
+			if ncs.Items[i].Spec.NodeName == nodeName {
+				if err := c.refresh(ctx, &ncs.Items[i]); err != nil {
+					lg.Error("unable to refresh power curve", "err", err)
+				}
+				return
+			}
+		}
+		lg.Debug("NodeConfig not found")
+	}()
+}
+
+// refresh predicts watts at the points under the current environment of the node, and stores the curve.
+func (c *PowerCurveClient) refresh(ctx context.Context, nc *waov1beta1.NodeConfig) error {
+	lg := slog.With("func", "PowerCurveClient.refresh", "node", nc.Spec.NodeName)
+
+	var node corev1.Node
+	if err := c.reader.Get(ctx, types.NamespacedName{Name: nc.Spec.NodeName}, &node); err != nil {
+		return fmt.Errorf("unable to get Node: %w", err)
+	}
+	env, err := c.metricsclient.GetNodeFeatures(ctx, &node, append([]string{predictor.FeatureInletTemp, predictor.FeatureDeltaP}, c.opts.ExtraFeatures...))
+	if err != nil {
+		return fmt.Errorf("unable to get features: %w", err)
+	}
+
+	maxCPUUsage := 100.0
+	if !c.opts.CPUUsagePercent {
+		maxCPUUsage = node.Status.Capacity.Cpu().AsApproximateFloat64()
+	}
+	if maxCPUUsage <= 0 {
+		return fmt.Errorf("CPU capacity of node=%s is unknown", node.Name)
+	}
+	calibrated := c.calibrated(node.Name)
+	cpuUsages := make([]float64, c.opts.Points)
+	features := make([]predictor.Features, c.opts.Points)
+	for i := range cpuUsages {
+		cpuUsages[i] = maxCPUUsage * float64(i) / float64(c.opts.Points-1)
+		features[i] = predictor.Features{predictor.FeatureCPUUsage: cpuUsages[i]}
+		maps.Copy(features[i], env)
+	}
+	watts, err := c.predictorclient.PredictNodePowerConsumptionFeaturesBatch(ctx, nc, features)
+	if err != nil {
+		return fmt.Errorf("unable to predict: %w", err)
+	}
+
+	c.curves.Store(node.Name, &PowerCurve{
+		Environment: env,
+		CPUUsages:   cpuUsages,
+		Watts:       watts,
+		Calibrated:  calibrated,
+		CreatedAt:   time.Now(),
+	})
+	lg.Debug("power curve refreshed", "env", env, "watts", watts, "calibrated", calibrated)
+	return nil
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	waov1beta1 "github.com/waok8s/waok8s/wao-core/api/node/v1beta1"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
+)
+
+func TestPowerCurve_Interpolate(t *testing.T) {
+	curve := &PowerCurve{
+		CPUUsages: []float64{0, 50, 100},
+		Watts:     []float64{100, 200, 400},
+	}
+	tests := []struct {
+		cpuUsage float64
+		want     float64
+	}{
+		{cpuUsage: 0, want: 100},
+		{cpuUsage: 25, want: 150},
+		{cpuUsage: 50, want: 200},
+		{cpuUsage: 75, want: 300},
+		{cpuUsage: 100, want: 400},
+		{cpuUsage: -10, want: 80},  // extrapolated with the first segment
+		{cpuUsage: 110, want: 440}, // extrapolated with the last segment
+	}
+	for _, tt := range tests {
+		if got := curve.Interpolate(tt.cpuUsage); got != tt.want {
+			t.Errorf("Interpolate(%v) = %v, want %v", tt.cpuUsage, got, tt.want)
+		}
+	}
+}
+
+func TestPowerCurve_Drifted(t *testing.T) {
+	curve := &PowerCurve{Environment: predictor.Features{predictor.FeatureInletTemp: 20, predictor.FeatureDeltaP: 10}}
+	near0 := &PowerCurve{Environment: predictor.Features{predictor.FeatureDeltaP: 0.1}}
+	tests := []struct {
+		name  string
+		curve *PowerCurve
+		env   predictor.Features
+		floor float64
+		want  bool
+	}{
+		{"same", curve, predictor.Features{predictor.FeatureCPUUsage: 50, predictor.FeatureInletTemp: 20, predictor.FeatureDeltaP: 10}, 0, false},
+		{"within_threshold", curve, predictor.Features{predictor.FeatureInletTemp: 20.9, predictor.FeatureDeltaP: 9.6}, 0, false},
+		{"drifted", curve, predictor.Features{predictor.FeatureInletTemp: 22, predictor.FeatureDeltaP: 10}, 0, true},
+		{"missing_feature", curve, predictor.Features{predictor.FeatureInletTemp: 20}, 0, true},
+		{"extra_feature", curve, predictor.Features{predictor.FeatureInletTemp: 20, predictor.FeatureDeltaP: 10, predictor.FeatureMemoryUsage: 40}, 0, true},
+		{"near_0_without_floor", near0, predictor.Features{predictor.FeatureDeltaP: 0.2}, 0, true},
+		{"near_0_within_floor", near0, predictor.Features{predictor.FeatureDeltaP: 0.2}, 0.5, false},
+		{"near_0_drifted", near0, predictor.Features{predictor.FeatureDeltaP: 1}, 0.5, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.curve.Drifted(tt.env, 0.05, tt.floor); got != tt.want {
+				t.Errorf("Drifted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPowerCurveClient_PredictPowerConsumption_calibrated(t *testing.T) {
+	scheme := runtime.NewScheme()
+	waov1beta1.AddToScheme(scheme)
+	pc := NewCachedPredictorClient(kubefake.NewSimpleClientset(), time.Minute, DefaultPredictorCacheSize)
+	pc.Calibrator = NewCalibrator(nil, nil, nil, CalibrationOptions{MinSamples: 1})
+	c := NewPowerCurveClient(fake.NewClientBuilder().WithScheme(scheme).Build(), nil, pc, PowerCurveOptions{})
+
+	env := predictor.Features{predictor.FeatureInletTemp: 20}
+	c.curves.Store("node-a", &PowerCurve{Environment: env, CPUUsages: []float64{0, 100}, Watts: []float64{100, 200}})
+	features := predictor.Features{predictor.FeatureCPUUsage: 50, predictor.FeatureInletTemp: 20}
+	if got, ok := c.PredictPowerConsumption("node-a", features); !ok || got != 150 {
+		t.Fatalf("PredictPowerConsumption() = %v, %v, want 150", got, ok)
+	}
+
+	// the curve was created without calibration, so it is refreshed once calibration is activated
+	pc.Calibrator.Observe("node-a", "model-1", 150, 180, time.Now())
+	if _, ok := c.PredictPowerConsumption("node-a", features); ok {
+		t.Errorf("PredictPowerConsumption() served the uncalibrated curve after calibration is activated")
+	}
+}
+
+func TestPowerCurveClient_RefreshAll_prune(t *testing.T) {
+	scheme := runtime.NewScheme()
+	waov1beta1.AddToScheme(scheme)
+	pc := NewCachedPredictorClient(kubefake.NewSimpleClientset(), time.Minute, DefaultPredictorCacheSize)
+	c := NewPowerCurveClient(fake.NewClientBuilder().WithScheme(scheme).Build(), nil, pc, PowerCurveOptions{})
+
+	c.curves.Store("node-a", &PowerCurve{CPUUsages: []float64{0, 100}, Watts: []float64{100, 200}})
+	if err := c.RefreshAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.curves.Load("node-a"); ok {
+		t.Error("curve of node-a without NodeConfigs is not removed")
+	}
+}
@@ -4,10 +4,10 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"sync"
 	"time"
 
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/lru"
 
 	waov1beta1 "github.com/waok8s/waok8s/wao-core/api/node/v1beta1"
 
@@ -18,137 +18,286 @@ import (
 type CachedPredictorClient struct {
 	client kubernetes.Interface
 
-	ttl   time.Duration
-	cache sync.Map
+	cache *lruCache[*predictionCache]
+
+	// Quantization is the step to round each feature to before predicting, so that close inputs share a cache entry,
+	// e.g., DefaultQuantization. Nil disables quantization. Set before use.
+	Quantization map[string]float64
+
+	// BatchWindow is the time to wait for concurrent power consumption predictions to the same endpoint
+	// to coalesce into a single request. Zero (default) disables coalescing. Set before use.
+	BatchWindow time.Duration
+	// MaxBatchSize is the max number of inputs in a coalesced request. Set before use.
+	MaxBatchSize int
+	// Calibrator corrects predictions of the power consumption predictor of NodeConfigs. Nil disables calibration. Set before use.
+	// See: PredictNodePowerConsumptionFeaturesBatch
+	Calibrator *Calibrator
+	// batchers holds a *powerConsumptionBatcher for each endpoint with a pending batch, up to maxBatchers.
+	batchers *lru.Cache
 }
 
-func NewCachedPredictorClient(client kubernetes.Interface, ttl time.Duration) *CachedPredictorClient {
+// NewCachedPredictorClient inits the client. The cache holds up to size entries (size <= 0 means unbounded) for ttl.
+func NewCachedPredictorClient(client kubernetes.Interface, ttl time.Duration, size int) *CachedPredictorClient {
+	RegisterMetrics()
 	return &CachedPredictorClient{
-		client: client,
-		ttl:    ttl,
+		client:       client,
+		cache:        newLRUCache[*predictionCache](cacheNamePredictor, ttl, size),
+		batchers:     lru.New(maxBatchers),
+		BatchWindow:  DefaultBatchWindow,
+		MaxBatchSize: DefaultMaxBatchSize,
 	}
 }
 
+func endpointKey(namespace string, endpointTerm *waov1beta1.EndpointTerm) string {
+	secretName := ""
+	if endpointTerm.BasicAuthSecret != nil {
+		secretName = endpointTerm.BasicAuthSecret.Name
+	}
+	return fmt.Sprintf("%s#%s|%s|%s|%+v|%+v|%+v", namespace, endpointTerm.Type, endpointTerm.Endpoint, secretName, endpointTerm.ModelSchema, endpointTerm.LinearModel, endpointTerm.StaticPower)
+}
+
 func predictorCacheKey(valueType string,
 	namespace string, endpointTerm *waov1beta1.EndpointTerm, // common
 	predictorType predictor.PredictorType, // GetPredictorEndpoint
-	cpuUsage, inletTemp, deltaP float64, // PredictPowerConsumption
+	features predictor.Features, // PredictPowerConsumption, PredictResponseTime
 ) string {
 	secretName := ""
 	if endpointTerm.BasicAuthSecret != nil {
 		secretName = endpointTerm.BasicAuthSecret.Name
 	}
-	ep := fmt.Sprintf("%s|%s|%s", endpointTerm.Type, endpointTerm.Endpoint, secretName)
-	return fmt.Sprintf("%s#%s#%s#%s#%f#%f#%f", valueType, namespace, ep, predictorType, cpuUsage, inletTemp, deltaP)
+	ep := fmt.Sprintf("%s|%s|%s|%+v|%+v|%+v", endpointTerm.Type, endpointTerm.Endpoint, secretName, endpointTerm.ModelSchema, endpointTerm.LinearModel, endpointTerm.StaticPower)
+	return fmt.Sprintf("%s#%s#%s#%s#%s", valueType, namespace, ep, predictorType, features)
 }
 
 const (
 	valueTypePowerConsumptionEndpoint = "PowerConsumptionEndpoint"
 	valueTypeWatt                     = "Watt"
 
-	// valueTypeResponseTimeEndpoint = "ResponseTimeEndpoint"
-	// valueTypeResponseTime         = "ResponseTime"
+	valueTypeResponseTimeEndpoint = "ResponseTimeEndpoint"
+	valueTypeResponseTime         = "ResponseTime"
 )
 
 type predictionCache struct {
 	PowerConsumptionEndpoint *waov1beta1.EndpointTerm
 	Watt                     float64
 
-	// ResponseTimeEndpoint *waov1beta1.EndpointTerm
-	// ResponseTime         float64
-
-	ExpiredAt time.Time
-
-	// mu is used to avoid concurrent requests for the same key (which would result in multiple requests to origin servers)
-	mu sync.Mutex
+	ResponseTimeEndpoint *waov1beta1.EndpointTerm
+	ResponseTime         float64
 }
 
 func (c *CachedPredictorClient) do(ctx context.Context, valueType string,
 	namespace string, endpointTerm *waov1beta1.EndpointTerm, // common
 	predictorType predictor.PredictorType, // GetPredictorEndpoint
-	cpuUsage, inletTemp, deltaP float64, // PredictPowerConsumption
+	features predictor.Features, // PredictPowerConsumption, PredictResponseTime
 ) (*predictionCache, error) {
 
-	key := predictorCacheKey(valueType, namespace, endpointTerm, predictorType, cpuUsage, inletTemp, deltaP)
+	features = quantize(features, c.Quantization)
+	key := predictorCacheKey(valueType, namespace, endpointTerm, predictorType, features)
 	lg := slog.With("func", "CachedPredictorClient.do", "key", key)
 
-	if v, ok1 := c.cache.Load(key); ok1 {
-		if cv, ok2 := v.(*predictionCache); ok2 {
-
-			// Wait until the cache is ready
-			cv.mu.Lock()
-			lg.Debug("predictor cache is available")
-			cv.mu.Unlock() // NOTE: any better way to do this?
+	return c.cache.getOrLoad(ctx, key, func(ctx context.Context) (*predictionCache, error) {
+		lg.Debug("predictor cache missed")
 
-			// Check if the cache is expired
-			if cv.ExpiredAt.After(time.Now()) {
-				lg.Debug("predictor cache hit")
-				return cv, nil
+		cv := &predictionCache{}
+		switch valueType {
+		case valueTypePowerConsumptionEndpoint, valueTypeResponseTimeEndpoint:
+			prov, err := fromnodeconfig.NewEndpointProvider(c.client, namespace, endpointTerm)
+			if err != nil {
+				return nil, err
+			}
+			ep, err := prov.Get(ctx, predictorType)
+			if err != nil {
+				return nil, err
+			}
+			if valueType == valueTypeResponseTimeEndpoint {
+				cv.ResponseTimeEndpoint = ep
+			} else {
+				cv.PowerConsumptionEndpoint = ep
+			}
+		case valueTypeWatt:
+			watt, err := c.predictPowerConsumption(ctx, namespace, endpointTerm, features)
+			if err != nil {
+				return nil, err
 			}
+			cv.Watt = watt
+		case valueTypeResponseTime:
+			pred, err := fromnodeconfig.NewResponseTimePredictor(c.client, namespace, endpointTerm)
+			if err != nil {
+				return nil, err
+			}
+			in, err := features.PowerConsumptionInput()
+			if err != nil {
+				return nil, err
+			}
+			ms, err := pred.Predict(ctx, in.CPUUsage, in.InletTemp, in.DeltaP)
+			if err != nil {
+				return nil, err
+			}
+			cv.ResponseTime = ms
+		default:
+			return nil, fmt.Errorf("unknown valueType=%s", valueType)
 		}
-	}
-	lg.Debug("predictor cache missed")
+		return cv, nil
+	})
+}
 
-	// Push an empty cache and lock it to avoid concurrent requests
-	cv := &predictionCache{
-		ExpiredAt: time.Now().Add(c.ttl),
+// predictPowerConsumption predicts via the batcher of the endpoint if coalescing is enabled.
+func (c *CachedPredictorClient) predictPowerConsumption(ctx context.Context, namespace string, endpointTerm *waov1beta1.EndpointTerm, features predictor.Features) (watt float64, err error) {
+	newPredictor := func() (predictor.PowerConsumptionPredictor, error) {
+		return fromnodeconfig.NewPowerConsumptionPredictor(c.client, namespace, endpointTerm)
 	}
-	cv.mu.Lock()
-	c.cache.Store(key, cv)
-
-	switch valueType {
-	case valueTypePowerConsumptionEndpoint:
-		prov, err := fromnodeconfig.NewEndpointProvider(c.client, namespace, endpointTerm)
+	if c.BatchWindow <= 0 {
+		pred, err := newPredictor()
 		if err != nil {
-			cv.mu.Unlock()
-			c.cache.Delete(key)
-			return nil, err
+			return 0.0, err
 		}
-		ep, err := prov.Get(ctx, predictorType)
+		watts, err := predictor.AsFeaturePowerConsumptionPredictor(pred).PredictFeatures(ctx, []predictor.Features{features})
 		if err != nil {
-			cv.mu.Unlock()
-			c.cache.Delete(key)
-			return nil, err
+			return 0.0, err
+		}
+		if len(watts) == 0 {
+			return 0.0, fmt.Errorf("got no predictions")
+		}
+		return watts[0], nil
+	}
+	key := endpointKey(namespace, endpointTerm)
+	v, ok := c.batchers.Get(key)
+	if !ok {
+		// A concurrent call may add another batcher for the key, which only splits the batch once.
+		b := newPowerConsumptionBatcher(newPredictor, c.BatchWindow, c.MaxBatchSize)
+		b.onIdle = func() {
+			if v, ok := c.batchers.Get(key); ok && v == b {
+				c.batchers.Remove(key)
+			}
 		}
-		cv.PowerConsumptionEndpoint = ep
-	case valueTypeWatt:
-		pred, err := fromnodeconfig.NewPowerConsumptionPredictor(c.client, namespace, endpointTerm)
+		v = b
+		c.batchers.Add(key, v)
+	}
+	return v.(*powerConsumptionBatcher).Predict(ctx, features)
+}
+
+func (c *CachedPredictorClient) GetPredictorEndpoint(ctx context.Context, namespace string, ep *waov1beta1.EndpointTerm, predictorType predictor.PredictorType) (*waov1beta1.EndpointTerm, error) {
+	if predictorType == predictor.TypeResponseTime {
+		cv, err := c.do(ctx, valueTypeResponseTimeEndpoint, namespace, ep, predictorType, nil)
 		if err != nil {
-			cv.mu.Unlock()
-			c.cache.Delete(key)
 			return nil, err
 		}
-		watt, err := pred.Predict(ctx, cpuUsage, inletTemp, deltaP)
+		return cv.ResponseTimeEndpoint, nil
+	}
+	cv, err := c.do(ctx, valueTypePowerConsumptionEndpoint, namespace, ep, predictorType, nil)
+	if err != nil {
+		return nil, err
+	}
+	return cv.PowerConsumptionEndpoint, nil
+}
+
+// PowerConsumptionEndpoint returns a copy of the power consumption predictor of the NodeConfig,
+// with the type and endpoint from PowerConsumptionEndpointProvider if it is set.
+func (c *CachedPredictorClient) PowerConsumptionEndpoint(ctx context.Context, nc *waov1beta1.NodeConfig) (*waov1beta1.EndpointTerm, error) {
+	ep := &waov1beta1.EndpointTerm{}
+	if nc.Spec.Predictor.PowerConsumption != nil {
+		ep = nc.Spec.Predictor.PowerConsumption.DeepCopy()
+	}
+	if nc.Spec.Predictor.PowerConsumptionEndpointProvider != nil {
+		ep2, err := c.GetPredictorEndpoint(ctx, nc.Namespace, nc.Spec.Predictor.PowerConsumptionEndpointProvider, predictor.TypePowerConsumption)
 		if err != nil {
-			cv.mu.Unlock()
-			c.cache.Delete(key)
 			return nil, err
 		}
-		cv.Watt = watt
-	default:
-		cv.mu.Unlock()
-		c.cache.Delete(cv)
-		return nil, fmt.Errorf("unknown valueType=%s", valueType)
+		if ep2 == nil {
+			return nil, fmt.Errorf("endpoint provider returned no endpoint")
+		}
+		ep.Type = ep2.Type
+		ep.Endpoint = ep2.Endpoint
 	}
+	return ep, nil
+}
 
-	c.cache.Store(key, cv)
-	cv.mu.Unlock()
+// ModelName returns the endpoint of the predictor, which usually contains the model name and version,
+// e.g., "https://.../v2/models/<name>/versions/<version>/infer". In-process predictors without endpoints use the type.
+func ModelName(ep *waov1beta1.EndpointTerm) string {
+	if ep.Endpoint != "" {
+		return ep.Endpoint
+	}
+	return ep.Type
+}
 
-	return cv, nil
+func (c *CachedPredictorClient) PredictPowerConsumption(ctx context.Context, namespace string, ep *waov1beta1.EndpointTerm, cpuUsage, inletTemp, deltaP float64) (watt float64, err error) {
+	return c.PredictPowerConsumptionFeatures(ctx, namespace, ep, predictor.PowerConsumptionInput{CPUUsage: cpuUsage, InletTemp: inletTemp, DeltaP: deltaP}.Features())
 }
 
-func (c *CachedPredictorClient) GetPredictorEndpoint(ctx context.Context, namespace string, ep *waov1beta1.EndpointTerm, predictorType predictor.PredictorType) (*waov1beta1.EndpointTerm, error) {
-	cv, err := c.do(ctx, valueTypePowerConsumptionEndpoint, namespace, ep, predictorType, 0.0, 0.0, 0.0)
+// PredictPowerConsumptionFeatures predicts with named features.
+// Predictors that do not implement predictor.FeaturePowerConsumptionPredictor use cpu_usage, inlet_temp and delta_p, and ignore others.
+func (c *CachedPredictorClient) PredictPowerConsumptionFeatures(ctx context.Context, namespace string, ep *waov1beta1.EndpointTerm, features predictor.Features) (watt float64, err error) {
+	cv, err := c.do(ctx, valueTypeWatt, namespace, ep, "", features)
+	if err != nil {
+		return 0.0, err
+	}
+	return cv.Watt, nil
+}
+
+// PredictPowerConsumptionBatch predicts inputs, sending uncached ones in a single request.
+// Watts are returned in the same order as inputs.
+func (c *CachedPredictorClient) PredictPowerConsumptionBatch(ctx context.Context, namespace string, ep *waov1beta1.EndpointTerm, inputs []predictor.PowerConsumptionInput) (watts []float64, err error) {
+	features := make([]predictor.Features, len(inputs))
+	for i, in := range inputs {
+		features[i] = in.Features()
+	}
+	return c.PredictPowerConsumptionFeaturesBatch(ctx, namespace, ep, features)
+}
+
+// PredictPowerConsumptionFeaturesBatch is PredictPowerConsumptionBatch with named features.
+// The request does not wait for BatchWindow, as the inputs are already batched.
+func (c *CachedPredictorClient) PredictPowerConsumptionFeaturesBatch(ctx context.Context, namespace string, ep *waov1beta1.EndpointTerm, features []predictor.Features) (watts []float64, err error) {
+	watts = make([]float64, len(features))
+	keys := make([]string, len(features))
+	missed := map[string]int{} // key -> index in inputs
+	var inputs []predictor.Features
+	for i, f := range features {
+		f = quantize(f, c.Quantization)
+		keys[i] = predictorCacheKey(valueTypeWatt, namespace, ep, "", f)
+		if cv, ok := c.cache.get(keys[i]); ok {
+			cacheHitsTotal.WithLabelValues(cacheNamePredictor).Inc()
+			watts[i] = cv.Watt
+			continue
+		}
+		if _, ok := missed[keys[i]]; !ok {
+			cacheMissesTotal.WithLabelValues(cacheNamePredictor).Inc()
+			missed[keys[i]] = len(inputs)
+			inputs = append(inputs, f)
+		}
+	}
+	if len(inputs) == 0 {
+		return watts, nil
+	}
+
+	lg := slog.With("func", "CachedPredictorClient.PredictPowerConsumptionFeaturesBatch", "size", len(inputs))
+	lg.Debug("predictor cache missed")
+	pred, err := fromnodeconfig.NewPowerConsumptionPredictor(c.client, namespace, ep)
 	if err != nil {
 		return nil, err
 	}
-	return cv.PowerConsumptionEndpoint, nil
+	got, err := predictor.AsFeaturePowerConsumptionPredictor(pred).PredictFeatures(ctx, inputs)
+	if err != nil {
+		return nil, err
+	}
+	if len(got) != len(inputs) {
+		return nil, fmt.Errorf("got %d predictions for %d inputs", len(got), len(inputs))
+	}
+	for key, j := range missed {
+		c.cache.add(key, &predictionCache{Watt: got[j]})
+	}
+	for i, key := range keys {
+		if j, ok := missed[key]; ok {
+			watts[i] = got[j]
+		}
+	}
+	return watts, nil
 }
 
-func (c *CachedPredictorClient) PredictPowerConsumption(ctx context.Context, namespace string, ep *waov1beta1.EndpointTerm, cpuUsage, inletTemp, deltaP float64) (watt float64, err error) {
-	cv, err := c.do(ctx, valueTypeWatt, namespace, ep, "", cpuUsage, inletTemp, deltaP)
+func (c *CachedPredictorClient) PredictResponseTime(ctx context.Context, namespace string, ep *waov1beta1.EndpointTerm, cpuUsage, inletTemp, deltaP float64) (milliseconds float64, err error) {
+	cv, err := c.do(ctx, valueTypeResponseTime, namespace, ep, "", predictor.PowerConsumptionInput{CPUUsage: cpuUsage, InletTemp: inletTemp, DeltaP: deltaP}.Features())
 	if err != nil {
 		return 0.0, err
 	}
-	return cv.Watt, nil
+	return cv.ResponseTime, nil
 }
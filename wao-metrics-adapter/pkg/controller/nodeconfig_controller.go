@@ -2,11 +2,17 @@ package controller
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strconv"
+	"strings"
 	"time"
 
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -15,14 +21,27 @@ import (
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	waov1beta1 "github.com/waok8s/waok8s/wao-core/api/node/v1beta1"
 
 	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
 	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics/dpapi"
 	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics/fake"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics/httpjson"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics/modbus"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics/nodeagent"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics/prometheus"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics/push"
 	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics/redfish"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics/replay"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics/snmp"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor/fromnodeconfig"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor/v2inferenceprotocol"
 	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/util"
 )
 
@@ -30,6 +49,12 @@ var (
 	Scheme = runtime.NewScheme()
 )
 
+// Reasons of the PredictorSchemaValid condition.
+const (
+	ReasonSchemaMatched          = "SchemaMatched"
+	ReasonSchemaValidationFailed = "ValidationFailed"
+)
+
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(Scheme))
 	utilruntime.Must(waov1beta1.AddToScheme(Scheme))
@@ -54,6 +79,23 @@ type NodeConfigReconciler struct {
 
 	MetricsCollector *metrics.Collector
 	MetricsStore     *metrics.Store
+
+	// Sharder is used to run multiple replicas. If set, only NodeConfigs owned by this replica are collected.
+	// +optional
+	Sharder Sharder
+
+	// PushReceiver accepts pushed values for metrics with Type Push. If nil, Type Push is not supported.
+	// +optional
+	PushReceiver *push.Receiver
+}
+
+// Sharder decides which NodeConfigs this replica is responsible for.
+// See: sharding.Sharder
+type Sharder interface {
+	// Owns returns true if this replica owns the object with the given key.
+	Owns(key string) bool
+	// Changes returns a channel that receives a value when the ownership may have changed.
+	Changes() <-chan struct{}
 }
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
@@ -76,26 +118,152 @@ func (r *NodeConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		r.reconcileNodeConfigDeletion(ctx, req.NamespacedName)
 		return ctrl.Result{}, nil
 	}
+	// pushes may arrive at any replica, so push targets are registered regardless of sharding
+	if err := r.reconcilePushTargets(ctx, req.NamespacedName, &nc); err != nil {
+		lg.Error(err, "unable to reconcile push targets", "obj", &nc)
+		return ctrl.Result{}, err
+	}
+	if r.Sharder != nil && !r.Sharder.Owns(req.NamespacedName.String()) {
+		lg.Info("NodeConfig is owned by another replica")
+		r.unregisterAgents(req.NamespacedName)
+		return ctrl.Result{}, nil
+	}
 
 	if err := r.reconcileNodeConfig(ctx, req.NamespacedName, &nc); err != nil {
 		lg.Error(err, "unable to reconcile NodeConfig", "obj", &nc)
 		return ctrl.Result{}, err
 	}
+	valid, err := r.reconcilePredictorSchema(ctx, &nc)
+	if err != nil {
+		lg.Error(err, "unable to reconcile predictor schema", "obj", &nc)
+		return ctrl.Result{}, err
+	}
+	if !valid {
+		// the model may be fixed without changing the NodeConfig
+		return ctrl.Result{RequeueAfter: v2inferenceprotocol.MetadataRetryMaxInterval}, nil
+	}
 
 	return ctrl.Result{}, nil
 }
 
+// reconcilePredictorSchema validates the modelSchema of the power consumption predictors against the model metadata,
+// so that mismatches are reported when the NodeConfig changes instead of on the first prediction, and sets the PredictorSchemaValid condition.
+// Predictors from powerConsumptionEndpointProvider are resolved on predictions, so they are validated then.
+func (r *NodeConfigReconciler) reconcilePredictorSchema(ctx context.Context, nc *waov1beta1.NodeConfig) (valid bool, err error) {
+	lg := log.FromContext(ctx).WithValues("func", "reconcilePredictorSchema")
+
+	var fields []string
+	var terms []*waov1beta1.EndpointTerm
+	if et := nc.Spec.Predictor.PowerConsumption; et != nil && et.ModelSchema != nil && nc.Spec.Predictor.PowerConsumptionEndpointProvider == nil {
+		fields = append(fields, "powerConsumption")
+		terms = append(terms, et)
+	}
+	for i := range nc.Spec.Predictor.PowerConsumptionFallbacks {
+		if et := &nc.Spec.Predictor.PowerConsumptionFallbacks[i]; et.ModelSchema != nil {
+			fields = append(fields, fmt.Sprintf("powerConsumptionFallbacks[%d]", i))
+			terms = append(terms, et)
+		}
+	}
+
+	patch := client.MergeFrom(nc.DeepCopy())
+	var changed bool
+	if len(terms) == 0 {
+		valid = true
+		changed = meta.RemoveStatusCondition(&nc.Status.Conditions, waov1beta1.ConditionPredictorSchemaValid)
+	} else {
+		var errs []string
+		for i, et := range terms {
+			if err := fromnodeconfig.ValidatePowerConsumptionPredictor(ctx, r.SecretClient, nc.Namespace, et); err != nil {
+				errs = append(errs, fmt.Sprintf("predictor.%s: %v", fields[i], err))
+			}
+		}
+		valid = len(errs) == 0
+		cond := metav1.Condition{
+			Type:               waov1beta1.ConditionPredictorSchemaValid,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: nc.Generation,
+			Reason:             ReasonSchemaMatched,
+			Message:            "modelSchema matches the model",
+		}
+		if !valid {
+			cond.Status = metav1.ConditionFalse
+			cond.Reason = ReasonSchemaValidationFailed
+			cond.Message = strings.Join(errs, "; ")
+			lg.Info("unable to validate modelSchema", "obj", nc, "err", cond.Message)
+		}
+		changed = meta.SetStatusCondition(&nc.Status.Conditions, cond)
+	}
+	if !changed {
+		return valid, nil
+	}
+	if err := r.Status().Patch(ctx, nc, patch); err != nil {
+		return valid, fmt.Errorf("unable to update PredictorSchemaValid condition: %w", err)
+	}
+	return valid, nil
+}
+
 func (r *NodeConfigReconciler) reconcileNodeConfigDeletion(ctx context.Context, objKey types.NamespacedName) {
 	lg := log.FromContext(ctx).WithValues("func", "reconcileNodeConfigDeletion")
 	lg.Info("called")
 
+	r.unregisterAgents(objKey)
+	if r.PushReceiver != nil {
+		for _, vt := range metrics.ValueTypes {
+			r.PushReceiver.Unregister(string(metrics.CollectorKey(objKey, vt)))
+		}
+	}
+}
+
+func (r *NodeConfigReconciler) unregisterAgents(objKey types.NamespacedName) {
 	for _, vt := range metrics.ValueTypes {
 		r.MetricsCollector.Unregister(metrics.CollectorKey(objKey, vt))
 	}
 }
 
+// metricSource is a metric collected for a NodeConfig.
+type metricSource struct {
+	valueType metrics.ValueType
+	// field is the field name in metricsCollector, used in error messages.
+	field string
+	// conf is nil if the metric is not configured.
+	conf *waov1beta1.EndpointTerm
+	// fallback is nil if the metric has no additional sources.
+	fallback *waov1beta1.FallbackTerm
+}
+
+func metricSources(nc *waov1beta1.NodeConfig) []metricSource {
+	mc := &nc.Spec.MetricsCollector
+	return []metricSource{
+		{metrics.ValueInletTemperature, "inletTemp", &mc.InletTemp, mc.InletTempFallback},
+		{metrics.ValueDeltaPressure, "deltaP", &mc.DeltaP, mc.DeltaPFallback},
+		{metrics.ValuePowerConsumption, "powerConsumption", mc.PowerConsumption, mc.PowerConsumptionFallback},
+	}
+}
+
+func (r *NodeConfigReconciler) reconcilePushTargets(ctx context.Context, objKey types.NamespacedName, nc *waov1beta1.NodeConfig) error {
+	for _, src := range metricSources(nc) {
+		vt, conf := src.valueType, src.conf
+		k := string(metrics.CollectorKey(objKey, vt))
+		// Type Push with fallback sources is rejected in reconcileNodeConfig()
+		if conf == nil || conf.Type != waov1beta1.TypePush || src.fallback != nil {
+			if r.PushReceiver != nil {
+				r.PushReceiver.Unregister(k)
+			}
+			continue
+		}
+		if r.PushReceiver == nil {
+			return fmt.Errorf("type %s is used for %s but push ingestion is disabled", waov1beta1.TypePush, vt)
+		}
+		username, password := util.GetBasicAuthFromNamespaceScopedSecret(ctx, r.SecretClient, objKey.Namespace, conf.BasicAuthSecret)
+		r.PushReceiver.Register(k, nc.Spec.NodeName, vt, username, password)
+	}
+	return nil
+}
+
 const (
 	DefaultFetchInterval = 15 * time.Second
+	// DefaultStreamIdleTimeout is the minimum duration to wait for data (including keep-alives) before reconnecting streams.
+	DefaultStreamIdleTimeout = 1 * time.Minute
 )
 
 func defaultEndpointTerm(et *waov1beta1.EndpointTerm) {
@@ -104,64 +272,361 @@ func defaultEndpointTerm(et *waov1beta1.EndpointTerm) {
 	}
 }
 
+// endpointTermFingerprint returns a hash of everything the agent is built from,
+// so that the Collector restarts the agent only when one of them changes.
+// Credentials are hashed so that they are not kept in memory as is.
+func endpointTermFingerprint(nodeName string, et waov1beta1.EndpointTerm, username, password string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%q %q %q ", nodeName, username, password)
+	json.NewEncoder(h).Encode(et) // covers type-specific options too
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fallbackFingerprint returns a hash of the fingerprints of the sources and the fallback options.
+func fallbackFingerprint(sourceFingerprints []string, fb waov1beta1.FallbackTerm) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%q %v ", fb.Policy, sourceFingerprints)
+	json.NewEncoder(h).Encode(fb.FailureThreshold)
+	json.NewEncoder(h).Encode(fb.OpenDuration)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func prometheusQuery(et waov1beta1.EndpointTerm) (string, error) {
+	if et.Prometheus == nil || et.Prometheus.Query == "" {
+		return "", fmt.Errorf("prometheus.query is required for type %s", waov1beta1.TypePrometheus)
+	}
+	return et.Prometheus.Query, nil
+}
+
+func httpJSONOptions(et waov1beta1.EndpointTerm) (httpjson.Options, error) {
+	var opts httpjson.Options
+	if et.HTTPJSON == nil || et.HTTPJSON.JSONPath == "" {
+		return opts, fmt.Errorf("httpJSON.jsonPath is required for type %s", waov1beta1.TypeHTTPJSON)
+	}
+	opts.Method = et.HTTPJSON.Method
+	opts.Body = et.HTTPJSON.Body
+	opts.JSONPath = et.HTTPJSON.JSONPath
+	opts.Unit = httpjson.Unit(et.HTTPJSON.Unit)
+	var err error
+	if opts.Scale, err = parseDecimal("httpJSON.scale", et.HTTPJSON.Scale); err != nil {
+		return opts, err
+	}
+	if opts.Offset, err = parseDecimal("httpJSON.offset", et.HTTPJSON.Offset); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+func modbusOptions(et waov1beta1.EndpointTerm) (modbus.Options, error) {
+	var opts modbus.Options
+	if et.Modbus == nil {
+		return opts, fmt.Errorf("modbus is required for type %s", waov1beta1.TypeModbusTCP)
+	}
+	opts.UnitID = 1
+	if et.Modbus.UnitID != nil {
+		opts.UnitID = byte(*et.Modbus.UnitID)
+	}
+	opts.Address = uint16(et.Modbus.Address)
+	opts.RegisterType = modbus.RegisterType(et.Modbus.RegisterType)
+	opts.DataType = modbus.DataType(et.Modbus.DataType)
+	opts.ByteOrder = modbus.ByteOrder(et.Modbus.ByteOrder)
+	var err error
+	if opts.Scale, err = parseDecimal("modbus.scale", et.Modbus.Scale); err != nil {
+		return opts, err
+	}
+	if opts.Offset, err = parseDecimal("modbus.offset", et.Modbus.Offset); err != nil {
+		return opts, err
+	}
+	// share batched reads with other agents on the same device within half of the interval
+	opts.MaxAge = et.FetchInterval.Duration / 2
+	return opts, nil
+}
+
+func snmpOptions(et waov1beta1.EndpointTerm, username, password string) (snmp.Security, snmp.Options, error) {
+	var sec snmp.Security
+	var opts snmp.Options
+	if et.SNMP == nil || et.SNMP.OID == "" {
+		return sec, opts, fmt.Errorf("snmp.oid is required for type %s", waov1beta1.TypeSNMP)
+	}
+	sec.Version = snmp.Version(et.SNMP.Version)
+	if sec.Version == snmp.Version3 {
+		sec.UserName = username
+		sec.SecurityLevel = snmp.SecurityLevel(et.SNMP.SecurityLevel)
+		sec.AuthProtocol = snmp.AuthProtocol(et.SNMP.AuthProtocol)
+		sec.AuthPassword = password
+	} else {
+		sec.Community = password
+	}
+	opts.OID = et.SNMP.OID
+	var err error
+	if opts.Scale, err = parseDecimal("snmp.scale", et.SNMP.Scale); err != nil {
+		return sec, opts, err
+	}
+	if opts.Offset, err = parseDecimal("snmp.offset", et.SNMP.Offset); err != nil {
+		return sec, opts, err
+	}
+	// share batched requests with other agents on the same device within half of the interval
+	opts.MaxAge = et.FetchInterval.Duration / 2
+	return sec, opts, nil
+}
+
+// parseDecimal parses s as a float64. Empty s means 0.
+func parseDecimal(field, s string) (float64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", field, err)
+	}
+	return v, nil
+}
+
 func (r *NodeConfigReconciler) reconcileNodeConfig(ctx context.Context, objKey types.NamespacedName, nc *waov1beta1.NodeConfig) error {
 	lg := log.FromContext(ctx).WithValues("func", "reconcileNodeConfig")
 	lg.Info("called")
 
-	// setup inlet temp agent
-	{
-		conf := nc.Spec.MetricsCollector.InletTemp
-		defaultEndpointTerm(&conf)
-		var agent metrics.Agent
-		username, password := util.GetBasicAuthFromNamespaceScopedSecret(ctx, r.SecretClient, objKey.Namespace, conf.BasicAuthSecret)
-		fetchTimeout := conf.FetchInterval.Duration - 300*time.Millisecond
-		switch conf.Type {
-		case waov1beta1.TypeFake:
-			agent = fake.NewInletTempAgent(15.5, nil, 100*time.Millisecond) // fake agent always returns this value
-		case waov1beta1.TypeRedfish:
-			insecureSkipVerify := true
-			requestTimeout := fetchTimeout - 300*time.Millisecond
-			requestEditorFns := []util.RequestEditorFn{
-				util.WithBasicAuth(username, password),
-				util.WithCurlLogger(slog.With("func", "WithCurlLogger(RedfishClient.Fetch)", "node", nc.Spec.NodeName)),
+	for _, src := range metricSources(nc) {
+		k := metrics.CollectorKey(objKey, src.valueType)
+		if src.conf == nil {
+			r.MetricsCollector.Unregister(k)
+			if src.fallback != nil {
+				return fmt.Errorf("invalid metricsCollector.%sFallback: %s is required to use fallback sources", src.field, src.field)
 			}
-			agent = redfish.NewInletTempAgent(conf.Endpoint, redfish.TypeAutoDetect, insecureSkipVerify, requestTimeout, requestEditorFns...)
-		default:
-			return fmt.Errorf("unsupported metricsCollector.inletTemp.type: %s", conf.Type)
+			continue
 		}
-		r.MetricsCollector.Register(metrics.CollectorKey(objKey, metrics.ValueInletTemperature), agent, r.MetricsStore, nc.Spec.NodeName, conf.FetchInterval.Duration, fetchTimeout)
-	}
-
-	// setup delta pressure agent
-	{
-		conf := nc.Spec.MetricsCollector.DeltaP
+		conf := *src.conf
 		defaultEndpointTerm(&conf)
-		var agent metrics.Agent
-		username, password := util.GetBasicAuthFromNamespaceScopedSecret(ctx, r.SecretClient, objKey.Namespace, conf.BasicAuthSecret)
 		fetchTimeout := conf.FetchInterval.Duration - 300*time.Millisecond
-		switch conf.Type {
-		case waov1beta1.TypeFake:
-			agent = fake.NewDeltaPAgent(7.5, nil, 100*time.Millisecond) // fake agent always returns this value
-		case waov1beta1.TypeDPAPI:
-			insecureSkipVerify := true
-			requestTimeout := fetchTimeout - 300*time.Millisecond
-			requestEditorFns := []util.RequestEditorFn{
-				util.WithBasicAuth(username, password),
-				util.WithCurlLogger(slog.With("func", "WithCurlLogger(DifferentialPressureAPIClient.Fetch)", "node", nc.Spec.NodeName)),
+		if src.fallback != nil {
+			agent, fingerprint, err := r.newFallbackAgent(ctx, objKey.Namespace, nc.Spec.NodeName, src, conf, fetchTimeout)
+			if err != nil {
+				return err
 			}
-			agent = dpapi.NewDeltaPAgent(conf.Endpoint, "", nc.Spec.NodeName, "", insecureSkipVerify, requestTimeout, requestEditorFns...)
-		default:
-			return fmt.Errorf("unsupported metricsCollector.deltaP.type: %s", conf.Type)
+			// sources are fetched one by one in the worst case
+			timeout := fetchTimeout * time.Duration(1+len(src.fallback.Sources))
+			r.MetricsCollector.Register(k, fingerprint, agent, r.MetricsStore, nc.Spec.NodeName, conf.FetchInterval.Duration, timeout)
+			continue
+		}
+		username, password := util.GetBasicAuthFromNamespaceScopedSecret(ctx, r.SecretClient, objKey.Namespace, conf.BasicAuthSecret)
+		agent, err := newAgent(src.valueType, conf, nc.Spec.NodeName, username, password, fetchTimeout)
+		if err != nil {
+			return fmt.Errorf("invalid metricsCollector.%s: %w", src.field, err)
+		}
+		if agent == nil {
+			r.MetricsCollector.Unregister(k)
+		} else {
+			fingerprint := endpointTermFingerprint(nc.Spec.NodeName, conf, username, password)
+			r.MetricsCollector.Register(k, fingerprint, agent, r.MetricsStore, nc.Spec.NodeName, conf.FetchInterval.Duration, fetchTimeout)
 		}
-		r.MetricsCollector.Register(metrics.CollectorKey(objKey, metrics.ValueDeltaPressure), agent, r.MetricsStore, nc.Spec.NodeName, conf.FetchInterval.Duration, fetchTimeout)
 	}
 
 	return nil
 }
 
+// newFallbackAgent returns a FallbackAgent with conf (defaulted) as the first source followed by src.fallback.Sources.
+func (r *NodeConfigReconciler) newFallbackAgent(ctx context.Context, namespace, nodeName string, src metricSource, conf waov1beta1.EndpointTerm, fetchTimeout time.Duration) (metrics.Agent, string, error) {
+	fb := *src.fallback
+	fields := []string{src.field}
+	terms := []waov1beta1.EndpointTerm{conf}
+	for i, et := range fb.Sources {
+		fields = append(fields, fmt.Sprintf("%sFallback.sources[%d]", src.field, i))
+		// the interval of the metric is used for all sources
+		et.FetchInterval = conf.FetchInterval
+		terms = append(terms, et)
+	}
+
+	var sources []metrics.FallbackSource
+	var fingerprints []string
+	// agents built so far are closed on errors, as they may hold shared connections
+	closeSources := func() {
+		for _, s := range sources {
+			metrics.CloseAgent(s.Agent)
+		}
+	}
+	for i, et := range terms {
+		if et.Type == waov1beta1.TypePush {
+			closeSources()
+			return nil, "", fmt.Errorf("invalid metricsCollector.%s: type %s is not supported with fallback sources", fields[i], et.Type)
+		}
+		username, password := util.GetBasicAuthFromNamespaceScopedSecret(ctx, r.SecretClient, namespace, et.BasicAuthSecret)
+		weight, err := parseDecimal("weight", et.Weight)
+		if err != nil {
+			closeSources()
+			return nil, "", fmt.Errorf("invalid metricsCollector.%s: %w", fields[i], err)
+		}
+		agent, err := newAgent(src.valueType, et, nodeName, username, password, fetchTimeout)
+		if err != nil {
+			closeSources()
+			return nil, "", fmt.Errorf("invalid metricsCollector.%s: %w", fields[i], err)
+		}
+		if et.Weight == "" {
+			weight = 1
+		}
+		sources = append(sources, metrics.FallbackSource{
+			Name:    fmt.Sprintf("%s-%d", strings.ToLower(et.Type), i),
+			Agent:   agent,
+			Weight:  weight,
+			Timeout: fetchTimeout,
+		})
+		fingerprints = append(fingerprints, endpointTermFingerprint(nodeName, et, username, password))
+	}
+
+	var breaker metrics.CircuitBreaker
+	if fb.FailureThreshold != nil {
+		breaker.FailureThreshold = int(*fb.FailureThreshold)
+	}
+	if fb.OpenDuration != nil {
+		breaker.OpenDuration = fb.OpenDuration.Duration
+	}
+	agent, err := metrics.NewFallbackAgent(src.valueType, metrics.Policy(fb.Policy), breaker, sources)
+	if err != nil {
+		closeSources()
+		return nil, "", fmt.Errorf("invalid metricsCollector.%sFallback: %w", src.field, err)
+	}
+	return agent, fallbackFingerprint(fingerprints, fb), nil
+}
+
+// newAgent returns the agent for the EndpointTerm, or nil if values are not fetched by an agent (e.g., Type Push).
+func newAgent(vt metrics.ValueType, conf waov1beta1.EndpointTerm, nodeName string, username, password string, fetchTimeout time.Duration) (metrics.Agent, error) {
+	insecureSkipVerify := true
+	requestTimeout := fetchTimeout - 300*time.Millisecond
+	curlLogger := func(client string) util.RequestEditorFn {
+		return util.WithCurlLogger(slog.With("func", fmt.Sprintf("WithCurlLogger(%s.Fetch)", client), "node", nodeName))
+	}
+
+	switch {
+	case conf.Type == waov1beta1.TypeFake:
+		profile, err := fake.ParseProfile(conf.Endpoint, nodeName)
+		if err != nil {
+			return nil, err
+		}
+		if profile != nil {
+			return fake.NewProfileAgent(vt, profile, 100*time.Millisecond), nil
+		}
+		// fake agent always returns this value if the endpoint is not a profile
+		switch vt {
+		case metrics.ValueInletTemperature:
+			return fake.NewInletTempAgent(15.5, nil, 100*time.Millisecond), nil
+		case metrics.ValueDeltaPressure:
+			return fake.NewDeltaPAgent(7.5, nil, 100*time.Millisecond), nil
+		case metrics.ValuePowerConsumption:
+			return fake.NewPowerConsumptionAgent(200.0, nil, 100*time.Millisecond), nil
+		}
+	case conf.Type == waov1beta1.TypeRedfish && vt == metrics.ValueInletTemperature:
+		return redfish.NewInletTempAgent(conf.Endpoint, redfish.TypeAutoDetect, insecureSkipVerify, requestTimeout,
+			util.WithBasicAuth(username, password), curlLogger("RedfishClient")), nil
+	case conf.Type == waov1beta1.TypeRedfish && vt == metrics.ValuePowerConsumption:
+		return redfish.NewPowerConsumptionAgent(conf.Endpoint, redfish.TypeAutoDetect, insecureSkipVerify, requestTimeout,
+			util.WithBasicAuth(username, password), curlLogger("RedfishClient")), nil
+	case conf.Type == waov1beta1.TypeRedfishSSE && vt == metrics.ValueInletTemperature:
+		idleTimeout := max(3*conf.FetchInterval.Duration, DefaultStreamIdleTimeout)
+		return redfish.NewStreamingInletTempAgent(conf.Endpoint, redfish.TypeAutoDetect, insecureSkipVerify, requestTimeout, idleTimeout,
+			util.WithBasicAuth(username, password), curlLogger("RedfishStreamingClient")), nil
+	case conf.Type == waov1beta1.TypeRedfishSSE && vt == metrics.ValuePowerConsumption:
+		idleTimeout := max(3*conf.FetchInterval.Duration, DefaultStreamIdleTimeout)
+		return redfish.NewStreamingPowerConsumptionAgent(conf.Endpoint, redfish.TypeAutoDetect, insecureSkipVerify, requestTimeout, idleTimeout,
+			util.WithBasicAuth(username, password), curlLogger("RedfishStreamingClient")), nil
+	case conf.Type == waov1beta1.TypeDPAPI && vt == metrics.ValueDeltaPressure:
+		return dpapi.NewDeltaPAgent(conf.Endpoint, "", nodeName, "", insecureSkipVerify, requestTimeout,
+			util.WithBasicAuth(username, password), curlLogger("DifferentialPressureAPIClient")), nil
+	case conf.Type == waov1beta1.TypeDPAPI && vt == metrics.ValueInletTemperature:
+		return dpapi.NewInletTempAgent(conf.Endpoint, "", nodeName, "", insecureSkipVerify, requestTimeout,
+			util.WithBasicAuth(username, password), curlLogger("DifferentialPressureAPIClient")), nil
+	case conf.Type == waov1beta1.TypePrometheus:
+		query, err := prometheusQuery(conf)
+		if err != nil {
+			return nil, err
+		}
+		return prometheus.NewAgent(vt, conf.Endpoint, query, insecureSkipVerify, requestTimeout,
+			util.WithBasicAuth(username, password), curlLogger("PrometheusClient")), nil
+	case conf.Type == waov1beta1.TypeHTTPJSON:
+		opts, err := httpJSONOptions(conf)
+		if err != nil {
+			return nil, err
+		}
+		a, err := httpjson.NewAgent(vt, conf.Endpoint, opts, insecureSkipVerify, requestTimeout,
+			util.WithBasicAuth(username, password), curlLogger("HTTPJSONClient"))
+		if err != nil {
+			return nil, err
+		}
+		return a, nil
+	case conf.Type == waov1beta1.TypeModbusTCP:
+		opts, err := modbusOptions(conf)
+		if err != nil {
+			return nil, err
+		}
+		a, err := modbus.NewAgent(vt, conf.Endpoint, opts, requestTimeout)
+		if err != nil {
+			return nil, err
+		}
+		return a, nil
+	case conf.Type == waov1beta1.TypeSNMP:
+		sec, opts, err := snmpOptions(conf, username, password)
+		if err != nil {
+			return nil, err
+		}
+		a, err := snmp.NewAgent(vt, conf.Endpoint, sec, opts, requestTimeout)
+		if err != nil {
+			return nil, err
+		}
+		return a, nil
+	case conf.Type == waov1beta1.TypeNodeAgent && vt != metrics.ValueDeltaPressure:
+		return nodeagent.NewAgent(vt, conf.Endpoint, insecureSkipVerify, requestTimeout,
+			util.WithBasicAuth(username, password), curlLogger("NodeAgentClient")), nil
+	case conf.Type == waov1beta1.TypeReplay:
+		a, err := replay.NewAgentFromEndpoint(vt, conf.Endpoint, nodeName)
+		if err != nil {
+			return nil, err
+		}
+		return a, nil
+	case conf.Type == waov1beta1.TypePush:
+		// values are pushed to PushReceiver, see reconcilePushTargets()
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported type: %s", conf.Type)
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *NodeConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&waov1beta1.NodeConfig{}).
-		Complete(r)
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&waov1beta1.NodeConfig{})
+
+	if r.Sharder != nil {
+		// re-reconcile all NodeConfigs when the ownership changes
+		ch := make(chan event.GenericEvent)
+		if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+			return r.resyncOnShardChanges(ctx, ch)
+		})); err != nil {
+			return err
+		}
+		b = b.WatchesRawSource(source.Channel(ch, &handler.EnqueueRequestForObject{}))
+	}
+
+	return b.Complete(r)
+}
+
+func (r *NodeConfigReconciler) resyncOnShardChanges(ctx context.Context, ch chan<- event.GenericEvent) error {
+	lg := log.FromContext(ctx).WithValues("func", "resyncOnShardChanges")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-r.Sharder.Changes():
+			var ncs waov1beta1.NodeConfigList
+			if err := r.List(ctx, &ncs); err != nil {
+				lg.Error(err, "unable to list NodeConfigs")
+				continue
+			}
+			lg.Info("shard changed so resync all NodeConfigs", "n", len(ncs.Items))
+			for i := range ncs.Items {
+				select {
+				case <-ctx.Done():
+					return nil
+				case ch <- event.GenericEvent{Object: &ncs.Items[i]}:
+				}
+			}
+		}
+	}
 }
@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	waov1beta1 "github.com/waok8s/waok8s/wao-core/api/node/v1beta1"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+)
+
+func TestNodeConfigReconciler_reconcilePredictorSchema(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/v2/models/power" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"inputs": [{"name": "predict-prob", "datatype": "FP32", "shape": [-1, 3]}], "outputs": [{"name": "predict", "datatype": "FP64", "shape": [-1, 1]}]}`))
+	}))
+	defer srv.Close()
+	endpoint := srv.URL + "/v2/models/power/infer"
+
+	tests := []struct {
+		name       string
+		predictor  waov1beta1.Predictor
+		wantValid  bool
+		wantStatus metav1.ConditionStatus // empty means no condition
+	}{
+		{
+			name:       "matched",
+			predictor:  waov1beta1.Predictor{PowerConsumption: &waov1beta1.EndpointTerm{Type: waov1beta1.TypeV2InferenceProtocol, Endpoint: endpoint, ModelSchema: &waov1beta1.ModelSchemaTerm{InputName: "predict-prob"}}},
+			wantValid:  true,
+			wantStatus: metav1.ConditionTrue,
+		},
+		{
+			name: "fallback_mismatched",
+			predictor: waov1beta1.Predictor{
+				PowerConsumption:          &waov1beta1.EndpointTerm{Type: waov1beta1.TypeV2InferenceProtocol, Endpoint: endpoint, ModelSchema: &waov1beta1.ModelSchemaTerm{InputName: "predict-prob"}},
+				PowerConsumptionFallbacks: []waov1beta1.EndpointTerm{{Type: waov1beta1.TypeV2InferenceProtocol, Endpoint: endpoint, ModelSchema: &waov1beta1.ModelSchemaTerm{InputName: "other"}}},
+			},
+			wantValid:  false,
+			wantStatus: metav1.ConditionFalse,
+		},
+		{
+			name:       "unreachable",
+			predictor:  waov1beta1.Predictor{PowerConsumption: &waov1beta1.EndpointTerm{Type: waov1beta1.TypeV2InferenceProtocol, Endpoint: srv.URL + "/v2/models/missing/infer", ModelSchema: &waov1beta1.ModelSchemaTerm{}}},
+			wantValid:  false,
+			wantStatus: metav1.ConditionFalse,
+		},
+		{
+			name:      "no_schema",
+			predictor: waov1beta1.Predictor{PowerConsumption: &waov1beta1.EndpointTerm{Type: waov1beta1.TypeV2InferenceProtocol, Endpoint: endpoint}},
+			wantValid: true,
+		},
+	}
+
+	ctx := context.Background()
+	nc := &waov1beta1.NodeConfig{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nc-0"}}
+	c := fake.NewClientBuilder().WithScheme(Scheme).WithObjects(nc).WithStatusSubresource(nc).Build()
+	r := &NodeConfigReconciler{Client: c, Scheme: Scheme}
+	// run in order on the same NodeConfig, so that the condition is updated and removed
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got waov1beta1.NodeConfig
+			if err := c.Get(ctx, client.ObjectKeyFromObject(nc), &got); err != nil {
+				t.Fatal(err)
+			}
+			got.Spec.Predictor = tt.predictor
+			valid, err := r.reconcilePredictorSchema(ctx, &got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if valid != tt.wantValid {
+				t.Errorf("reconcilePredictorSchema() = %v, want %v", valid, tt.wantValid)
+			}
+
+			if err := c.Get(ctx, client.ObjectKeyFromObject(nc), &got); err != nil {
+				t.Fatal(err)
+			}
+			cond := meta.FindStatusCondition(got.Status.Conditions, waov1beta1.ConditionPredictorSchemaValid)
+			switch {
+			case tt.wantStatus == "" && cond != nil:
+				t.Errorf("got condition %+v, want none", cond)
+			case tt.wantStatus != "" && (cond == nil || cond.Status != tt.wantStatus):
+				t.Errorf("got condition %+v, want status %s", cond, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestNodeConfigReconciler_reconcileNodeConfig_fallback(t *testing.T) {
+	fakeTerm := waov1beta1.EndpointTerm{Type: waov1beta1.TypeFake, Endpoint: "constant?value=20"}
+	tests := []struct {
+		name    string
+		mc      waov1beta1.MetricsCollector
+		want    int // registered runners
+		wantErr bool
+	}{
+		{
+			name: "power_consumption_fallback",
+			mc: waov1beta1.MetricsCollector{InletTemp: fakeTerm, DeltaP: fakeTerm,
+				PowerConsumption: &fakeTerm, PowerConsumptionFallback: &waov1beta1.FallbackTerm{Sources: []waov1beta1.EndpointTerm{fakeTerm}}},
+			want: 3,
+		},
+		{
+			name: "power_consumption_fallback_without_power_consumption",
+			mc: waov1beta1.MetricsCollector{InletTemp: fakeTerm, DeltaP: fakeTerm,
+				PowerConsumptionFallback: &waov1beta1.FallbackTerm{Sources: []waov1beta1.EndpointTerm{fakeTerm}}},
+			want:    2,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &NodeConfigReconciler{MetricsCollector: &metrics.Collector{}, MetricsStore: &metrics.Store{}}
+			defer r.MetricsCollector.UnregisterAll()
+			nc := &waov1beta1.NodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nc-0"},
+				Spec:       waov1beta1.NodeConfigSpec{NodeName: "node-0", MetricsCollector: tt.mc},
+			}
+			err := r.reconcileNodeConfig(context.Background(), client.ObjectKeyFromObject(nc), nc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("reconcileNodeConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := r.MetricsCollector.Len(); got != tt.want {
+				t.Errorf("got %d registered runners, want %d", got, tt.want)
+			}
+		})
+	}
+}
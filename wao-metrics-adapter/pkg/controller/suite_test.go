@@ -73,7 +73,7 @@ var _ = BeforeSuite(func() {
 
 	// init clients
 	secretClient = kubernetes.NewForConfigOrDie(cfg)
-	cachedPredictorClient = waoclient.NewCachedPredictorClient(secretClient, 10*time.Second)
+	cachedPredictorClient = waoclient.NewCachedPredictorClient(secretClient, 10*time.Second, waoclient.DefaultPredictorCacheSize)
 })
 
 var _ = AfterSuite(func() {
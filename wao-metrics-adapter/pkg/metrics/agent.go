@@ -2,9 +2,44 @@ package metrics
 
 import (
 	"context"
+	"errors"
+	"time"
 )
 
 type Agent interface {
 	ValueType() ValueType
 	Fetch(ctx context.Context) (value float64, err error)
 }
+
+// LabeledAgent is an Agent that attaches labels to fetched values.
+// Labels is called right after a successful Fetch, so it may return labels of the last fetched value.
+type LabeledAgent interface {
+	Agent
+	Labels() map[string]string
+}
+
+// ClosableAgent is an Agent that holds resources shared with other agents (e.g., connections to a device).
+// Close is called once the agent is no longer used, i.e., its runner has stopped or it was not registered.
+type ClosableAgent interface {
+	Agent
+	Close()
+}
+
+// CloseAgent closes the agent if it is a ClosableAgent.
+func CloseAgent(a Agent) {
+	if ca, ok := a.(ClosableAgent); ok {
+		ca.Close()
+	}
+}
+
+// ErrStreamNotSupported is returned by StreamingAgent.Stream if the source does not support streaming.
+var ErrStreamNotSupported = errors.New("streaming not supported")
+
+// StreamingAgent is an Agent that can receive values as they change.
+// Fetch is still used as a fallback while no value is streamed (e.g., disconnected).
+type StreamingAgent interface {
+	Agent
+	// Stream blocks until ctx is done or the stream ends, calling emit for each received value.
+	// It returns ErrStreamNotSupported (possibly wrapped) if the source does not support streaming.
+	Stream(ctx context.Context, emit func(value float64, timestamp time.Time)) error
+}
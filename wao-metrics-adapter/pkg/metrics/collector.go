@@ -2,10 +2,13 @@ package metrics
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"k8s.io/apimachinery/pkg/types"
@@ -36,11 +39,25 @@ var agentRunnerMaxInitialDelay = 10 * time.Second
 
 func (r *agentRunner) Run() {
 	lg := slog.With("func", "agentRunner.Run", "nodeName", r.nodeName, "agent.ValueType", r.agent.ValueType())
+	defer CloseAgent(r.agent)
 
 	// random sleep to avoid spikes
 	d := time.Duration(rand.Int63n(int64(min(r.interval, agentRunnerMaxInitialDelay))))
 	lg.Info("start with initial delay", "delay", d)
-	time.Sleep(d)
+	select {
+	case <-r.stopCh:
+		lg.Info("stopped")
+		return
+	case <-time.After(d):
+	}
+
+	if sa, ok := r.agent.(StreamingAgent); ok {
+		r.runStreaming(sa)
+		return
+	}
+
+	// fetch once right after the initial delay so that values are available as soon as possible
+	r.fetch()
 
 	for {
 		select {
@@ -48,29 +65,107 @@ func (r *agentRunner) Run() {
 			lg.Info("stopped")
 			return
 		case <-time.After(r.interval):
-			ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
-			v, err := r.agent.Fetch(ctx)
-			cancel()
-			if err != nil {
-				lg.Error("failed to fetch", "error", err)
-				continue
-			}
+			r.fetch()
+		}
+	}
+}
+
+func (r *agentRunner) fetch() {
+	lg := slog.With("func", "agentRunner.fetch", "nodeName", r.nodeName, "agent.ValueType", r.agent.ValueType())
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	v, err := r.agent.Fetch(ctx)
+	cancel()
+	if err != nil {
+		lg.Error("failed to fetch", "error", err)
+		return
+	}
+
+	r.set(v, time.Now())
+}
 
-			k := StoreKeyForNode(r.nodeName)
-			m := r.store.GetOrInit(k)
-			switch r.agent.ValueType() {
-			case ValueInletTemperature:
-				m.InletTemp = v
-				m.InletTempTimestamp = time.Now()
-			case ValueDeltaPressure:
-				m.DeltaPressure = v
-				m.DeltaPressureTimestamp = time.Now()
+// set writes v into the Store. NaN and infinite values are rejected, as they would break predictions and aggregations.
+func (r *agentRunner) set(v float64, timestamp time.Time) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		slog.Warn("invalid value, so ignore", "func", "agentRunner.set", "nodeName", r.nodeName, "agent.ValueType", r.agent.ValueType(), "value", v)
+		collectedInvalidValuesTotal.WithLabelValues(r.nodeName, string(r.agent.ValueType())).Inc()
+		return
+	}
+
+	var labels map[string]string
+	if la, ok := r.agent.(LabeledAgent); ok {
+		labels = la.Labels()
+	}
+
+	r.store.Update(StoreKeyForNode(r.nodeName), func(m MetricData) MetricData {
+		return m.WithValue(r.agent.ValueType(), v, timestamp, labels)
+	})
+	r.store.Record(Sample{Node: r.nodeName, Metric: r.agent.ValueType(), Value: v, Timestamp: timestamp, Labels: labels})
+}
+
+var (
+	streamMinBackoff = 1 * time.Second
+	streamMaxBackoff = 5 * time.Minute
+)
+
+// runStreaming streams values in background, and polls only while no value is streamed in the last interval.
+// So polling works as a fallback when the stream is not supported, disconnected or quiet.
+func (r *agentRunner) runStreaming(sa StreamingAgent) {
+	lg := slog.With("func", "agentRunner.runStreaming", "nodeName", r.nodeName, "agent.ValueType", r.agent.ValueType())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// fetch once before streaming so that values are available while connecting
+	r.fetch()
+
+	var lastStreamed atomic.Int64 // UnixNano
+	go r.stream(ctx, sa, &lastStreamed)
+	for {
+		select {
+		case <-r.stopCh:
+			lg.Info("stopped")
+			return
+		case <-time.After(r.interval):
+			if time.Since(time.Unix(0, lastStreamed.Load())) > r.interval {
+				r.fetch()
 			}
-			r.store.Set(k, m)
 		}
 	}
 }
 
+// stream keeps the stream connected with exponential backoff until ctx is done or streaming is not supported.
+func (r *agentRunner) stream(ctx context.Context, sa StreamingAgent, lastStreamed *atomic.Int64) {
+	lg := slog.With("func", "agentRunner.stream", "nodeName", r.nodeName, "agent.ValueType", r.agent.ValueType())
+
+	backoff := streamMinBackoff
+	for {
+		start := time.Now()
+		err := sa.Stream(ctx, func(v float64, timestamp time.Time) {
+			lastStreamed.Store(time.Now().UnixNano())
+			r.set(v, timestamp)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, ErrStreamNotSupported) {
+			lg.Info("streaming not supported, so use polling", "err", err)
+			return
+		}
+		// reset backoff if the stream has been working for a while
+		if time.Since(start) > streamMaxBackoff {
+			backoff = streamMinBackoff
+		}
+		lg.Error("stream disconnected, so reconnect later", "err", err, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, streamMaxBackoff)
+	}
+}
+
 func (r *agentRunner) Stop() {
 	lg := slog.With("func", "agentRunner.Stop", "nodeName", r.nodeName, "agent.ValueType", r.agent.ValueType())
 	lg.Info("stop")
@@ -83,36 +178,87 @@ func CollectorKey(objKey types.NamespacedName, valueType ValueType) collectorKey
 	return collectorKey(fmt.Sprintf("%s#%s", objKey, valueType))
 }
 
-type Collector struct{ m sync.Map }
+// Collector runs an agentRunner for each collectorKey.
+type Collector struct {
+	mu sync.Mutex
+	m  map[collectorKey]*registeredRunner
+}
+
+type registeredRunner struct {
+	runner      *agentRunner
+	fingerprint string
+}
 
 const MinInterval = 1 * time.Second
 
-func (c *Collector) Register(k collectorKey, a Agent, s *Store, nodeName string, interval time.Duration, timeout time.Duration) {
+// Register starts an agentRunner for k.
+// fingerprint identifies the agent config (e.g., type, endpoint, auth and interval).
+// If a runner with the same fingerprint is already registered, Register keeps it, closes a and returns false.
+// Otherwise, it stops the old runner (if any), starts a new one and returns true.
+// The agent of a runner is closed when the runner stops.
+func (c *Collector) Register(k collectorKey, fingerprint string, a Agent, s *Store, nodeName string, interval time.Duration, timeout time.Duration) bool {
 	lg := slog.With("func", "Collector.Register", "key", k, "nodeName", nodeName)
-	lg.Info("register")
+
+	RegisterMetrics()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.m == nil {
+		c.m = map[collectorKey]*registeredRunner{}
+	}
+	if old, ok := c.m[k]; ok {
+		if old.fingerprint == fingerprint {
+			lg.Debug("already registered")
+			CloseAgent(a)
+			return false
+		}
+		lg.Info("config changed, so restart")
+		old.runner.Stop()
+	} else {
+		lg.Info("register")
+	}
 
 	ar := newAgentRunner(a, s, nodeName, max(interval, MinInterval), timeout)
 	go ar.Run()
-	c.m.Store(k, ar)
+	c.m[k] = &registeredRunner{runner: ar, fingerprint: fingerprint}
+	collectorRegisteredRunners.Set(float64(len(c.m)))
+	return true
 }
 
 func (c *Collector) Unregister(k collectorKey) {
 	lg := slog.With("func", "Collector.Unregister", "key", k)
-	lg.Info("unregister")
 
-	defer c.m.Delete(k)
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	v, ok := c.m.Load(k)
+	rr, ok := c.m[k]
 	if !ok {
-		lg.Error("agentRunner not found")
+		lg.Debug("agentRunner not found")
 		return
 	}
+	lg.Info("unregister")
+	rr.runner.Stop()
+	delete(c.m, k)
+	collectorRegisteredRunners.Set(float64(len(c.m)))
+}
 
-	ar, ok := v.(*agentRunner)
-	if !ok {
-		lg.Error("agentRunner type assertion failed")
-		return
+// UnregisterAll stops all registered agentRunners.
+func (c *Collector) UnregisterAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k, rr := range c.m {
+		rr.runner.Stop()
+		delete(c.m, k)
 	}
+	collectorRegisteredRunners.Set(0)
+}
+
+// Len returns the number of registered agentRunners.
+func (c *Collector) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	ar.Stop()
+	return len(c.m)
 }
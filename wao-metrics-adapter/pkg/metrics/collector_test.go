@@ -0,0 +1,156 @@
+package metrics
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+type testAgent struct{}
+
+func (testAgent) ValueType() ValueType                       { return ValueInletTemperature }
+func (testAgent) Fetch(ctx context.Context) (float64, error) { return 1, nil }
+
+type testClosableAgent struct {
+	testAgent
+	closed chan struct{}
+}
+
+func (a testClosableAgent) Close() { close(a.closed) }
+
+func TestCollector_Register_close(t *testing.T) {
+	c := &Collector{}
+	s := &Store{}
+	k := CollectorKey(types.NamespacedName{Namespace: "default", Name: "node-0"}, ValueInletTemperature)
+	defer c.UnregisterAll()
+
+	a1 := testClosableAgent{closed: make(chan struct{})}
+	a2 := testClosableAgent{closed: make(chan struct{})}
+	c.Register(k, "fp1", a1, s, "node-0", time.Hour, time.Second)
+	c.Register(k, "fp1", a2, s, "node-0", time.Hour, time.Second)
+	select {
+	case <-a2.closed:
+	case <-time.After(time.Second):
+		t.Error("discarded agent not closed")
+	}
+
+	c.Unregister(k)
+	select {
+	case <-a1.closed:
+	case <-time.After(time.Second):
+		t.Error("agent of the stopped runner not closed")
+	}
+}
+
+func TestCollector_Register(t *testing.T) {
+	c := &Collector{}
+	s := &Store{}
+	k := CollectorKey(types.NamespacedName{Namespace: "default", Name: "node-0"}, ValueInletTemperature)
+	defer c.UnregisterAll()
+
+	if !c.Register(k, "fp1", testAgent{}, s, "node-0", time.Hour, time.Second) {
+		t.Error("Register() = false, want true on first registration")
+	}
+	first := c.m[k].runner
+
+	if c.Register(k, "fp1", testAgent{}, s, "node-0", time.Hour, time.Second) {
+		t.Error("Register() = true, want false on same fingerprint")
+	}
+	if c.m[k].runner != first {
+		t.Error("runner replaced on same fingerprint")
+	}
+
+	if !c.Register(k, "fp2", testAgent{}, s, "node-0", time.Hour, time.Second) {
+		t.Error("Register() = false, want true on changed fingerprint")
+	}
+	select {
+	case <-first.stopCh:
+	default:
+		t.Error("old runner not stopped")
+	}
+	if got := c.Len(); got != 1 {
+		t.Errorf("Len() = %d, want 1", got)
+	}
+	if got, err := testutil.GetGaugeMetricValue(collectorRegisteredRunners); err != nil || got != 1 {
+		t.Errorf("registered_runners = %v, %v, want 1", got, err)
+	}
+
+	c.Unregister(k)
+	c.Unregister(k) // no-op
+	if got := c.Len(); got != 0 {
+		t.Errorf("Len() = %d, want 0", got)
+	}
+	if got, err := testutil.GetGaugeMetricValue(collectorRegisteredRunners); err != nil || got != 0 {
+		t.Errorf("registered_runners = %v, %v, want 0", got, err)
+	}
+}
+
+type testStreamingAgent struct {
+	testAgent
+	streamErr error
+	streamed  float64
+}
+
+func (a testStreamingAgent) Stream(ctx context.Context, emit func(float64, time.Time)) error {
+	if a.streamErr != nil {
+		return a.streamErr
+	}
+	emit(a.streamed, time.Now())
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestAgentRunner_streaming(t *testing.T) {
+	tests := []struct {
+		name  string
+		agent testStreamingAgent
+		want  float64
+	}{
+		{"streamed", testStreamingAgent{streamed: 2}, 2},
+		{"fallback_to_fetch", testStreamingAgent{streamErr: ErrStreamNotSupported}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Store{}
+			ar := newAgentRunner(tt.agent, s, "node-0", time.Hour, time.Second)
+			go ar.runStreaming(tt.agent) // skip the initial delay
+			defer ar.Stop()
+
+			// the runner fetches once at start, then the stream overwrites the value
+			deadline := time.Now().Add(5 * time.Second)
+			for time.Now().Before(deadline) {
+				if m, _ := s.Get(StoreKeyForNode("node-0")); m.InletTemp == tt.want {
+					return
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			m, _ := s.Get(StoreKeyForNode("node-0"))
+			t.Errorf("InletTemp = %v, want %v", m.InletTemp, tt.want)
+		})
+	}
+}
+
+func TestAgentRunner_set(t *testing.T) {
+	RegisterMetrics()
+	s := &Store{}
+	ar := newAgentRunner(testAgent{}, s, "node-invalid", time.Hour, time.Second)
+
+	ar.set(20, time.Now())
+	for _, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		ar.set(v, time.Now())
+	}
+	if m, _ := s.Get(StoreKeyForNode("node-invalid")); m.InletTemp != 20 {
+		t.Errorf("InletTemp = %v, want 20", m.InletTemp)
+	}
+	got, err := testutil.GetCounterMetricValue(collectedInvalidValuesTotal.WithLabelValues("node-invalid", string(ValueInletTemperature)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 3 {
+		t.Errorf("invalid values = %v, want 3", got)
+	}
+}
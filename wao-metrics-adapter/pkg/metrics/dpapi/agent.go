@@ -29,9 +29,12 @@ type DeltaPAgent struct {
 
 	client    *http.Client
 	editorFns []util.RequestEditorFn
+
+	// lastSensorID holds the sensor ID of the last fetched value.
+	lastSensorID string
 }
 
-var _ metrics.Agent = (*DeltaPAgent)(nil)
+var _ metrics.LabeledAgent = (*DeltaPAgent)(nil)
 
 // NewDeltaPAgent inits the client.
 // At least one of sensorName, nodeName or nodeIP must be specified.
@@ -134,7 +137,39 @@ func (a *DeltaPAgent) Fetch(ctx context.Context) (float64, error) {
 	if err != nil {
 		return 0.0, err
 	}
+	a.lastSensorID = v.SensorID
 	return v.Pressure, nil
 }
 
 func (a *DeltaPAgent) ValueType() metrics.ValueType { return metrics.ValueDeltaPressure }
+
+func (a *DeltaPAgent) Labels() map[string]string {
+	labels := map[string]string{metrics.LabelSource: "dpapi"}
+	if a.lastSensorID != "" {
+		labels[metrics.LabelSensor] = a.lastSensorID
+	}
+	return labels
+}
+
+// InletTempAgent gets the temperature field of the differential pressure sensor as inlet temperature.
+type InletTempAgent struct {
+	*DeltaPAgent
+}
+
+var _ metrics.LabeledAgent = (*InletTempAgent)(nil)
+
+// NewInletTempAgent inits the client. See NewDeltaPAgent.
+func NewInletTempAgent(address string, sensorName, nodeName, nodeIP string, insecureSkipVerify bool, timeout time.Duration, editorFns ...util.RequestEditorFn) *InletTempAgent {
+	return &InletTempAgent{DeltaPAgent: NewDeltaPAgent(address, sensorName, nodeName, nodeIP, insecureSkipVerify, timeout, editorFns...)}
+}
+
+func (a *InletTempAgent) Fetch(ctx context.Context) (float64, error) {
+	v, err := a.GetSensorValue(ctx)
+	if err != nil {
+		return 0.0, err
+	}
+	a.lastSensorID = v.SensorID
+	return v.Temperature, nil
+}
+
+func (a *InletTempAgent) ValueType() metrics.ValueType { return metrics.ValueInletTemperature }
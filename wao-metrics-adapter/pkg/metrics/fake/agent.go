@@ -10,11 +10,13 @@ import (
 type FakeAgent struct {
 	Type  metrics.ValueType
 	Value float64
-	Error error
-	Delay time.Duration
+	// Profile generates the value if set, otherwise Value is returned.
+	Profile Profile
+	Error   error
+	Delay   time.Duration
 }
 
-var _ metrics.Agent = (*FakeAgent)(nil)
+var _ metrics.LabeledAgent = (*FakeAgent)(nil)
 
 func NewInletTempAgent(value float64, err error, delay time.Duration) *FakeAgent {
 	return &FakeAgent{Type: metrics.ValueInletTemperature, Value: value, Error: err, Delay: delay}
@@ -24,8 +26,19 @@ func NewDeltaPAgent(value float64, err error, delay time.Duration) *FakeAgent {
 	return &FakeAgent{Type: metrics.ValueDeltaPressure, Value: value, Error: err, Delay: delay}
 }
 
+func NewPowerConsumptionAgent(value float64, err error, delay time.Duration) *FakeAgent {
+	return &FakeAgent{Type: metrics.ValuePowerConsumption, Value: value, Error: err, Delay: delay}
+}
+
+// NewProfileAgent returns an agent that returns values generated by the profile.
+func NewProfileAgent(valueType metrics.ValueType, profile Profile, delay time.Duration) *FakeAgent {
+	return &FakeAgent{Type: valueType, Profile: profile, Delay: delay}
+}
+
 func (a *FakeAgent) ValueType() metrics.ValueType { return a.Type }
 
+func (a *FakeAgent) Labels() map[string]string { return map[string]string{metrics.LabelSource: "fake"} }
+
 func (a *FakeAgent) Fetch(ctx context.Context) (float64, error) {
 	select {
 	case <-ctx.Done():
@@ -37,5 +50,8 @@ func (a *FakeAgent) Fetch(ctx context.Context) (float64, error) {
 	if a.Error != nil {
 		return 0.0, a.Error
 	}
+	if a.Profile != nil {
+		return a.Profile.Value(time.Now(), nil)
+	}
 	return a.Value, nil
 }
@@ -0,0 +1,572 @@
+package fake
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"hash/fnv"
+	"io"
+	"math"
+	"math/rand"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/utils/lru"
+)
+
+// EnvVarCSVDir is the environment variable of the directory csv profiles read files from.
+// csv profiles are disabled if it is not set, as anyone who can edit NodeConfigs could otherwise read any file of the process.
+const EnvVarCSVDir = "WAO_FAKE_CSV_DIR"
+
+// maxSharedProfiles is the max number of profiles SharedProfile keeps.
+const maxSharedProfiles = 1024
+
+// Profile generates fake values over time.
+type Profile interface {
+	// Value returns the value at now. vars contains the inputs of predictors, e.g., "cpu_usage".
+	Value(now time.Time, vars map[string]float64) (float64, error)
+}
+
+// ParseProfile parses a profile spec written in the endpoint of Type Fake.
+// It returns nil if spec is not a profile (e.g., empty or a URL), so that the default value is used.
+//
+// Specs are in "{name}?{key}={value}&..." format.
+//
+//   - constant?value=15.5
+//   - sine?base=25&amplitude=3&period=1h&phase=15m
+//   - step?values=20,25,30&interval=10m
+//   - randomwalk?start=25&step=0.5&min=15&max=35&seed=42
+//   - csv?file=/traces/node-0.csv&loop=true
+//   - formula?expr=100+200*cpu_usage/100
+//
+// sine and step follow the wall clock, so all nodes with the same spec have the same value at the same time.
+// randomwalk moves by up to step on each call. The default seed is derived from seedKey (e.g., the node name).
+// csv replays rows of "{offset},{value}" from the first call, where offset is seconds or an RFC 3339 timestamp.
+// The file must be in the directory of EnvVarCSVDir, and relative paths are resolved from it.
+// formula evaluates an expression of vars (see ParseFormula), only names in vars are allowed.
+func ParseProfile(spec string, seedKey string, vars ...string) (Profile, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	if u, err := url.Parse(spec); err == nil && u.Scheme != "" {
+		return nil, nil
+	}
+	name, rawParams, _ := strings.Cut(spec, "?")
+	params, err := parseProfileParams(rawParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var p Profile
+	switch name {
+	case "constant":
+		p, err = newConstantProfile(params)
+	case "sine":
+		p, err = newSineProfile(params)
+	case "step":
+		p, err = newStepProfile(params)
+	case "randomwalk":
+		p, err = newRandomWalkProfile(params, seedKey)
+	case "csv":
+		p, err = newCSVProfile(params)
+	case "formula":
+		p, err = ParseFormula(params.get("expr"), vars...)
+	default:
+		return nil, fmt.Errorf("unknown fake profile: %q", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid fake profile %q: %w", name, err)
+	}
+	if unused := params.unused(); len(unused) > 0 {
+		return nil, fmt.Errorf("invalid fake profile %q: unknown params %v", name, unused)
+	}
+	return p, nil
+}
+
+// sharedProfiles holds profiles by spec and seedKey. See SharedProfile.
+var sharedProfiles = lru.New(maxSharedProfiles)
+
+type sharedProfileKey struct{ spec, seedKey string }
+
+// SharedProfile is ParseProfile, but returns the profile parsed before for the same spec and seedKey,
+// so that stateful profiles (randomwalk and csv) keep their state across fakes created for each use, e.g., predictors.
+// Up to maxSharedProfiles profiles are kept, and the least recently used one starts over when parsed again.
+func SharedProfile(spec string, seedKey string, vars ...string) (Profile, error) {
+	key := sharedProfileKey{spec: spec, seedKey: seedKey}
+	if v, ok := sharedProfiles.Get(key); ok {
+		return v.(Profile), nil
+	}
+	p, err := ParseProfile(spec, seedKey, vars...)
+	if err != nil || p == nil {
+		return p, err
+	}
+	sharedProfiles.Add(key, p)
+	return p, nil
+}
+
+type profileParams struct {
+	m    map[string]string
+	used map[string]bool
+}
+
+// parseProfileParams parses "k=v&..." like a URL query, but keeps "+" as is for formulas.
+func parseProfileParams(s string) (*profileParams, error) {
+	p := &profileParams{m: map[string]string{}, used: map[string]bool{}}
+	if s == "" {
+		return p, nil
+	}
+	for _, kv := range strings.Split(s, "&") {
+		k, v, _ := strings.Cut(kv, "=")
+		v, err := url.PathUnescape(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid param %q: %w", k, err)
+		}
+		p.m[k] = v
+	}
+	return p, nil
+}
+
+func (p *profileParams) get(k string) string {
+	p.used[k] = true
+	return p.m[k]
+}
+
+func (p *profileParams) float(k string, def float64) (float64, error) {
+	s := p.get(k)
+	if s == "" {
+		return def, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", k, err)
+	}
+	return v, nil
+}
+
+func (p *profileParams) duration(k string, def time.Duration) (time.Duration, error) {
+	s := p.get(k)
+	if s == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", k, err)
+	}
+	return d, nil
+}
+
+func (p *profileParams) unused() []string {
+	var keys []string
+	for k := range p.m {
+		if !p.used[k] {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+type constantProfile struct{ value float64 }
+
+func newConstantProfile(params *profileParams) (Profile, error) {
+	if params.get("value") == "" {
+		return nil, errors.New("value is required")
+	}
+	v, err := params.float("value", 0)
+	return &constantProfile{value: v}, err
+}
+
+func (p *constantProfile) Value(time.Time, map[string]float64) (float64, error) { return p.value, nil }
+
+type sineProfile struct {
+	base, amplitude float64
+	period, phase   time.Duration
+}
+
+func newSineProfile(params *profileParams) (Profile, error) {
+	p := &sineProfile{}
+	var err error
+	if p.base, err = params.float("base", 0); err != nil {
+		return nil, err
+	}
+	if p.amplitude, err = params.float("amplitude", 1); err != nil {
+		return nil, err
+	}
+	if p.period, err = params.duration("period", time.Hour); err != nil {
+		return nil, err
+	}
+	if p.phase, err = params.duration("phase", 0); err != nil {
+		return nil, err
+	}
+	if p.period <= 0 {
+		return nil, errors.New("period must be positive")
+	}
+	return p, nil
+}
+
+func (p *sineProfile) Value(now time.Time, _ map[string]float64) (float64, error) {
+	t := now.Add(p.phase).UnixNano() % int64(p.period)
+	return p.base + p.amplitude*math.Sin(2*math.Pi*float64(t)/float64(p.period)), nil
+}
+
+type stepProfile struct {
+	values   []float64
+	interval time.Duration
+}
+
+func newStepProfile(params *profileParams) (Profile, error) {
+	p := &stepProfile{}
+	for _, s := range strings.Split(params.get("values"), ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid values: %w", err)
+		}
+		p.values = append(p.values, v)
+	}
+	var err error
+	if p.interval, err = params.duration("interval", 10*time.Minute); err != nil {
+		return nil, err
+	}
+	if p.interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+	return p, nil
+}
+
+func (p *stepProfile) Value(now time.Time, _ map[string]float64) (float64, error) {
+	i := (now.UnixNano() / int64(p.interval)) % int64(len(p.values))
+	return p.values[i], nil
+}
+
+type randomWalkProfile struct {
+	step, min, max float64
+
+	mu    sync.Mutex
+	rng   *rand.Rand
+	value float64
+}
+
+func newRandomWalkProfile(params *profileParams, seedKey string) (Profile, error) {
+	p := &randomWalkProfile{}
+	var err error
+	if p.value, err = params.float("start", 0); err != nil {
+		return nil, err
+	}
+	if p.step, err = params.float("step", 1); err != nil {
+		return nil, err
+	}
+	if p.min, err = params.float("min", math.Inf(-1)); err != nil {
+		return nil, err
+	}
+	if p.max, err = params.float("max", math.Inf(1)); err != nil {
+		return nil, err
+	}
+	if p.min > p.max {
+		return nil, errors.New("min must not be greater than max")
+	}
+	h := fnv.New64a()
+	io.WriteString(h, seedKey)
+	seed := int64(h.Sum64())
+	if s := params.get("seed"); s != "" {
+		if seed, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid seed: %w", err)
+		}
+	}
+	p.rng = rand.New(rand.NewSource(seed))
+	return p, nil
+}
+
+func (p *randomWalkProfile) Value(time.Time, map[string]float64) (float64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.value = min(max(p.value+p.step*(2*p.rng.Float64()-1), p.min), p.max)
+	return p.value, nil
+}
+
+type csvProfile struct {
+	offsets []time.Duration
+	values  []float64
+	loop    bool
+
+	mu    sync.Mutex
+	start time.Time
+}
+
+func newCSVProfile(params *profileParams) (Profile, error) {
+	file := params.get("file")
+	if file == "" {
+		return nil, errors.New("file is required")
+	}
+	p := &csvProfile{loop: params.get("loop") != "false"}
+
+	file, err := csvPath(file)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = 2
+	r.TrimLeadingSpace = true
+	var first time.Time
+	for line := 1; ; line++ {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		v, err := strconv.ParseFloat(rec[1], 64)
+		if err != nil {
+			if line == 1 {
+				continue // header
+			}
+			return nil, fmt.Errorf("line %d: invalid value: %w", line, err)
+		}
+		var offset time.Duration
+		if sec, err := strconv.ParseFloat(rec[0], 64); err == nil {
+			offset = time.Duration(sec * float64(time.Second))
+		} else if t, err := time.Parse(time.RFC3339Nano, rec[0]); err == nil {
+			if first.IsZero() {
+				first = t
+			}
+			offset = t.Sub(first)
+		} else {
+			return nil, fmt.Errorf("line %d: invalid offset %q", line, rec[0])
+		}
+		if n := len(p.offsets); n > 0 && offset < p.offsets[n-1] {
+			return nil, fmt.Errorf("line %d: rows must be sorted by time", line)
+		}
+		p.offsets = append(p.offsets, offset)
+		p.values = append(p.values, v)
+	}
+	if len(p.values) == 0 {
+		return nil, fmt.Errorf("no rows in %s", file)
+	}
+	// shift so that the trace starts at the first row
+	base := p.offsets[0]
+	for i := range p.offsets {
+		p.offsets[i] -= base
+	}
+	return p, nil
+}
+
+// csvPath resolves file in the directory of EnvVarCSVDir, and returns an error if it is outside the directory.
+func csvPath(file string) (string, error) {
+	dir := os.Getenv(EnvVarCSVDir)
+	if dir == "" {
+		return "", fmt.Errorf("csv profiles are disabled as %s is not set", EnvVarCSVDir)
+	}
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s: %w", EnvVarCSVDir, err)
+	}
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(dir, file)
+	}
+	// resolve symlinks so that they cannot point outside the directory
+	path, err := filepath.EvalSymlinks(file)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("file %q is not in %s", file, dir)
+	}
+	return path, nil
+}
+
+func (p *csvProfile) Value(now time.Time, _ map[string]float64) (float64, error) {
+	p.mu.Lock()
+	if p.start.IsZero() {
+		p.start = now
+	}
+	elapsed := now.Sub(p.start)
+	p.mu.Unlock()
+
+	// the last row lasts as long as the average interval so that looping keeps the pace
+	last := p.offsets[len(p.offsets)-1]
+	span := last
+	if n := len(p.offsets); n > 1 {
+		span += last / time.Duration(n-1)
+	}
+	if p.loop && span > 0 {
+		elapsed %= span
+	}
+	i, found := slices.BinarySearch(p.offsets, elapsed)
+	if !found {
+		i--
+	}
+	return p.values[max(i, 0)], nil
+}
+
+// Formula is a Profile that evaluates an arithmetic expression.
+type Formula struct {
+	expr ast.Expr
+}
+
+var formulaFuncs = map[string]func(args ...float64) (float64, error){
+	"min":   func(args ...float64) (float64, error) { return nArgs(args, 2, math.Min) },
+	"max":   func(args ...float64) (float64, error) { return nArgs(args, 2, math.Max) },
+	"pow":   func(args ...float64) (float64, error) { return nArgs(args, 2, math.Pow) },
+	"abs":   func(args ...float64) (float64, error) { return nArgs(args, 1, math.Abs) },
+	"exp":   func(args ...float64) (float64, error) { return nArgs(args, 1, math.Exp) },
+	"log":   func(args ...float64) (float64, error) { return nArgs(args, 1, math.Log) },
+	"sqrt":  func(args ...float64) (float64, error) { return nArgs(args, 1, math.Sqrt) },
+	"clamp": func(args ...float64) (float64, error) { return nArgs(args, 3, clamp) },
+}
+
+func clamp(v, lo, hi float64) float64 { return min(max(v, lo), hi) }
+
+// nArgs calls f, which is func(float64) float64, func(float64, float64) float64 or clamp, with args.
+func nArgs(args []float64, n int, f any) (float64, error) {
+	if len(args) != n {
+		return 0, fmt.Errorf("want %d args, got %d", n, len(args))
+	}
+	switch f := f.(type) {
+	case func(float64) float64:
+		return f(args[0]), nil
+	case func(float64, float64) float64:
+		return f(args[0], args[1]), nil
+	case func(float64, float64, float64) float64:
+		return f(args[0], args[1], args[2]), nil
+	}
+	return 0, fmt.Errorf("unsupported function %T", f)
+}
+
+// ParseFormula parses an expression of numbers, vars, + - * / and parentheses,
+// and functions min, max, pow, abs, exp, log, sqrt and clamp(v, lo, hi).
+// E.g., "100 + 2.5*cpu_usage + clamp(inlet_temp-20, 0, 15)*3"
+func ParseFormula(s string, vars ...string) (*Formula, error) {
+	if s == "" {
+		return nil, errors.New("expr is required")
+	}
+	expr, err := parser.ParseExpr(s)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateFormula(expr, vars); err != nil {
+		return nil, err
+	}
+	return &Formula{expr: expr}, nil
+}
+
+func validateFormula(expr ast.Expr, vars []string) error {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.INT && e.Kind != token.FLOAT {
+			return fmt.Errorf("unsupported literal %s", e.Value)
+		}
+		return nil
+	case *ast.Ident:
+		if !slices.Contains(vars, e.Name) {
+			return fmt.Errorf("unknown var %q (available: %v)", e.Name, vars)
+		}
+		return nil
+	case *ast.ParenExpr:
+		return validateFormula(e.X, vars)
+	case *ast.UnaryExpr:
+		if e.Op != token.ADD && e.Op != token.SUB {
+			return fmt.Errorf("unsupported operator %s", e.Op)
+		}
+		return validateFormula(e.X, vars)
+	case *ast.BinaryExpr:
+		if !slices.Contains([]token.Token{token.ADD, token.SUB, token.MUL, token.QUO}, e.Op) {
+			return fmt.Errorf("unsupported operator %s", e.Op)
+		}
+		return errors.Join(validateFormula(e.X, vars), validateFormula(e.Y, vars))
+	case *ast.CallExpr:
+		id, ok := e.Fun.(*ast.Ident)
+		if !ok || formulaFuncs[id.Name] == nil {
+			return fmt.Errorf("unsupported function %s", types.ExprString(e.Fun))
+		}
+		var err error
+		for _, arg := range e.Args {
+			err = errors.Join(err, validateFormula(arg, vars))
+		}
+		return err
+	}
+	return fmt.Errorf("unsupported expression %s", types.ExprString(expr))
+}
+
+func (f *Formula) Value(_ time.Time, vars map[string]float64) (float64, error) {
+	v, err := evalFormula(f.expr, vars)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("formula returned %v", v)
+	}
+	return v, nil
+}
+
+func evalFormula(expr ast.Expr, vars map[string]float64) (float64, error) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		return strconv.ParseFloat(e.Value, 64)
+	case *ast.Ident:
+		v, ok := vars[e.Name]
+		if !ok {
+			return 0, fmt.Errorf("var %q is not set", e.Name)
+		}
+		return v, nil
+	case *ast.ParenExpr:
+		return evalFormula(e.X, vars)
+	case *ast.UnaryExpr:
+		v, err := evalFormula(e.X, vars)
+		if e.Op == token.SUB {
+			v = -v
+		}
+		return v, err
+	case *ast.BinaryExpr:
+		x, err := evalFormula(e.X, vars)
+		if err != nil {
+			return 0, err
+		}
+		y, err := evalFormula(e.Y, vars)
+		if err != nil {
+			return 0, err
+		}
+		switch e.Op {
+		case token.ADD:
+			return x + y, nil
+		case token.SUB:
+			return x - y, nil
+		case token.MUL:
+			return x * y, nil
+		case token.QUO:
+			return x / y, nil
+		}
+	case *ast.CallExpr:
+		args := make([]float64, len(e.Args))
+		for i, arg := range e.Args {
+			v, err := evalFormula(arg, vars)
+			if err != nil {
+				return 0, err
+			}
+			args[i] = v
+		}
+		name := e.Fun.(*ast.Ident).Name
+		v, err := formulaFuncs[name](args...)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", name, err)
+		}
+		return v, nil
+	}
+	return 0, fmt.Errorf("unsupported expression %T", expr)
+}
@@ -0,0 +1,132 @@
+package fake
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseProfile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(EnvVarCSVDir, dir)
+	csvFile := filepath.Join(dir, "trace.csv")
+	if err := os.WriteFile(csvFile, []byte("offset,value\n# comment\n0,20\n10,21.5\n30,25\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	outsideFile := filepath.Join(t.TempDir(), "secret.csv")
+	if err := os.WriteFile(outsideFile, []byte("0,1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outsideFile, filepath.Join(dir, "link.csv")); err != nil {
+		t.Fatal(err)
+	}
+	tsFile := filepath.Join(dir, "trace-ts.csv")
+	if err := os.WriteFile(tsFile, []byte("2025-01-01T00:00:00Z,100\n2025-01-01T00:01:00Z,200\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	vars := map[string]float64{"cpu_usage": 50, "inlet_temp": 25, "delta_p": 7.5}
+	type call struct {
+		at   time.Duration // since t0
+		want float64
+	}
+	tests := []struct {
+		name    string
+		spec    string
+		calls   []call
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "empty", spec: "", wantNil: true},
+		{name: "url", spec: "http://10.0.0.100/fake", wantNil: true},
+		{name: "constant", spec: "constant?value=15.5", calls: []call{{0, 15.5}, {time.Hour, 15.5}}},
+		{name: "sine", spec: "sine?base=25&amplitude=2&period=1h", calls: []call{{0, 25}, {15 * time.Minute, 27}, {45 * time.Minute, 23}}},
+		{name: "sine_phase", spec: "sine?base=25&amplitude=2&period=1h&phase=15m", calls: []call{{0, 27}}},
+		{name: "step", spec: "step?values=20,25,30&interval=10m", calls: []call{{0, 20}, {10 * time.Minute, 25}, {25 * time.Minute, 30}, {30 * time.Minute, 20}}},
+		{name: "csv", spec: "csv?file=" + csvFile, calls: []call{{0, 20}, {5 * time.Second, 20}, {10 * time.Second, 21.5}, {44 * time.Second, 25}, {45 * time.Second, 20}}},
+		{name: "csv_noloop", spec: "csv?file=" + csvFile + "&loop=false", calls: []call{{0, 20}, {time.Hour, 25}}},
+		{name: "csv_timestamps", spec: "csv?file=" + tsFile, calls: []call{{0, 100}, {time.Minute, 200}, {2 * time.Minute, 100}}},
+		{name: "formula", spec: "formula?expr=100+2*cpu_usage+clamp(inlet_temp-20,%200,%2015)*3", calls: []call{{0, 215}}},
+		{name: "formula_unknown_var", spec: "formula?expr=100+watts", wantErr: true},
+		{name: "formula_unsupported", spec: "formula?expr=os.Exit(1)", wantErr: true},
+		{name: "unknown_profile", spec: "square?period=1m", wantErr: true},
+		{name: "unknown_param", spec: "constant?value=1&valeu=2", wantErr: true},
+		{name: "csv_relative", spec: "csv?file=trace.csv&loop=false", calls: []call{{0, 20}, {time.Hour, 25}}},
+		{name: "missing_file", spec: "csv?file=nonexistent.csv", wantErr: true},
+		{name: "csv_outside_dir", spec: "csv?file=" + outsideFile, wantErr: true},
+		{name: "csv_parent_dir", spec: "csv?file=../" + filepath.Base(filepath.Dir(outsideFile)) + "/" + filepath.Base(outsideFile), wantErr: true},
+		{name: "csv_symlink_outside_dir", spec: "csv?file=link.csv", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParseProfile(tt.spec, "node-0", "cpu_usage", "inlet_temp", "delta_p")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (p == nil) != (tt.wantNil || tt.wantErr) {
+				t.Fatalf("ParseProfile() = %v", p)
+			}
+			for _, c := range tt.calls {
+				got, err := p.Value(t0.Add(c.at), vars)
+				if err != nil {
+					t.Fatalf("Value(%v) error = %v", c.at, err)
+				}
+				if math.Abs(got-c.want) > 1e-9 {
+					t.Errorf("Value(%v) = %v, want %v", c.at, got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseProfile_randomwalk(t *testing.T) {
+	walk := func(spec, seedKey string) []float64 {
+		p, err := ParseProfile(spec, seedKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var vs []float64
+		for range 100 {
+			v, _ := p.Value(time.Now(), nil)
+			vs = append(vs, v)
+		}
+		return vs
+	}
+	spec := "randomwalk?start=25&step=0.5&min=24&max=26"
+	a, b, c := walk(spec, "node-0"), walk(spec, "node-0"), walk(spec, "node-1")
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("same seed got different values at %d: %v != %v", i, a[i], b[i])
+		}
+		if a[i] < 24 || a[i] > 26 {
+			t.Errorf("value %v out of range", a[i])
+		}
+	}
+	if a[len(a)-1] == c[len(c)-1] {
+		t.Error("different nodes got the same walk")
+	}
+	if d := walk(spec+"&seed=1", "node-1"); d[0] != walk(spec+"&seed=1", "node-0")[0] {
+		t.Error("explicit seed is not used")
+	}
+}
+
+func TestSharedProfile(t *testing.T) {
+	spec := "randomwalk?start=25&step=0.5&min=15&max=35"
+	p1, err := SharedProfile(spec, "default/fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, _ := SharedProfile(spec, "default/fake")
+	if p1 != p2 {
+		t.Error("got a new profile for the same spec and seedKey, so the walk restarts")
+	}
+	if p3, _ := SharedProfile(spec, "other/fake"); p3 == p1 {
+		t.Error("got the same profile for another seedKey")
+	}
+	if p, err := SharedProfile("", "default/fake"); p != nil || err != nil {
+		t.Errorf("SharedProfile() of an empty spec = %v, %v, want nil, nil", p, err)
+	}
+}
@@ -0,0 +1,282 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Policy decides how FallbackAgent combines its sources.
+type Policy string
+
+const (
+	// PolicyFirstHealthy uses the first source in order that returns a value.
+	PolicyFirstHealthy Policy = "FirstHealthy"
+	// PolicyMedian uses the median of all sources that return a value.
+	PolicyMedian Policy = "Median"
+	// PolicyWeightedAverage uses the weighted average of all sources that return a value.
+	PolicyWeightedAverage Policy = "WeightedAverage"
+)
+
+// LabelActiveSource is the name of the source(s) the value of a FallbackAgent was computed from.
+// Multiple sources are joined with ".".
+const LabelActiveSource = "active_source"
+
+// FallbackSource is a source of FallbackAgent.
+type FallbackSource struct {
+	// Name identifies the source in labels and logs, e.g., "redfish-0".
+	Name  string
+	Agent Agent
+	// Weight is used by PolicyWeightedAverage. Must be positive.
+	Weight float64
+	// Timeout is the timeout of a single Fetch of the source.
+	Timeout time.Duration
+}
+
+// CircuitBreaker configures when FallbackAgent stops fetching a failing source.
+// After FailureThreshold consecutive failures, the source is skipped for OpenDuration,
+// then a single Fetch is tried again and the source is used again if it succeeds.
+type CircuitBreaker struct {
+	FailureThreshold int
+	OpenDuration     time.Duration
+}
+
+// DefaultCircuitBreaker is used if CircuitBreaker fields are zero.
+var DefaultCircuitBreaker = CircuitBreaker{FailureThreshold: 3, OpenDuration: 1 * time.Minute}
+
+type fallbackSourceState struct {
+	FallbackSource
+
+	failures  int
+	openUntil time.Time
+}
+
+// FallbackAgent fetches a value from multiple sources of the same ValueType.
+// Sources whose circuit breaker is open are skipped. StreamingAgent sources are polled.
+type FallbackAgent struct {
+	valueType ValueType
+	policy    Policy
+	breaker   CircuitBreaker
+	sources   []*fallbackSourceState
+
+	// now is replaced in tests.
+	now func() time.Time
+
+	mu     sync.Mutex
+	labels map[string]string
+}
+
+var _ LabeledAgent = (*FallbackAgent)(nil)
+var _ ClosableAgent = (*FallbackAgent)(nil)
+
+// NewFallbackAgent inits the agent. All sources must have the same ValueType.
+func NewFallbackAgent(valueType ValueType, policy Policy, breaker CircuitBreaker, sources []FallbackSource) (*FallbackAgent, error) {
+	switch policy {
+	case "":
+		policy = PolicyFirstHealthy
+	case PolicyFirstHealthy, PolicyMedian, PolicyWeightedAverage:
+	default:
+		return nil, fmt.Errorf("unsupported policy: %s", policy)
+	}
+	if breaker.FailureThreshold <= 0 {
+		breaker.FailureThreshold = DefaultCircuitBreaker.FailureThreshold
+	}
+	if breaker.OpenDuration <= 0 {
+		breaker.OpenDuration = DefaultCircuitBreaker.OpenDuration
+	}
+	if len(sources) == 0 {
+		return nil, errors.New("no sources")
+	}
+	a := &FallbackAgent{valueType: valueType, policy: policy, breaker: breaker, now: time.Now}
+	for i, src := range sources {
+		if src.Agent == nil {
+			return nil, fmt.Errorf("sources[%d]: agent is nil", i)
+		}
+		if vt := src.Agent.ValueType(); vt != valueType {
+			return nil, fmt.Errorf("sources[%d]: value type %s does not match %s", i, vt, valueType)
+		}
+		if !(src.Weight > 0) {
+			return nil, fmt.Errorf("sources[%d]: weight must be positive", i)
+		}
+		a.sources = append(a.sources, &fallbackSourceState{FallbackSource: src})
+	}
+	return a, nil
+}
+
+func (a *FallbackAgent) ValueType() ValueType { return a.valueType }
+
+// Close closes the sources.
+func (a *FallbackAgent) Close() {
+	for _, src := range a.sources {
+		CloseAgent(src.Agent)
+	}
+}
+
+func (a *FallbackAgent) Labels() map[string]string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.labels
+}
+
+// fallbackResult is the result of fetching a source.
+type fallbackResult struct {
+	src    *fallbackSourceState
+	value  float64
+	labels map[string]string
+	err    error
+}
+
+func (a *FallbackAgent) Fetch(ctx context.Context) (float64, error) {
+	if a.policy == PolicyFirstHealthy {
+		return a.fetchFirstHealthy(ctx)
+	}
+	return a.fetchAll(ctx)
+}
+
+func (a *FallbackAgent) fetchFirstHealthy(ctx context.Context) (float64, error) {
+	var errs []error
+	for _, src := range a.sources {
+		if !a.allow(src) {
+			errs = append(errs, fmt.Errorf("%s: circuit open", src.Name))
+			continue
+		}
+		res := a.fetchSource(ctx, src)
+		if res.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", src.Name, res.err))
+			continue
+		}
+		labels := map[string]string{}
+		for k, v := range res.labels {
+			labels[k] = v
+		}
+		labels[LabelActiveSource] = src.Name
+		a.setLabels(labels)
+		return res.value, nil
+	}
+	return 0.0, fmt.Errorf("no healthy source: %w", errors.Join(errs...))
+}
+
+// fetchAll fetches all allowed sources concurrently and aggregates the values.
+func (a *FallbackAgent) fetchAll(ctx context.Context) (float64, error) {
+	var errs []error
+	ch := make(chan fallbackResult)
+	n := 0
+	for _, src := range a.sources {
+		if !a.allow(src) {
+			errs = append(errs, fmt.Errorf("%s: circuit open", src.Name))
+			continue
+		}
+		n++
+		go func() { ch <- a.fetchSource(ctx, src) }()
+	}
+	var results []fallbackResult
+	for range n {
+		res := <-ch
+		if res.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", res.src.Name, res.err))
+			continue
+		}
+		results = append(results, res)
+	}
+	if len(results) == 0 {
+		return 0.0, fmt.Errorf("no healthy source: %w", errors.Join(errs...))
+	}
+	// keep the source order so that labels are stable
+	slices.SortFunc(results, func(x, y fallbackResult) int {
+		return slices.Index(a.sources, x.src) - slices.Index(a.sources, y.src)
+	})
+
+	var v float64
+	switch a.policy {
+	case PolicyMedian:
+		values := make([]float64, len(results))
+		for i, res := range results {
+			values[i] = res.value
+		}
+		v = median(values)
+	case PolicyWeightedAverage:
+		var sum, weights float64
+		for _, res := range results {
+			sum += res.value * res.src.Weight
+			weights += res.src.Weight
+		}
+		v = sum / weights
+	}
+
+	names := make([]string, len(results))
+	for i, res := range results {
+		names[i] = res.src.Name
+	}
+	a.setLabels(map[string]string{
+		LabelSource:       strings.ToLower(string(a.policy)),
+		LabelActiveSource: strings.Join(names, "."),
+	})
+	return v, nil
+}
+
+func median(values []float64) float64 {
+	slices.Sort(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
+
+func (a *FallbackAgent) fetchSource(ctx context.Context, src *fallbackSourceState) fallbackResult {
+	res := fallbackResult{src: src}
+	if src.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, src.Timeout)
+		defer cancel()
+	}
+	res.value, res.err = src.Agent.Fetch(ctx)
+	if res.err == nil {
+		if la, ok := src.Agent.(LabeledAgent); ok {
+			res.labels = la.Labels()
+		}
+	}
+	a.record(src, res.err)
+	return res
+}
+
+// allow returns false if the circuit breaker of src is open.
+func (a *FallbackAgent) allow(src *fallbackSourceState) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return !a.now().Before(src.openUntil)
+}
+
+// record updates the circuit breaker of src.
+func (a *FallbackAgent) record(src *fallbackSourceState, err error) {
+	lg := slog.With("func", "FallbackAgent.record", "valueType", a.valueType, "source", src.Name)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err == nil {
+		if src.failures >= a.breaker.FailureThreshold {
+			lg.Info("source recovered, so close the circuit")
+		}
+		src.failures = 0
+		src.openUntil = time.Time{}
+		return
+	}
+	src.failures++
+	// once the threshold is reached, every failure (including the trial after OpenDuration) reopens the circuit
+	if src.failures >= a.breaker.FailureThreshold {
+		src.openUntil = a.now().Add(a.breaker.OpenDuration)
+		lg.Warn("source is failing, so open the circuit", "failures", src.failures, "openUntil", src.openUntil, "err", err)
+	}
+}
+
+func (a *FallbackAgent) setLabels(labels map[string]string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.labels = labels
+}
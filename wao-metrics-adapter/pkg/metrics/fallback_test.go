@@ -0,0 +1,154 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"testing"
+	"time"
+)
+
+type testFallbackSourceAgent struct {
+	value float64
+	err   error
+	calls int
+}
+
+func (a *testFallbackSourceAgent) ValueType() ValueType { return ValueInletTemperature }
+func (a *testFallbackSourceAgent) Fetch(ctx context.Context) (float64, error) {
+	a.calls++
+	return a.value, a.err
+}
+func (a *testFallbackSourceAgent) Labels() map[string]string {
+	return map[string]string{LabelSource: "test"}
+}
+
+func TestFallbackAgent_Fetch(t *testing.T) {
+	errDown := errors.New("down")
+	tests := []struct {
+		name       string
+		policy     Policy
+		agents     []*testFallbackSourceAgent
+		weights    []float64
+		want       float64
+		wantLabels map[string]string
+		wantErr    bool
+	}{
+		{
+			name:       "first_healthy",
+			policy:     PolicyFirstHealthy,
+			agents:     []*testFallbackSourceAgent{{value: 20}, {value: 30}},
+			want:       20,
+			wantLabels: map[string]string{LabelSource: "test", LabelActiveSource: "s0"},
+		},
+		{
+			name:       "first_healthy_fallback",
+			policy:     "",
+			agents:     []*testFallbackSourceAgent{{err: errDown}, {value: 30}},
+			want:       30,
+			wantLabels: map[string]string{LabelSource: "test", LabelActiveSource: "s1"},
+		},
+		{
+			name:       "median_odd",
+			policy:     PolicyMedian,
+			agents:     []*testFallbackSourceAgent{{value: 40}, {value: 20}, {err: errDown}, {value: 21}},
+			want:       21,
+			wantLabels: map[string]string{LabelSource: "median", LabelActiveSource: "s0.s1.s3"},
+		},
+		{
+			name:       "median_even",
+			policy:     PolicyMedian,
+			agents:     []*testFallbackSourceAgent{{value: 20}, {value: 22}},
+			want:       21,
+			wantLabels: map[string]string{LabelSource: "median", LabelActiveSource: "s0.s1"},
+		},
+		{
+			name:       "weighted_average",
+			policy:     PolicyWeightedAverage,
+			agents:     []*testFallbackSourceAgent{{value: 20}, {value: 30}, {err: errDown}},
+			weights:    []float64{3, 1, 100},
+			want:       22.5,
+			wantLabels: map[string]string{LabelSource: "weightedaverage", LabelActiveSource: "s0.s1"},
+		},
+		{
+			name:    "all_down",
+			policy:  PolicyMedian,
+			agents:  []*testFallbackSourceAgent{{err: errDown}, {err: errDown}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sources []FallbackSource
+			for i, ag := range tt.agents {
+				w := 1.0
+				if tt.weights != nil {
+					w = tt.weights[i]
+				}
+				sources = append(sources, FallbackSource{Name: fmt.Sprintf("s%d", i), Agent: ag, Weight: w})
+			}
+			a, err := NewFallbackAgent(ValueInletTemperature, tt.policy, CircuitBreaker{}, sources)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := a.Fetch(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Fetch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Fetch() = %v, want %v", got, tt.want)
+			}
+			if !tt.wantErr && !maps.Equal(a.Labels(), tt.wantLabels) {
+				t.Errorf("Labels() = %v, want %v", a.Labels(), tt.wantLabels)
+			}
+		})
+	}
+}
+
+func TestFallbackAgent_circuitBreaker(t *testing.T) {
+	primary := &testFallbackSourceAgent{value: 20, err: errors.New("down")}
+	secondary := &testFallbackSourceAgent{value: 30}
+	a, err := NewFallbackAgent(ValueInletTemperature, PolicyFirstHealthy, CircuitBreaker{FailureThreshold: 2, OpenDuration: time.Minute}, []FallbackSource{
+		{Name: "primary", Agent: primary, Weight: 1},
+		{Name: "secondary", Agent: secondary, Weight: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	a.now = func() time.Time { return now }
+
+	fetch := func() {
+		t.Helper()
+		if v, err := a.Fetch(context.Background()); err != nil || v != 30 {
+			t.Fatalf("Fetch() = %v, %v, want 30", v, err)
+		}
+	}
+
+	// 2 failures open the circuit
+	fetch()
+	fetch()
+	fetch()
+	if primary.calls != 2 {
+		t.Errorf("primary called %d times, want 2 (skipped while open)", primary.calls)
+	}
+
+	// the trial after OpenDuration fails, so the circuit is reopened
+	now = now.Add(time.Minute)
+	fetch()
+	fetch()
+	if primary.calls != 3 {
+		t.Errorf("primary called %d times, want 3", primary.calls)
+	}
+
+	// the primary is used again once it recovers
+	now = now.Add(time.Minute)
+	primary.err = nil
+	if v, err := a.Fetch(context.Background()); err != nil || v != 20 {
+		t.Fatalf("Fetch() = %v, %v, want 20", v, err)
+	}
+	if got := a.Labels()[LabelActiveSource]; got != "primary" {
+		t.Errorf("active source = %q, want primary", got)
+	}
+}
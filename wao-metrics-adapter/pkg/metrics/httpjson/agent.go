@@ -0,0 +1,167 @@
+package httpjson
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/util/jsonpath"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/util"
+)
+
+// Options configures how to send a request and extract a value from the response.
+type Options struct {
+	// Method is the HTTP method. Default is GET.
+	Method string
+	// Body is the request body. Sent as application/json if not empty.
+	Body string
+	// JSONPath is a JSONPath expression that selects a single number or numeric string.
+	// Surrounding braces can be omitted, e.g., ".sensors[0].temperature".
+	JSONPath string
+	// Unit is the unit of the extracted value.
+	Unit Unit
+	// Scale is multiplied to the extracted value before unit conversion. Zero means 1.
+	Scale float64
+	// Offset is added to the extracted value after scaling.
+	Offset float64
+}
+
+// Agent sends an HTTP request and extracts a value from the JSON response.
+type Agent struct {
+	valueType metrics.ValueType
+
+	// url contains the full URL.
+	// E.g., "http://10.0.0.1:8080/api/v1/sensors?rack=A1"
+	url  string
+	opts Options
+	jp   *jsonpath.JSONPath
+
+	client    *http.Client
+	editorFns []util.RequestEditorFn
+}
+
+var _ metrics.LabeledAgent = (*Agent)(nil)
+
+// NewAgent inits the client.
+// It returns an error if the JSONPath is invalid or the unit does not match the valueType.
+func NewAgent(valueType metrics.ValueType, url string, opts Options, insecureSkipVerify bool, timeout time.Duration, editorFns ...util.RequestEditorFn) (*Agent, error) {
+	if opts.Method == "" {
+		opts.Method = http.MethodGet
+	}
+	if opts.Scale == 0 {
+		opts.Scale = 1
+	}
+	if err := opts.Unit.Validate(valueType); err != nil {
+		return nil, err
+	}
+
+	expr := strings.TrimSpace(opts.JSONPath)
+	if !strings.HasPrefix(expr, "{") {
+		expr = "{" + expr + "}"
+	}
+	jp := jsonpath.New("value")
+	if err := jp.Parse(expr); err != nil {
+		return nil, fmt.Errorf("unable to parse JSONPath %q: %w", opts.JSONPath, err)
+	}
+
+	return &Agent{
+		valueType: valueType,
+		url:       url,
+		opts:      opts,
+		jp:        jp,
+		client: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: insecureSkipVerify}},
+			Timeout:   timeout,
+		},
+		editorFns: editorFns,
+	}, nil
+}
+
+// GetRawValue sends the request and returns the value selected by the JSONPath without conversion.
+func (a *Agent) GetRawValue(ctx context.Context) (float64, error) {
+	var body io.Reader
+	if a.opts.Body != "" {
+		body = strings.NewReader(a.opts.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, a.opts.Method, a.url, body)
+	if err != nil {
+		return 0.0, fmt.Errorf("unable to create HTTP request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	for i, f := range a.editorFns {
+		if err := f(ctx, req); err != nil {
+			return 0.0, fmt.Errorf("editorFns[%d] got error: %w", i, err)
+		}
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return 0.0, fmt.Errorf("unable to send HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var data any
+		if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+			return 0.0, fmt.Errorf("could not decode resp: %w", err)
+		}
+		return a.extract(data)
+	default:
+		return 0.0, fmt.Errorf("HTTP status=%s", resp.Status)
+	}
+}
+
+func (a *Agent) extract(data any) (float64, error) {
+	results, err := a.jp.FindResults(data)
+	if err != nil {
+		return 0.0, fmt.Errorf("unable to evaluate JSONPath %q: %w", a.opts.JSONPath, err)
+	}
+	var values []any
+	for _, r := range results {
+		for _, v := range r {
+			values = append(values, v.Interface())
+		}
+	}
+	if len(values) != 1 {
+		return 0.0, fmt.Errorf("JSONPath %q must select exactly one value but got %d", a.opts.JSONPath, len(values))
+	}
+
+	switch v := values[0].(type) {
+	case float64:
+		return v, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0.0, fmt.Errorf("JSONPath %q selected a non-numeric string %q", a.opts.JSONPath, v)
+		}
+		return f, nil
+	default:
+		return 0.0, fmt.Errorf("JSONPath %q selected a non-numeric value %v", a.opts.JSONPath, v)
+	}
+}
+
+func (a *Agent) Fetch(ctx context.Context) (float64, error) {
+	v, err := a.GetRawValue(ctx)
+	if err != nil {
+		return 0.0, err
+	}
+	return a.opts.Unit.Convert(v*a.opts.Scale + a.opts.Offset), nil
+}
+
+func (a *Agent) ValueType() metrics.ValueType { return a.valueType }
+
+func (a *Agent) Labels() map[string]string {
+	return map[string]string{metrics.LabelSource: "httpjson"}
+}
@@ -0,0 +1,72 @@
+package httpjson
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+)
+
+func TestAgent_Fetch(t *testing.T) {
+	const resp = `{"sensors":[{"host":"node0","temp_f":"77.0","dp_inh2o":0.04},{"host":"node1","temp_f":"80.6","dp_inh2o":0.05}],"raw":123}`
+
+	tests := []struct {
+		name      string
+		valueType metrics.ValueType
+		opts      Options
+		want      float64
+		wantErr   bool
+	}{
+		{"fahrenheit", metrics.ValueInletTemperature, Options{JSONPath: `{.sensors[?(@.host=="node0")].temp_f}`, Unit: UnitFahrenheit}, 25, false},
+		{"inch_of_water", metrics.ValueDeltaPressure, Options{JSONPath: `.sensors[1].dp_inh2o`, Unit: UnitInchOfWater}, 0.05 * pascalsPerInchOfWater, false},
+		{"scale_offset", metrics.ValueInletTemperature, Options{JSONPath: `.raw`, Scale: 0.1, Offset: 5}, 17.3, false},
+		{"post_body", metrics.ValueInletTemperature, Options{Method: http.MethodPost, Body: `{"host":"node0"}`, JSONPath: `.raw`}, 123, false},
+		{"multiple_values", metrics.ValueInletTemperature, Options{JSONPath: `.sensors[*].temp_f`}, 0, true},
+		{"not_found", metrics.ValueInletTemperature, Options{JSONPath: `.missing`}, 0, true},
+		{"not_number", metrics.ValueInletTemperature, Options{JSONPath: `.sensors[0].host`}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				wantMethod := tt.opts.Method
+				if wantMethod == "" {
+					wantMethod = http.MethodGet
+				}
+				if r.Method != wantMethod {
+					t.Errorf("method = %s, want %s", r.Method, wantMethod)
+				}
+				if b, _ := io.ReadAll(r.Body); string(b) != tt.opts.Body {
+					t.Errorf("body = %s, want %s", b, tt.opts.Body)
+				}
+				w.Write([]byte(resp))
+			}))
+			defer srv.Close()
+
+			a, err := NewAgent(tt.valueType, srv.URL, tt.opts, false, time.Second)
+			if err != nil {
+				t.Fatalf("NewAgent() error = %v", err)
+			}
+			got, err := a.Fetch(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Fetch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Fetch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewAgent_invalid(t *testing.T) {
+	if _, err := NewAgent(metrics.ValueDeltaPressure, "http://localhost", Options{JSONPath: ".v", Unit: UnitFahrenheit}, false, time.Second); err == nil {
+		t.Error("NewAgent() with a temperature unit for delta_p should fail")
+	}
+	if _, err := NewAgent(metrics.ValueInletTemperature, "http://localhost", Options{JSONPath: "{.v"}, false, time.Second); err == nil {
+		t.Error("NewAgent() with an invalid JSONPath should fail")
+	}
+}
@@ -0,0 +1,70 @@
+package httpjson
+
+import (
+	"fmt"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+)
+
+// Unit is a unit of a value returned by sensor APIs.
+// Values are converted to the unit of metrics.ValueType (Celsius or Pascal).
+type Unit string
+
+const (
+	UnitNone Unit = ""
+
+	UnitCelsius    Unit = "Celsius"
+	UnitFahrenheit Unit = "Fahrenheit"
+	UnitKelvin     Unit = "Kelvin"
+
+	UnitPascal      Unit = "Pascal"
+	UnitHectopascal Unit = "Hectopascal"
+	UnitKilopascal  Unit = "Kilopascal"
+	// UnitInchOfWater is the conventional inch of water (inH2O at 4°C).
+	UnitInchOfWater Unit = "InchOfWater"
+)
+
+const pascalsPerInchOfWater = 249.08891
+
+var unitValueTypes = map[Unit]metrics.ValueType{
+	UnitCelsius:     metrics.ValueInletTemperature,
+	UnitFahrenheit:  metrics.ValueInletTemperature,
+	UnitKelvin:      metrics.ValueInletTemperature,
+	UnitPascal:      metrics.ValueDeltaPressure,
+	UnitHectopascal: metrics.ValueDeltaPressure,
+	UnitKilopascal:  metrics.ValueDeltaPressure,
+	UnitInchOfWater: metrics.ValueDeltaPressure,
+}
+
+// Validate returns an error if u is unknown or is not a unit of vt.
+func (u Unit) Validate(vt metrics.ValueType) error {
+	if u == UnitNone {
+		return nil
+	}
+	uvt, ok := unitValueTypes[u]
+	if !ok {
+		return fmt.Errorf("unknown unit %q", u)
+	}
+	if uvt != vt {
+		return fmt.Errorf("unit %q cannot be used for %s", u, vt)
+	}
+	return nil
+}
+
+// Convert converts v in u to Celsius or Pascal.
+func (u Unit) Convert(v float64) float64 {
+	switch u {
+	case UnitFahrenheit:
+		return (v - 32) * 5 / 9
+	case UnitKelvin:
+		return v - 273.15
+	case UnitHectopascal:
+		return v * 100
+	case UnitKilopascal:
+		return v * 1000
+	case UnitInchOfWater:
+		return v * pascalsPerInchOfWater
+	default:
+		return v
+	}
+}
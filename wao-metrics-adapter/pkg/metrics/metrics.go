@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"sync"
+
+	basemetrics "k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+var (
+	recordedSamplesDroppedTotal = basemetrics.NewCounter(
+		&basemetrics.CounterOpts{
+			Namespace:      "wao",
+			Subsystem:      "metrics_recorder",
+			Name:           "dropped_samples_total",
+			Help:           "Cumulative number of samples dropped as the recording buffer is full",
+			StabilityLevel: basemetrics.ALPHA,
+		},
+	)
+	collectedInvalidValuesTotal = basemetrics.NewCounterVec(
+		&basemetrics.CounterOpts{
+			Namespace:      "wao",
+			Subsystem:      "metrics_collector",
+			Name:           "invalid_values_total",
+			Help:           "Cumulative number of NaN or infinite values rejected by the collector",
+			StabilityLevel: basemetrics.ALPHA,
+		},
+		[]string{"node", "metric"},
+	)
+	collectorRegisteredRunners = basemetrics.NewGauge(
+		&basemetrics.GaugeOpts{
+			Namespace:      "wao",
+			Subsystem:      "metrics_collector",
+			Name:           "registered_runners",
+			Help:           "Current number of agent runners registered to the collector",
+			StabilityLevel: basemetrics.ALPHA,
+		},
+	)
+)
+
+var registerMetricsOnce sync.Once
+
+// RegisterMetrics registers collector and recorder metrics to the legacy registry, which is served by the custom metrics apiserver.
+// NewFileRecorder and Collector.Register call this.
+func RegisterMetrics() {
+	registerMetricsOnce.Do(func() {
+		legacyregistry.MustRegister(recordedSamplesDroppedTotal)
+		legacyregistry.MustRegister(collectedInvalidValuesTotal)
+		legacyregistry.MustRegister(collectorRegisteredRunners)
+	})
+}
@@ -0,0 +1,209 @@
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+)
+
+type RegisterType string
+
+const (
+	RegisterHolding RegisterType = "Holding"
+	RegisterInput   RegisterType = "Input"
+)
+
+type DataType string
+
+const (
+	DataInt16   DataType = "Int16"
+	DataUint16  DataType = "Uint16"
+	DataInt32   DataType = "Int32"
+	DataUint32  DataType = "Uint32"
+	DataFloat32 DataType = "Float32"
+)
+
+// ByteOrder is the order of bytes ABCD of a 32-bit value, where registers are [AB, CD] in big-endian.
+// For 16-bit values, only the order within a register (AB or BA) matters.
+type ByteOrder string
+
+const (
+	// ByteOrderABCD is big-endian.
+	ByteOrderABCD ByteOrder = "ABCD"
+	// ByteOrderDCBA is little-endian.
+	ByteOrderDCBA ByteOrder = "DCBA"
+	// ByteOrderCDAB is big-endian with swapped words, commonly used for float32 by many devices.
+	ByteOrderCDAB ByteOrder = "CDAB"
+	// ByteOrderBADC is little-endian with swapped words.
+	ByteOrderBADC ByteOrder = "BADC"
+)
+
+// Options configures which register to read and how to decode it.
+type Options struct {
+	// UnitID is the unit (slave) ID.
+	UnitID byte
+	// Address is the 0-based register address.
+	Address uint16
+	// RegisterType is Holding (default) or Input.
+	RegisterType RegisterType
+	// DataType is Int16 (default), Uint16, Int32, Uint32 or Float32.
+	DataType DataType
+	// ByteOrder is ABCD (default), DCBA, CDAB or BADC.
+	ByteOrder ByteOrder
+	// Scale is multiplied to the decoded value. Zero means 1.
+	Scale float64
+	// Offset is added to the decoded value after scaling.
+	Offset float64
+	// MaxAge is the maximum age of a batched read result shared with other agents on the same device.
+	// Zero disables sharing results, but reads are still batched.
+	MaxAge time.Duration
+}
+
+// Agent reads a register via Modbus TCP.
+// Agents on the same device (address, unit ID and register type) share batched reads.
+type Agent struct {
+	valueType metrics.ValueType
+
+	// address contains host and port.
+	// E.g., "10.0.0.1:502"
+	address string
+	opts    Options
+
+	device *device
+	rng    registerRange
+
+	closeOnce sync.Once
+}
+
+var _ metrics.LabeledAgent = (*Agent)(nil)
+var _ metrics.ClosableAgent = (*Agent)(nil)
+
+// NewAgent inits the client.
+// address is "host:port" (port defaults to 502) optionally prefixed with "tcp://".
+func NewAgent(valueType metrics.ValueType, address string, opts Options, timeout time.Duration) (*Agent, error) {
+	address = strings.TrimPrefix(address, "tcp://")
+	if !strings.Contains(address, ":") {
+		address += ":502"
+	}
+	if opts.RegisterType == "" {
+		opts.RegisterType = RegisterHolding
+	}
+	if opts.DataType == "" {
+		opts.DataType = DataInt16
+	}
+	if opts.ByteOrder == "" {
+		opts.ByteOrder = ByteOrderABCD
+	}
+	if opts.Scale == 0 {
+		opts.Scale = 1
+	}
+
+	var fc byte
+	switch opts.RegisterType {
+	case RegisterHolding:
+		fc = FuncReadHoldingRegisters
+	case RegisterInput:
+		fc = FuncReadInputRegisters
+	default:
+		return nil, fmt.Errorf("unknown register type %q", opts.RegisterType)
+	}
+	var qty uint16
+	switch opts.DataType {
+	case DataInt16, DataUint16:
+		qty = 1
+	case DataInt32, DataUint32, DataFloat32:
+		qty = 2
+	default:
+		return nil, fmt.Errorf("unknown data type %q", opts.DataType)
+	}
+	switch opts.ByteOrder {
+	case ByteOrderABCD, ByteOrderDCBA, ByteOrderCDAB, ByteOrderBADC:
+	default:
+		return nil, fmt.Errorf("unknown byte order %q", opts.ByteOrder)
+	}
+	if int(opts.Address)+int(qty) > math.MaxUint16+1 {
+		return nil, fmt.Errorf("address %d out of range", opts.Address)
+	}
+
+	return &Agent{
+		valueType: valueType,
+		address:   address,
+		opts:      opts,
+		device:    getDevice(address, opts.UnitID, fc, timeout),
+		rng:       registerRange{start: opts.Address, qty: qty},
+	}, nil
+}
+
+// Decode decodes registers into a value without scaling.
+func Decode(regs []uint16, dataType DataType, byteOrder ByteOrder) (float64, error) {
+	b := make([]byte, 2*len(regs))
+	for i, r := range regs {
+		binary.BigEndian.PutUint16(b[2*i:], r)
+	}
+	switch len(b) {
+	case 2:
+		if byteOrder == ByteOrderDCBA || byteOrder == ByteOrderBADC {
+			b[0], b[1] = b[1], b[0]
+		}
+	case 4:
+		switch byteOrder {
+		case ByteOrderDCBA:
+			b[0], b[1], b[2], b[3] = b[3], b[2], b[1], b[0]
+		case ByteOrderCDAB:
+			b[0], b[1], b[2], b[3] = b[2], b[3], b[0], b[1]
+		case ByteOrderBADC:
+			b[0], b[1], b[2], b[3] = b[1], b[0], b[3], b[2]
+		}
+	}
+
+	switch {
+	case dataType == DataInt16 && len(b) == 2:
+		return float64(int16(binary.BigEndian.Uint16(b))), nil
+	case dataType == DataUint16 && len(b) == 2:
+		return float64(binary.BigEndian.Uint16(b)), nil
+	case dataType == DataInt32 && len(b) == 4:
+		return float64(int32(binary.BigEndian.Uint32(b))), nil
+	case dataType == DataUint32 && len(b) == 4:
+		return float64(binary.BigEndian.Uint32(b)), nil
+	case dataType == DataFloat32 && len(b) == 4:
+		v := float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return 0.0, fmt.Errorf("invalid float32 value %v", v)
+		}
+		return v, nil
+	default:
+		return 0.0, fmt.Errorf("cannot decode %d registers as %s", len(regs), dataType)
+	}
+}
+
+func (a *Agent) Fetch(ctx context.Context) (float64, error) {
+	regs, err := a.device.read(ctx, a.rng, a.opts.MaxAge)
+	if err != nil {
+		return 0.0, err
+	}
+	v, err := Decode(regs, a.opts.DataType, a.opts.ByteOrder)
+	if err != nil {
+		return 0.0, err
+	}
+	return v*a.opts.Scale + a.opts.Offset, nil
+}
+
+func (a *Agent) ValueType() metrics.ValueType { return a.valueType }
+
+// Close releases the device, and closes the connection if no other agent uses it.
+func (a *Agent) Close() {
+	a.closeOnce.Do(func() { releaseDevice(a.device) })
+}
+
+func (a *Agent) Labels() map[string]string {
+	return map[string]string{
+		metrics.LabelSource: "modbus",
+		metrics.LabelSensor: fmt.Sprintf("unit%d-%s%d", a.opts.UnitID, strings.ToLower(string(a.opts.RegisterType)), a.opts.Address),
+	}
+}
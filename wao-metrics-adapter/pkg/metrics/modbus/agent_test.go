@@ -0,0 +1,228 @@
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"math"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+)
+
+// testServer is a minimal Modbus TCP server that serves function codes 3 and 4 from a register map.
+type testServer struct {
+	ln        net.Listener
+	holding   map[uint16]uint16
+	input     map[uint16]uint16
+	nRequests atomic.Int32
+}
+
+func newTestServer(t *testing.T, holding, input map[uint16]uint16) *testServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testServer{ln: ln, holding: holding, input: input}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *testServer) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		req := make([]byte, 12)
+		if _, err := io.ReadFull(conn, req); err != nil {
+			return
+		}
+		s.nRequests.Add(1)
+		fc := req[7]
+		addr := binary.BigEndian.Uint16(req[8:])
+		qty := binary.BigEndian.Uint16(req[10:])
+
+		regs := map[byte]map[uint16]uint16{FuncReadHoldingRegisters: s.holding, FuncReadInputRegisters: s.input}[fc]
+		pdu := []byte{fc, byte(2 * qty)}
+		for i := uint16(0); i < qty; i++ {
+			v, ok := regs[addr+i]
+			if !ok {
+				pdu = []byte{fc | 0x80, 0x02} // illegal data address
+				break
+			}
+			pdu = binary.BigEndian.AppendUint16(pdu, v)
+		}
+		resp := make([]byte, 7, 7+len(pdu))
+		copy(resp, req[:4])
+		binary.BigEndian.PutUint16(resp[4:], uint16(1+len(pdu)))
+		resp[6] = req[6]
+		if _, err := conn.Write(append(resp, pdu...)); err != nil {
+			return
+		}
+	}
+}
+
+func TestAgent_Fetch(t *testing.T) {
+	f := math.Float32bits(12.5)
+	hi, lo := uint16(f>>16), uint16(f)
+	srv := newTestServer(t,
+		map[uint16]uint16{
+			0: 0xFF38,    // int16 -200
+			1: hi, 2: lo, // float32 ABCD
+			3: lo, 4: hi, // float32 CDAB
+			5: 250, // uint16 scaled
+		},
+		map[uint16]uint16{100: 0x0001, 101: 0x86A0}, // uint32 100000
+	)
+	addr := srv.ln.Addr().String()
+
+	tests := []struct {
+		name    string
+		opts    Options
+		want    float64
+		wantErr bool
+	}{
+		{"int16", Options{Address: 0}, -200, false},
+		{"float32_abcd", Options{Address: 1, DataType: DataFloat32}, 12.5, false},
+		{"float32_cdab", Options{Address: 3, DataType: DataFloat32, ByteOrder: ByteOrderCDAB}, 12.5, false},
+		{"uint16_scaled", Options{Address: 5, DataType: DataUint16, Scale: 0.1, Offset: -1}, 24, false},
+		{"uint32_input", Options{Address: 100, RegisterType: RegisterInput, DataType: DataUint32}, 100000, false},
+		{"illegal_address", Options{Address: 200}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewAgent(metrics.ValueDeltaPressure, addr, tt.opts, time.Second)
+			if err != nil {
+				t.Fatalf("NewAgent() error = %v", err)
+			}
+			got, err := a.Fetch(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Fetch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Fetch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAgent_Fetch_batched(t *testing.T) {
+	regs := map[uint16]uint16{}
+	for i := uint16(0); i < 20; i++ {
+		regs[i] = i * 10
+	}
+	srv := newTestServer(t, regs, nil)
+	addr := srv.ln.Addr().String()
+
+	var agents []*Agent
+	for _, a := range []uint16{0, 4, 19} {
+		agent, err := NewAgent(metrics.ValueDeltaPressure, addr, Options{UnitID: 7, Address: a, MaxAge: time.Minute}, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		agents = append(agents, agent)
+	}
+
+	// the first round registers ranges, the second round is served by a single batched read
+	for round := 0; round < 2; round++ {
+		for i, a := range agents {
+			if _, err := a.Fetch(context.Background()); err != nil {
+				t.Fatalf("round %d agent %d: %v", round, i, err)
+			}
+		}
+	}
+	before := srv.nRequests.Load()
+	agents[0].device.readAt = time.Time{} // expire the shared result
+	for _, a := range agents {
+		if _, err := a.Fetch(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := srv.nRequests.Load() - before; got != 2 {
+		t.Errorf("requests = %d, want 2 (blocks [0, 5) and [19, 20))", got)
+	}
+	if v, _ := agents[2].Fetch(context.Background()); v != 190 {
+		t.Errorf("Fetch() = %v, want 190", v)
+	}
+}
+
+func TestClient_ReadRegisters_shortFrame(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				req := make([]byte, 12)
+				if _, err := io.ReadFull(conn, req); err != nil {
+					return
+				}
+				// MBAP length 2, i.e., unit ID + function code only
+				resp := append(append([]byte{}, req[:4]...), 0x00, 0x02, req[6], req[7])
+				conn.Write(resp)
+			}()
+		}
+	}()
+
+	c := NewClient(ln.Addr().String(), time.Second)
+	defer c.Close()
+	if _, err := c.ReadRegisters(context.Background(), 1, FuncReadHoldingRegisters, 0, 1); err == nil {
+		t.Errorf("ReadRegisters() error = nil, want invalid response length")
+	}
+}
+
+func TestAgent_Close(t *testing.T) {
+	srv := newTestServer(t, map[uint16]uint16{0: 1, 1: 2}, nil)
+	addr := srv.ln.Addr().String()
+
+	a1, err := NewAgent(metrics.ValueInletTemperature, addr, Options{UnitID: 9, Address: 0}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a2, err := NewAgent(metrics.ValueDeltaPressure, addr, Options{UnitID: 9, Address: 1}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a1.Fetch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	k := deviceKey{addr, 9, FuncReadHoldingRegisters}
+
+	a1.Close()
+	a1.Close() // idempotent
+	devicesMu.Lock()
+	_, ok := devices[k]
+	devicesMu.Unlock()
+	if !ok {
+		t.Fatalf("device removed while used by another agent")
+	}
+
+	a2.Close()
+	devicesMu.Lock()
+	_, dok := devices[k]
+	_, cok := clients[addr]
+	devicesMu.Unlock()
+	if dok || cok {
+		t.Errorf("device or client not removed after all agents are closed: device=%v client=%v", dok, cok)
+	}
+	if a1.device.client.conn != nil {
+		t.Errorf("connection not closed")
+	}
+}
@@ -0,0 +1,128 @@
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Function codes.
+const (
+	FuncReadHoldingRegisters byte = 0x03
+	FuncReadInputRegisters   byte = 0x04
+)
+
+// MaxRegistersPerRead is the maximum quantity of registers in a single read request.
+const MaxRegistersPerRead = 125
+
+// Client is a Modbus TCP client. It keeps a single connection and serializes requests.
+type Client struct {
+	// address contains host and port.
+	// E.g., "10.0.0.1:502"
+	address string
+	timeout time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+	tid  uint16
+}
+
+func NewClient(address string, timeout time.Duration) *Client {
+	return &Client{address: address, timeout: timeout}
+}
+
+// ReadRegisters reads qty registers starting at addr with the function code fc.
+func (c *Client) ReadRegisters(ctx context.Context, unitID byte, fc byte, addr, qty uint16) ([]uint16, error) {
+	if qty == 0 || qty > MaxRegistersPerRead {
+		return nil, fmt.Errorf("invalid quantity %d", qty)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	regs, err := c.readRegisters(ctx, unitID, fc, addr, qty)
+	if err != nil {
+		// the connection may be broken or out of sync, so reconnect next time
+		c.close()
+	}
+	return regs, err
+}
+
+func (c *Client) readRegisters(ctx context.Context, unitID byte, fc byte, addr, qty uint16) ([]uint16, error) {
+	if c.conn == nil {
+		d := net.Dialer{Timeout: c.timeout}
+		conn, err := d.DialContext(ctx, "tcp", c.address)
+		if err != nil {
+			return nil, fmt.Errorf("unable to connect: %w", err)
+		}
+		c.conn = conn
+	}
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	c.conn.SetDeadline(deadline)
+
+	c.tid++
+	// MBAP header (transaction ID, protocol ID, length, unit ID) + PDU (function code, address, quantity)
+	req := make([]byte, 12)
+	binary.BigEndian.PutUint16(req[0:], c.tid)
+	binary.BigEndian.PutUint16(req[2:], 0)
+	binary.BigEndian.PutUint16(req[4:], 6)
+	req[6] = unitID
+	req[7] = fc
+	binary.BigEndian.PutUint16(req[8:], addr)
+	binary.BigEndian.PutUint16(req[10:], qty)
+	if _, err := c.conn.Write(req); err != nil {
+		return nil, fmt.Errorf("unable to send request: %w", err)
+	}
+
+	header := make([]byte, 7)
+	if _, err := io.ReadFull(c.conn, header); err != nil {
+		return nil, fmt.Errorf("unable to read response header: %w", err)
+	}
+	length := binary.BigEndian.Uint16(header[4:])
+	// unit ID + function code + at least 1 byte (exception code or byte count)
+	if length < 3 || length > 256 {
+		return nil, fmt.Errorf("invalid response length %d", length)
+	}
+	pdu := make([]byte, length-1)
+	if _, err := io.ReadFull(c.conn, pdu); err != nil {
+		return nil, fmt.Errorf("unable to read response: %w", err)
+	}
+	if tid := binary.BigEndian.Uint16(header[0:]); tid != c.tid {
+		return nil, fmt.Errorf("transaction ID mismatch: want %d but got %d", c.tid, tid)
+	}
+
+	switch {
+	case pdu[0] == fc|0x80:
+		return nil, fmt.Errorf("exception code=0x%02x", pdu[1])
+	case pdu[0] != fc:
+		return nil, fmt.Errorf("function code mismatch: want 0x%02x but got 0x%02x", fc, pdu[0])
+	case int(pdu[1]) != 2*int(qty) || len(pdu) != 2+2*int(qty):
+		return nil, fmt.Errorf("byte count mismatch: want %d but got %d", 2*qty, pdu[1])
+	}
+	regs := make([]uint16, qty)
+	for i := range regs {
+		regs[i] = binary.BigEndian.Uint16(pdu[2+2*i:])
+	}
+	return regs, nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.close()
+}
+
+func (c *Client) close() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
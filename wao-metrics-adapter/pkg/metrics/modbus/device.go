@@ -0,0 +1,190 @@
+package modbus
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	// maxGap is the maximum number of unused registers between ranges merged into a single read.
+	maxGap uint16 = 8
+	// rangeTTL is the duration a register range is kept in batched reads after it was last requested.
+	rangeTTL = 10 * time.Minute
+)
+
+type registerRange struct {
+	start uint16
+	qty   uint16
+}
+
+func (r registerRange) end() int { return int(r.start) + int(r.qty) }
+
+// mergeRanges merges ranges into blocks that can be read at once.
+func mergeRanges(ranges []registerRange) []registerRange {
+	slices.SortFunc(ranges, func(a, b registerRange) int { return int(a.start) - int(b.start) })
+	var blocks []registerRange
+	for _, r := range ranges {
+		if n := len(blocks); n > 0 {
+			b := &blocks[n-1]
+			if r.end() <= b.end() {
+				continue
+			}
+			if int(r.start) <= b.end()+int(maxGap) && r.end()-int(b.start) <= MaxRegistersPerRead {
+				b.qty = uint16(r.end() - int(b.start))
+				continue
+			}
+		}
+		blocks = append(blocks, r)
+	}
+	return blocks
+}
+
+// device batches register reads of multiple agents on the same unit.
+// Each read covers all ranges requested recently, and the result is shared for maxAge.
+type device struct {
+	key    deviceKey
+	client *Client
+	unitID byte
+	fc     byte
+
+	// refs is the number of agents using the device. Guarded by devicesMu.
+	refs int
+
+	mu     sync.Mutex
+	ranges map[registerRange]time.Time // last requested
+	values map[uint16]uint16
+	readAt time.Time
+}
+
+func (d *device) read(ctx context.Context, r registerRange, maxAge time.Duration) ([]uint16, error) {
+	lg := slog.With("func", "device.read", "address", d.client.address, "unitID", d.unitID, "fc", d.fc)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	d.ranges[r] = now
+	if now.Sub(d.readAt) <= maxAge {
+		if regs, ok := d.get(r); ok {
+			return regs, nil
+		}
+	}
+
+	var ranges []registerRange
+	for rr, t := range d.ranges {
+		if now.Sub(t) > rangeTTL {
+			delete(d.ranges, rr)
+			continue
+		}
+		ranges = append(ranges, rr)
+	}
+	values := map[uint16]uint16{}
+	var readErr error
+	for _, b := range mergeRanges(ranges) {
+		regs, err := d.client.ReadRegisters(ctx, d.unitID, d.fc, b.start, b.qty)
+		if err != nil && int(b.start) <= int(r.start) && r.end() <= b.end() && b != r {
+			// some devices reject reads across unmapped registers, so retry only the requested range
+			lg.Debug("unable to read registers, so retry without batching", "start", b.start, "qty", b.qty, "err", err)
+			b = r
+			regs, err = d.client.ReadRegisters(ctx, d.unitID, d.fc, b.start, b.qty)
+		}
+		if err != nil {
+			lg.Debug("unable to read registers", "start", b.start, "qty", b.qty, "err", err)
+			if int(b.start) <= int(r.start) && r.end() <= b.end() {
+				readErr = err
+			}
+			continue
+		}
+		for i, v := range regs {
+			values[b.start+uint16(i)] = v
+		}
+	}
+	d.values = values
+	d.readAt = now
+
+	if readErr != nil {
+		return nil, fmt.Errorf("unable to read registers: %w", readErr)
+	}
+	regs, ok := d.get(r)
+	if !ok {
+		return nil, fmt.Errorf("registers [%d, %d) not read", r.start, r.end())
+	}
+	return regs, nil
+}
+
+func (d *device) get(r registerRange) ([]uint16, bool) {
+	regs := make([]uint16, r.qty)
+	for i := range regs {
+		v, ok := d.values[r.start+uint16(i)]
+		if !ok {
+			return nil, false
+		}
+		regs[i] = v
+	}
+	return regs, true
+}
+
+type deviceKey struct {
+	address string
+	unitID  byte
+	fc      byte
+}
+
+// sharedClient is a Client shared by devices with the same address.
+type sharedClient struct {
+	*Client
+	// refs is the number of devices using the client. Guarded by devicesMu.
+	refs int
+}
+
+var (
+	devicesMu sync.Mutex
+	clients   = map[string]*sharedClient{}
+	devices   = map[deviceKey]*device{}
+)
+
+// getDevice returns the device shared by all agents with the same address, unit ID and function code.
+// Call releaseDevice when the agent is closed.
+func getDevice(address string, unitID byte, fc byte, timeout time.Duration) *device {
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+
+	k := deviceKey{address, unitID, fc}
+	if d, ok := devices[k]; ok {
+		d.refs++
+		return d
+	}
+	c, ok := clients[address]
+	if !ok {
+		c = &sharedClient{Client: NewClient(address, timeout)}
+		clients[address] = c
+	}
+	c.refs++
+	d := &device{key: k, client: c.Client, unitID: unitID, fc: fc, ranges: map[registerRange]time.Time{}, refs: 1}
+	devices[k] = d
+	return d
+}
+
+// releaseDevice removes the device if no agent uses it, and closes the connection if no device uses it.
+func releaseDevice(d *device) {
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+
+	if d.refs--; d.refs > 0 {
+		return
+	}
+	delete(devices, d.key)
+	c, ok := clients[d.key.address]
+	if !ok {
+		return
+	}
+	if c.refs--; c.refs > 0 {
+		return
+	}
+	delete(clients, d.key.address)
+	c.Close()
+}
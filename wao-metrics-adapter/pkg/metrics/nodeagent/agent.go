@@ -0,0 +1,128 @@
+package nodeagent
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/util"
+)
+
+// ReadingsPath is the path the node agent serves the latest readings at.
+const ReadingsPath = "/v1/readings"
+
+// Readings is the response of ReadingsPath.
+//
+// e.g.
+//
+//	{
+//	  "node": "worker-0",
+//	  "values": {
+//	    "power_consumption": {"value": 182.4, "timestamp": "2025-01-01T00:00:00Z", "sensor": "package"},
+//	    "inlet_temp": {"value": 24.5, "timestamp": "2025-01-01T00:00:00Z", "sensor": "nct6775-SYSTIN"}
+//	  }
+//	}
+type Readings struct {
+	Node string `json:"node"`
+	// Values contains fresh readings only. Metrics the node cannot measure are omitted.
+	Values map[metrics.ValueType]Reading `json:"values"`
+}
+
+type Reading struct {
+	Value     float64   `json:"value"`
+	Timestamp time.Time `json:"timestamp"`
+	// Sensor is the sensor the value was read from, exposed as the sensor label.
+	Sensor string `json:"sensor,omitempty"`
+}
+
+// Agent polls the readings endpoint of the wao node agent running on the node.
+type Agent struct {
+	valueType metrics.ValueType
+
+	// address contains scheme, host and port.
+	// E.g., "http://10.0.0.1:9102"
+	address string
+
+	client    *http.Client
+	editorFns []util.RequestEditorFn
+
+	// sensor is the sensor of the last value, used for labels.
+	sensor string
+}
+
+var _ metrics.LabeledAgent = (*Agent)(nil)
+
+// NewAgent inits the client.
+func NewAgent(valueType metrics.ValueType, address string, insecureSkipVerify bool, timeout time.Duration, editorFns ...util.RequestEditorFn) *Agent {
+	return &Agent{
+		valueType: valueType,
+		address:   address,
+		client: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: insecureSkipVerify}},
+			Timeout:   timeout,
+		},
+		editorFns: editorFns,
+	}
+}
+
+// Readings gets all readings from the node agent.
+//
+//   - URL: http://{NODE}:9102/v1/readings
+func (a *Agent) Readings(ctx context.Context) (*Readings, error) {
+	u, err := url.JoinPath(a.address, ReadingsPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not build URL: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create HTTP request: %w", err)
+	}
+	for i, f := range a.editorFns {
+		if err := f(ctx, req); err != nil {
+			return nil, fmt.Errorf("editorFns[%d] got error: %w", i, err)
+		}
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to send HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP status=%s", resp.Status)
+	}
+
+	var r Readings
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("could not decode resp: %w", err)
+	}
+	return &r, nil
+}
+
+func (a *Agent) Fetch(ctx context.Context) (float64, error) {
+	r, err := a.Readings(ctx)
+	if err != nil {
+		return 0.0, err
+	}
+	v, ok := r.Values[a.valueType]
+	if !ok {
+		return 0.0, fmt.Errorf("no %s reading from node %q", a.valueType, r.Node)
+	}
+	a.sensor = v.Sensor
+	return v.Value, nil
+}
+
+func (a *Agent) ValueType() metrics.ValueType { return a.valueType }
+
+func (a *Agent) Labels() map[string]string {
+	labels := map[string]string{metrics.LabelSource: "nodeagent"}
+	if a.sensor != "" {
+		labels[metrics.LabelSensor] = a.sensor
+	}
+	return labels
+}
@@ -0,0 +1,175 @@
+package prometheus
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/util"
+)
+
+// Agent evaluates a PromQL instant query and returns the result as a value.
+type Agent struct {
+	valueType metrics.ValueType
+
+	// address contains scheme, host and port (and path prefix if any).
+	// E.g., "http://prometheus.monitoring:9090"
+	address string
+	// query contains a PromQL query that returns a single value.
+	// E.g., `avg(ipmi_temperature_celsius{instance="node0",name="Inlet_Temp"})`
+	query string
+
+	client    *http.Client
+	editorFns []util.RequestEditorFn
+}
+
+var _ metrics.LabeledAgent = (*Agent)(nil)
+
+// NewAgent inits the client.
+func NewAgent(valueType metrics.ValueType, address string, query string, insecureSkipVerify bool, timeout time.Duration, editorFns ...util.RequestEditorFn) *Agent {
+	return &Agent{
+		valueType: valueType,
+		address:   address,
+		query:     query,
+		client: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: insecureSkipVerify}},
+			Timeout:   timeout,
+		},
+		editorFns: editorFns,
+	}
+}
+
+// NewInletTempAgent inits the client for inlet temperature.
+func NewInletTempAgent(address string, query string, insecureSkipVerify bool, timeout time.Duration, editorFns ...util.RequestEditorFn) *Agent {
+	return NewAgent(metrics.ValueInletTemperature, address, query, insecureSkipVerify, timeout, editorFns...)
+}
+
+// NewDeltaPAgent inits the client for differential pressure.
+func NewDeltaPAgent(address string, query string, insecureSkipVerify bool, timeout time.Duration, editorFns ...util.RequestEditorFn) *Agent {
+	return NewAgent(metrics.ValueDeltaPressure, address, query, insecureSkipVerify, timeout, editorFns...)
+}
+
+// Endpoint constructs the API endpoint.
+//
+// E.g., http://prometheus.monitoring:9090/api/v1/query?query=up
+func (a *Agent) Endpoint() (string, error) {
+	u, err := url.JoinPath(a.address, "api", "v1", "query")
+	if err != nil {
+		return "", err
+	}
+	return u + "?" + url.Values{"query": {a.query}}.Encode(), nil
+}
+
+// queryResponse holds a response.
+//
+// e.g.
+//
+//	{
+//	  "status": "success",
+//	  "data": {
+//	    "resultType": "vector",
+//	    "result": [
+//	      {
+//	        "metric": {"instance": "node0"},
+//	        "value": [1700000000.123, "25.5"]
+//	      }
+//	    ]
+//	  }
+//	}
+type queryResponse struct {
+	Status    string    `json:"status"`
+	Data      queryData `json:"data"`
+	ErrorType string    `json:"errorType"`
+	Error     string    `json:"error"`
+}
+
+type queryData struct {
+	ResultType string          `json:"resultType"`
+	Result     json.RawMessage `json:"result"`
+}
+
+type vectorSample struct {
+	Metric map[string]string `json:"metric"`
+	Value  sampleValue       `json:"value"`
+}
+
+// sampleValue is a pair of [<unix_time>, "<value>"].
+type sampleValue [2]any
+
+func (v sampleValue) Float64() (float64, error) {
+	s, ok := v[1].(string)
+	if !ok {
+		return 0.0, fmt.Errorf("invalid sample value %v", v)
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// Query evaluates the query and returns the single value.
+// Scalar results and vector results with exactly one sample are accepted.
+func (a *Agent) Query(ctx context.Context) (float64, error) {
+	url, err := a.Endpoint()
+	if err != nil {
+		return 0.0, fmt.Errorf("unable to get endpoint URL: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0.0, fmt.Errorf("unable to create HTTP request: %w", err)
+	}
+	for i, f := range a.editorFns {
+		if err := f(ctx, req); err != nil {
+			return 0.0, fmt.Errorf("editorFns[%d] got error: %w", i, err)
+		}
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return 0.0, fmt.Errorf("unable to send HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Prometheus returns the same body on 4xx/5xx errors
+	var apiResp queryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return 0.0, fmt.Errorf("HTTP status=%s", resp.Status)
+		}
+		return 0.0, fmt.Errorf("could not decode resp: %w", err)
+	}
+	if apiResp.Status != "success" {
+		return 0.0, fmt.Errorf("query failed HTTP status=%s errorType=%s error=%s", resp.Status, apiResp.ErrorType, apiResp.Error)
+	}
+
+	switch apiResp.Data.ResultType {
+	case "scalar":
+		var v sampleValue
+		if err := json.Unmarshal(apiResp.Data.Result, &v); err != nil {
+			return 0.0, fmt.Errorf("could not decode scalar result: %w", err)
+		}
+		return v.Float64()
+	case "vector":
+		var samples []vectorSample
+		if err := json.Unmarshal(apiResp.Data.Result, &samples); err != nil {
+			return 0.0, fmt.Errorf("could not decode vector result: %w", err)
+		}
+		if len(samples) != 1 {
+			return 0.0, fmt.Errorf("query must return exactly one sample but got %d", len(samples))
+		}
+		return samples[0].Value.Float64()
+	default:
+		return 0.0, fmt.Errorf("unsupported resultType=%s", apiResp.Data.ResultType)
+	}
+}
+
+func (a *Agent) Fetch(ctx context.Context) (float64, error) { return a.Query(ctx) }
+
+func (a *Agent) ValueType() metrics.ValueType { return a.valueType }
+
+func (a *Agent) Labels() map[string]string {
+	return map[string]string{metrics.LabelSource: "prometheus"}
+}
@@ -0,0 +1,54 @@
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAgent_Fetch(t *testing.T) {
+	const query = `avg(ipmi_temperature_celsius{instance="node0"})`
+
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    float64
+		wantErr bool
+	}{
+		{"vector", http.StatusOK, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1700000000.123,"25.5"]}]}}`, 25.5, false},
+		{"scalar", http.StatusOK, `{"status":"success","data":{"resultType":"scalar","result":[1700000000.123,"7.5"]}}`, 7.5, false},
+		{"empty", http.StatusOK, `{"status":"success","data":{"resultType":"vector","result":[]}}`, 0, true},
+		{"multiple", http.StatusOK, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"a":"1"},"value":[1,"1"]},{"metric":{"a":"2"},"value":[1,"2"]}]}}`, 0, true},
+		{"matrix", http.StatusOK, `{"status":"success","data":{"resultType":"matrix","result":[]}}`, 0, true},
+		{"bad_query", http.StatusBadRequest, `{"status":"error","errorType":"bad_data","error":"parse error"}`, 0, true},
+		{"unavailable", http.StatusServiceUnavailable, `unavailable`, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v1/query" {
+					t.Errorf("path = %s", r.URL.Path)
+				}
+				if got := r.URL.Query().Get("query"); got != query {
+					t.Errorf("query = %s, want %s", got, query)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			a := NewInletTempAgent(srv.URL, query, false, time.Second)
+			got, err := a.Fetch(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Fetch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Fetch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
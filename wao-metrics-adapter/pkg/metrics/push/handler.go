@@ -0,0 +1,99 @@
+package push
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+)
+
+const (
+	// NodePath receives a single value, e.g., POST /ingest/v1/nodes/node-0/inlet_temp {"value": 25.5}
+	NodePath = "/ingest/v1/nodes/{node}/{metric}"
+	// BatchPath receives multiple values, e.g., POST /ingest/v1/batch {"samples": [{"node": "node-0", "metric": "inlet_temp", "value": 25.5}]}
+	BatchPath = "/ingest/v1/batch"
+
+	maxBodyBytes = 1 << 20
+)
+
+// BatchRequest is the body of BatchPath.
+type BatchRequest struct {
+	Samples []Sample `json:"samples"`
+}
+
+// BatchResponse is the response of BatchPath.
+type BatchResponse struct {
+	Accepted int          `json:"accepted"`
+	Errors   []BatchError `json:"errors,omitempty"`
+}
+
+type BatchError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+// NewHandler returns a http.Handler that serves the ingestion API.
+//
+// Requests must be authenticated with the basic auth credentials of the NodeConfig that
+// has Type Push for the metric.
+func NewHandler(r *Receiver) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+NodePath, func(w http.ResponseWriter, req *http.Request) {
+		var s Sample
+		if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxBodyBytes)).Decode(&s); err != nil {
+			http.Error(w, "could not decode body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.Node = req.PathValue("node")
+		s.Metric = metrics.ValueType(req.PathValue("metric"))
+
+		username, password, _ := req.BasicAuth()
+		if err := r.Receive(s, username, password, time.Now()); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST "+BatchPath, func(w http.ResponseWriter, req *http.Request) {
+		var br BatchRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxBodyBytes)).Decode(&br); err != nil {
+			http.Error(w, "could not decode body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		username, password, _ := req.BasicAuth()
+		now := time.Now()
+		var resp BatchResponse
+		for i, s := range br.Samples {
+			if err := r.Receive(s, username, password, now); err != nil {
+				resp.Errors = append(resp.Errors, BatchError{Index: i, Error: err.Error()})
+				continue
+			}
+			resp.Accepted++
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if len(resp.Errors) > 0 {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			slog.Error("unable to encode response", "func", "PushHandler", "err", err)
+		}
+	})
+	return mux
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrUnauthorized):
+		w.Header().Set("WWW-Authenticate", `Basic realm="wao-metrics-adapter"`)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, ErrNotPushTarget):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	}
+}
@@ -0,0 +1,81 @@
+package push
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+)
+
+func TestHandler(t *testing.T) {
+	store := &metrics.Store{}
+	r := NewReceiver(store, DefaultMaxAge, DefaultMaxClockSkew)
+	r.Register("default/node-0#inlet_temp", "node-0", metrics.ValueInletTemperature, "user", "pass")
+	r.Register("default/node-1#inlet_temp", "node-1", metrics.ValueInletTemperature, "user", "pass")
+	srv := httptest.NewServer(NewHandler(r))
+	defer srv.Close()
+
+	post := func(path, body, username, password string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+path, strings.NewReader(body))
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	ts := time.Now().Add(-5 * time.Second).UTC().Truncate(time.Second)
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		username   string
+		wantStatus int
+	}{
+		{"ok", "/ingest/v1/nodes/node-0/inlet_temp", `{"value":25.5,"timestamp":"` + ts.Format(time.RFC3339) + `","sensor":"bms-1"}`, "user", http.StatusNoContent},
+		{"no_auth", "/ingest/v1/nodes/node-0/inlet_temp", `{"value":25.5}`, "", http.StatusUnauthorized},
+		{"wrong_auth", "/ingest/v1/nodes/node-0/inlet_temp", `{"value":25.5}`, "other", http.StatusUnauthorized},
+		{"not_target", "/ingest/v1/nodes/node-0/delta_p", `{"value":7.5}`, "user", http.StatusNotFound},
+		{"not_target_no_auth", "/ingest/v1/nodes/node-0/delta_p", `{"value":7.5}`, "", http.StatusUnauthorized},
+		{"not_target_wrong_auth", "/ingest/v1/nodes/node-9/inlet_temp", `{"value":7.5}`, "other", http.StatusUnauthorized},
+		{"too_old", "/ingest/v1/nodes/node-0/inlet_temp", `{"value":25.5,"timestamp":"2000-01-01T00:00:00Z"}`, "user", http.StatusUnprocessableEntity},
+		{"bad_body", "/ingest/v1/nodes/node-0/inlet_temp", `{"value":"x"}`, "user", http.StatusBadRequest},
+		{"batch_partial", "/ingest/v1/batch", `{"samples":[{"node":"node-1","metric":"inlet_temp","value":20},{"node":"node-2","metric":"inlet_temp","value":20}]}`, "user", http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := post(tt.path, tt.body, tt.username, "pass"); resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+
+	m, _ := store.Get(metrics.StoreKeyForNode("node-0"))
+	v, gotTS, labels, ok := m.Value(metrics.ValueInletTemperature)
+	if !ok || v != 25.5 || !gotTS.Equal(ts) || labels[metrics.LabelSource] != "push" || labels[metrics.LabelSensor] != "bms-1" {
+		t.Errorf("node-0 = %v %v %v %v", v, gotTS, labels, ok)
+	}
+	if m, _ := store.Get(metrics.StoreKeyForNode("node-1")); m.InletTemp != 20 {
+		t.Errorf("node-1 = %v, want 20 from the batch", m.InletTemp)
+	}
+
+	// older pushes do not overwrite newer values
+	old := ts.Add(-time.Second).Format(time.RFC3339)
+	post("/ingest/v1/nodes/node-0/inlet_temp", `{"value":99,"timestamp":"`+old+`"}`, "user", "pass")
+	if m, _ := store.Get(metrics.StoreKeyForNode("node-0")); m.InletTemp != 25.5 {
+		t.Errorf("node-0 = %v, want 25.5", m.InletTemp)
+	}
+
+	r.Unregister("default/node-0#inlet_temp")
+	if resp := post("/ingest/v1/nodes/node-0/inlet_temp", `{"value":25.5}`, "user", "pass"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status after Unregister = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,178 @@
+package push
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+)
+
+const (
+	DefaultMaxAge       = 60 * time.Second
+	DefaultMaxClockSkew = 30 * time.Second
+)
+
+var (
+	ErrUnauthorized  = errors.New("unauthorized")
+	ErrNotPushTarget = errors.New("not a push target")
+	ErrInvalidValue  = errors.New("invalid value")
+)
+
+// Sample is a pushed value.
+type Sample struct {
+	Node   string            `json:"node,omitempty"`
+	Metric metrics.ValueType `json:"metric,omitempty"`
+	Value  float64           `json:"value"`
+	// Timestamp is the time the value was measured at the source. Defaults to the time received.
+	Timestamp time.Time `json:"timestamp,omitempty"`
+	// Sensor is an optional sensor ID exposed as the sensor label.
+	Sensor string `json:"sensor,omitempty"`
+}
+
+type target struct {
+	nodeName  string
+	valueType metrics.ValueType
+	username  string
+	password  string
+}
+
+// authenticate reports whether username and password match the credentials of t.
+func (t target) authenticate(username, password string) bool {
+	return t.username != "" && t.password != "" &&
+		subtle.ConstantTimeCompare([]byte(username), []byte(t.username)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(password), []byte(t.password)) == 1
+}
+
+type targetKey struct {
+	nodeName  string
+	valueType metrics.ValueType
+}
+
+// Receiver accepts pushed values for registered targets and writes them into the Store.
+//
+// NOTE: Targets are registered by the NodeConfig controller for metrics with Type Push.
+// Each target requires the basic auth credentials of its NodeConfig.
+type Receiver struct {
+	store *metrics.Store
+
+	// maxAge rejects values older than this, as they would be expired in the Store anyway.
+	maxAge time.Duration
+	// maxClockSkew rejects values with timestamps too far in the future.
+	maxClockSkew time.Duration
+
+	mu      sync.RWMutex
+	targets map[string]target // by owner key (e.g., collectorKey)
+	index   map[targetKey]string
+}
+
+func NewReceiver(store *metrics.Store, maxAge, maxClockSkew time.Duration) *Receiver {
+	return &Receiver{
+		store:        store,
+		maxAge:       maxAge,
+		maxClockSkew: maxClockSkew,
+		targets:      map[string]target{},
+		index:        map[targetKey]string{},
+	}
+}
+
+// Register accepts pushes of valueType for nodeName, authenticated with username and password.
+// ownerKey identifies the registration (e.g., the collectorKey of the NodeConfig).
+func (r *Receiver) Register(ownerKey string, nodeName string, valueType metrics.ValueType, username, password string) {
+	lg := slog.With("func", "Receiver.Register", "key", ownerKey, "nodeName", nodeName)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.unregister(ownerKey)
+	t := target{nodeName: nodeName, valueType: valueType, username: username, password: password}
+	r.targets[ownerKey] = t
+	r.index[targetKey{nodeName, valueType}] = ownerKey
+	lg.Debug("registered")
+}
+
+func (r *Receiver) Unregister(ownerKey string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.unregister(ownerKey)
+}
+
+func (r *Receiver) unregister(ownerKey string) {
+	t, ok := r.targets[ownerKey]
+	if !ok {
+		return
+	}
+	delete(r.targets, ownerKey)
+	if k := (targetKey{t.nodeName, t.valueType}); r.index[k] == ownerKey {
+		delete(r.index, k)
+	}
+}
+
+// Len returns the number of registered targets.
+func (r *Receiver) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.targets)
+}
+
+// Receive validates s and writes it into the Store.
+// Requests for unknown targets return ErrNotPushTarget only if the credentials are valid for any target, otherwise ErrUnauthorized.
+// Values older than the one in the Store are ignored without errors.
+func (r *Receiver) Receive(s Sample, username, password string, now time.Time) error {
+	r.mu.RLock()
+	ownerKey, ok := r.index[targetKey{s.Node, s.Metric}]
+	t := r.targets[ownerKey]
+	// authenticate before telling whether the target exists, so unauthenticated clients cannot enumerate targets
+	authenticated := ok && t.authenticate(username, password)
+	if !ok {
+		for _, t := range r.targets {
+			if t.authenticate(username, password) {
+				authenticated = true
+				break
+			}
+		}
+	}
+	r.mu.RUnlock()
+	if !authenticated {
+		return ErrUnauthorized
+	}
+	if !ok {
+		return fmt.Errorf("%w: node=%s metric=%s", ErrNotPushTarget, s.Node, s.Metric)
+	}
+
+	if math.IsNaN(s.Value) || math.IsInf(s.Value, 0) {
+		return fmt.Errorf("%w: value must be finite", ErrInvalidValue)
+	}
+	if s.Timestamp.IsZero() {
+		s.Timestamp = now
+	}
+	if s.Timestamp.After(now.Add(r.maxClockSkew)) {
+		return fmt.Errorf("%w: timestamp %s is in the future", ErrInvalidValue, s.Timestamp.Format(time.RFC3339))
+	}
+	if now.Sub(s.Timestamp) > r.maxAge {
+		return fmt.Errorf("%w: timestamp %s is older than %s", ErrInvalidValue, s.Timestamp.Format(time.RFC3339), r.maxAge)
+	}
+
+	labels := map[string]string{metrics.LabelSource: "push"}
+	if s.Sensor != "" {
+		labels[metrics.LabelSensor] = s.Sensor
+	}
+	accepted := false
+	r.store.Update(metrics.StoreKeyForNode(s.Node), func(m metrics.MetricData) metrics.MetricData {
+		// Merge keeps the newer value, so late pushes never overwrite fresher ones
+		if _, t, _, ok := m.Value(s.Metric); !ok || s.Timestamp.After(t) {
+			accepted = true
+		}
+		return m.Merge(metrics.MetricData{}.WithValue(s.Metric, s.Value, s.Timestamp, labels))
+	})
+	if accepted {
+		r.store.Record(metrics.Sample{Node: s.Node, Metric: s.Metric, Value: s.Value, Timestamp: s.Timestamp, Labels: labels})
+	}
+	return nil
+}
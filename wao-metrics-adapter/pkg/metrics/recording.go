@@ -0,0 +1,246 @@
+package metrics
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sample is a value accepted into the Store from an agent or a push.
+type Sample struct {
+	Node      string            `json:"node"`
+	Metric    ValueType         `json:"metric"`
+	Value     float64           `json:"value"`
+	Timestamp time.Time         `json:"timestamp"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// Recorder records accepted samples, e.g., to reproduce incidents later with the replay agent.
+// Record must not block for long as it is called in the fetch path.
+type Recorder interface {
+	Record(s Sample)
+}
+
+// RecordBufferSize is the number of samples FileRecorder buffers. Samples are dropped while the buffer is full.
+const RecordBufferSize = 1024
+
+// FileRecorder records samples to a JSONL file, one Sample per line.
+// The file is rotated to "{name}-{timestamp}{ext}" when it exceeds MaxSize, and only MaxBackups rotated files are kept.
+// Samples are written in background, so Record does not block on file I/O.
+type FileRecorder struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	// f and size are owned by the writer goroutine after NewFileRecorder returns.
+	f    *os.File
+	size int64
+
+	// mu guards ch against Close.
+	mu       sync.RWMutex
+	ch       chan []byte
+	closed   bool
+	done     chan struct{}
+	closeErr error
+}
+
+var _ Recorder = (*FileRecorder)(nil)
+
+// NewFileRecorder opens path for appending and starts the writer. maxSize <= 0 disables rotation, maxBackups <= 0 keeps all rotated files.
+func NewFileRecorder(path string, maxSize int64, maxBackups int) (*FileRecorder, error) {
+	RegisterMetrics()
+	r := &FileRecorder{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+		ch:         make(chan []byte, RecordBufferSize),
+		done:       make(chan struct{}),
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	go r.run()
+	return r, nil
+}
+
+func (r *FileRecorder) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("unable to open recording file: %w", err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("unable to stat recording file: %w", err)
+	}
+	r.f, r.size = f, fi.Size()
+	return nil
+}
+
+// Record queues the sample to be written. It does not block, and drops the sample if the buffer is full or the recorder is closed.
+func (r *FileRecorder) Record(s Sample) {
+	lg := slog.With("func", "FileRecorder.Record", "path", r.path)
+
+	line, err := json.Marshal(s)
+	if err != nil {
+		lg.Error("unable to encode sample", "err", err)
+		return
+	}
+	line = append(line, '\n')
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return
+	}
+	select {
+	case r.ch <- line:
+	default:
+		recordedSamplesDroppedTotal.Inc()
+	}
+}
+
+// run writes queued lines until Close, then closes the file.
+func (r *FileRecorder) run() {
+	defer close(r.done)
+	for line := range r.ch {
+		r.write(line)
+	}
+	if r.f != nil {
+		r.closeErr = r.f.Close()
+		r.f = nil
+	}
+}
+
+func (r *FileRecorder) write(line []byte) {
+	lg := slog.With("func", "FileRecorder.write", "path", r.path)
+
+	if r.f == nil {
+		// the previous rotation failed, so retry opening the file
+		if err := r.open(); err != nil {
+			lg.Error("unable to reopen recording file", "err", err)
+			return
+		}
+	}
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(line)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			lg.Error("unable to rotate recording file", "err", err)
+			if r.f == nil {
+				return
+			}
+		}
+	}
+	n, err := r.f.Write(line)
+	r.size += int64(n)
+	if err != nil {
+		lg.Error("unable to write sample", "err", err)
+	}
+}
+
+// rotate renames the current file and opens a new one. Only the writer goroutine calls this.
+func (r *FileRecorder) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+	r.f = nil
+	ext := filepath.Ext(r.path)
+	rotated := fmt.Sprintf("%s-%s%s", strings.TrimSuffix(r.path, ext), time.Now().UTC().Format("20060102T150405.000"), ext)
+	if err := os.Rename(r.path, rotated); err != nil {
+		return err
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	if r.maxBackups <= 0 {
+		return nil
+	}
+	backups, err := filepath.Glob(fmt.Sprintf("%s-*%s", strings.TrimSuffix(r.path, ext), ext))
+	if err != nil {
+		return err
+	}
+	slices.Sort(backups) // timestamps sort chronologically
+	for _, b := range backups[:max(len(backups)-r.maxBackups, 0)] {
+		if err := os.Remove(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close writes the queued samples and closes the file. Samples recorded after Close are dropped.
+func (r *FileRecorder) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		<-r.done
+		return nil
+	}
+	r.closed = true
+	close(r.ch)
+	r.mu.Unlock()
+
+	<-r.done
+	return r.closeErr
+}
+
+// ReadRecordings reads samples from files matching the glob pattern (e.g., "/data/samples*.jsonl" to include rotated files),
+// sorted by timestamp.
+func ReadRecordings(pattern string) ([]Sample, error) {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no recording files match %q", pattern)
+	}
+	return ReadRecordingFiles(files)
+}
+
+// ReadRecordingFiles reads samples from files, sorted by timestamp.
+func ReadRecordingFiles(files []string) ([]Sample, error) {
+	var samples []Sample
+	for _, file := range files {
+		ss, err := readRecording(file)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, ss...)
+	}
+	slices.SortStableFunc(samples, func(a, b Sample) int { return a.Timestamp.Compare(b.Timestamp) })
+	return samples, nil
+}
+
+func readRecording(file string) ([]Sample, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var samples []Sample
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var s Sample
+		if err := json.Unmarshal(sc.Bytes(), &s); err != nil {
+			// the last line may be partially written if the adapter was killed
+			slog.Warn("skip invalid line in recording", "func", "readRecording", "file", file, "line", line, "err", err)
+			continue
+		}
+		samples = append(samples, s)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", file, err)
+	}
+	return samples, nil
+}
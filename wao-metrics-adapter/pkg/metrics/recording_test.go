@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileRecorder(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "samples.jsonl")
+	r, err := NewFileRecorder(path, 200, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 10 {
+		r.Record(Sample{Node: "n0", Metric: ValueInletTemperature, Value: float64(i), Timestamp: t0.Add(time.Duration(9-i) * time.Second)})
+		time.Sleep(2 * time.Millisecond) // rotated file names have millisecond precision
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r.Record(Sample{Node: "n0", Metric: ValueInletTemperature, Value: 100, Timestamp: t0}) // dropped
+
+	files, _ := filepath.Glob(filepath.Join(dir, "samples*.jsonl"))
+	if len(files) != 3 {
+		t.Fatalf("got %d files, want the current file and 2 backups: %v", len(files), files)
+	}
+	for _, f := range files {
+		if fi, _ := os.Stat(f); fi.Size() > 200 {
+			t.Errorf("%s is %d bytes, want <= 200", f, fi.Size())
+		}
+	}
+
+	// append a partially written line
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"node":"n0","met`)
+	f.Close()
+
+	samples, err := ReadRecordings(filepath.Join(dir, "samples*.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// samples are recorded with decreasing timestamps, and the oldest ones are pruned with the rotated files
+	if len(samples) == 0 || len(samples) == 10 || samples[0].Value != 9 {
+		t.Fatalf("ReadRecordings() = %v, want the latest samples starting with 9", samples)
+	}
+	for i := 1; i < len(samples); i++ {
+		if samples[i].Timestamp.Before(samples[i-1].Timestamp) {
+			t.Errorf("samples are not sorted at %d", i)
+		}
+	}
+
+	if _, err := ReadRecordings(filepath.Join(dir, "none*.jsonl")); err == nil {
+		t.Error("ReadRecordings() with no files got no error")
+	}
+}
@@ -28,12 +28,28 @@ const (
 
 type GetInletTempFunc func(ctx context.Context, server string, client *http.Client, editorFns ...util.RequestEditorFn) (float64, error)
 
+type GetPowerConsumptionFunc func(ctx context.Context, server string, client *http.Client, editorFns ...util.RequestEditorFn) (float64, error)
+
 var (
 	GetInletTempFns = map[ServerType]GetInletTempFunc{
 		TypeDelliDRAC:      GetInletTempForTypeDelliDRAC,
 		TypeLenovoXClarity: GetInletTempForTypeLenovoXClarity,
 		TypeSupermicroSSM:  GetInletTempForTypeSupermicroSSM,
 	}
+
+	// InletTempSensorIDs holds the sensor read by GetInletTempFns for each ServerType.
+	// Used as the value of the metrics.LabelSensor label, so values must be valid label values.
+	InletTempSensorIDs = map[ServerType]string{
+		TypeDelliDRAC:      "SystemBoardInletTemp",
+		TypeLenovoXClarity: "128L0",
+		TypeSupermicroSSM:  "SystemTemp",
+	}
+
+	GetPowerConsumptionFns = map[ServerType]GetPowerConsumptionFunc{
+		TypeDelliDRAC:      GetPowerConsumptionForTypeDelliDRAC,
+		TypeLenovoXClarity: GetPowerConsumptionForTypeLenovoXClarity,
+		TypeSupermicroSSM:  GetPowerConsumptionForTypeSupermicroSSM,
+	}
 )
 
 // GetInletTempForTypeDelliDRAC returns inlet temp.
@@ -178,6 +194,73 @@ func GetInletTempForTypeSupermicroSSM(ctx context.Context, server string, client
 	}
 }
 
+// GetPowerConsumptionForTypeDelliDRAC returns power consumption.
+//
+//   - URL: https://{SERVER}/redfish/v1/Chassis/System.Embedded.1/Power
+//   - Key: ["PowerControl"][0]["PowerConsumedWatts"]
+func GetPowerConsumptionForTypeDelliDRAC(ctx context.Context, server string, client *http.Client, editorFns ...util.RequestEditorFn) (float64, error) {
+	return getPowerConsumedWatts(ctx, server, "redfish/v1/Chassis/System.Embedded.1/Power", client, editorFns...)
+}
+
+// GetPowerConsumptionForTypeLenovoXClarity returns power consumption.
+//
+//   - URL: https://{SERVER}/redfish/v1/Chassis/1/Power
+//   - Key: ["PowerControl"][0]["PowerConsumedWatts"]
+func GetPowerConsumptionForTypeLenovoXClarity(ctx context.Context, server string, client *http.Client, editorFns ...util.RequestEditorFn) (float64, error) {
+	return getPowerConsumedWatts(ctx, server, "redfish/v1/Chassis/1/Power", client, editorFns...)
+}
+
+// GetPowerConsumptionForTypeSupermicroSSM returns power consumption.
+//
+//   - URL: https://{SERVER}/redfish/v1/Chassis/1/Power
+//   - Key: ["PowerControl"][0]["PowerConsumedWatts"]
+func GetPowerConsumptionForTypeSupermicroSSM(ctx context.Context, server string, client *http.Client, editorFns ...util.RequestEditorFn) (float64, error) {
+	return getPowerConsumedWatts(ctx, server, "redfish/v1/Chassis/1/Power", client, editorFns...)
+}
+
+// getPowerConsumedWatts returns PowerConsumedWatts of the first PowerControl of the Power resource at path.
+func getPowerConsumedWatts(ctx context.Context, server, path string, client *http.Client, editorFns ...util.RequestEditorFn) (float64, error) {
+
+	type apiResponse struct {
+		PowerControl []struct {
+			PowerConsumedWatts *float64 `json:"PowerConsumedWatts"`
+		} `json:"PowerControl"`
+	}
+
+	u, err := url.JoinPath(server, path)
+	if err != nil {
+		return 0.0, fmt.Errorf("could not build URL: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return 0.0, fmt.Errorf("unable to create HTTP request: %w", err)
+	}
+	for i, f := range editorFns {
+		if err := f(ctx, req); err != nil {
+			return 0.0, fmt.Errorf("editorFns[%d] got error: %w", i, err)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0.0, fmt.Errorf("unable to send HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var apiResp apiResponse
+		if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+			return 0.0, fmt.Errorf("could not decode resp: %w", err)
+		}
+		if len(apiResp.PowerControl) == 0 || apiResp.PowerControl[0].PowerConsumedWatts == nil {
+			return 0.0, fmt.Errorf("PowerConsumedWatts not found")
+		}
+		return *apiResp.PowerControl[0].PowerConsumedWatts, nil
+	default:
+		return 0.0, fmt.Errorf("HTTP status=%s", resp.Status)
+	}
+}
+
 type InletTempAgent struct {
 	// address contains scheme, host and port.
 	// E.g., "http://10.0.0.1:8080"
@@ -189,7 +272,7 @@ type InletTempAgent struct {
 	editorFns []util.RequestEditorFn
 }
 
-var _ metrics.Agent = (*InletTempAgent)(nil)
+var _ metrics.LabeledAgent = (*InletTempAgent)(nil)
 
 // NewInletTempAgent inits the client.
 // If serverType is not specified, the client will try all known endpoints.
@@ -253,3 +336,63 @@ func (a *InletTempAgent) Fetch(ctx context.Context) (float64, error) {
 }
 
 func (a *InletTempAgent) ValueType() metrics.ValueType { return metrics.ValueInletTemperature }
+
+func (a *InletTempAgent) Labels() map[string]string {
+	labels := map[string]string{metrics.LabelSource: "redfish"}
+	if id, ok := InletTempSensorIDs[a.serverType]; ok {
+		labels[metrics.LabelSensor] = id
+	}
+	return labels
+}
+
+// PowerConsumptionAgent polls power consumption from the PowerControl of the Power resource.
+type PowerConsumptionAgent struct {
+	// address contains scheme, host and port.
+	// E.g., "http://10.0.0.1:8080"
+	address string
+	// serverType contains server type.
+	serverType ServerType
+
+	client    *http.Client
+	editorFns []util.RequestEditorFn
+}
+
+var _ metrics.LabeledAgent = (*PowerConsumptionAgent)(nil)
+
+// NewPowerConsumptionAgent inits the client.
+// If serverType is not specified, the client will try all known endpoints.
+func NewPowerConsumptionAgent(address string, serverType ServerType, insecureSkipVerify bool, timeout time.Duration, editorFns ...util.RequestEditorFn) *PowerConsumptionAgent {
+	return &PowerConsumptionAgent{
+		address:    address,
+		serverType: serverType,
+		client: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: insecureSkipVerify}},
+			Timeout:   timeout,
+		},
+		editorFns: editorFns,
+	}
+}
+
+func (a *PowerConsumptionAgent) Fetch(ctx context.Context) (float64, error) {
+	if fn, ok := GetPowerConsumptionFns[a.serverType]; ok {
+		return fn(ctx, a.address, a.client, a.editorFns...)
+	}
+	// try all known endpoints in order and remember the first one that works
+	var errs []error
+	for _, st := range []ServerType{TypeDelliDRAC, TypeLenovoXClarity, TypeSupermicroSSM} {
+		v, err := GetPowerConsumptionFns[st](ctx, a.address, a.client, a.editorFns...)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		a.serverType = st
+		return v, nil
+	}
+	return 0.0, errors.Join(append([]error{errors.New("all GetPowerConsumptionFuncs got error")}, errs...)...)
+}
+
+func (a *PowerConsumptionAgent) ValueType() metrics.ValueType { return metrics.ValuePowerConsumption }
+
+func (a *PowerConsumptionAgent) Labels() map[string]string {
+	return map[string]string{metrics.LabelSource: "redfish", metrics.LabelSensor: "PowerControl"}
+}
@@ -0,0 +1,31 @@
+package redfish
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPowerConsumptionAgent_Fetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /redfish/v1/Chassis/1/Power", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"PowerControl":[{"Name":"Server Power Control","PowerConsumedWatts":312}]}`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	a := NewPowerConsumptionAgent(srv.URL, TypeAutoDetect, false, time.Second)
+	v, err := a.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch() err = %v", err)
+	}
+	if v != 312 {
+		t.Errorf("Fetch() = %v, want 312", v)
+	}
+	if a.serverType != TypeLenovoXClarity {
+		t.Errorf("serverType = %v, want %v", a.serverType, TypeLenovoXClarity)
+	}
+}
@@ -0,0 +1,306 @@
+package redfish
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/util"
+)
+
+// DefaultMaxClockSkew is the max difference between timestamps in MetricReports and the local clock.
+// Readings with timestamps out of this range are stamped with the time received instead,
+// as the clock of the BMC is off and the timestamps would make values look stale or fresh forever.
+const DefaultMaxClockSkew = 30 * time.Second
+
+// IsInletTempMetricValue reports whether a MetricValue in a MetricReport is an inlet temperature reading.
+// Vendors use different IDs, so this matches loosely by default, e.g.,
+// "/redfish/v1/Chassis/System.Embedded.1/Sensors/SystemBoardInletTemp/Reading" (iDRAC) or "Ambient Temp" (XClarity).
+var IsInletTempMetricValue = func(metricID, metricProperty string) bool {
+	s := strings.ToLower(metricID + " " + metricProperty)
+	return strings.Contains(s, "inlet") || strings.Contains(s, "ambient")
+}
+
+// IsPowerConsumptionMetricValue reports whether a MetricValue in a MetricReport is a power consumption reading.
+// This matches PowerControl readings by default, e.g., "/redfish/v1/Chassis/1/Power#/PowerControl/0/PowerConsumedWatts".
+var IsPowerConsumptionMetricValue = func(metricID, metricProperty string) bool {
+	s := strings.ToLower(metricID + " " + metricProperty)
+	return strings.Contains(s, "powercontrol") || strings.Contains(s, "powerconsumedwatts")
+}
+
+// StreamingAgent receives values from MetricReports sent over the Redfish EventService SSE stream.
+// It also implements Fetch by polling like InletTempAgent or PowerConsumptionAgent, which is used while the stream is not available.
+//
+// NOTE: The BMC must be configured to send MetricReports containing the value
+// (e.g., by enabling a TelemetryService MetricReportDefinition with the RedfishEvent report action).
+type StreamingAgent struct {
+	valueType metrics.ValueType
+	// isMetricValue reports whether a MetricValue is a reading of valueType.
+	isMetricValue func(metricID, metricProperty string) bool
+
+	// mu serializes Fetch and Labels, as they may be called while streaming.
+	mu    sync.Mutex
+	agent metrics.LabeledAgent
+
+	// address, client and editorFns are used for discovering the SSE endpoint.
+	address   string
+	client    *http.Client
+	editorFns []util.RequestEditorFn
+
+	// streamClient has no timeout as the stream is long-lived, so idleTimeout is used instead.
+	streamClient *http.Client
+	// idleTimeout reconnects the stream if nothing (including keep-alive comments) is received for this duration.
+	idleTimeout time.Duration
+	// maxClockSkew is the max difference between timestamps in MetricReports and the local clock.
+	maxClockSkew time.Duration
+}
+
+var (
+	_ metrics.LabeledAgent   = (*StreamingAgent)(nil)
+	_ metrics.StreamingAgent = (*StreamingAgent)(nil)
+)
+
+// NewStreamingInletTempAgent inits the client for inlet temperature.
+// timeout is used for polling and for discovering the SSE endpoint.
+func NewStreamingInletTempAgent(address string, serverType ServerType, insecureSkipVerify bool, timeout time.Duration, idleTimeout time.Duration, editorFns ...util.RequestEditorFn) *StreamingAgent {
+	a := NewInletTempAgent(address, serverType, insecureSkipVerify, timeout, editorFns...)
+	return newStreamingAgent(metrics.ValueInletTemperature, IsInletTempMetricValue, a, a.client, address, insecureSkipVerify, idleTimeout, editorFns)
+}
+
+// NewStreamingPowerConsumptionAgent inits the client for power consumption.
+// timeout is used for polling and for discovering the SSE endpoint.
+func NewStreamingPowerConsumptionAgent(address string, serverType ServerType, insecureSkipVerify bool, timeout time.Duration, idleTimeout time.Duration, editorFns ...util.RequestEditorFn) *StreamingAgent {
+	a := NewPowerConsumptionAgent(address, serverType, insecureSkipVerify, timeout, editorFns...)
+	return newStreamingAgent(metrics.ValuePowerConsumption, IsPowerConsumptionMetricValue, a, a.client, address, insecureSkipVerify, idleTimeout, editorFns)
+}
+
+func newStreamingAgent(vt metrics.ValueType, isMetricValue func(string, string) bool, agent metrics.LabeledAgent, client *http.Client, address string, insecureSkipVerify bool, idleTimeout time.Duration, editorFns []util.RequestEditorFn) *StreamingAgent {
+	return &StreamingAgent{
+		valueType:     vt,
+		isMetricValue: isMetricValue,
+		agent:         agent,
+		address:       address,
+		client:        client,
+		editorFns:     editorFns,
+		streamClient: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: insecureSkipVerify}},
+		},
+		idleTimeout:  idleTimeout,
+		maxClockSkew: DefaultMaxClockSkew,
+	}
+}
+
+func (a *StreamingAgent) Fetch(ctx context.Context) (float64, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.agent.Fetch(ctx)
+}
+
+func (a *StreamingAgent) ValueType() metrics.ValueType { return a.valueType }
+
+func (a *StreamingAgent) Labels() map[string]string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.agent.Labels()
+}
+
+// SSEEndpoint returns the SSE URL advertised by the EventService.
+//
+//   - URL: https://{SERVER}/redfish/v1/EventService
+//   - Key: ["ServerSentEventUri"]
+func (a *StreamingAgent) SSEEndpoint(ctx context.Context) (string, error) {
+	type apiResponse struct {
+		ServiceEnabled     *bool  `json:"ServiceEnabled"`
+		ServerSentEventUri string `json:"ServerSentEventUri"`
+	}
+
+	u, err := url.JoinPath(a.address, "redfish/v1/EventService")
+	if err != nil {
+		return "", fmt.Errorf("could not build URL: %w", err)
+	}
+	req, err := a.newRequest(ctx, u)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("unable to send HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var apiResp apiResponse
+		if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+			return "", fmt.Errorf("could not decode resp: %w", err)
+		}
+		if apiResp.ServiceEnabled != nil && !*apiResp.ServiceEnabled {
+			return "", fmt.Errorf("%w: EventService is disabled", metrics.ErrStreamNotSupported)
+		}
+		if apiResp.ServerSentEventUri == "" {
+			return "", fmt.Errorf("%w: ServerSentEventUri is empty", metrics.ErrStreamNotSupported)
+		}
+		base, err := url.Parse(a.address)
+		if err != nil {
+			return "", fmt.Errorf("could not parse address: %w", err)
+		}
+		ref, err := url.Parse(apiResp.ServerSentEventUri)
+		if err != nil {
+			return "", fmt.Errorf("could not parse ServerSentEventUri: %w", err)
+		}
+		return base.ResolveReference(ref).String(), nil
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return "", fmt.Errorf("%w: HTTP status=%s", metrics.ErrStreamNotSupported, resp.Status)
+	default:
+		return "", fmt.Errorf("HTTP status=%s", resp.Status)
+	}
+}
+
+func (a *StreamingAgent) newRequest(ctx context.Context, u string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create HTTP request: %w", err)
+	}
+	for i, f := range a.editorFns {
+		if err := f(ctx, req); err != nil {
+			return nil, fmt.Errorf("editorFns[%d] got error: %w", i, err)
+		}
+	}
+	return req, nil
+}
+
+// metricReport holds a MetricReport sent as an SSE event.
+//
+// e.g.
+//
+//	{
+//	  "@odata.type": "#MetricReport.v1_4_2.MetricReport",
+//	  "Id": "ThermalSensor",
+//	  "MetricValues": [
+//	    {
+//	      "MetricId": "TemperatureReading",
+//	      "MetricProperty": "/redfish/v1/Chassis/System.Embedded.1/Sensors/SystemBoardInletTemp/Reading",
+//	      "MetricValue": "24.0",
+//	      "Timestamp": "2025-01-01T00:00:00+00:00"
+//	    }
+//	  ]
+//	}
+type metricReport struct {
+	MetricValues []struct {
+		MetricID       string `json:"MetricId"`
+		MetricProperty string `json:"MetricProperty"`
+		MetricValue    string `json:"MetricValue"`
+		Timestamp      string `json:"Timestamp"`
+	} `json:"MetricValues"`
+}
+
+// Stream subscribes to the SSE stream and emits the value in received MetricReports.
+// Other events (e.g., alerts) and readings are ignored.
+// Readings are stamped with the time received if their timestamps are missing or off by more than maxClockSkew.
+func (a *StreamingAgent) Stream(ctx context.Context, emit func(value float64, timestamp time.Time)) error {
+	u, err := a.SSEEndpoint(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get SSE endpoint: %w", err)
+	}
+	// receive only MetricReports if the service supports filtering (ignored otherwise)
+	u += "?" + url.Values{"$filter": {"EventFormatType eq MetricReport"}}.Encode()
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	req, err := a.newRequest(streamCtx, u)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := a.streamClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to send HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return fmt.Errorf("%w: HTTP status=%s", metrics.ErrStreamNotSupported, resp.Status)
+	default:
+		return fmt.Errorf("HTTP status=%s", resp.Status)
+	}
+
+	// cancel the request if nothing is received for idleTimeout
+	idle := time.AfterFunc(a.idleTimeout, cancel)
+	defer idle.Stop()
+
+	err = readSSE(resp.Body, func() { idle.Reset(a.idleTimeout) }, func(data []byte) {
+		var report metricReport
+		if err := json.Unmarshal(data, &report); err != nil {
+			return // not JSON, e.g., a vendor-specific keep-alive
+		}
+		for _, mv := range report.MetricValues {
+			if !a.isMetricValue(mv.MetricID, mv.MetricProperty) {
+				continue
+			}
+			v, err := strconv.ParseFloat(mv.MetricValue, 64)
+			if err != nil {
+				continue
+			}
+			now := time.Now()
+			ts, err := time.Parse(time.RFC3339, mv.Timestamp)
+			if err != nil || ts.Sub(now).Abs() > a.maxClockSkew {
+				ts = now
+			}
+			emit(v, ts)
+			return // one value per report
+		}
+	})
+	switch {
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case streamCtx.Err() != nil:
+		return fmt.Errorf("nothing received for %s", a.idleTimeout)
+	case err != nil:
+		return fmt.Errorf("stream closed: %w", err)
+	default:
+		return fmt.Errorf("stream closed by server")
+	}
+}
+
+// readSSE reads server-sent events and calls onEvent with the data of each event.
+// onLine is called for every line including comments, which is used as a keep-alive.
+func readSSE(r io.Reader, onLine func(), onEvent func(data []byte)) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	var data bytes.Buffer
+	for sc.Scan() {
+		onLine()
+		line := sc.Bytes()
+		switch {
+		case len(line) == 0:
+			// dispatch
+			if data.Len() > 0 {
+				onEvent(data.Bytes())
+				data.Reset()
+			}
+		case line[0] == ':':
+			// comment
+		case bytes.HasPrefix(line, []byte("data:")):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.Write(bytes.TrimPrefix(bytes.TrimPrefix(line, []byte("data:")), []byte(" ")))
+		default:
+			// event, id, retry fields are not used
+		}
+	}
+	return sc.Err()
+}
@@ -0,0 +1,127 @@
+package redfish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+)
+
+func newSSEMockServer(t *testing.T, events ...string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /redfish/v1/EventService", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"ServiceEnabled":true,"ServerSentEventUri":"/redfish/v1/SSE"}`)
+	})
+	mux.HandleFunc("GET /redfish/v1/SSE", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Accept"); got != "text/event-stream" {
+			t.Errorf("Accept = %s", got)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": keep-alive\n\n")
+		for _, e := range events {
+			fmt.Fprintf(w, "id: 1\ndata: %s\n\n", e)
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestStreamingAgent_Stream(t *testing.T) {
+	ts := time.Now().Add(-time.Second).UTC().Truncate(time.Second)
+	report := func(ts time.Time) string {
+		return `{"@odata.type":"#MetricReport.v1_4_2.MetricReport","MetricValues":[` +
+			`{"MetricId":"PowerConsumption","MetricProperty":"/redfish/v1/Chassis/1/Power#/PowerControl/0/PowerConsumedWatts","MetricValue":"300","Timestamp":"` + ts.Format(time.RFC3339) + `"},` +
+			`{"MetricId":"TemperatureReading","MetricProperty":"/redfish/v1/Chassis/System.Embedded.1/Sensors/SystemBoardInletTemp/Reading","MetricValue":"24.5","Timestamp":"` + ts.Format(time.RFC3339) + `"}]}`
+	}
+	tests := []struct {
+		name     string
+		newAgent func(address string) *StreamingAgent
+		reportTS time.Time
+		wantV    float64
+		wantTS   time.Time // zero if stamped with the time received
+	}{
+		{"inlet_temp", func(address string) *StreamingAgent {
+			return NewStreamingInletTempAgent(address, TypeAutoDetect, false, time.Second, 5*time.Second)
+		}, ts, 24.5, ts},
+		{"power_consumption", func(address string) *StreamingAgent {
+			return NewStreamingPowerConsumptionAgent(address, TypeAutoDetect, false, time.Second, 5*time.Second)
+		}, ts, 300, ts},
+		{"clock_skew_past", func(address string) *StreamingAgent {
+			return NewStreamingInletTempAgent(address, TypeAutoDetect, false, time.Second, 5*time.Second)
+		}, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), 24.5, time.Time{}},
+		{"clock_skew_future", func(address string) *StreamingAgent {
+			return NewStreamingPowerConsumptionAgent(address, TypeAutoDetect, false, time.Second, 5*time.Second)
+		}, ts.Add(time.Hour), 300, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newSSEMockServer(t,
+				`{"@odata.type":"#Event.v1_4_0.Event","Events":[{"MessageId":"Alert"}]}`,
+				report(tt.reportTS),
+			)
+			defer srv.Close()
+
+			a := tt.newAgent(srv.URL)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			type value struct {
+				v  float64
+				ts time.Time
+			}
+			ch := make(chan value, 10)
+			errCh := make(chan error, 1)
+			start := time.Now()
+			go func() { errCh <- a.Stream(ctx, func(v float64, ts time.Time) { ch <- value{v, ts} }) }()
+
+			select {
+			case got := <-ch:
+				if got.v != tt.wantV {
+					t.Errorf("emitted %v, want %v", got.v, tt.wantV)
+				}
+				if tt.wantTS.IsZero() {
+					if got.ts.Before(start) || got.ts.After(time.Now()) {
+						t.Errorf("emitted at %v, want the time received", got.ts)
+					}
+				} else if !got.ts.Equal(tt.wantTS) {
+					t.Errorf("emitted at %v, want %v", got.ts, tt.wantTS)
+				}
+			case err := <-errCh:
+				t.Fatalf("Stream() returned early: %v", err)
+			case <-time.After(5 * time.Second):
+				t.Fatal("no value emitted")
+			}
+			cancel()
+			if err := <-errCh; err == nil {
+				t.Error("Stream() = nil after cancel, want error")
+			}
+		})
+	}
+}
+
+func TestStreamingAgent_Stream_idleTimeout(t *testing.T) {
+	srv := newSSEMockServer(t)
+	defer srv.Close()
+
+	a := NewStreamingInletTempAgent(srv.URL, TypeAutoDetect, false, time.Second, 200*time.Millisecond)
+	err := a.Stream(context.Background(), func(float64, time.Time) {})
+	if err == nil || errors.Is(err, metrics.ErrStreamNotSupported) {
+		t.Errorf("Stream() error = %v, want idle timeout", err)
+	}
+}
+
+func TestStreamingAgent_Stream_notSupported(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	a := NewStreamingInletTempAgent(srv.URL, TypeAutoDetect, false, time.Second, time.Second)
+	if err := a.Stream(context.Background(), func(float64, time.Time) {}); !errors.Is(err, metrics.ErrStreamNotSupported) {
+		t.Errorf("Stream() error = %v, want ErrStreamNotSupported", err)
+	}
+}
@@ -0,0 +1,321 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+)
+
+// LabelReplayed is added to the recorded labels of replayed values.
+const LabelReplayed = "replayed"
+
+// EnvVarReplayDir is the environment variable of the directory recordings are replayed from.
+// NewAgentFromEndpoint is disabled if it is not set, as anyone who can edit NodeConfigs could otherwise read any file of the process.
+const EnvVarReplayDir = "WAO_REPLAY_DIR"
+
+// Options configures Agent.
+type Options struct {
+	// Node is the node whose samples are replayed.
+	Node string
+	// Speed scales time, e.g., 10 replays 10 minutes of recording in a minute. Default is 1.
+	Speed float64
+	// Loop restarts the recording from the beginning after the last sample.
+	Loop bool
+	// Start skips samples before this time.
+	Start time.Time
+}
+
+// ParseEndpoint parses an endpoint in "{glob}?node={node}&speed={speed}&loop={bool}&start={RFC 3339}" format.
+// node defaults to nodeName, and the other params are optional.
+//
+// E.g., "/recordings/samples*.jsonl?speed=10&start=2025-01-01T09:00:00Z"
+func ParseEndpoint(endpoint, nodeName string) (pattern string, opts Options, err error) {
+	pattern, rawQuery := cutQuery(endpoint)
+	q, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", Options{}, fmt.Errorf("invalid params: %w", err)
+	}
+	opts.Node = nodeName
+	if v := q.Get("node"); v != "" {
+		opts.Node = v
+	}
+	if v := q.Get("speed"); v != "" {
+		if opts.Speed, err = strconv.ParseFloat(v, 64); err != nil {
+			return "", Options{}, fmt.Errorf("invalid speed: %w", err)
+		}
+	}
+	if v := q.Get("loop"); v != "" {
+		if opts.Loop, err = strconv.ParseBool(v); err != nil {
+			return "", Options{}, fmt.Errorf("invalid loop: %w", err)
+		}
+	}
+	if v := q.Get("start"); v != "" {
+		if opts.Start, err = time.Parse(time.RFC3339, v); err != nil {
+			return "", Options{}, fmt.Errorf("invalid start: %w", err)
+		}
+	}
+	return pattern, opts, nil
+}
+
+// cutQuery splits the endpoint at the last "?" followed by params, as globs may contain "?" too.
+func cutQuery(endpoint string) (pattern, rawQuery string) {
+	i := strings.LastIndex(endpoint, "?")
+	if i < 0 || !strings.Contains(endpoint[i+1:], "=") {
+		return endpoint, ""
+	}
+	return endpoint[:i], endpoint[i+1:]
+}
+
+// Agent replays recorded samples of a node and a ValueType as if they were fetched now.
+// The recording starts on the first Fetch or Stream and advances with the wall clock scaled by Options.Speed.
+type Agent struct {
+	valueType metrics.ValueType
+	opts      Options
+
+	// samples are sorted by Timestamp. offsets are durations from the first sample.
+	// They are set by NewAgent, or on the first Fetch or Stream of an agent created by NewAgentFromEndpoint.
+	samples []metrics.Sample
+	offsets []time.Duration
+	// read reads the recording. It is retried on each Fetch or Stream until it succeeds.
+	read   func() ([]metrics.Sample, error)
+	loadMu sync.Mutex
+
+	// now is replaced in tests.
+	now func() time.Time
+
+	mu     sync.Mutex
+	start  time.Time
+	labels map[string]string
+}
+
+var _ metrics.LabeledAgent = (*Agent)(nil)
+var _ metrics.StreamingAgent = (*Agent)(nil)
+
+// NewAgent inits the agent with samples (e.g., from metrics.ReadRecordings) sorted by timestamp.
+func NewAgent(valueType metrics.ValueType, samples []metrics.Sample, opts Options) (*Agent, error) {
+	a, err := newAgent(valueType, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.setSamples(samples); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// NewAgentFromEndpoint inits the agent with the recording files. See ParseEndpoint.
+// The files must be in the directory of EnvVarReplayDir, and relative patterns are resolved from it.
+// The files are read on the first Fetch or Stream, so creating an agent that is discarded (e.g., on a reconcile without changes) is cheap.
+func NewAgentFromEndpoint(valueType metrics.ValueType, endpoint, nodeName string) (*Agent, error) {
+	pattern, opts, err := ParseEndpoint(endpoint, nodeName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	dir := os.Getenv(EnvVarReplayDir)
+	if dir == "" {
+		return nil, fmt.Errorf("replay is disabled as %s is not set", EnvVarReplayDir)
+	}
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+	a, err := newAgent(valueType, opts)
+	if err != nil {
+		return nil, err
+	}
+	a.read = func() ([]metrics.Sample, error) { return readRecordings(dir, pattern) }
+	return a, nil
+}
+
+// readRecordings reads the files matching pattern, and returns an error if any of them is outside dir.
+func readRecordings(dir, pattern string) ([]metrics.Sample, error) {
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", EnvVarReplayDir, err)
+	}
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no recording files match %q", pattern)
+	}
+	for i, file := range files {
+		// resolve symlinks so that they cannot point outside the directory
+		path, err := filepath.EvalSymlinks(file)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("file %q is not in %s", file, dir)
+		}
+		files[i] = path
+	}
+	return metrics.ReadRecordingFiles(files)
+}
+
+func newAgent(valueType metrics.ValueType, opts Options) (*Agent, error) {
+	if opts.Speed == 0 {
+		opts.Speed = 1
+	}
+	if opts.Speed < 0 {
+		return nil, errors.New("speed must be positive")
+	}
+	return &Agent{valueType: valueType, opts: opts, now: time.Now}, nil
+}
+
+// setSamples sets the samples of the node and the ValueType.
+// Of samples with the same timestamp, the last one is kept, and samples not sorted by timestamp are rejected.
+func (a *Agent) setSamples(samples []metrics.Sample) error {
+	var filtered []metrics.Sample
+	var offsets []time.Duration
+	for _, s := range samples {
+		if s.Node != a.opts.Node || s.Metric != a.valueType || s.Timestamp.Before(a.opts.Start) {
+			continue
+		}
+		if n := len(filtered); n > 0 && !s.Timestamp.After(filtered[n-1].Timestamp) {
+			if !s.Timestamp.Equal(filtered[n-1].Timestamp) {
+				return fmt.Errorf("samples are not sorted by timestamp: %s after %s", s.Timestamp, filtered[n-1].Timestamp)
+			}
+			filtered[n-1] = s
+			continue
+		}
+		if len(filtered) == 0 {
+			offsets = append(offsets, 0)
+		} else {
+			offsets = append(offsets, s.Timestamp.Sub(filtered[0].Timestamp))
+		}
+		filtered = append(filtered, s)
+	}
+	if len(filtered) == 0 {
+		return fmt.Errorf("no %s samples of node %q in the recording", a.valueType, a.opts.Node)
+	}
+	a.samples, a.offsets = filtered, offsets
+	return nil
+}
+
+// load reads the recording if not read yet. The samples are not modified after it returns nil.
+func (a *Agent) load() error {
+	a.loadMu.Lock()
+	defer a.loadMu.Unlock()
+	if a.samples != nil {
+		return nil
+	}
+	samples, err := a.read()
+	if err != nil {
+		return err
+	}
+	return a.setSamples(samples)
+}
+
+// span is the duration of a loop. The last sample lasts as long as the average interval, or a second if there is only one sample.
+func (a *Agent) span() time.Duration {
+	last := a.offsets[len(a.offsets)-1]
+	span := last
+	if n := len(a.offsets); n > 1 {
+		span += last / time.Duration(n-1)
+	}
+	if span <= 0 {
+		return time.Second
+	}
+	return span
+}
+
+// position returns the recording offset at now, starting the replay if not started yet.
+func (a *Agent) position(now time.Time) time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.start.IsZero() {
+		a.start = now
+	}
+	pos := time.Duration(float64(now.Sub(a.start)) * a.opts.Speed)
+	if a.opts.Loop {
+		pos %= a.span()
+	}
+	return pos
+}
+
+// wallTime returns the wall clock time the recording reaches pos in the loop starting at loopStart.
+func (a *Agent) wallTime(loopStart time.Time, pos time.Duration) time.Time {
+	return loopStart.Add(time.Duration(float64(pos) / a.opts.Speed))
+}
+
+// index returns the index of the last sample at or before pos.
+func (a *Agent) index(pos time.Duration) int {
+	i := sort.Search(len(a.offsets), func(i int) bool { return a.offsets[i] > pos })
+	return max(i-1, 0)
+}
+
+func (a *Agent) setLabels(s metrics.Sample) {
+	labels := maps.Clone(s.Labels)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[LabelReplayed] = "true"
+	a.mu.Lock()
+	a.labels = labels
+	a.mu.Unlock()
+}
+
+func (a *Agent) Fetch(ctx context.Context) (float64, error) {
+	if err := a.load(); err != nil {
+		return 0, err
+	}
+	s := a.samples[a.index(a.position(a.now()))]
+	a.setLabels(s)
+	return s.Value, nil
+}
+
+// Stream emits each sample at its time-scaled position, so changes between fetch intervals are replayed too.
+// Values are emitted with the current time as timestamps. Without Options.Loop, it blocks after the last sample until ctx is done.
+func (a *Agent) Stream(ctx context.Context, emit func(value float64, timestamp time.Time)) error {
+	if err := a.load(); err != nil {
+		return err
+	}
+	now := a.now()
+	pos := a.position(now)
+	loopStart := now.Add(-time.Duration(float64(pos) / a.opts.Speed))
+	i := a.index(pos) + 1
+	for {
+		if i >= len(a.samples) {
+			if !a.opts.Loop {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			loopStart = a.wallTime(loopStart, a.span())
+			i = 0
+		}
+		at := a.wallTime(loopStart, a.offsets[i])
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Until(at)):
+		}
+		a.setLabels(a.samples[i])
+		emit(a.samples[i].Value, time.Now())
+		i++
+	}
+}
+
+func (a *Agent) ValueType() metrics.ValueType { return a.valueType }
+
+// Labels returns the recorded labels of the last value with LabelReplayed.
+func (a *Agent) Labels() map[string]string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.labels
+}
@@ -0,0 +1,178 @@
+package replay
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+)
+
+func TestParseEndpoint(t *testing.T) {
+	tests := []struct {
+		name        string
+		endpoint    string
+		wantPattern string
+		wantOpts    Options
+		wantErr     bool
+	}{
+		{name: "default", endpoint: "/rec/samples*.jsonl", wantPattern: "/rec/samples*.jsonl", wantOpts: Options{Node: "node-0"}},
+		{name: "glob_question", endpoint: "/rec/samples-?.jsonl", wantPattern: "/rec/samples-?.jsonl", wantOpts: Options{Node: "node-0"}},
+		{
+			name:        "params",
+			endpoint:    "/rec/samples-?.jsonl?node=node-1&speed=10&loop=true&start=2025-01-01T09:00:00Z",
+			wantPattern: "/rec/samples-?.jsonl",
+			wantOpts:    Options{Node: "node-1", Speed: 10, Loop: true, Start: time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)},
+		},
+		{name: "invalid_speed", endpoint: "/rec/a.jsonl?speed=fast", wantErr: true},
+		{name: "invalid_start", endpoint: "/rec/a.jsonl?start=yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern, opts, err := ParseEndpoint(tt.endpoint, "node-0")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseEndpoint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if pattern != tt.wantPattern || opts != tt.wantOpts {
+				t.Errorf("ParseEndpoint() = %q, %+v, want %q, %+v", pattern, opts, tt.wantPattern, tt.wantOpts)
+			}
+		})
+	}
+}
+
+func TestAgent_Fetch(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var samples []metrics.Sample
+	for i, v := range []float64{20, 21, 22, 23} {
+		samples = append(samples, metrics.Sample{Node: "node-0", Metric: metrics.ValueInletTemperature, Value: v, Timestamp: t0.Add(time.Duration(i) * 10 * time.Second), Labels: map[string]string{metrics.LabelSource: "redfish"}})
+	}
+	samples = append(samples,
+		metrics.Sample{Node: "node-1", Metric: metrics.ValueInletTemperature, Value: 99, Timestamp: t0.Add(5 * time.Second)},
+		metrics.Sample{Node: "node-0", Metric: metrics.ValueDeltaPressure, Value: 99, Timestamp: t0.Add(5 * time.Second)},
+	)
+
+	type call struct {
+		at   time.Duration // since the first Fetch
+		want float64
+	}
+	tests := []struct {
+		name  string
+		opts  Options
+		calls []call
+	}{
+		{name: "realtime", opts: Options{Node: "node-0"}, calls: []call{{0, 20}, {9 * time.Second, 20}, {10 * time.Second, 21}, {35 * time.Second, 23}, {time.Hour, 23}}},
+		{name: "speed", opts: Options{Node: "node-0", Speed: 10}, calls: []call{{0, 20}, {time.Second, 21}, {2 * time.Second, 22}}},
+		{name: "loop", opts: Options{Node: "node-0", Loop: true}, calls: []call{{0, 20}, {30 * time.Second, 23}, {40 * time.Second, 20}, {50 * time.Second, 21}}},
+		{name: "start", opts: Options{Node: "node-0", Start: t0.Add(15 * time.Second)}, calls: []call{{0, 22}, {10 * time.Second, 23}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewAgent(metrics.ValueInletTemperature, samples, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			now := time.Now()
+			for _, c := range tt.calls {
+				a.now = func() time.Time { return now.Add(c.at) }
+				got, err := a.Fetch(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != c.want {
+					t.Errorf("Fetch() at %v = %v, want %v", c.at, got, c.want)
+				}
+			}
+			if l := a.Labels(); l[metrics.LabelSource] != "redfish" || l[LabelReplayed] != "true" {
+				t.Errorf("Labels() = %v", l)
+			}
+		})
+	}
+
+	if _, err := NewAgent(metrics.ValueInletTemperature, samples, Options{Node: "node-2"}); err == nil {
+		t.Error("NewAgent() with no samples got no error")
+	}
+}
+
+func TestAgent_Fetch_sameTimestamps(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	sample := func(v float64, ts time.Time) metrics.Sample {
+		return metrics.Sample{Node: "node-0", Metric: metrics.ValueInletTemperature, Value: v, Timestamp: ts}
+	}
+	tests := []struct {
+		name    string
+		samples []metrics.Sample
+		want    []float64 // at 0s, 10s, 20s since the first Fetch
+		wantErr bool
+	}{
+		{name: "all_same", samples: []metrics.Sample{sample(20, t0), sample(21, t0)}, want: []float64{21, 21, 21}},
+		{name: "some_same", samples: []metrics.Sample{sample(20, t0), sample(21, t0), sample(22, t0.Add(10*time.Second))}, want: []float64{21, 22, 21}},
+		{name: "unsorted", samples: []metrics.Sample{sample(20, t0.Add(time.Second)), sample(21, t0)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewAgent(metrics.ValueInletTemperature, tt.samples, Options{Node: "node-0", Loop: true})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewAgent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			now := time.Now()
+			for i, want := range tt.want {
+				at := time.Duration(i) * 10 * time.Second
+				a.now = func() time.Time { return now.Add(at) }
+				if got, err := a.Fetch(context.Background()); err != nil || got != want {
+					t.Errorf("Fetch() at %v = %v, %v, want %v", at, got, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewAgentFromEndpoint(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewAgentFromEndpoint(metrics.ValueInletTemperature, filepath.Join(dir, "samples*.jsonl"), "node-0"); err == nil {
+		t.Errorf("NewAgentFromEndpoint() without %s got no error", EnvVarReplayDir)
+	}
+	t.Setenv(EnvVarReplayDir, dir)
+
+	a, err := NewAgentFromEndpoint(metrics.ValueInletTemperature, "samples*.jsonl", "node-0")
+	if err != nil {
+		t.Fatalf("NewAgentFromEndpoint() reads the recording before the first fetch: %v", err)
+	}
+	if _, err := a.Fetch(context.Background()); err == nil {
+		t.Fatal("Fetch() without recording files got no error")
+	}
+
+	r, err := metrics.NewFileRecorder(filepath.Join(dir, "samples.jsonl"), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Record(metrics.Sample{Node: "node-0", Metric: metrics.ValueInletTemperature, Value: 20, Timestamp: time.Now()})
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := a.Fetch(context.Background()); err != nil || got != 20 {
+		t.Errorf("Fetch() = %v, %v, want 20", got, err)
+	}
+
+	// files outside the directory, including via symlinks, are rejected
+	outside := t.TempDir()
+	if err := os.Rename(filepath.Join(dir, "samples.jsonl"), filepath.Join(outside, "samples.jsonl")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "samples.jsonl"), filepath.Join(dir, "link.jsonl")); err != nil {
+		t.Fatal(err)
+	}
+	for _, endpoint := range []string{filepath.Join(outside, "samples*.jsonl"), "../*/samples.jsonl", "link.jsonl"} {
+		a, err := NewAgentFromEndpoint(metrics.ValueInletTemperature, endpoint, "node-0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := a.Fetch(context.Background()); err == nil {
+			t.Errorf("Fetch() of %q outside %s got no error", endpoint, EnvVarReplayDir)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// SnapshotBackend saves and loads Store snapshots.
+type SnapshotBackend interface {
+	Save(ctx context.Context, data []byte) error
+	// Load returns nil if no snapshot has been saved yet.
+	Load(ctx context.Context) ([]byte, error)
+}
+
+// FileSnapshotBackend saves snapshots to a local file.
+type FileSnapshotBackend struct {
+	Path string
+}
+
+var _ SnapshotBackend = (*FileSnapshotBackend)(nil)
+
+func (b *FileSnapshotBackend) Save(_ context.Context, data []byte) error {
+	// write to a temp file and rename it to avoid partial writes
+	f, err := os.CreateTemp(filepath.Dir(b.Path), filepath.Base(b.Path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), b.Path)
+}
+
+func (b *FileSnapshotBackend) Load(_ context.Context) ([]byte, error) {
+	data, err := os.ReadFile(b.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// ConfigMapSnapshotBackend saves snapshots to a ConfigMap.
+// Unlike FileSnapshotBackend, snapshots survive pod replacement (e.g., rolling upgrades).
+type ConfigMapSnapshotBackend struct {
+	Client    kubernetes.Interface
+	Namespace string
+	Name      string
+	// Identity distinguishes replicas that save snapshots at the same time, e.g., the shard identity with sharding.
+	// If set, each replica saves to "{Name}-{Identity}" and restores from the snapshots of all replicas,
+	// and snapshots not saved for configMapSnapshotMaxAge (i.e., of replicas that are gone) are deleted.
+	Identity string
+}
+
+var _ SnapshotBackend = (*ConfigMapSnapshotBackend)(nil)
+
+const (
+	// ConfigMapSnapshotKey is the key in ConfigMap.binaryData that holds the snapshot.
+	ConfigMapSnapshotKey = "store.json"
+	// LabelSnapshot is the label of ConfigMaps saved with Identity, whose value is the Name of the backend.
+	LabelSnapshot = "waok8s.github.io/snapshot"
+	// AnnotationSnapshotSavedAt is the time a snapshot was saved at in RFC 3339 format.
+	AnnotationSnapshotSavedAt = "waok8s.github.io/snapshot-saved-at"
+
+	// MaxConfigMapSnapshotSize is the max size of a snapshot, as a ConfigMap cannot hold more than 1MiB.
+	// Some headroom is left for the metadata.
+	MaxConfigMapSnapshotSize = 1<<20 - 16<<10
+
+	// configMapSnapshotMaxAge is the age of snapshots of other replicas to be deleted.
+	// Restored values older than the metric TTL are expired anyway, so this is only for garbage collection.
+	configMapSnapshotMaxAge = time.Hour
+)
+
+func (b *ConfigMapSnapshotBackend) name() string {
+	if b.Identity == "" {
+		return b.Name
+	}
+	return b.Name + "-" + b.Identity
+}
+
+func (b *ConfigMapSnapshotBackend) Save(ctx context.Context, data []byte) error {
+	if len(data) > MaxConfigMapSnapshotSize {
+		return fmt.Errorf("snapshot is %d bytes, larger than the max %d bytes of a ConfigMap", len(data), MaxConfigMapSnapshotSize)
+	}
+
+	now := time.Now()
+	cms := b.Client.CoreV1().ConfigMaps(b.Namespace)
+	cm, err := cms.Get(ctx, b.name(), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: b.name(), Namespace: b.Namespace}}
+		b.setData(cm, data, now)
+		if _, err := cms.Create(ctx, cm, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	} else {
+		b.setData(cm, data, now)
+		if _, err := cms.Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+
+	if b.Identity != "" {
+		b.deleteStale(ctx, now)
+	}
+	return nil
+}
+
+func (b *ConfigMapSnapshotBackend) setData(cm *corev1.ConfigMap, data []byte, now time.Time) {
+	cm.BinaryData = map[string][]byte{ConfigMapSnapshotKey: data}
+	if b.Identity == "" {
+		return
+	}
+	if cm.Labels == nil {
+		cm.Labels = map[string]string{}
+	}
+	cm.Labels[LabelSnapshot] = b.Name
+	if cm.Annotations == nil {
+		cm.Annotations = map[string]string{}
+	}
+	cm.Annotations[AnnotationSnapshotSavedAt] = now.UTC().Format(time.RFC3339)
+}
+
+// deleteStale deletes snapshots of other replicas not saved for configMapSnapshotMaxAge. Errors are logged.
+func (b *ConfigMapSnapshotBackend) deleteStale(ctx context.Context, now time.Time) {
+	lg := slog.With("func", "ConfigMapSnapshotBackend.deleteStale", "name", b.Name)
+
+	cms, err := b.list(ctx)
+	if err != nil {
+		lg.Error("unable to list snapshots", "err", err)
+		return
+	}
+	for _, cm := range cms {
+		if cm.Name == b.name() {
+			continue
+		}
+		savedAt, err := time.Parse(time.RFC3339, cm.Annotations[AnnotationSnapshotSavedAt])
+		if err == nil && now.Sub(savedAt) <= configMapSnapshotMaxAge {
+			continue
+		}
+		if err := b.Client.CoreV1().ConfigMaps(b.Namespace).Delete(ctx, cm.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			lg.Error("unable to delete stale snapshot", "configmap", cm.Name, "err", err)
+			continue
+		}
+		lg.Info("stale snapshot deleted", "configmap", cm.Name)
+	}
+}
+
+func (b *ConfigMapSnapshotBackend) list(ctx context.Context) ([]corev1.ConfigMap, error) {
+	l, err := b.Client.CoreV1().ConfigMaps(b.Namespace).List(ctx, metav1.ListOptions{LabelSelector: LabelSnapshot + "=" + b.Name})
+	if err != nil {
+		return nil, err
+	}
+	return l.Items, nil
+}
+
+// Load returns the snapshot, or the entries of the snapshots of all replicas if Identity is set.
+func (b *ConfigMapSnapshotBackend) Load(ctx context.Context) ([]byte, error) {
+	if b.Identity == "" {
+		cm, err := b.Client.CoreV1().ConfigMaps(b.Namespace).Get(ctx, b.Name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return cm.BinaryData[ConfigMapSnapshotKey], nil
+	}
+
+	cms, err := b.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(cms) == 0 {
+		return nil, nil
+	}
+	// concat entries, as Store.Merge keeps the newer value of each metric
+	var entries []json.RawMessage
+	for _, cm := range cms {
+		var es []json.RawMessage
+		if err := json.Unmarshal(cm.BinaryData[ConfigMapSnapshotKey], &es); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal snapshot %s: %w", cm.Name, err)
+		}
+		entries = append(entries, es...)
+	}
+	return json.Marshal(entries)
+}
+
+// Checkpointer periodically saves the Store to a SnapshotBackend and restores it at startup,
+// so that the adapter can answer queries right after restarts.
+//
+// NOTE: Restored values keep their original timestamps, so they are still subject to TTL.
+type Checkpointer struct {
+	store    *Store
+	backend  SnapshotBackend
+	interval time.Duration
+}
+
+func NewCheckpointer(store *Store, backend SnapshotBackend, interval time.Duration) *Checkpointer {
+	return &Checkpointer{
+		store:    store,
+		backend:  backend,
+		interval: interval,
+	}
+}
+
+// Save saves the current Store.
+func (c *Checkpointer) Save(ctx context.Context) error {
+	data, err := json.Marshal(c.store.Entries())
+	if err != nil {
+		return fmt.Errorf("unable to marshal store entries: %w", err)
+	}
+	return c.backend.Save(ctx, data)
+}
+
+// Restore merges the saved snapshot into the Store.
+// Values already in the Store are kept if they are newer.
+func (c *Checkpointer) Restore(ctx context.Context) error {
+	data, err := c.backend.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to load snapshot: %w", err)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	var entries []StoreEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("unable to unmarshal snapshot: %w", err)
+	}
+	c.store.Merge(entries)
+	return nil
+}
+
+// Run saves the Store periodically until ctx is done, and saves it once more before returning.
+func (c *Checkpointer) Run(ctx context.Context) {
+	lg := slog.With("func", "Checkpointer.Run")
+
+	t := time.NewTicker(c.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			ctx2, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := c.Save(ctx2); err != nil {
+				lg.Error("unable to save snapshot", "err", err)
+			}
+			cancel()
+			lg.Info("stopped")
+			return
+		case <-t.C:
+			if err := c.Save(ctx); err != nil {
+				lg.Error("unable to save snapshot", "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCheckpointer_SaveRestore(t *testing.T) {
+	ctx := context.Background()
+	backend := &FileSnapshotBackend{Path: filepath.Join(t.TempDir(), "store.json")}
+
+	// restore from a missing snapshot is not an error
+	if err := NewCheckpointer(&Store{}, backend, time.Second).Restore(ctx); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	t0 := time.Now().Add(-10 * time.Second).Round(0)
+	src := &Store{}
+	src.Set(StoreKeyForNode("node-0"), MetricData{
+		InletTemp:          20.5,
+		InletTempTimestamp: t0,
+		InletTempLabels:    map[string]string{LabelSource: "redfish"},
+	})
+	if err := NewCheckpointer(src, backend, time.Second).Save(ctx); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// newer values in the Store are kept
+	t1 := time.Now().Round(0)
+	dst := &Store{}
+	dst.Set(StoreKeyForNode("node-0"), MetricData{DeltaPressure: 7.5, DeltaPressureTimestamp: t1})
+	if err := NewCheckpointer(dst, backend, time.Second).Restore(ctx); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	got, ok := dst.Get(StoreKeyForNode("node-0"))
+	if !ok {
+		t.Fatal("node-0 not restored")
+	}
+	if got.InletTemp != 20.5 || !got.InletTempTimestamp.Equal(t0) || got.InletTempLabels[LabelSource] != "redfish" {
+		t.Errorf("restored inlet_temp = %v %v %v", got.InletTemp, got.InletTempTimestamp, got.InletTempLabels)
+	}
+	if got.DeltaPressure != 7.5 || !got.DeltaPressureTimestamp.Equal(t1) {
+		t.Errorf("delta_p = %v %v, want kept", got.DeltaPressure, got.DeltaPressureTimestamp)
+	}
+}
+
+func TestConfigMapSnapshotBackend_identity(t *testing.T) {
+	ctx := context.Background()
+	stale := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name:        "snapshot-gone",
+		Namespace:   "default",
+		Labels:      map[string]string{LabelSnapshot: "snapshot"},
+		Annotations: map[string]string{AnnotationSnapshotSavedAt: time.Now().Add(-2 * configMapSnapshotMaxAge).Format(time.RFC3339)},
+	}}
+	client := kubefake.NewSimpleClientset(stale)
+
+	// each replica saves its own shard
+	t0 := time.Now().Round(0)
+	for _, id := range []string{"a", "b"} {
+		src := &Store{}
+		src.Set(StoreKeyForNode("node-"+id), MetricData{InletTemp: 20.5, InletTempTimestamp: t0})
+		backend := &ConfigMapSnapshotBackend{Client: client, Namespace: "default", Name: "snapshot", Identity: id}
+		if err := NewCheckpointer(src, backend, time.Second).Save(ctx); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	for _, name := range []string{"snapshot-a", "snapshot-b"} {
+		if _, err := client.CoreV1().ConfigMaps("default").Get(ctx, name, metav1.GetOptions{}); err != nil {
+			t.Errorf("ConfigMap %s: %v", name, err)
+		}
+	}
+	if _, err := client.CoreV1().ConfigMaps("default").Get(ctx, stale.Name, metav1.GetOptions{}); err == nil {
+		t.Errorf("stale ConfigMap %s is not deleted", stale.Name)
+	}
+
+	// a new replica restores all shards
+	dst := &Store{}
+	backend := &ConfigMapSnapshotBackend{Client: client, Namespace: "default", Name: "snapshot", Identity: "c"}
+	if err := NewCheckpointer(dst, backend, time.Second).Restore(ctx); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	for _, node := range []string{"node-a", "node-b"} {
+		if got, ok := dst.Get(StoreKeyForNode(node)); !ok || got.InletTemp != 20.5 {
+			t.Errorf("%s = %v, %v, want restored", node, got.InletTemp, ok)
+		}
+	}
+}
+
+func TestConfigMapSnapshotBackend_Save_tooLarge(t *testing.T) {
+	backend := &ConfigMapSnapshotBackend{Client: kubefake.NewSimpleClientset(), Namespace: "default", Name: "snapshot"}
+	if err := backend.Save(context.Background(), make([]byte, MaxConfigMapSnapshotSize+1)); err == nil {
+		t.Error("Save() of a snapshot larger than a ConfigMap got no error")
+	}
+}
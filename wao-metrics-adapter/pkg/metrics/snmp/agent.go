@@ -0,0 +1,96 @@
+package snmp
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+)
+
+// Options configures which OID to get and how to convert it.
+type Options struct {
+	// OID is the numeric OID of the value, e.g., "1.3.6.1.4.1.318.1.1.26.9.4.3.1.7.12".
+	OID string
+	// Scale is multiplied to the value. Zero means 1.
+	// E.g., 0.1 for values in tenths of degrees.
+	Scale float64
+	// Offset is added to the value after scaling.
+	Offset float64
+	// MaxAge is the maximum age of a batched result shared with other agents on the same device.
+	// Zero disables sharing results, but requests are still batched.
+	MaxAge time.Duration
+}
+
+// Agent gets a value via SNMP.
+// Agents on the same device (address and credentials) share batched GetRequests.
+type Agent struct {
+	valueType metrics.ValueType
+
+	// address contains host and port.
+	// E.g., "10.0.0.1:161"
+	address string
+	opts    Options
+
+	device *device
+
+	closeOnce sync.Once
+}
+
+var _ metrics.LabeledAgent = (*Agent)(nil)
+var _ metrics.ClosableAgent = (*Agent)(nil)
+
+// NewAgent inits the client.
+// address is "host:port" (port defaults to 161) optionally prefixed with "udp://".
+func NewAgent(valueType metrics.ValueType, address string, sec Security, opts Options, timeout time.Duration) (*Agent, error) {
+	address = strings.TrimPrefix(address, "udp://")
+	if !strings.Contains(address, ":") {
+		address += ":161"
+	}
+	oid, err := ParseOID(opts.OID)
+	if err != nil {
+		return nil, err
+	}
+	opts.OID = FormatOID(oid)
+	if opts.Scale == 0 {
+		opts.Scale = 1
+	}
+
+	d, err := getDevice(address, sec, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &Agent{
+		valueType: valueType,
+		address:   address,
+		opts:      opts,
+		device:    d,
+	}, nil
+}
+
+func (a *Agent) Fetch(ctx context.Context) (float64, error) {
+	v, err := a.device.get(ctx, a.opts.OID, a.opts.MaxAge)
+	if err != nil {
+		return 0.0, err
+	}
+	f, err := v.Float64()
+	if err != nil {
+		return 0.0, err
+	}
+	return f*a.opts.Scale + a.opts.Offset, nil
+}
+
+func (a *Agent) ValueType() metrics.ValueType { return a.valueType }
+
+// Close releases the device, and closes the socket if no other agent uses it.
+func (a *Agent) Close() {
+	a.closeOnce.Do(func() { releaseDevice(a.device) })
+}
+
+func (a *Agent) Labels() map[string]string {
+	return map[string]string{
+		metrics.LabelSource: "snmp",
+		metrics.LabelSensor: a.opts.OID,
+	}
+}
@@ -0,0 +1,433 @@
+package snmp
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"math"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+)
+
+// testResponder is a minimal SNMP agent that serves GetRequests from a value map.
+// It supports v2c with a community and v3 USM with authNoPriv.
+type testResponder struct {
+	conn   net.PacketConn
+	values map[string]Variable
+
+	community    string
+	engineID     []byte
+	userName     string
+	authProtocol AuthProtocol
+	authKey      []byte
+
+	mu          sync.Mutex
+	engineBoots int64
+	engineTime  int64
+
+	nRequests atomic.Int32
+}
+
+func newTestResponder(t *testing.T, values map[string]Variable, optFns ...func(*testResponder)) *testResponder {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testResponder{conn: conn, values: values, community: "public", engineID: []byte{0x80, 0x00, 0x1f, 0x88, 0x04, 't', 'e', 's', 't'}, engineBoots: 1, engineTime: 100}
+	for _, f := range optFns {
+		f(s)
+	}
+	go func() {
+		buf := make([]byte, maxMessageSize)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if resp := s.handle(buf[:n]); resp != nil {
+				conn.WriteTo(resp, addr)
+			}
+		}
+	}()
+	t.Cleanup(func() { conn.Close() })
+	return s
+}
+
+func withUser(userName string, p AuthProtocol, password string) func(*testResponder) {
+	return func(s *testResponder) {
+		s.userName, s.authProtocol = userName, p
+		if password != "" {
+			s.authKey = localizeKey(p, password, s.engineID)
+		}
+	}
+}
+
+func (s *testResponder) addr() string { return s.conn.LocalAddr().String() }
+
+func (s *testResponder) respond(req *pdu) []byte {
+	if len(req.variables) > 0 {
+		s.nRequests.Add(1)
+	}
+	var vars []Variable
+	for _, v := range req.variables {
+		if v.OID == testOIDFail {
+			return appendResponse(nil, tagResponse, req.requestID, 5, 0, nil) // genErr
+		}
+		val, ok := s.values[v.OID]
+		if !ok {
+			val = Variable{Type: tagNoSuchInstance}
+		}
+		val.OID = v.OID
+		vars = append(vars, val)
+	}
+	return appendResponse(nil, tagResponse, req.requestID, 0, 0, vars)
+}
+
+func (s *testResponder) handle(b []byte) []byte {
+	d := &decoder{b: b}
+	m, err := d.expect(tagSequence)
+	if err != nil {
+		return nil
+	}
+	ver, err := m.integer()
+	if err != nil {
+		return nil
+	}
+	switch ver {
+	case 1:
+		community, err := m.octetString()
+		if err != nil || string(community) != s.community {
+			return nil // agents silently drop requests with a wrong community
+		}
+		req, err := decodePDU(m)
+		if err != nil {
+			return nil
+		}
+		v := appendInteger(nil, 1)
+		v = appendOctetString(v, community)
+		v = append(v, s.respond(req)...)
+		return appendTLV(nil, tagSequence, v)
+	case 3:
+		return s.handleV3(b)
+	}
+	return nil
+}
+
+func (s *testResponder) handleV3(b []byte) []byte {
+	msg, err := decodeV3(b)
+	if err != nil {
+		return nil
+	}
+	s.mu.Lock()
+	usm := usmParams{engineID: s.engineID, engineBoots: s.engineBoots, engineTime: s.engineTime, userName: msg.usm.userName}
+	s.mu.Unlock()
+	report := func(name string, authKey []byte) []byte {
+		var oid string
+		for k, v := range usmStatsNames {
+			if v == name {
+				oid = k
+			}
+		}
+		pdu := appendResponse(nil, tagReport, msg.pdu.requestID, 0, 0, []Variable{{OID: oid, Type: tagCounter32, Value: []byte{1}}})
+		var flags byte
+		if authKey != nil {
+			flags = flagAuth
+		}
+		return encodeV3(int32(msg.msgID), flags, usm, pdu, s.authProtocol, authKey)
+	}
+
+	switch {
+	case !bytes.Equal(msg.usm.engineID, s.engineID):
+		return report("unknownEngineIDs", nil)
+	case string(msg.usm.userName) != s.userName:
+		return report("unknownUserNames", nil)
+	case s.authKey != nil && !msg.verify(b, s.authProtocol, s.authKey):
+		return report("wrongDigests", nil)
+	case s.authKey != nil && (msg.usm.engineBoots != usm.engineBoots || math.Abs(float64(msg.usm.engineTime-usm.engineTime)) > 150):
+		return report("notInTimeWindows", s.authKey)
+	}
+	var flags byte
+	if s.authKey != nil {
+		flags = flagAuth
+	}
+	return encodeV3(int32(msg.msgID), flags, usm, s.respond(msg.pdu), s.authProtocol, s.authKey)
+}
+
+// appendResponse appends a Response (or Report) PDU.
+func appendResponse(b []byte, tag byte, requestID int64, errorStatus, errorIndex int64, vars []Variable) []byte {
+	var vbs []byte
+	for _, v := range vars {
+		oid, _ := ParseOID(v.OID)
+		vb := appendOID(nil, oid)
+		vb = appendTLV(vb, v.Type, v.Value)
+		vbs = appendTLV(vbs, tagSequence, vb)
+	}
+	v := appendInteger(nil, requestID)
+	v = appendInteger(v, errorStatus)
+	v = appendInteger(v, errorIndex)
+	v = appendTLV(v, tagSequence, vbs)
+	return appendTLV(b, tag, v)
+}
+
+// PDU values used in tests, e.g., APC PowerNet-MIB rPDU2OutletMeteredStatusPower and rPDU2SensorTempHumidityStatusTempC.
+const (
+	testOIDOutletPower = "1.3.6.1.4.1.318.1.1.26.9.4.3.1.7"
+	testOIDTempC       = "1.3.6.1.4.1.318.1.1.26.10.2.2.1.8.1"
+	// testOIDFail makes requests containing it fail with genErr.
+	testOIDFail = testOIDOutletPower + ".4"
+)
+
+var testValues = map[string]Variable{
+	testOIDOutletPower + ".1": {Type: tagInteger, Value: []byte{0x01, 0x2C}},   // 300 W
+	testOIDOutletPower + ".2": {Type: tagGauge32, Value: []byte{0x00, 0xFA}},   // 250 W
+	testOIDOutletPower + ".3": {Type: tagOctetString, Value: []byte("187.5")},  // string
+	testOIDTempC:              {Type: tagInteger, Value: []byte{0xFF, 0xF6}},   // -10 (tenths)
+	"1.3.6.1.2.1.1.3.0":       {Type: tagTimeTicks, Value: []byte{0x01, 0x00}}, // sysUpTime
+}
+
+func TestLocalizeKey(t *testing.T) {
+	// RFC 3414 A.3
+	engineID, _ := hex.DecodeString("000000000000000000000002")
+	tests := []struct {
+		p    AuthProtocol
+		want string
+	}{
+		{AuthMD5, "526f5eed9fcce26f8964c2930787d82b"},
+		{AuthSHA, "6695febc9288e36282235fc7151f128497b38f3f"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.p), func(t *testing.T) {
+			if got := hex.EncodeToString(localizeKey(tt.p, "maplesyrup", engineID)); got != tt.want {
+				t.Errorf("localizeKey() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAgent_Fetch(t *testing.T) {
+	srv := newTestResponder(t, testValues)
+
+	tests := []struct {
+		name    string
+		sec     Security
+		opts    Options
+		want    float64
+		wantErr bool
+	}{
+		{"integer", Security{}, Options{OID: testOIDOutletPower + ".1"}, 300, false},
+		{"gauge", Security{Community: "public"}, Options{OID: "." + testOIDOutletPower + ".2"}, 250, false},
+		{"string", Security{}, Options{OID: testOIDOutletPower + ".3"}, 187.5, false},
+		{"scaled", Security{}, Options{OID: testOIDTempC, Scale: 0.1, Offset: 30}, 29, false},
+		{"no_such_instance", Security{}, Options{OID: testOIDOutletPower + ".99"}, 0, true},
+		{"wrong_community", Security{Community: "private"}, Options{OID: testOIDOutletPower + ".1"}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewAgent(metrics.ValuePowerConsumption, "udp://"+srv.addr(), tt.sec, tt.opts, 200*time.Millisecond)
+			if err != nil {
+				t.Fatalf("NewAgent() error = %v", err)
+			}
+			got, err := a.Fetch(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Fetch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Fetch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAgent_Fetch_v3(t *testing.T) {
+	for _, p := range []AuthProtocol{AuthMD5, AuthSHA} {
+		t.Run(string(p), func(t *testing.T) {
+			srv := newTestResponder(t, testValues, withUser("wao", p, "maplesyrup"))
+
+			a, err := NewAgent(metrics.ValuePowerConsumption, srv.addr(), Security{Version: Version3, UserName: "wao", AuthProtocol: p, AuthPassword: "maplesyrup"}, Options{OID: testOIDOutletPower + ".1"}, time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := a.Fetch(context.Background()); err != nil || got != 300 {
+				t.Fatalf("Fetch() = %v, %v, want 300", got, err)
+			}
+
+			// the agent rebooted, so the client resyncs the engine time
+			srv.mu.Lock()
+			srv.engineBoots, srv.engineTime = 2, 5
+			srv.mu.Unlock()
+			if got, err := a.Fetch(context.Background()); err != nil || got != 300 {
+				t.Fatalf("Fetch() after reboot = %v, %v, want 300", got, err)
+			}
+
+			wrong, err := NewAgent(metrics.ValuePowerConsumption, srv.addr(), Security{Version: Version3, UserName: "wao", AuthProtocol: p, AuthPassword: "wrongpassword"}, Options{OID: testOIDOutletPower + ".1"}, time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := wrong.Fetch(context.Background()); err == nil {
+				t.Error("Fetch() with a wrong password succeeded")
+			}
+		})
+	}
+}
+
+func TestAgent_Fetch_batched(t *testing.T) {
+	srv := newTestResponder(t, testValues)
+
+	var agents []*Agent
+	for _, oid := range []string{testOIDOutletPower + ".1", testOIDOutletPower + ".2", testOIDTempC} {
+		a, err := NewAgent(metrics.ValuePowerConsumption, srv.addr(), Security{}, Options{OID: oid, MaxAge: time.Minute}, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		agents = append(agents, a)
+	}
+
+	// the first round registers OIDs, then a single GetRequest serves all agents
+	for _, a := range agents {
+		if _, err := a.Fetch(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	agents[0].device.readAt = time.Time{} // expire the shared result
+	before := srv.nRequests.Load()
+	for _, a := range agents {
+		if _, err := a.Fetch(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := srv.nRequests.Load() - before; got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+
+	// an OID failing the whole request does not break the others
+	failing, err := NewAgent(metrics.ValuePowerConsumption, srv.addr(), Security{}, Options{OID: testOIDFail, MaxAge: time.Minute}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := failing.Fetch(context.Background()); err == nil {
+		t.Error("Fetch() of the failing OID succeeded")
+	}
+	agents[0].device.readAt = time.Time{}
+	if got, err := agents[1].Fetch(context.Background()); err != nil || got != 250 {
+		t.Errorf("Fetch() = %v, %v, want 250", got, err)
+	}
+}
+
+func TestNewClient_securityLevel(t *testing.T) {
+	tests := []struct {
+		name      string
+		sec       Security
+		wantLevel SecurityLevel
+		wantErr   bool
+	}{
+		{"no_auth_default", Security{Version: Version3, UserName: "wao"}, NoAuthNoPriv, false},
+		{"auth_default", Security{Version: Version3, UserName: "wao", AuthPassword: "maplesyrup"}, AuthNoPriv, false},
+		{"auth_no_password", Security{Version: Version3, UserName: "wao", SecurityLevel: AuthNoPriv}, "", true},
+		{"no_auth_with_password", Security{Version: Version3, UserName: "wao", SecurityLevel: NoAuthNoPriv, AuthPassword: "maplesyrup"}, "", true},
+		{"auth_priv", Security{Version: Version3, UserName: "wao", SecurityLevel: AuthPriv, AuthPassword: "maplesyrup"}, "", true},
+		{"unknown", Security{Version: Version3, UserName: "wao", SecurityLevel: "authAndPriv"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewClient("127.0.0.1:161", tt.sec, time.Second)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewClient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && c.sec.SecurityLevel != tt.wantLevel {
+				t.Errorf("SecurityLevel = %v, want %v", c.sec.SecurityLevel, tt.wantLevel)
+			}
+		})
+	}
+}
+
+func TestAgent_Close(t *testing.T) {
+	srv := newTestResponder(t, testValues)
+
+	a1, err := NewAgent(metrics.ValuePowerConsumption, srv.addr(), Security{Community: "close"}, Options{OID: testOIDOutletPower + ".1"}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a2, err := NewAgent(metrics.ValuePowerConsumption, srv.addr(), Security{Community: "close"}, Options{OID: testOIDOutletPower + ".2"}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a1.device != a2.device {
+		t.Fatalf("agents with the same address and credentials do not share the device")
+	}
+	a1.Fetch(context.Background())
+	k := a1.device.key
+
+	a1.Close()
+	a1.Close() // idempotent
+	devicesMu.Lock()
+	_, ok := devices[k]
+	devicesMu.Unlock()
+	if !ok {
+		t.Fatalf("device removed while used by another agent")
+	}
+
+	a2.Close()
+	devicesMu.Lock()
+	_, ok = devices[k]
+	devicesMu.Unlock()
+	if ok {
+		t.Errorf("device not removed after all agents are closed")
+	}
+	if a1.device.client.conn != nil {
+		t.Errorf("socket not closed")
+	}
+}
+
+func FuzzDecodeV3(f *testing.F) {
+	usm := usmParams{engineID: []byte{0x80, 0x00, 0x1f, 0x88, 0x04}, engineBoots: 1, engineTime: 100, userName: []byte("wao")}
+	v := testValues[testOIDTempC]
+	v.OID = testOIDTempC
+	pdu := appendResponse(nil, tagResponse, 1, 0, 0, []Variable{v})
+	authKey := localizeKey(AuthSHA, "maplesyrup", usm.engineID)
+	f.Add(encodeV3(1, flagAuth, usm, pdu, AuthSHA, authKey))
+	f.Add(encodeV3(2, flagReportable, usmParams{}, appendGetRequest(nil, 2, nil), "", nil))
+	f.Add(encodeV3(3, flagAuth|flagPriv, usm, pdu, AuthMD5, authKey))
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		msg, err := decodeV3(b)
+		if err != nil {
+			return
+		}
+		if msg.flags&flagPriv != 0 {
+			t.Fatalf("decoded a message with privacy")
+		}
+		msg.verify(b, AuthSHA, authKey)
+		for _, v := range msg.pdu.variables {
+			v.Float64()
+		}
+	})
+}
+
+func FuzzDecodePDU(f *testing.F) {
+	var vars []Variable
+	for oid, v := range testValues {
+		v.OID = oid
+		vars = append(vars, v)
+	}
+	f.Add(appendResponse(nil, tagResponse, 1, 0, 0, vars))
+	f.Add(appendResponse(nil, tagReport, 2, 5, 1, nil))
+	f.Add(appendGetRequest(nil, 3, [][]uint32{{1, 3, 6, 1, 2, 1, 1, 3, 0}}))
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		p, err := decodePDU(&decoder{b: b})
+		if err != nil {
+			return
+		}
+		for _, v := range p.variables {
+			v.Float64()
+			if _, err := ParseOID(v.OID); err != nil {
+				t.Fatalf("decoded OID %q does not parse: %v", v.OID, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,239 @@
+package snmp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// BER tags used in SNMP messages.
+const (
+	tagInteger     byte = 0x02
+	tagOctetString byte = 0x04
+	tagNull        byte = 0x05
+	tagOID         byte = 0x06
+	tagSequence    byte = 0x30
+
+	tagIPAddress byte = 0x40
+	tagCounter32 byte = 0x41
+	tagGauge32   byte = 0x42
+	tagTimeTicks byte = 0x43
+	tagOpaque    byte = 0x44
+	tagCounter64 byte = 0x46
+
+	tagNoSuchObject   byte = 0x80
+	tagNoSuchInstance byte = 0x81
+	tagEndOfMibView   byte = 0x82
+
+	tagGetRequest byte = 0xA0
+	tagResponse   byte = 0xA2
+	tagReport     byte = 0xA8
+)
+
+// appendTLV appends a BER TLV with definite length.
+func appendTLV(b []byte, tag byte, value []byte) []byte {
+	b = append(b, tag)
+	b = appendLength(b, len(value))
+	return append(b, value...)
+}
+
+func appendLength(b []byte, n int) []byte {
+	switch {
+	case n < 0x80:
+		return append(b, byte(n))
+	case n <= 0xFF:
+		return append(b, 0x81, byte(n))
+	case n <= 0xFFFF:
+		return append(b, 0x82, byte(n>>8), byte(n))
+	default:
+		return append(b, 0x83, byte(n>>16), byte(n>>8), byte(n))
+	}
+}
+
+// headerLen returns the length of the tag and length octets of a TLV with n content octets.
+func headerLen(n int) int { return len(appendLength([]byte{0}, n)) }
+
+func appendInteger(b []byte, v int64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(v))
+	i := 0
+	// minimal two's complement encoding
+	for i < 7 && ((buf[i] == 0x00 && buf[i+1]&0x80 == 0) || (buf[i] == 0xFF && buf[i+1]&0x80 != 0)) {
+		i++
+	}
+	return appendTLV(b, tagInteger, buf[i:])
+}
+
+func appendOctetString(b []byte, s []byte) []byte { return appendTLV(b, tagOctetString, s) }
+
+func appendNull(b []byte) []byte { return append(b, tagNull, 0x00) }
+
+func appendOID(b []byte, oid []uint32) []byte {
+	var v []byte
+	v = appendBase128(v, oid[0]*40+oid[1])
+	for _, n := range oid[2:] {
+		v = appendBase128(v, n)
+	}
+	return appendTLV(b, tagOID, v)
+}
+
+func appendBase128(b []byte, n uint32) []byte {
+	var tmp [5]byte
+	i := len(tmp) - 1
+	tmp[i] = byte(n & 0x7F)
+	for n >>= 7; n > 0; n >>= 7 {
+		i--
+		tmp[i] = byte(n&0x7F) | 0x80
+	}
+	return append(b, tmp[i:]...)
+}
+
+// ParseOID parses a numeric OID like "1.3.6.1.2.1.1.3.0". A leading dot is allowed.
+func ParseOID(s string) ([]uint32, error) {
+	ss := strings.Split(strings.TrimPrefix(s, "."), ".")
+	if len(ss) < 2 {
+		return nil, fmt.Errorf("invalid OID %q", s)
+	}
+	oid := make([]uint32, len(ss))
+	for i, s := range ss {
+		n, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OID %q: %w", s, err)
+		}
+		oid[i] = uint32(n)
+	}
+	if oid[0] > 2 || (oid[0] < 2 && oid[1] >= 40) {
+		return nil, fmt.Errorf("invalid OID %q", s)
+	}
+	return oid, nil
+}
+
+// FormatOID formats an OID without a leading dot.
+func FormatOID(oid []uint32) string {
+	ss := make([]string, len(oid))
+	for i, n := range oid {
+		ss[i] = strconv.FormatUint(uint64(n), 10)
+	}
+	return strings.Join(ss, ".")
+}
+
+var errTruncated = errors.New("truncated BER data")
+
+// decoder reads TLVs from a byte slice.
+type decoder struct {
+	b []byte
+	// off is the offset of b in the original data, used to locate fields (e.g., authentication parameters).
+	off int
+}
+
+// next reads a TLV and returns its tag and a decoder for its contents.
+func (d *decoder) next() (byte, *decoder, error) {
+	if len(d.b) < 2 {
+		return 0, nil, errTruncated
+	}
+	tag := d.b[0]
+	n := int(d.b[1])
+	hl := 2
+	if n&0x80 != 0 {
+		nb := n & 0x7F
+		if nb == 0 || nb > 3 || len(d.b) < 2+nb {
+			return 0, nil, fmt.Errorf("unsupported BER length")
+		}
+		n = 0
+		for _, c := range d.b[2 : 2+nb] {
+			n = n<<8 | int(c)
+		}
+		hl += nb
+	}
+	if len(d.b) < hl+n {
+		return 0, nil, errTruncated
+	}
+	v := &decoder{b: d.b[hl : hl+n], off: d.off + hl}
+	d.b = d.b[hl+n:]
+	d.off += hl + n
+	return tag, v, nil
+}
+
+// expect reads a TLV with the given tag.
+func (d *decoder) expect(tag byte) (*decoder, error) {
+	t, v, err := d.next()
+	if err != nil {
+		return nil, err
+	}
+	if t != tag {
+		return nil, fmt.Errorf("unexpected BER tag: want 0x%02x but got 0x%02x", tag, t)
+	}
+	return v, nil
+}
+
+func (d *decoder) integer() (int64, error) {
+	v, err := d.expect(tagInteger)
+	if err != nil {
+		return 0, err
+	}
+	return parseInt(v.b)
+}
+
+func (d *decoder) octetString() ([]byte, error) {
+	v, err := d.expect(tagOctetString)
+	if err != nil {
+		return nil, err
+	}
+	return v.b, nil
+}
+
+func parseInt(b []byte) (int64, error) {
+	if len(b) == 0 || len(b) > 8 {
+		return 0, fmt.Errorf("invalid integer length %d", len(b))
+	}
+	var n int64
+	if b[0]&0x80 != 0 {
+		n = -1
+	}
+	for _, c := range b {
+		n = n<<8 | int64(c)
+	}
+	return n, nil
+}
+
+func parseUint(b []byte) (uint64, error) {
+	if len(b) == 0 || len(b) > 9 || (len(b) == 9 && b[0] != 0) {
+		return 0, fmt.Errorf("invalid unsigned integer length %d", len(b))
+	}
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n, nil
+}
+
+func parseOID(b []byte) ([]uint32, error) {
+	var oid []uint32
+	var n uint64
+	for i, c := range b {
+		n = n<<7 | uint64(c&0x7F)
+		if n > math.MaxUint32 {
+			return nil, fmt.Errorf("OID sub-identifier overflows")
+		}
+		if c&0x80 != 0 {
+			if i == len(b)-1 {
+				return nil, errTruncated
+			}
+			continue
+		}
+		if len(oid) == 0 {
+			first := min(n/40, 2)
+			oid = append(oid, uint32(first), uint32(n-40*first))
+		} else {
+			oid = append(oid, uint32(n))
+		}
+		n = 0
+	}
+	if len(oid) == 0 {
+		return nil, fmt.Errorf("empty OID")
+	}
+	return oid, nil
+}
@@ -0,0 +1,554 @@
+package snmp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"hash"
+	"net"
+	"sync"
+	"time"
+)
+
+type Version string
+
+const (
+	Version2c Version = "v2c"
+	Version3  Version = "v3"
+)
+
+type AuthProtocol string
+
+const (
+	// AuthMD5 is HMAC-MD5-96 (RFC 3414).
+	AuthMD5 AuthProtocol = "MD5"
+	// AuthSHA is HMAC-SHA-96 (RFC 3414).
+	AuthSHA AuthProtocol = "SHA"
+)
+
+// SecurityLevel is the SNMPv3 security level (RFC 3411).
+type SecurityLevel string
+
+const (
+	NoAuthNoPriv SecurityLevel = "noAuthNoPriv"
+	AuthNoPriv   SecurityLevel = "authNoPriv"
+	// AuthPriv is rejected as privacy (encryption) is not supported.
+	AuthPriv SecurityLevel = "authPriv"
+)
+
+// Security specifies the SNMP version and credentials.
+type Security struct {
+	// Version is v2c (default) or v3.
+	Version Version
+	// Community is used for v2c. Default is "public".
+	Community string
+	// UserName is the USM security name used for v3.
+	UserName string
+	// SecurityLevel is used for v3. Default is authNoPriv if AuthPassword is set, otherwise noAuthNoPriv.
+	// authPriv is rejected as privacy (encryption) is not supported.
+	SecurityLevel SecurityLevel
+	// AuthProtocol is MD5 or SHA (default), used for v3 with authNoPriv.
+	AuthProtocol AuthProtocol
+	// AuthPassword is the authentication passphrase used for v3 with authNoPriv.
+	AuthPassword string
+}
+
+// msgFlags in SNMPv3 messages.
+const (
+	flagAuth       byte = 0x01
+	flagPriv       byte = 0x02
+	flagReportable byte = 0x04
+)
+
+const (
+	// maxMessageSize is the maximum message size we can receive.
+	maxMessageSize = 65507
+	// authParamsLen is the length of HMAC-MD5-96 and HMAC-SHA-96 authentication parameters.
+	authParamsLen = 12
+	// usmStatsPrefix is the prefix of the OIDs reported on USM errors.
+	usmStatsPrefix = "1.3.6.1.6.3.15.1.1."
+)
+
+// usmStatsNames are the names of usmStats counters reported on USM errors (RFC 3414).
+var usmStatsNames = map[string]string{
+	usmStatsPrefix + "1.0": "unsupportedSecLevels",
+	usmStatsPrefix + "2.0": "notInTimeWindows",
+	usmStatsPrefix + "3.0": "unknownUserNames",
+	usmStatsPrefix + "4.0": "unknownEngineIDs",
+	usmStatsPrefix + "5.0": "wrongDigests",
+	usmStatsPrefix + "6.0": "decryptionErrors",
+}
+
+// Client is an SNMP client that sends GetRequests over UDP. It keeps a single socket and serializes requests.
+type Client struct {
+	// address contains host and port.
+	// E.g., "10.0.0.1:161"
+	address string
+	sec     Security
+	timeout time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+	id   int32
+
+	// SNMPv3 engine of the agent, discovered on the first request
+	engineID     []byte
+	engineBoots  int64
+	engineTime   int64
+	engineTimeAt time.Time
+	authKey      []byte
+}
+
+// NewClient inits the client.
+func NewClient(address string, sec Security, timeout time.Duration) (*Client, error) {
+	if sec.Version == "" {
+		sec.Version = Version2c
+	}
+	switch sec.Version {
+	case Version2c:
+		if sec.Community == "" {
+			sec.Community = "public"
+		}
+	case Version3:
+		if sec.UserName == "" {
+			return nil, fmt.Errorf("user name is required for %s", sec.Version)
+		}
+		if sec.SecurityLevel == "" {
+			sec.SecurityLevel = NoAuthNoPriv
+			if sec.AuthPassword != "" {
+				sec.SecurityLevel = AuthNoPriv
+			}
+		}
+		switch sec.SecurityLevel {
+		case NoAuthNoPriv:
+			if sec.AuthPassword != "" {
+				return nil, fmt.Errorf("auth password is set for %s", sec.SecurityLevel)
+			}
+		case AuthNoPriv:
+			if sec.AuthProtocol == "" {
+				sec.AuthProtocol = AuthSHA
+			}
+			if sec.AuthProtocol != AuthMD5 && sec.AuthProtocol != AuthSHA {
+				return nil, fmt.Errorf("unknown auth protocol %q", sec.AuthProtocol)
+			}
+			if len(sec.AuthPassword) < 8 {
+				return nil, fmt.Errorf("auth password must be at least 8 characters for %s", sec.SecurityLevel)
+			}
+		case AuthPriv:
+			return nil, fmt.Errorf("%s is not supported as privacy (encryption) is not implemented", sec.SecurityLevel)
+		default:
+			return nil, fmt.Errorf("unknown security level %q", sec.SecurityLevel)
+		}
+	default:
+		return nil, fmt.Errorf("unknown version %q", sec.Version)
+	}
+	return &Client{address: address, sec: sec, timeout: timeout}, nil
+}
+
+// Get sends a GetRequest for the OIDs and returns the variables in the same order.
+// Variables that do not exist are returned with an exception type (e.g., noSuchInstance), so check Variable.Float64().
+func (c *Client) Get(ctx context.Context, oids []string) ([]Variable, error) {
+	parsed := make([][]uint32, len(oids))
+	for i, s := range oids {
+		oid, err := ParseOID(s)
+		if err != nil {
+			return nil, err
+		}
+		parsed[i] = oid
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var p *pdu
+	var err error
+	if c.sec.Version == Version3 {
+		p, err = c.getV3(ctx, parsed)
+	} else {
+		p, err = c.getV2c(ctx, parsed)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.errorStatus != 0 {
+		return nil, &ResponseError{Status: p.errorStatus, Index: p.errorIndex}
+	}
+	if len(p.variables) != len(oids) {
+		return nil, fmt.Errorf("variable bindings mismatch: want %d but got %d", len(oids), len(p.variables))
+	}
+	return p.variables, nil
+}
+
+func (c *Client) nextID() int32 {
+	c.id++
+	if c.id <= 0 {
+		c.id = 1
+	}
+	return c.id
+}
+
+func (c *Client) getV2c(ctx context.Context, oids [][]uint32) (*pdu, error) {
+	id := c.nextID()
+	v := appendInteger(nil, 1) // version-2c
+	v = appendOctetString(v, []byte(c.sec.Community))
+	v = appendGetRequest(v, id, oids)
+	msg := appendTLV(nil, tagSequence, v)
+
+	var resp *pdu
+	err := c.exchange(ctx, msg, func(b []byte) (bool, error) {
+		d := &decoder{b: b}
+		m, err := d.expect(tagSequence)
+		if err != nil {
+			return false, nil
+		}
+		if ver, err := m.integer(); err != nil || ver != 1 {
+			return false, nil
+		}
+		if _, err := m.octetString(); err != nil {
+			return false, nil
+		}
+		p, err := decodePDU(m)
+		if err != nil || p.requestID != int64(id) {
+			return false, nil
+		}
+		resp = p
+		return true, nil
+	})
+	return resp, err
+}
+
+// exchange sends req and waits for a response accepted by match.
+// match returns false for datagrams that are not the response (e.g., late responses to previous requests).
+// The request is sent twice at most within the timeout, as UDP datagrams may be lost.
+func (c *Client) exchange(ctx context.Context, req []byte, match func(b []byte) (bool, error)) error {
+	if c.conn == nil {
+		d := net.Dialer{Timeout: c.timeout}
+		conn, err := d.DialContext(ctx, "udp", c.address)
+		if err != nil {
+			return fmt.Errorf("unable to connect: %w", err)
+		}
+		c.conn = conn
+	}
+
+	const attempts = 2
+	buf := make([]byte, maxMessageSize)
+	for i := 0; i < attempts; i++ {
+		deadline := time.Now().Add(c.timeout / attempts)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		c.conn.SetDeadline(deadline)
+		if _, err := c.conn.Write(req); err != nil {
+			c.close()
+			return fmt.Errorf("unable to send request: %w", err)
+		}
+		for {
+			n, err := c.conn.Read(buf)
+			if err != nil {
+				var ne net.Error
+				if errors.As(err, &ne) && ne.Timeout() && ctx.Err() == nil {
+					break // retry
+				}
+				c.close()
+				return fmt.Errorf("unable to read response: %w", err)
+			}
+			ok, err := match(buf[:n])
+			if ok {
+				return err
+			}
+		}
+	}
+	return fmt.Errorf("no response within %s", c.timeout)
+}
+
+// Close closes the socket.
+func (c *Client) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.close()
+}
+
+func (c *Client) close() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
+// usmParams is UsmSecurityParameters (RFC 3414).
+type usmParams struct {
+	engineID    []byte
+	engineBoots int64
+	engineTime  int64
+	userName    []byte
+	authParams  []byte
+	// authOffset is the offset of authParams in the whole message.
+	authOffset int
+}
+
+// v3Message is a decoded SNMPv3 message without privacy.
+type v3Message struct {
+	msgID int64
+	flags byte
+	usm   usmParams
+	pdu   *pdu
+}
+
+// encodeV3 encodes an SNMPv3 message. If authKey is set, the message is signed.
+func encodeV3(msgID int32, flags byte, usm usmParams, pdu []byte, authProtocol AuthProtocol, authKey []byte) []byte {
+	if authKey != nil {
+		usm.authParams = make([]byte, authParamsLen) // placeholder, filled after encoding
+	}
+	sp := appendOctetString(nil, usm.engineID)
+	sp = appendInteger(sp, usm.engineBoots)
+	sp = appendInteger(sp, usm.engineTime)
+	sp = appendOctetString(sp, usm.userName)
+	authOffset := len(sp) + headerLen(len(usm.authParams))
+	sp = appendOctetString(sp, usm.authParams)
+	sp = appendOctetString(sp, nil) // privacy parameters
+	authOffset += headerLen(len(sp))
+	sp = appendTLV(nil, tagSequence, sp)
+
+	global := appendInteger(nil, int64(msgID))
+	global = appendInteger(global, maxMessageSize)
+	global = appendOctetString(global, []byte{flags})
+	global = appendInteger(global, 3) // USM
+
+	scoped := appendOctetString(nil, usm.engineID) // contextEngineID
+	scoped = appendOctetString(scoped, nil)        // contextName
+	scoped = append(scoped, pdu...)
+
+	v := appendInteger(nil, 3) // version-3
+	v = appendTLV(v, tagSequence, global)
+	authOffset += len(v) + headerLen(len(sp))
+	v = appendOctetString(v, sp)
+	v = appendTLV(v, tagSequence, scoped)
+	msg := appendTLV(nil, tagSequence, v)
+	authOffset += headerLen(len(v))
+
+	if authKey != nil {
+		copy(msg[authOffset:], authenticate(authProtocol, authKey, msg))
+	}
+	return msg
+}
+
+// decodeV3 decodes an SNMPv3 message without privacy.
+func decodeV3(b []byte) (*v3Message, error) {
+	d := &decoder{b: b}
+	m, err := d.expect(tagSequence)
+	if err != nil {
+		return nil, err
+	}
+	if ver, err := m.integer(); err != nil || ver != 3 {
+		return nil, fmt.Errorf("not an SNMPv3 message")
+	}
+	msg := &v3Message{}
+	global, err := m.expect(tagSequence)
+	if err != nil {
+		return nil, err
+	}
+	if msg.msgID, err = global.integer(); err != nil {
+		return nil, err
+	}
+	if _, err := global.integer(); err != nil {
+		return nil, err
+	}
+	flags, err := global.octetString()
+	if err != nil || len(flags) != 1 {
+		return nil, fmt.Errorf("invalid msgFlags")
+	}
+	msg.flags = flags[0]
+	if msg.flags&flagPriv != 0 {
+		return nil, fmt.Errorf("privacy is not supported")
+	}
+	if model, err := global.integer(); err != nil || model != 3 {
+		return nil, fmt.Errorf("unsupported security model")
+	}
+
+	spv, err := m.expect(tagOctetString)
+	if err != nil {
+		return nil, err
+	}
+	sp, err := spv.expect(tagSequence)
+	if err != nil {
+		return nil, err
+	}
+	if msg.usm.engineID, err = sp.octetString(); err != nil {
+		return nil, err
+	}
+	if msg.usm.engineBoots, err = sp.integer(); err != nil {
+		return nil, err
+	}
+	if msg.usm.engineTime, err = sp.integer(); err != nil {
+		return nil, err
+	}
+	if msg.usm.userName, err = sp.octetString(); err != nil {
+		return nil, err
+	}
+	ap, err := sp.expect(tagOctetString)
+	if err != nil {
+		return nil, err
+	}
+	msg.usm.authParams, msg.usm.authOffset = ap.b, ap.off
+
+	scoped, err := m.expect(tagSequence)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := scoped.octetString(); err != nil { // contextEngineID
+		return nil, err
+	}
+	if _, err := scoped.octetString(); err != nil { // contextName
+		return nil, err
+	}
+	if msg.pdu, err = decodePDU(scoped); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// verify checks the authentication parameters of a decoded message.
+func (msg *v3Message) verify(raw []byte, authProtocol AuthProtocol, authKey []byte) bool {
+	if msg.flags&flagAuth == 0 || len(msg.usm.authParams) != authParamsLen {
+		return false
+	}
+	b := make([]byte, len(raw))
+	copy(b, raw)
+	clear(b[msg.usm.authOffset : msg.usm.authOffset+authParamsLen])
+	return hmac.Equal(msg.usm.authParams, authenticate(authProtocol, authKey, b))
+}
+
+func hashFunc(p AuthProtocol) func() hash.Hash {
+	if p == AuthMD5 {
+		return md5.New
+	}
+	return sha1.New
+}
+
+// authenticate returns the truncated HMAC of msg.
+func authenticate(p AuthProtocol, authKey []byte, msg []byte) []byte {
+	mac := hmac.New(hashFunc(p), authKey)
+	mac.Write(msg)
+	return mac.Sum(nil)[:authParamsLen]
+}
+
+// localizeKey derives the localized key from the password and the engine ID (RFC 3414 A.2).
+func localizeKey(p AuthProtocol, password string, engineID []byte) []byte {
+	h := hashFunc(p)()
+	buf := make([]byte, 64)
+	pw := []byte(password)
+	for i, n := 0, 0; n < 1048576; n += len(buf) {
+		for j := range buf {
+			buf[j] = pw[i%len(pw)]
+			i++
+		}
+		h.Write(buf)
+	}
+	ku := h.Sum(nil)
+	h.Reset()
+	h.Write(ku)
+	h.Write(engineID)
+	h.Write(ku)
+	return h.Sum(nil)
+}
+
+// discover learns the engine ID, boots and time of the agent (RFC 3414 section 4).
+func (c *Client) discover(ctx context.Context) error {
+	id := c.nextID()
+	msg := encodeV3(id, flagReportable, usmParams{}, appendGetRequest(nil, id, nil), "", nil)
+	var resp *v3Message
+	err := c.exchange(ctx, msg, func(b []byte) (bool, error) {
+		m, err := decodeV3(b)
+		if err != nil || m.msgID != int64(id) {
+			return false, nil
+		}
+		resp = m
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to discover engine: %w", err)
+	}
+	if len(resp.usm.engineID) == 0 {
+		return fmt.Errorf("unable to discover engine: empty engine ID")
+	}
+	c.engineID = resp.usm.engineID
+	c.setEngineTime(resp.usm.engineBoots, resp.usm.engineTime)
+	c.authKey = nil
+	if c.sec.AuthPassword != "" {
+		c.authKey = localizeKey(c.sec.AuthProtocol, c.sec.AuthPassword, c.engineID)
+	}
+	return nil
+}
+
+func (c *Client) setEngineTime(boots, t int64) {
+	c.engineBoots, c.engineTime, c.engineTimeAt = boots, t, time.Now()
+}
+
+func (c *Client) getV3(ctx context.Context, oids [][]uint32) (*pdu, error) {
+	if c.engineID == nil {
+		if err := c.discover(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	for retried := false; ; retried = true {
+		p, err := c.requestV3(ctx, oids)
+		if err != nil {
+			return nil, err
+		}
+		if p.tag != tagReport {
+			return p, nil
+		}
+		name := "unknown"
+		if len(p.variables) > 0 {
+			if n, ok := usmStatsNames[p.variables[0].OID]; ok {
+				name = n
+			}
+		}
+		if name == "notInTimeWindows" && !retried {
+			continue // engine time is updated by requestV3
+		}
+		// the agent may have been replaced, so rediscover next time
+		c.engineID = nil
+		return nil, fmt.Errorf("agent reported %s", name)
+	}
+}
+
+func (c *Client) requestV3(ctx context.Context, oids [][]uint32) (*pdu, error) {
+	id := c.nextID()
+	flags := flagReportable
+	if c.authKey != nil {
+		flags |= flagAuth
+	}
+	usm := usmParams{
+		engineID:    c.engineID,
+		engineBoots: c.engineBoots,
+		engineTime:  c.engineTime + int64(time.Since(c.engineTimeAt).Seconds()),
+		userName:    []byte(c.sec.UserName),
+	}
+	msg := encodeV3(id, flags, usm, appendGetRequest(nil, id, oids), c.sec.AuthProtocol, c.authKey)
+
+	var resp *v3Message
+	err := c.exchange(ctx, msg, func(b []byte) (bool, error) {
+		m, err := decodeV3(b)
+		if err != nil || m.msgID != int64(id) {
+			return false, nil
+		}
+		authenticated := c.authKey != nil && m.verify(b, c.sec.AuthProtocol, c.authKey)
+		switch {
+		case authenticated:
+			c.setEngineTime(m.usm.engineBoots, m.usm.engineTime)
+		case c.authKey != nil && m.pdu.tag != tagReport:
+			return true, fmt.Errorf("response authentication failed")
+		}
+		resp = m
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.pdu, nil
+}
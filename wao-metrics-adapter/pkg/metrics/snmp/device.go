@@ -0,0 +1,130 @@
+package snmp
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	// maxOIDsPerRequest is the maximum number of OIDs in a single GetRequest.
+	// Agents return tooBig if the response does not fit in a datagram, so keep this moderate.
+	maxOIDsPerRequest = 32
+	// oidTTL is the duration an OID is kept in batched requests after it was last requested.
+	oidTTL = 10 * time.Minute
+)
+
+// device batches GetRequests of multiple agents on the same SNMP agent (e.g., a rack PDU).
+// Each request covers all OIDs requested recently, and the result is shared for maxAge.
+type device struct {
+	key    string
+	client *Client
+	// refs is the number of agents using the device. Guarded by devicesMu.
+	refs int
+
+	mu     sync.Mutex
+	oids   map[string]time.Time // last requested
+	values map[string]Variable
+	readAt time.Time
+}
+
+func (d *device) get(ctx context.Context, oid string, maxAge time.Duration) (Variable, error) {
+	lg := slog.With("func", "device.get", "address", d.client.address)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	d.oids[oid] = now
+	if now.Sub(d.readAt) <= maxAge {
+		if v, ok := d.values[oid]; ok {
+			return v, nil
+		}
+	}
+
+	var oids []string
+	for o, t := range d.oids {
+		if now.Sub(t) > oidTTL {
+			delete(d.oids, o)
+			continue
+		}
+		oids = append(oids, o)
+	}
+	slices.Sort(oids)
+
+	values := map[string]Variable{}
+	var getErr error
+	for chunk := range slices.Chunk(oids, maxOIDsPerRequest) {
+		vars, err := d.client.Get(ctx, chunk)
+		if err != nil && len(chunk) > 1 && slices.Contains(chunk, oid) {
+			// an error-status fails the whole request, so retry only the requested OID
+			lg.Debug("unable to get OIDs, so retry without batching", "n", len(chunk), "err", err)
+			chunk = []string{oid}
+			vars, err = d.client.Get(ctx, chunk)
+		}
+		if err != nil {
+			lg.Debug("unable to get OIDs", "n", len(chunk), "err", err)
+			if slices.Contains(chunk, oid) {
+				getErr = err
+			}
+			continue
+		}
+		for i, v := range vars {
+			values[chunk[i]] = v
+		}
+	}
+	d.values = values
+	d.readAt = now
+
+	if getErr != nil {
+		return Variable{}, fmt.Errorf("unable to get %s: %w", oid, getErr)
+	}
+	v, ok := values[oid]
+	if !ok {
+		return Variable{}, fmt.Errorf("%s not read", oid)
+	}
+	return v, nil
+}
+
+var (
+	devicesMu sync.Mutex
+	devices   = map[string]*device{}
+)
+
+// getDevice returns the device shared by all agents with the same address and credentials.
+// Call releaseDevice when the agent is closed.
+func getDevice(address string, sec Security, timeout time.Duration) (*device, error) {
+	// credentials are hashed so that they are not kept in keys as is
+	k := fmt.Sprintf("%s %x", address, sha256.Sum256(fmt.Appendf(nil, "%q %q %q %q %q %q", sec.Version, sec.Community, sec.UserName, sec.SecurityLevel, sec.AuthProtocol, sec.AuthPassword)))
+
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+
+	if d, ok := devices[k]; ok {
+		d.refs++
+		return d, nil
+	}
+	c, err := NewClient(address, sec, timeout)
+	if err != nil {
+		return nil, err
+	}
+	d := &device{key: k, client: c, oids: map[string]time.Time{}, refs: 1}
+	devices[k] = d
+	return d, nil
+}
+
+// releaseDevice removes the device and closes its socket if no agent uses it.
+func releaseDevice(d *device) {
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+
+	if d.refs--; d.refs > 0 {
+		return
+	}
+	delete(devices, d.key)
+	d.client.Close()
+}
@@ -0,0 +1,142 @@
+package snmp
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Variable is a variable binding in a response.
+type Variable struct {
+	OID string
+	// Type is the BER tag of the value.
+	Type  byte
+	Value []byte
+}
+
+// Float64 returns the numeric value of the variable.
+// Integers, counters, gauges, numeric strings (e.g., "23.5") and Opaque floats are supported.
+func (v Variable) Float64() (float64, error) {
+	switch v.Type {
+	case tagInteger:
+		n, err := parseInt(v.Value)
+		return float64(n), err
+	case tagCounter32, tagGauge32, tagTimeTicks, tagCounter64:
+		n, err := parseUint(v.Value)
+		return float64(n), err
+	case tagOctetString:
+		f, err := strconv.ParseFloat(strings.TrimSpace(string(v.Value)), 64)
+		if err != nil {
+			return 0.0, fmt.Errorf("non-numeric string %q", v.Value)
+		}
+		return f, nil
+	case tagOpaque:
+		// Net-SNMP encodes floats as Opaque wrapping a BER float (0x9f78) or double (0x9f79)
+		b := v.Value
+		switch {
+		case len(b) == 7 && b[0] == 0x9f && b[1] == 0x78 && b[2] == 4:
+			return float64(math.Float32frombits(uint32(b[3])<<24 | uint32(b[4])<<16 | uint32(b[5])<<8 | uint32(b[6]))), nil
+		case len(b) == 11 && b[0] == 0x9f && b[1] == 0x79 && b[2] == 8:
+			n, _ := parseUint(b[3:])
+			return math.Float64frombits(n), nil
+		}
+		return 0.0, fmt.Errorf("unsupported Opaque value")
+	case tagNoSuchObject:
+		return 0.0, fmt.Errorf("no such object: %s", v.OID)
+	case tagNoSuchInstance:
+		return 0.0, fmt.Errorf("no such instance: %s", v.OID)
+	case tagEndOfMibView:
+		return 0.0, fmt.Errorf("end of MIB view: %s", v.OID)
+	default:
+		return 0.0, fmt.Errorf("unsupported value type 0x%02x", v.Type)
+	}
+}
+
+// ResponseError is a non-zero error-status in a response PDU.
+type ResponseError struct {
+	Status int64
+	// Index is the 1-based index of the variable binding that caused the error, or 0 if unknown.
+	Index int64
+}
+
+// errorStatusNames are the names of error-status values defined in RFC 3416.
+var errorStatusNames = []string{
+	"noError", "tooBig", "noSuchName", "badValue", "readOnly", "genErr", "noAccess", "wrongType", "wrongLength",
+	"wrongEncoding", "wrongValue", "noCreation", "inconsistentValue", "resourceUnavailable", "commitFailed",
+	"undoFailed", "authorizationError", "notWritable", "inconsistentName",
+}
+
+func (e *ResponseError) Error() string {
+	name := strconv.FormatInt(e.Status, 10)
+	if e.Status >= 0 && e.Status < int64(len(errorStatusNames)) {
+		name = errorStatusNames[e.Status]
+	}
+	return fmt.Sprintf("error-status=%s error-index=%d", name, e.Index)
+}
+
+// pdu is a decoded PDU.
+type pdu struct {
+	tag         byte
+	requestID   int64
+	errorStatus int64
+	errorIndex  int64
+	variables   []Variable
+}
+
+// appendGetRequest appends a GetRequest PDU.
+func appendGetRequest(b []byte, requestID int32, oids [][]uint32) []byte {
+	var vbs []byte
+	for _, oid := range oids {
+		vb := appendOID(nil, oid)
+		vb = appendNull(vb)
+		vbs = appendTLV(vbs, tagSequence, vb)
+	}
+	v := appendInteger(nil, int64(requestID))
+	v = appendInteger(v, 0) // error-status
+	v = appendInteger(v, 0) // error-index
+	v = appendTLV(v, tagSequence, vbs)
+	return appendTLV(b, tagGetRequest, v)
+}
+
+// decodePDU decodes any PDU. The variable binding values are not interpreted.
+func decodePDU(d *decoder) (*pdu, error) {
+	tag, v, err := d.next()
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode PDU: %w", err)
+	}
+	p := &pdu{tag: tag}
+	if p.requestID, err = v.integer(); err != nil {
+		return nil, fmt.Errorf("unable to decode request-id: %w", err)
+	}
+	if p.errorStatus, err = v.integer(); err != nil {
+		return nil, fmt.Errorf("unable to decode error-status: %w", err)
+	}
+	if p.errorIndex, err = v.integer(); err != nil {
+		return nil, fmt.Errorf("unable to decode error-index: %w", err)
+	}
+	vbs, err := v.expect(tagSequence)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode variable bindings: %w", err)
+	}
+	for len(vbs.b) > 0 {
+		vb, err := vbs.expect(tagSequence)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode variable binding: %w", err)
+		}
+		oidv, err := vb.expect(tagOID)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode variable binding: %w", err)
+		}
+		oid, err := parseOID(oidv.b)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode variable binding: %w", err)
+		}
+		t, val, err := vb.next()
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode variable binding: %w", err)
+		}
+		p.variables = append(p.variables, Variable{OID: FormatOID(oid), Type: t, Value: val.b})
+	}
+	return p, nil
+}
@@ -2,8 +2,10 @@ package metrics
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
 )
 
@@ -29,7 +31,34 @@ func StoreKeyForNode(name string) storeKey {
 	return storeKey(fmt.Sprintf("/nodes/%s", name))
 }
 
-type Store struct{ m sync.Map }
+// parse splits the storeKey into namespace, GroupResource and name.
+// ok is false if the storeKey is malformed.
+func (k storeKey) parse() (namespace string, gr schema.GroupResource, name string, ok bool) {
+	ss := strings.SplitN(string(k), "/", 3)
+	if len(ss) != 3 {
+		return "", schema.GroupResource{}, "", false
+	}
+	return ss[0], schema.ParseGroupResource(ss[1]), ss[2], true
+}
+
+type Store struct {
+	m sync.Map
+
+	// mu serializes read-modify-write operations. See Store.Update.
+	mu sync.Mutex
+
+	// Recorder is called with each value accepted from agents and pushes, if set.
+	// Values merged from peers and snapshots are not recorded.
+	Recorder Recorder
+}
+
+// Record passes the accepted sample to the Recorder if set.
+// Thread-safe if the Recorder is.
+func (s *Store) Record(sample Sample) {
+	if s.Recorder != nil {
+		s.Recorder.Record(sample)
+	}
+}
 
 // Get returns a MetricData for the given storeKey.
 // Thread-safe.
@@ -55,3 +84,57 @@ func (s *Store) GetOrInit(k storeKey) MetricData {
 
 // Set sets a MetricData. Thread-safe.
 func (s *Store) Set(k storeKey, m MetricData) { s.m.Store(k, m) }
+
+// Update replaces the MetricData for the given storeKey with f(current).
+// Concurrent Updates are serialized, so writers of different ValueTypes do not overwrite each other.
+// Thread-safe.
+func (s *Store) Update(k storeKey, f func(m MetricData) MetricData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Set(k, f(s.GetOrInit(k)))
+}
+
+// Range calls f sequentially for each object and its MetricData. If f returns false, Range stops the iteration.
+// Thread-safe.
+func (s *Store) Range(f func(namespace string, gr schema.GroupResource, name string, m MetricData) bool) {
+	s.m.Range(func(k, v any) bool {
+		kk, _ := k.(storeKey)
+		namespace, gr, name, ok := kk.parse()
+		if !ok {
+			return true
+		}
+		vv, _ := v.(MetricData)
+		return f(namespace, gr, name, vv)
+	})
+}
+
+// StoreEntry is a serializable form of a Store entry.
+type StoreEntry struct {
+	Namespace string     `json:"namespace,omitempty"`
+	Resource  string     `json:"resource"`
+	Name      string     `json:"name"`
+	Data      MetricData `json:"data"`
+}
+
+// Entries returns all entries in the Store.
+// Thread-safe.
+func (s *Store) Entries() []StoreEntry {
+	var entries []StoreEntry
+	s.Range(func(namespace string, gr schema.GroupResource, name string, m MetricData) bool {
+		entries = append(entries, StoreEntry{Namespace: namespace, Resource: gr.String(), Name: name, Data: m})
+		return true
+	})
+	return entries
+}
+
+// Merge merges the given entries into the Store.
+// For each ValueType, the value with the newer timestamp wins.
+// Thread-safe.
+func (s *Store) Merge(entries []StoreEntry) {
+	for _, e := range entries {
+		info := provider.CustomMetricInfo{GroupResource: schema.ParseGroupResource(e.Resource)}
+		s.Update(StoreKey(e.Namespace, e.Name, info), func(m MetricData) MetricData {
+			return m.Merge(e.Data)
+		})
+	}
+}
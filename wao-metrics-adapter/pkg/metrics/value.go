@@ -7,16 +7,79 @@ type ValueType string
 const (
 	ValueInletTemperature = "inlet_temp"
 	ValueDeltaPressure    = "delta_p"
+	// ValuePowerConsumption is the measured power consumption in Watts.
+	ValuePowerConsumption = "power_consumption"
 )
 
 var ValueTypes = []ValueType{
 	ValueInletTemperature,
 	ValueDeltaPressure,
+	ValuePowerConsumption,
 }
 
+// Label keys attached to stored values.
+// Users can filter values with label selectors, e.g., `source=redfish`.
+const (
+	// LabelSource is the agent that fetched the value, e.g., "redfish", "dpapi".
+	LabelSource = "source"
+	// LabelSensor is the sensor ID the value was read from, if known.
+	LabelSensor = "sensor"
+)
+
 type MetricData struct {
-	InletTemp              float64
-	InletTempTimestamp     time.Time
-	DeltaPressure          float64
-	DeltaPressureTimestamp time.Time
+	InletTemp              float64           `json:"inletTemp"`
+	InletTempTimestamp     time.Time         `json:"inletTempTimestamp"`
+	InletTempLabels        map[string]string `json:"inletTempLabels,omitempty"`
+	DeltaPressure          float64           `json:"deltaPressure"`
+	DeltaPressureTimestamp time.Time         `json:"deltaPressureTimestamp"`
+	DeltaPressureLabels    map[string]string `json:"deltaPressureLabels,omitempty"`
+	// PowerConsumption is optional, as only some NodeConfigs have a source of measured power.
+	PowerConsumption          float64           `json:"powerConsumption"`
+	PowerConsumptionTimestamp time.Time         `json:"powerConsumptionTimestamp"`
+	PowerConsumptionLabels    map[string]string `json:"powerConsumptionLabels,omitempty"`
+}
+
+// Value returns the value, timestamp and labels of the given ValueType.
+// ok is false if the ValueType is unknown or no value has been stored yet.
+func (m MetricData) Value(vt ValueType) (value float64, timestamp time.Time, labels map[string]string, ok bool) {
+	switch vt {
+	case ValueInletTemperature:
+		value, timestamp, labels = m.InletTemp, m.InletTempTimestamp, m.InletTempLabels
+	case ValueDeltaPressure:
+		value, timestamp, labels = m.DeltaPressure, m.DeltaPressureTimestamp, m.DeltaPressureLabels
+	case ValuePowerConsumption:
+		value, timestamp, labels = m.PowerConsumption, m.PowerConsumptionTimestamp, m.PowerConsumptionLabels
+	default:
+		return 0.0, time.Time{}, nil, false
+	}
+	return value, timestamp, labels, !timestamp.IsZero()
+}
+
+// WithValue returns a copy of the MetricData with the value, timestamp and labels of the given ValueType replaced.
+// Unknown ValueTypes are ignored.
+func (m MetricData) WithValue(vt ValueType, value float64, timestamp time.Time, labels map[string]string) MetricData {
+	switch vt {
+	case ValueInletTemperature:
+		m.InletTemp, m.InletTempTimestamp, m.InletTempLabels = value, timestamp, labels
+	case ValueDeltaPressure:
+		m.DeltaPressure, m.DeltaPressureTimestamp, m.DeltaPressureLabels = value, timestamp, labels
+	case ValuePowerConsumption:
+		m.PowerConsumption, m.PowerConsumptionTimestamp, m.PowerConsumptionLabels = value, timestamp, labels
+	}
+	return m
+}
+
+// Merge returns a copy of the MetricData where each value is replaced by the one in o if o's is newer.
+func (m MetricData) Merge(o MetricData) MetricData {
+	for _, vt := range ValueTypes {
+		v, t, labels, ok := o.Value(vt)
+		if !ok {
+			continue
+		}
+		if _, tt, _, ok := m.Value(vt); ok && !t.After(tt) {
+			continue
+		}
+		m = m.WithValue(vt, v, t, labels)
+	}
+	return m
 }
@@ -0,0 +1,198 @@
+package nodeagent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+	nodeagentmetrics "github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics/nodeagent"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics/push"
+)
+
+// Agent samples sysfs counters of the node it runs on, and serves or pushes the readings.
+type Agent struct {
+	nodeName string
+	interval time.Duration
+
+	rapl  *RAPL
+	hwmon *Hwmon
+
+	mu       sync.Mutex
+	readings map[metrics.ValueType]nodeagentmetrics.Reading
+}
+
+// NewAgent inits the agent.
+// sysfsRoot is the sysfs mount point, e.g., "/sys" or "/host/sys" in a container.
+// tempSensor selects the hwmon sensor used as inlet temperature, nil disables it.
+func NewAgent(nodeName string, sysfsRoot string, tempSensor *regexp.Regexp, interval time.Duration) *Agent {
+	a := &Agent{
+		nodeName: nodeName,
+		interval: interval,
+		rapl:     &RAPL{Root: sysfsRoot},
+		readings: map[metrics.ValueType]nodeagentmetrics.Reading{},
+	}
+	if tempSensor != nil {
+		a.hwmon = &Hwmon{Root: sysfsRoot, Sensor: tempSensor}
+	}
+	return a
+}
+
+// Sample reads the counters and updates the readings.
+func (a *Agent) Sample(now time.Time) {
+	lg := slog.With("func", "Agent.Sample", "node", a.nodeName)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if w, sensor, err := a.rapl.Power(now); err != nil {
+		logSampleError(lg, "unable to get power consumption", err)
+	} else {
+		a.readings[metrics.ValuePowerConsumption] = nodeagentmetrics.Reading{Value: w, Timestamp: now, Sensor: sensor}
+	}
+	if a.hwmon != nil {
+		if c, sensor, err := a.hwmon.Temperature(); err != nil {
+			logSampleError(lg, "unable to get inlet temperature", err)
+		} else {
+			a.readings[metrics.ValueInletTemperature] = nodeagentmetrics.Reading{Value: c, Timestamp: now, Sensor: sensor}
+		}
+	}
+}
+
+func logSampleError(lg *slog.Logger, msg string, err error) {
+	if errors.Is(err, ErrNoData) {
+		lg.Debug(msg, "err", err)
+	} else {
+		lg.Warn(msg, "err", err)
+	}
+}
+
+// Readings returns the readings sampled within the last 3 intervals.
+func (a *Agent) Readings(now time.Time) nodeagentmetrics.Readings {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	r := nodeagentmetrics.Readings{Node: a.nodeName, Values: map[metrics.ValueType]nodeagentmetrics.Reading{}}
+	for vt, v := range a.readings {
+		if now.Sub(v.Timestamp) <= 3*a.interval {
+			r.Values[vt] = v
+		}
+	}
+	return r
+}
+
+// Run samples every interval until ctx is done. If pusher is not nil, readings are pushed after each sample.
+func (a *Agent) Run(ctx context.Context, pusher *Pusher) {
+	lg := slog.With("func", "Agent.Run", "node", a.nodeName)
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		now := time.Now()
+		a.Sample(now)
+		if pusher != nil {
+			pushCtx, cancel := context.WithTimeout(ctx, a.interval)
+			if err := pusher.Push(pushCtx, a.Readings(now)); err != nil {
+				lg.Warn("unable to push readings", "err", err)
+			}
+			cancel()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Handler returns a http.Handler that serves the readings at nodeagentmetrics.ReadingsPath.
+func (a *Agent) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+nodeagentmetrics.ReadingsPath, func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(a.Readings(time.Now()))
+	})
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	return mux
+}
+
+// Pusher pushes readings to the push ingestion endpoint of the metrics adapter.
+// The NodeConfig of the node must use Type Push for the pushed metrics.
+type Pusher struct {
+	// Address is the base URL of the ingestion endpoint, e.g., "http://wao-metrics-adapter.custom-metrics:8082".
+	Address  string
+	Username string
+	Password string
+	// Metrics limits the pushed metrics. Empty means all.
+	Metrics []metrics.ValueType
+	Client  *http.Client
+}
+
+// Push sends the readings in a single batch.
+func (p *Pusher) Push(ctx context.Context, r nodeagentmetrics.Readings) error {
+	var br push.BatchRequest
+	for vt, v := range r.Values {
+		if len(p.Metrics) > 0 && !slices.Contains(p.Metrics, vt) {
+			continue
+		}
+		br.Samples = append(br.Samples, push.Sample{Node: r.Node, Metric: vt, Value: v.Value, Timestamp: v.Timestamp, Sensor: v.Sensor})
+	}
+	if len(br.Samples) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(br)
+	if err != nil {
+		return err
+	}
+
+	u, err := url.JoinPath(p.Address, push.BatchPath)
+	if err != nil {
+		return fmt.Errorf("could not build URL: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to create HTTP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(p.Username, p.Password)
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to send HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnprocessableEntity:
+		var bresp push.BatchResponse
+		if err := json.NewDecoder(resp.Body).Decode(&bresp); err != nil {
+			return fmt.Errorf("HTTP status=%s", resp.Status)
+		}
+		var errs []error
+		for _, e := range bresp.Errors {
+			if e.Index < 0 || e.Index >= len(br.Samples) {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("%s: %s", br.Samples[e.Index].Metric, e.Error))
+		}
+		return fmt.Errorf("%d samples rejected: %w", len(errs), errors.Join(errs...))
+	default:
+		return fmt.Errorf("HTTP status=%s", resp.Status)
+	}
+}
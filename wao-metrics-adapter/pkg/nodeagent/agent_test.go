@@ -0,0 +1,97 @@
+package nodeagent
+
+import (
+	"context"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+	nodeagentmetrics "github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics/nodeagent"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics/push"
+)
+
+func newTestAgent(t *testing.T) (*Agent, time.Time) {
+	root := t.TempDir()
+	writeFiles(t, root, merge(raplZone("intel-rapl:0", "package-0", 0), map[string]string{
+		"class/hwmon/hwmon0/name":        "nct6775",
+		"class/hwmon/hwmon0/temp1_input": "24500",
+		"class/hwmon/hwmon0/temp1_label": "SYSTIN",
+	}))
+	a := NewAgent("node-0", root, regexp.MustCompile("SYSTIN"), 10*time.Second)
+	now := time.Now()
+	a.Sample(now.Add(-10 * time.Second))
+	writeFiles(t, root, raplZone("intel-rapl:0", "package-0", 1_500_000_000))
+	a.Sample(now)
+	return a, now
+}
+
+func TestAgent_scrape(t *testing.T) {
+	a, _ := newTestAgent(t)
+	srv := httptest.NewServer(a.Handler())
+	defer srv.Close()
+
+	tests := []struct {
+		vt   metrics.ValueType
+		want float64
+	}{
+		{metrics.ValuePowerConsumption, 150},
+		{metrics.ValueInletTemperature, 24.5},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.vt), func(t *testing.T) {
+			c := nodeagentmetrics.NewAgent(tt.vt, srv.URL, false, time.Second)
+			got, err := c.Fetch(context.Background())
+			if err != nil || got != tt.want {
+				t.Errorf("Fetch() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := nodeagentmetrics.NewAgent(metrics.ValueDeltaPressure, srv.URL, false, time.Second).Fetch(context.Background()); err == nil {
+		t.Error("Fetch() of delta_p succeeded")
+	}
+}
+
+func TestAgent_Readings_stale(t *testing.T) {
+	a, now := newTestAgent(t)
+	if got := a.Readings(now.Add(29 * time.Second)); len(got.Values) != 2 {
+		t.Errorf("Readings() = %v, want 2 values", got.Values)
+	}
+	if got := a.Readings(now.Add(31 * time.Second)); len(got.Values) != 0 {
+		t.Errorf("Readings() = %v, want no stale values", got.Values)
+	}
+}
+
+func TestPusher_Push(t *testing.T) {
+	a, now := newTestAgent(t)
+
+	store := &metrics.Store{}
+	receiver := push.NewReceiver(store, time.Minute, 5*time.Second)
+	receiver.Register("wao-system/node-0/power_consumption", "node-0", metrics.ValuePowerConsumption, "user", "pass")
+	srv := httptest.NewServer(push.NewHandler(receiver))
+	defer srv.Close()
+
+	// inlet_temp is not a push target, so it is rejected
+	p := &Pusher{Address: srv.URL, Username: "user", Password: "pass"}
+	if err := p.Push(context.Background(), a.Readings(now)); err == nil {
+		t.Error("Push() error = nil, want inlet_temp rejected")
+	}
+	p.Metrics = []metrics.ValueType{metrics.ValuePowerConsumption}
+	if err := p.Push(context.Background(), a.Readings(now)); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	m, ok := store.Get(metrics.StoreKeyForNode("node-0"))
+	if !ok {
+		t.Fatal("no value stored")
+	}
+	if v, _, labels, ok := m.Value(metrics.ValuePowerConsumption); !ok || v != 150 || labels[metrics.LabelSensor] != "package" {
+		t.Errorf("stored = %v %v %v", v, labels, ok)
+	}
+
+	p.Password = "wrong"
+	if err := p.Push(context.Background(), a.Readings(now)); err == nil {
+		t.Error("Push() with a wrong password succeeded")
+	}
+}
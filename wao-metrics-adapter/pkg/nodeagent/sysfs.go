@@ -0,0 +1,176 @@
+package nodeagent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RAPL reads package energy counters from the powercap framework and converts them to power.
+//
+//   - Zones: {ROOT}/class/powercap/intel-rapl:{N}/{name,energy_uj,max_energy_range_uj}
+//
+// Top-level zones are either packages ("package-0", ...) or the platform ("psys").
+// psys covers the whole SoC platform including packages, so it is used alone if present.
+// Subzones (e.g., "intel-rapl:0:0" for cores) are ignored as they are included in their package.
+type RAPL struct {
+	// Root is the sysfs mount point, e.g., "/sys".
+	Root string
+
+	prev map[string]energySample
+}
+
+type energySample struct {
+	uj      uint64
+	rangeUJ uint64
+	at      time.Time
+}
+
+// ErrNoData is returned if the node does not expose the data, e.g., no RAPL zones.
+var ErrNoData = errors.New("no data")
+
+// Power returns the average power in Watts since the previous call, and the sensor it was read from.
+// The first call only records the counters and returns ErrNoData.
+func (r *RAPL) Power(now time.Time) (watts float64, sensor string, err error) {
+	dirs, err := filepath.Glob(filepath.Join(r.Root, "class", "powercap", "intel-rapl:*"))
+	if err != nil {
+		return 0.0, "", err
+	}
+	zones := map[string]string{} // name -> dir
+	for _, dir := range dirs {
+		if strings.Count(filepath.Base(dir), ":") != 1 {
+			continue // subzone
+		}
+		name, err := readString(filepath.Join(dir, "name"))
+		if err != nil {
+			return 0.0, "", err
+		}
+		zones[name] = dir
+	}
+	if dir, ok := zones["psys"]; ok {
+		zones = map[string]string{"psys": dir}
+		sensor = "psys"
+	} else {
+		for name := range zones {
+			if !strings.HasPrefix(name, "package") {
+				delete(zones, name)
+			}
+		}
+		sensor = "package"
+	}
+	if len(zones) == 0 {
+		return 0.0, "", fmt.Errorf("%w: no RAPL zones in %s", ErrNoData, r.Root)
+	}
+
+	cur := map[string]energySample{}
+	for name, dir := range zones {
+		uj, err := readUint(filepath.Join(dir, "energy_uj"))
+		if err != nil {
+			return 0.0, "", err
+		}
+		rangeUJ, err := readUint(filepath.Join(dir, "max_energy_range_uj"))
+		if err != nil {
+			return 0.0, "", err
+		}
+		cur[name] = energySample{uj: uj, rangeUJ: rangeUJ, at: now}
+	}
+	prev := r.prev
+	r.prev = cur
+
+	var joules float64
+	var elapsed time.Duration
+	for name, c := range cur {
+		p, ok := prev[name]
+		if !ok {
+			return 0.0, "", fmt.Errorf("%w: no previous sample of %s", ErrNoData, name)
+		}
+		delta := c.uj - p.uj
+		if c.uj < p.uj {
+			// the counter wrapped around
+			delta = c.uj + (c.rangeUJ - p.uj)
+		}
+		joules += float64(delta) / 1e6
+		elapsed = c.at.Sub(p.at)
+	}
+	if elapsed <= 0 {
+		return 0.0, "", fmt.Errorf("%w: no time elapsed since the previous sample", ErrNoData)
+	}
+	return joules / elapsed.Seconds(), sensor, nil
+}
+
+// Hwmon reads a temperature sensor from the hwmon framework.
+//
+//   - Sensors: {ROOT}/class/hwmon/hwmon{N}/{name,temp{M}_input,temp{M}_label}
+type Hwmon struct {
+	// Root is the sysfs mount point, e.g., "/sys".
+	Root string
+	// Sensor selects the sensor by "{name}-{label}" (e.g., "nct6775-SYSTIN"), where label defaults to "temp{M}".
+	// The first sensor in sorted order is used if multiple sensors match.
+	Sensor *regexp.Regexp
+}
+
+// Temperature returns the temperature in Celsius and the sensor it was read from.
+func (h *Hwmon) Temperature() (celsius float64, sensor string, err error) {
+	inputs, err := filepath.Glob(filepath.Join(h.Root, "class", "hwmon", "hwmon*", "temp*_input"))
+	if err != nil {
+		return 0.0, "", err
+	}
+	slices.Sort(inputs)
+	for _, input := range inputs {
+		dir := filepath.Dir(input)
+		name, err := readString(filepath.Join(dir, "name"))
+		if err != nil {
+			continue
+		}
+		prefix := strings.TrimSuffix(filepath.Base(input), "_input")
+		label, err := readString(filepath.Join(dir, prefix+"_label"))
+		if err != nil {
+			label = prefix
+		}
+		id := sanitizeSensor(name + "-" + label)
+		if h.Sensor == nil || !h.Sensor.MatchString(id) {
+			continue
+		}
+		milli, err := readInt(input)
+		if err != nil {
+			return 0.0, "", err
+		}
+		return float64(milli) / 1000, id, nil
+	}
+	return 0.0, "", fmt.Errorf("%w: no hwmon temperature sensor matches %v in %s", ErrNoData, h.Sensor, h.Root)
+}
+
+var invalidSensorChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// sanitizeSensor makes s usable as a label value.
+func sanitizeSensor(s string) string { return invalidSensorChars.ReplaceAllString(s, "_") }
+
+func readString(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func readUint(path string) (uint64, error) {
+	s, err := readString(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func readInt(path string) (int64, error) {
+	s, err := readString(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
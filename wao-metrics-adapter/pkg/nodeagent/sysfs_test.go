@@ -0,0 +1,143 @@
+package nodeagent
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// writeFiles creates files relative to root.
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func raplZone(zone, name string, uj uint64) map[string]string {
+	dir := "class/powercap/" + zone
+	return map[string]string{
+		dir + "/name":                name,
+		dir + "/energy_uj":           strconv.FormatUint(uj, 10),
+		dir + "/max_energy_range_uj": "262143328850",
+	}
+}
+
+func merge(ms ...map[string]string) map[string]string {
+	out := map[string]string{}
+	for _, m := range ms {
+		for k, v := range m {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func TestRAPL_Power(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(10 * time.Second)
+
+	tests := []struct {
+		name       string
+		before     map[string]string
+		after      map[string]string
+		wantWatts  float64
+		wantSensor string
+		wantErr    error
+	}{
+		{
+			name:       "packages",
+			before:     merge(raplZone("intel-rapl:0", "package-0", 1_000_000), raplZone("intel-rapl:1", "package-1", 5_000_000), raplZone("intel-rapl:0:0", "core", 0)),
+			after:      merge(raplZone("intel-rapl:0", "package-0", 1_001_000_000), raplZone("intel-rapl:1", "package-1", 505_000_000), raplZone("intel-rapl:0:0", "core", 900_000_000)),
+			wantWatts:  150, // (1000 J + 500 J) / 10 s
+			wantSensor: "package",
+		},
+		{
+			name:       "wraparound",
+			before:     raplZone("intel-rapl:0", "package-0", 262143328850-500_000_000),
+			after:      raplZone("intel-rapl:0", "package-0", 500_000_000),
+			wantWatts:  100,
+			wantSensor: "package",
+		},
+		{
+			name:       "psys",
+			before:     merge(raplZone("intel-rapl:0", "package-0", 0), raplZone("intel-rapl:1", "psys", 0)),
+			after:      merge(raplZone("intel-rapl:0", "package-0", 1_000_000_000), raplZone("intel-rapl:1", "psys", 2_000_000_000)),
+			wantWatts:  200,
+			wantSensor: "psys",
+		},
+		{
+			name:    "no_rapl",
+			before:  map[string]string{"class/hwmon/hwmon0/name": "acpitz"},
+			after:   map[string]string{"class/hwmon/hwmon0/name": "acpitz"},
+			wantErr: ErrNoData,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			r := &RAPL{Root: root}
+			writeFiles(t, root, tt.before)
+			if _, _, err := r.Power(t0); !errors.Is(err, ErrNoData) {
+				t.Fatalf("first Power() error = %v, want ErrNoData", err)
+			}
+			writeFiles(t, root, tt.after)
+			got, sensor, err := r.Power(t1)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Power() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if math.Abs(got-tt.wantWatts) > 1e-9 || sensor != tt.wantSensor {
+				t.Errorf("Power() = %v %q, want %v %q", got, sensor, tt.wantWatts, tt.wantSensor)
+			}
+		})
+	}
+}
+
+func TestHwmon_Temperature(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"class/hwmon/hwmon0/name":        "coretemp",
+		"class/hwmon/hwmon0/temp1_input": "62000",
+		"class/hwmon/hwmon0/temp1_label": "Package id 0",
+		"class/hwmon/hwmon1/name":        "nct6775",
+		"class/hwmon/hwmon1/temp1_input": "24500",
+		"class/hwmon/hwmon1/temp1_label": "SYSTIN",
+		"class/hwmon/hwmon1/temp2_input": "-1500",
+	})
+
+	tests := []struct {
+		name       string
+		sensor     string
+		want       float64
+		wantSensor string
+		wantErr    bool
+	}{
+		{"label", `(?i)systin`, 24.5, "nct6775-SYSTIN", false},
+		{"no_label", `^nct6775-temp2$`, -1.5, "nct6775-temp2", false},
+		{"sanitized", `^coretemp-Package_id_0$`, 62, "coretemp-Package_id_0", false},
+		{"not_found", `(?i)inlet|ambient`, 0, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Hwmon{Root: root, Sensor: regexp.MustCompile(tt.sensor)}
+			got, sensor, err := h.Temperature()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Temperature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want || sensor != tt.wantSensor {
+				t.Errorf("Temperature() = %v %q, want %v %q", got, sensor, tt.want, tt.wantSensor)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	waov1beta1 "github.com/waok8s/waok8s/wao-core/api/node/v1beta1"
+	metricsfake "github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics/fake"
 	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
 )
 
@@ -55,11 +56,21 @@ type FakePowerConsumptionPredictor struct {
 	EndpointError error
 
 	PredictValue float64
-	PredictError error
-	PredictDelay time.Duration
+	// PredictProfile generates the value if set, otherwise PredictValue is returned.
+	// Vars are PredictorVars.
+	PredictProfile metricsfake.Profile
+	PredictError   error
+	PredictDelay   time.Duration
 }
 
-var _ predictor.PowerConsumptionPredictor = (*FakePowerConsumptionPredictor)(nil)
+// PredictorVars are the vars of PredictProfile, i.e., the inputs of Predict and the well-known features of PredictFeatures.
+var PredictorVars = []string{
+	predictor.FeatureCPUUsage, predictor.FeatureInletTemp, predictor.FeatureDeltaP,
+	predictor.FeatureMemoryUsage, predictor.FeaturePowerConsumption,
+}
+
+var _ predictor.BatchPowerConsumptionPredictor = (*FakePowerConsumptionPredictor)(nil)
+var _ predictor.FeaturePowerConsumptionPredictor = (*FakePowerConsumptionPredictor)(nil)
 
 func NewPowerConsumptionPredictor(endpointValue string, endpointError error, predictValue float64, predictError error, predictDelay time.Duration) *FakePowerConsumptionPredictor {
 	return &FakePowerConsumptionPredictor{
@@ -89,5 +100,97 @@ func (p *FakePowerConsumptionPredictor) Predict(ctx context.Context, cpuUsage, i
 	if p.PredictError != nil {
 		return 0.0, p.PredictError
 	}
+	if p.PredictProfile != nil {
+		return p.PredictProfile.Value(time.Now(), map[string]float64{
+			"cpu_usage":  cpuUsage,
+			"inlet_temp": inletTemp,
+			"delta_p":    deltaP,
+		})
+	}
+	return p.PredictValue, nil
+}
+
+// PredictBatch is PredictFeatures with the 3 inputs.
+func (p *FakePowerConsumptionPredictor) PredictBatch(ctx context.Context, inputs []predictor.PowerConsumptionInput) (watts []float64, err error) {
+	features := make([]predictor.Features, len(inputs))
+	for i, in := range inputs {
+		features[i] = in.Features()
+	}
+	return p.PredictFeatures(ctx, features)
+}
+
+// PredictFeatures waits PredictDelay once and returns PredictValue, or values of PredictProfile with the features as vars.
+func (p *FakePowerConsumptionPredictor) PredictFeatures(ctx context.Context, features []predictor.Features) (watts []float64, err error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(p.PredictDelay):
+		break
+	}
+
+	if p.PredictError != nil {
+		return nil, p.PredictError
+	}
+	watts = make([]float64, len(features))
+	for i, f := range features {
+		watts[i] = p.PredictValue
+		if p.PredictProfile != nil {
+			if watts[i], err = p.PredictProfile.Value(time.Now(), f); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return watts, nil
+}
+
+type FakeResponseTimePredictor struct {
+	EndpointValue string
+	EndpointError error
+
+	PredictValue float64
+	// PredictProfile generates the value if set, otherwise PredictValue is returned.
+	// Vars are PredictorVars.
+	PredictProfile metricsfake.Profile
+	PredictError   error
+	PredictDelay   time.Duration
+}
+
+var _ predictor.ResponseTimePredictor = (*FakeResponseTimePredictor)(nil)
+
+func NewResponseTimePredictor(endpointValue string, endpointError error, predictValue float64, predictError error, predictDelay time.Duration) *FakeResponseTimePredictor {
+	return &FakeResponseTimePredictor{
+		EndpointValue: endpointValue,
+		EndpointError: endpointError,
+		PredictValue:  predictValue,
+		PredictError:  predictError,
+		PredictDelay:  predictDelay,
+	}
+}
+
+func (p *FakeResponseTimePredictor) Endpoint() (string, error) {
+	if p.EndpointError != nil {
+		return "", p.EndpointError
+	}
+	return p.EndpointValue, nil
+}
+
+func (p *FakeResponseTimePredictor) Predict(ctx context.Context, cpuUsage, inletTemp, deltaP float64) (milliseconds float64, err error) {
+	select {
+	case <-ctx.Done():
+		return 0.0, ctx.Err()
+	case <-time.After(p.PredictDelay):
+		break
+	}
+
+	if p.PredictError != nil {
+		return 0.0, p.PredictError
+	}
+	if p.PredictProfile != nil {
+		return p.PredictProfile.Value(time.Now(), map[string]float64{
+			"cpu_usage":  cpuUsage,
+			"inlet_temp": inletTemp,
+			"delta_p":    deltaP,
+		})
+	}
 	return p.PredictValue, nil
 }
@@ -0,0 +1,134 @@
+package predictor
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Feature names of Features.
+// Consumers supply cpu_usage, inlet_temp and delta_p, and the extra features configured by users (see ValidateExtraFeatures).
+const (
+	// FeatureCPUUsage is CPU usage, in percent or raw cores depending on the consumer.
+	FeatureCPUUsage = "cpu_usage"
+	// FeatureInletTemp is inlet temperature in Celsius.
+	FeatureInletTemp = "inlet_temp"
+	// FeatureDeltaP is differential pressure in Pascal.
+	FeatureDeltaP = "delta_p"
+	// FeatureMemoryUsage is memory usage in percent of the capacity.
+	FeatureMemoryUsage = "memory_usage"
+	// FeaturePowerConsumption is the measured current power consumption in watts.
+	FeaturePowerConsumption = "power_consumption"
+)
+
+// SupportedExtraFeatures are the features consumers can supply in addition to cpu_usage, inlet_temp and delta_p.
+// memory_usage is from metrics-server, and the others are custom metrics of the node served by WAO Metrics Adapter.
+var SupportedExtraFeatures = []string{FeatureMemoryUsage, FeaturePowerConsumption}
+
+// ValidateExtraFeatures checks feature names that consumers supply in addition to cpu_usage, inlet_temp and delta_p.
+func ValidateExtraFeatures(names []string) error {
+	for i, name := range names {
+		switch {
+		case name == "":
+			return fmt.Errorf("extra feature [%d] is empty", i)
+		case name == FeatureCPUUsage || name == FeatureInletTemp || name == FeatureDeltaP:
+			return fmt.Errorf("extra feature %q is always supplied", name)
+		case !slices.Contains(SupportedExtraFeatures, name):
+			return fmt.Errorf("extra feature %q is not supported, must be one of %s", name, strings.Join(SupportedExtraFeatures, ", "))
+		case slices.Contains(names[:i], name):
+			return fmt.Errorf("extra feature %q is duplicated", name)
+		}
+	}
+	return nil
+}
+
+// Features is a named feature vector, e.g., {"cpu_usage": 50, "inlet_temp": 22.5, "delta_p": 7.5, "memory_usage": 40}.
+type Features map[string]float64
+
+// Features returns the input as a feature vector.
+func (in PowerConsumptionInput) Features() Features {
+	return Features{
+		FeatureCPUUsage:  in.CPUUsage,
+		FeatureInletTemp: in.InletTemp,
+		FeatureDeltaP:    in.DeltaP,
+	}
+}
+
+// PowerConsumptionInput returns the inputs of 3-input predictors, ignoring other features.
+func (f Features) PowerConsumptionInput() (PowerConsumptionInput, error) {
+	var in PowerConsumptionInput
+	for _, e := range []struct {
+		name string
+		dst  *float64
+	}{
+		{FeatureCPUUsage, &in.CPUUsage},
+		{FeatureInletTemp, &in.InletTemp},
+		{FeatureDeltaP, &in.DeltaP},
+	} {
+		v, ok := f[e.name]
+		if !ok {
+			return PowerConsumptionInput{}, fmt.Errorf("feature %q is missing", e.name)
+		}
+		*e.dst = v
+	}
+	return in, nil
+}
+
+// String returns the features sorted by name, e.g., "cpu_usage=50.000000,delta_p=7.500000".
+// It is stable, so it can be used as a cache key.
+func (f Features) String() string {
+	ss := make([]string, 0, len(f))
+	for _, k := range slices.Sorted(maps.Keys(f)) {
+		ss = append(ss, fmt.Sprintf("%s=%f", k, f[k]))
+	}
+	return strings.Join(ss, ",")
+}
+
+// FeaturePowerConsumptionPredictor predicts power consumption from named features, so that models can take inputs
+// other than CPU usage, inlet temperature and differential pressure.
+type FeaturePowerConsumptionPredictor interface {
+	Endpoint() (string, error)
+	// PredictFeatures returns watts in the same order as features.
+	PredictFeatures(ctx context.Context, features []Features) (watts []float64, err error)
+}
+
+// AsFeaturePowerConsumptionPredictor returns p if it implements FeaturePowerConsumptionPredictor,
+// otherwise an adapter that predicts the 3 inputs of each feature vector with PredictBatch or Predict.
+func AsFeaturePowerConsumptionPredictor(p PowerConsumptionPredictor) FeaturePowerConsumptionPredictor {
+	if fp, ok := p.(FeaturePowerConsumptionPredictor); ok {
+		return fp
+	}
+	return &featurePowerConsumptionAdapter{p}
+}
+
+type featurePowerConsumptionAdapter struct {
+	PowerConsumptionPredictor
+}
+
+func (a *featurePowerConsumptionAdapter) PredictFeatures(ctx context.Context, features []Features) (watts []float64, err error) {
+	inputs := make([]PowerConsumptionInput, len(features))
+	for i, f := range features {
+		if inputs[i], err = f.PowerConsumptionInput(); err != nil {
+			return nil, err
+		}
+	}
+	if bp, ok := a.PowerConsumptionPredictor.(BatchPowerConsumptionPredictor); ok {
+		watts, err := bp.PredictBatch(ctx, inputs)
+		if err != nil {
+			return nil, err
+		}
+		if len(watts) != len(inputs) {
+			return nil, fmt.Errorf("got %d predictions for %d inputs", len(watts), len(inputs))
+		}
+		return watts, nil
+	}
+	watts = make([]float64, len(inputs))
+	for i, in := range inputs {
+		if watts[i], err = a.Predict(ctx, in.CPUUsage, in.InletTemp, in.DeltaP); err != nil {
+			return nil, err
+		}
+	}
+	return watts, nil
+}
@@ -0,0 +1,51 @@
+package predictor
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+type testPredictor struct{}
+
+func (testPredictor) Endpoint() (string, error) { return "", nil }
+
+func (testPredictor) Predict(ctx context.Context, cpuUsage, inletTemp, deltaP float64) (float64, error) {
+	return cpuUsage + inletTemp + deltaP, nil
+}
+
+func TestAsFeaturePowerConsumptionPredictor(t *testing.T) {
+	p := AsFeaturePowerConsumptionPredictor(testPredictor{})
+	got, err := p.PredictFeatures(context.Background(), []Features{
+		{FeatureCPUUsage: 1, FeatureInletTemp: 2, FeatureDeltaP: 3},
+		{FeatureCPUUsage: 10, FeatureInletTemp: 20, FeatureDeltaP: 30, FeatureMemoryUsage: 40},
+	})
+	if err != nil || !slices.Equal(got, []float64{6, 60}) {
+		t.Errorf("PredictFeatures() = %v, %v, want [6 60]", got, err)
+	}
+	if _, err := p.PredictFeatures(context.Background(), []Features{{FeatureCPUUsage: 1}}); err == nil {
+		t.Error("PredictFeatures() without inlet_temp and delta_p got no error")
+	}
+}
+
+func TestValidateExtraFeatures(t *testing.T) {
+	tests := []struct {
+		name    string
+		names   []string
+		wantErr bool
+	}{
+		{name: "none", names: nil},
+		{name: "valid", names: []string{FeatureMemoryUsage, FeaturePowerConsumption}},
+		{name: "empty", names: []string{""}, wantErr: true},
+		{name: "base_feature", names: []string{FeatureCPUUsage}, wantErr: true},
+		{name: "unsupported", names: []string{"fan_speed"}, wantErr: true},
+		{name: "duplicated", names: []string{FeatureMemoryUsage, FeatureMemoryUsage}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateExtraFeatures(tt.names); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateExtraFeatures() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -4,15 +4,19 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"math"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"k8s.io/client-go/kubernetes"
 
 	waov1beta1 "github.com/waok8s/waok8s/wao-core/api/node/v1beta1"
+	metricsfake "github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics/fake"
 	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
 	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor/fake"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor/linear"
 	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor/redfish"
 	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor/v2inferenceprotocol"
 	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/util"
@@ -55,15 +59,33 @@ func newEndpointProvider(
 }
 
 func NewPowerConsumptionPredictor(client kubernetes.Interface, namespace string, endpointTerm *waov1beta1.EndpointTerm) (predictor.PowerConsumptionPredictor, error) {
+	switch endpointTerm.Type {
+	case waov1beta1.TypeLinearModel, waov1beta1.TypeStaticPower: // in-process, so no auth
+		return newLinearPowerConsumptionPredictor(endpointTerm)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 	username, password := util.GetBasicAuthFromNamespaceScopedSecret(ctx, client, namespace, endpointTerm.BasicAuthSecret)
 
-	return newPowerConsumptionPredictor(endpointTerm.Type, endpointTerm.Endpoint, username, password, true, 3*time.Second)
+	return newPowerConsumptionPredictor(namespace, endpointTerm.Type, endpointTerm.Endpoint, endpointTerm.ModelSchema, username, password, true, 3*time.Second)
+}
+
+// ValidatePowerConsumptionPredictor validates the modelSchema of the power consumption predictor against the model metadata,
+// e.g., when the NodeConfig changes, instead of on the first prediction. Predictors without modelSchema are not validated.
+func ValidatePowerConsumptionPredictor(ctx context.Context, client kubernetes.Interface, namespace string, endpointTerm *waov1beta1.EndpointTerm) error {
+	pred, err := NewPowerConsumptionPredictor(client, namespace, endpointTerm)
+	if err != nil {
+		return err
+	}
+	if v, ok := pred.(predictor.SchemaValidator); ok {
+		return v.ValidateSchema(ctx)
+	}
+	return nil
 }
 
 func newPowerConsumptionPredictor(
-	endpointType, endpoint string,
+	namespace, endpointType, endpoint string, modelSchema *waov1beta1.ModelSchemaTerm,
 	basicAuthUsername, basicAuthPassword string,
 	insecureSkipVerify bool, requestTimeout time.Duration,
 ) (predictor.PowerConsumptionPredictor, error) {
@@ -72,35 +94,259 @@ func newPowerConsumptionPredictor(
 
 	switch endpointType {
 	case waov1beta1.TypeFake:
-		pred = fake.NewPowerConsumptionPredictor(endpoint, nil, 3.14, nil, 50*time.Millisecond)
+		profile, err := metricsfake.SharedProfile(endpoint, fakeSeedKey(namespace, endpoint), fake.PredictorVars...)
+		if err != nil {
+			return nil, err
+		}
+		p := fake.NewPowerConsumptionPredictor(endpoint, nil, 3.14, nil, 50*time.Millisecond)
+		p.PredictProfile = profile
+		pred = p
 	case waov1beta1.TypeV2InferenceProtocol:
-		u, err := url.Parse(endpoint)
+		address, modelName, modelVersion, err := parseV2InferenceProtocolEndpoint(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		schema, err := parseModelSchema(modelSchema, v2inferenceprotocol.InferPowerConsumptionRequestInputName)
 		if err != nil {
 			return nil, err
 		}
-		address := fmt.Sprintf("%s://%s", u.Scheme, u.Host)
 
-		modelName := ""
-		modelVersion := ""
-		ss := strings.Split(endpoint, "/")
-		for i, s := range ss {
-			if s == "models" && len(ss) > i+1 {
-				modelName = ss[i+1]
+		requestEditorFns := []util.RequestEditorFn{
+			util.WithBasicAuth(basicAuthUsername, basicAuthPassword),
+			util.WithCurlLogger(slog.With("func", "WithCurlLogger(v2inferenceprotocol.PowerConsumptionClient.Predict)")),
+		}
+
+		pred = v2inferenceprotocol.NewPowerConsumptionPredictor(address, modelName, modelVersion, schema, insecureSkipVerify, requestTimeout, requestEditorFns...)
+	case waov1beta1.TypeV2InferenceProtocolGRPC:
+		address, modelName, modelVersion, err := parseV2InferenceProtocolEndpoint(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		schema, err := parseModelSchema(modelSchema, v2inferenceprotocol.InferPowerConsumptionRequestInputName)
+		if err != nil {
+			return nil, err
+		}
+		p, err := v2inferenceprotocol.NewGRPCPowerConsumptionPredictor(address, modelName, modelVersion, schema, insecureSkipVerify, requestTimeout, basicAuthUsername, basicAuthPassword)
+		if err != nil {
+			return nil, err
+		}
+		pred = p
+	default:
+		return nil, fmt.Errorf("unknown endpoint type: %s", endpointType)
+	}
+
+	return pred, nil
+}
+
+// fakeSeedKey returns the seed key of fake profiles of the endpoint.
+// Predictions are cached by namespace and endpoint, so NodeConfigs with the same endpoint in a namespace share the profile.
+func fakeSeedKey(namespace, endpoint string) string { return namespace + "/" + endpoint }
+
+// newLinearPowerConsumptionPredictor returns the in-process model of TypeLinearModel or TypeStaticPower.
+func newLinearPowerConsumptionPredictor(endpointTerm *waov1beta1.EndpointTerm) (predictor.PowerConsumptionPredictor, error) {
+	switch endpointTerm.Type {
+	case waov1beta1.TypeLinearModel:
+		term := endpointTerm.LinearModel
+		if term == nil {
+			return nil, fmt.Errorf("linearModel is required for type %s", waov1beta1.TypeLinearModel)
+		}
+		intercept, err := parseDecimal(term.Intercept, 0)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse linearModel.intercept: %w", err)
+		}
+		coefficients := make(map[string]float64, len(term.Coefficients))
+		for i, c := range term.Coefficients {
+			if c.Metric == "" {
+				return nil, fmt.Errorf("linearModel.coefficients[%d] has empty metric", i)
 			}
-			if s == "versions" && len(ss) > i+1 {
-				modelVersion = ss[i+1]
+			if _, ok := coefficients[c.Metric]; ok {
+				return nil, fmt.Errorf("linearModel.coefficients[%d] has duplicated metric %q", i, c.Metric)
 			}
+			if coefficients[c.Metric], err = strconv.ParseFloat(c.Coefficient, 64); err != nil {
+				return nil, fmt.Errorf("unable to parse linearModel.coefficients[%d].coefficient: %w", i, err)
+			}
+		}
+		return linear.NewPowerConsumptionPredictor(intercept, coefficients), nil
+	case waov1beta1.TypeStaticPower:
+		term := endpointTerm.StaticPower
+		if term == nil {
+			return nil, fmt.Errorf("staticPower is required for type %s", waov1beta1.TypeStaticPower)
 		}
-		if modelName == "" {
-			return nil, fmt.Errorf("model name is not specified")
+		idleWatts, err := strconv.ParseFloat(term.IdleWatts, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse staticPower.idleWatts: %w", err)
+		}
+		wattsPerCore, err := strconv.ParseFloat(term.WattsPerCore, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse staticPower.wattsPerCore: %w", err)
+		}
+		return linear.NewStaticPowerConsumptionPredictor(idleWatts, wattsPerCore), nil
+	default:
+		return nil, fmt.Errorf("unknown endpoint type: %s", endpointTerm.Type)
+	}
+}
+
+// NewPowerConsumptionSanityCheck converts SanityCheckTerm to predictor.SanityCheck.
+// A nil term means predictor.DefaultSanityCheck.
+func NewPowerConsumptionSanityCheck(term *waov1beta1.SanityCheckTerm) (predictor.SanityCheck, error) {
+	check := predictor.DefaultSanityCheck()
+	if term == nil {
+		return check, nil
+	}
+
+	var err error
+	if check.IdleWatts, err = parseDecimal(term.IdleWatts, 0); err != nil {
+		return check, fmt.Errorf("unable to parse idleWatts: %w", err)
+	}
+	if check.MaxWatts, err = parseDecimal(term.MaxWatts, math.Inf(1)); err != nil {
+		return check, fmt.Errorf("unable to parse maxWatts: %w", err)
+	}
+	if check.IdleWatts > check.MaxWatts {
+		return check, fmt.Errorf("idleWatts %v is greater than maxWatts %v", check.IdleWatts, check.MaxWatts)
+	}
+	check.Monotonic = term.Monotonic
+	switch term.Action {
+	case "", waov1beta1.SanityCheckActionReject:
+		check.Clamp = false
+	case waov1beta1.SanityCheckActionClamp:
+		check.Clamp = true
+	default:
+		return check, fmt.Errorf("unknown action: %s", term.Action)
+	}
+	return check, nil
+}
+
+// parseDecimal parses a decimal number, or returns defaultValue if s is empty.
+func parseDecimal(s string, defaultValue float64) (float64, error) {
+	if s == "" {
+		return defaultValue, nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// parseV2InferenceProtocolEndpoint parses an infer endpoint URL.
+// E.g., "http://10.0.0.1:8080/v2/models/myModel/versions/v0.1.0/infer" or "grpc://10.0.0.1:8001/v2/models/myModel"
+func parseV2InferenceProtocolEndpoint(endpoint string) (address, modelName, modelVersion string, err error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", "", "", err
+	}
+	address = fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+
+	ss := strings.Split(endpoint, "/")
+	for i, s := range ss {
+		if s == "models" && len(ss) > i+1 {
+			modelName = ss[i+1]
+		}
+		if s == "versions" && len(ss) > i+1 {
+			modelVersion = ss[i+1]
+		}
+	}
+	if modelName == "" {
+		return "", "", "", fmt.Errorf("model name is not specified")
+	}
+	return address, modelName, modelVersion, nil
+}
+
+// parseModelSchema converts ModelSchemaTerm to v2inferenceprotocol.Schema.
+// Unspecified fields are taken from v2inferenceprotocol.DefaultSchema(defaultInputName).
+// The schema is checked against the model metadata only if the term is specified.
+func parseModelSchema(term *waov1beta1.ModelSchemaTerm, defaultInputName string) (v2inferenceprotocol.Schema, error) {
+	schema := v2inferenceprotocol.DefaultSchema(defaultInputName)
+	if term == nil {
+		return schema, nil
+	}
+	schema.CheckMetadata = true
+
+	var err error
+	if term.InputName != "" {
+		schema.InputName = term.InputName
+	}
+	if term.InputDatatype != "" {
+		schema.InputDatatype = term.InputDatatype
+	}
+	if len(term.Features) > 0 {
+		schema.Features = make([]v2inferenceprotocol.Feature, len(term.Features))
+		for i, f := range term.Features {
+			schema.Features[i].Metric = f.Metric
+			if schema.Features[i].Scale, err = parseDecimal(f.Scale, 1); err != nil {
+				return schema, fmt.Errorf("unable to parse features[%d].scale: %w", i, err)
+			}
+			if schema.Features[i].Offset, err = parseDecimal(f.Offset, 0); err != nil {
+				return schema, fmt.Errorf("unable to parse features[%d].offset: %w", i, err)
+			}
+		}
+	}
+	schema.OutputName = term.OutputName
+	schema.OutputIndex = int(term.OutputIndex)
+	if schema.OutputScale, err = parseDecimal(term.OutputScale, 1); err != nil {
+		return schema, fmt.Errorf("unable to parse outputScale: %w", err)
+	}
+	if schema.OutputOffset, err = parseDecimal(term.OutputOffset, 0); err != nil {
+		return schema, fmt.Errorf("unable to parse outputOffset: %w", err)
+	}
+
+	if err := schema.Validate(); err != nil {
+		return schema, fmt.Errorf("invalid modelSchema: %w", err)
+	}
+	return schema, nil
+}
+
+func NewResponseTimePredictor(client kubernetes.Interface, namespace string, endpointTerm *waov1beta1.EndpointTerm) (predictor.ResponseTimePredictor, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	username, password := util.GetBasicAuthFromNamespaceScopedSecret(ctx, client, namespace, endpointTerm.BasicAuthSecret)
+
+	return newResponseTimePredictor(namespace, endpointTerm.Type, endpointTerm.Endpoint, endpointTerm.ModelSchema, username, password, true, 3*time.Second)
+}
+
+func newResponseTimePredictor(
+	namespace, endpointType, endpoint string, modelSchema *waov1beta1.ModelSchemaTerm,
+	basicAuthUsername, basicAuthPassword string,
+	insecureSkipVerify bool, requestTimeout time.Duration,
+) (predictor.ResponseTimePredictor, error) {
+
+	var pred predictor.ResponseTimePredictor
+
+	switch endpointType {
+	case waov1beta1.TypeFake:
+		profile, err := metricsfake.SharedProfile(endpoint, fakeSeedKey(namespace, endpoint), fake.PredictorVars...)
+		if err != nil {
+			return nil, err
+		}
+		p := fake.NewResponseTimePredictor(endpoint, nil, 10, nil, 50*time.Millisecond)
+		p.PredictProfile = profile
+		pred = p
+	case waov1beta1.TypeV2InferenceProtocol:
+		address, modelName, modelVersion, err := parseV2InferenceProtocolEndpoint(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		schema, err := parseModelSchema(modelSchema, v2inferenceprotocol.InferResponseTimeRequestInputName)
+		if err != nil {
+			return nil, err
 		}
 
 		requestEditorFns := []util.RequestEditorFn{
 			util.WithBasicAuth(basicAuthUsername, basicAuthPassword),
-			util.WithCurlLogger(slog.With("func", "WithCurlLogger(v2inferenceprotocol.PowerConsumptionClient.Predict)")),
+			util.WithCurlLogger(slog.With("func", "WithCurlLogger(v2inferenceprotocol.ResponseTimePredictor.Predict)")),
 		}
 
-		pred = v2inferenceprotocol.NewPowerConsumptionPredictor(address, modelName, modelVersion, insecureSkipVerify, requestTimeout, requestEditorFns...)
+		pred = v2inferenceprotocol.NewResponseTimePredictor(address, modelName, modelVersion, schema, insecureSkipVerify, requestTimeout, requestEditorFns...)
+	case waov1beta1.TypeV2InferenceProtocolGRPC:
+		address, modelName, modelVersion, err := parseV2InferenceProtocolEndpoint(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		schema, err := parseModelSchema(modelSchema, v2inferenceprotocol.InferResponseTimeRequestInputName)
+		if err != nil {
+			return nil, err
+		}
+		p, err := v2inferenceprotocol.NewGRPCResponseTimePredictor(address, modelName, modelVersion, schema, insecureSkipVerify, requestTimeout, basicAuthUsername, basicAuthPassword)
+		if err != nil {
+			return nil, err
+		}
+		pred = p
 	default:
 		return nil, fmt.Errorf("unknown endpoint type: %s", endpointType)
 	}
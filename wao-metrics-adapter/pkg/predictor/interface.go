@@ -23,7 +23,29 @@ type PowerConsumptionPredictor interface {
 	Predict(ctx context.Context, cpuUsage, inletTemp, deltaP float64) (watt float64, err error)
 }
 
+// PowerConsumptionInput is an input of BatchPowerConsumptionPredictor.PredictBatch.
+type PowerConsumptionInput struct {
+	CPUUsage  float64
+	InletTemp float64
+	DeltaP    float64
+}
+
+// BatchPowerConsumptionPredictor is a PowerConsumptionPredictor that predicts multiple inputs in a single request.
+type BatchPowerConsumptionPredictor interface {
+	PowerConsumptionPredictor
+	// PredictBatch returns watts in the same order as inputs.
+	PredictBatch(ctx context.Context, inputs []PowerConsumptionInput) (watts []float64, err error)
+}
+
+// SchemaValidator is a predictor that validates its input schema against the model, so that mismatches can be reported before predictions.
+type SchemaValidator interface {
+	// ValidateSchema returns an error if the schema does not match the model. Results are cached, so it is cheap to call repeatedly.
+	ValidateSchema(ctx context.Context) error
+}
+
 type ResponseTimePredictor interface {
 	Endpoint() (string, error)
-	// Predict() // TODO: define this method
+	// Predict returns the predicted response time in milliseconds of workloads on the node.
+	// The inputs are the same as PowerConsumptionPredictor.Predict.
+	Predict(ctx context.Context, cpuUsage, inletTemp, deltaP float64) (milliseconds float64, err error)
 }
@@ -0,0 +1,75 @@
+package linear
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
+)
+
+// PowerConsumptionPredictor is an in-process model that predicts Intercept + sum(Coefficients[name] * features[name]).
+// It does not depend on any server, so it is useful as a fallback of remote models.
+type PowerConsumptionPredictor struct {
+	Intercept    float64
+	Coefficients map[string]float64
+}
+
+var _ predictor.BatchPowerConsumptionPredictor = (*PowerConsumptionPredictor)(nil)
+var _ predictor.FeaturePowerConsumptionPredictor = (*PowerConsumptionPredictor)(nil)
+
+func NewPowerConsumptionPredictor(intercept float64, coefficients map[string]float64) *PowerConsumptionPredictor {
+	return &PowerConsumptionPredictor{
+		Intercept:    intercept,
+		Coefficients: coefficients,
+	}
+}
+
+// NewStaticPowerConsumptionPredictor returns a model that predicts idleWatts + wattsPerCore * cpu_usage.
+func NewStaticPowerConsumptionPredictor(idleWatts, wattsPerCore float64) *PowerConsumptionPredictor {
+	return NewPowerConsumptionPredictor(idleWatts, map[string]float64{predictor.FeatureCPUUsage: wattsPerCore})
+}
+
+// Endpoint returns the model as a string, e.g., "linear:80+10*cpu_usage", as there is no endpoint.
+func (p *PowerConsumptionPredictor) Endpoint() (string, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "linear:%g", p.Intercept)
+	for _, k := range slices.Sorted(maps.Keys(p.Coefficients)) {
+		fmt.Fprintf(&sb, "%+g*%s", p.Coefficients[k], k)
+	}
+	return sb.String(), nil
+}
+
+func (p *PowerConsumptionPredictor) Predict(ctx context.Context, cpuUsage, inletTemp, deltaP float64) (watt float64, err error) {
+	watts, err := p.PredictFeatures(ctx, []predictor.Features{predictor.PowerConsumptionInput{CPUUsage: cpuUsage, InletTemp: inletTemp, DeltaP: deltaP}.Features()})
+	if err != nil {
+		return 0.0, err
+	}
+	return watts[0], nil
+}
+
+func (p *PowerConsumptionPredictor) PredictBatch(ctx context.Context, inputs []predictor.PowerConsumptionInput) (watts []float64, err error) {
+	features := make([]predictor.Features, len(inputs))
+	for i, in := range inputs {
+		features[i] = in.Features()
+	}
+	return p.PredictFeatures(ctx, features)
+}
+
+// PredictFeatures returns an error if any feature with a coefficient is missing.
+func (p *PowerConsumptionPredictor) PredictFeatures(ctx context.Context, features []predictor.Features) (watts []float64, err error) {
+	watts = make([]float64, len(features))
+	for i, f := range features {
+		watts[i] = p.Intercept
+		for k, c := range p.Coefficients {
+			v, ok := f[k]
+			if !ok {
+				return nil, fmt.Errorf("feature %q is not supplied", k)
+			}
+			watts[i] += c * v
+		}
+	}
+	return watts, nil
+}
@@ -0,0 +1,44 @@
+package linear
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
+)
+
+func TestPowerConsumptionPredictor(t *testing.T) {
+	tests := []struct {
+		name         string
+		p            *PowerConsumptionPredictor
+		features     []predictor.Features
+		want         []float64
+		wantEndpoint string
+		wantErr      bool
+	}{
+		{"static", NewStaticPowerConsumptionPredictor(80, 10),
+			[]predictor.Features{{"cpu_usage": 0}, {"cpu_usage": 2.5, "inlet_temp": 20}},
+			[]float64{80, 105}, "linear:80+10*cpu_usage", false},
+		{"linear", NewPowerConsumptionPredictor(50, map[string]float64{"cpu_usage": 2, "inlet_temp": -0.5}),
+			[]predictor.Features{{"cpu_usage": 10, "inlet_temp": 20}},
+			[]float64{60}, "linear:50+2*cpu_usage-0.5*inlet_temp", false},
+		{"missing", NewPowerConsumptionPredictor(50, map[string]float64{"memory_usage": 1}),
+			[]predictor.Features{{"cpu_usage": 10}},
+			nil, "linear:50+1*memory_usage", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.p.PredictFeatures(context.Background(), tt.features)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PredictFeatures() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PredictFeatures() = %v, want %v", got, tt.want)
+			}
+			if ep, _ := tt.p.Endpoint(); ep != tt.wantEndpoint {
+				t.Errorf("Endpoint() = %v, want %v", ep, tt.wantEndpoint)
+			}
+		})
+	}
+}
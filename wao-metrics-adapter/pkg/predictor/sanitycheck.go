@@ -0,0 +1,95 @@
+package predictor
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Reasons of SanityCheck failures.
+const (
+	ReasonNotFinite    = "NotFinite"
+	ReasonBelowIdle    = "BelowIdle"
+	ReasonAboveMax     = "AboveMax"
+	ReasonNotMonotonic = "NotMonotonic"
+)
+
+// SanityCheck validates predicted watts.
+type SanityCheck struct {
+	// IdleWatts is the min valid watts.
+	IdleWatts float64
+	// MaxWatts is the max valid watts. Use math.Inf(1) for no upper bound.
+	MaxWatts float64
+	// Monotonic requires watts to be non-decreasing in cpu_usage among features with the same other values.
+	Monotonic bool
+	// Clamp clamps invalid watts instead of rejecting them. Non-finite watts are always rejected.
+	Clamp bool
+}
+
+// DefaultSanityCheck rejects non-finite and negative watts.
+func DefaultSanityCheck() SanityCheck {
+	return SanityCheck{IdleWatts: 0, MaxWatts: math.Inf(1)}
+}
+
+// Check validates watts predicted from features, which are in the same order.
+// It returns the watts (clamped if Clamp is set) and the sorted reasons of failures.
+// err is not nil if the watts are rejected.
+func (s SanityCheck) Check(features []Features, watts []float64) (checked []float64, reasons []string, err error) {
+	if len(features) != len(watts) {
+		return nil, nil, fmt.Errorf("got %d predictions for %d inputs", len(watts), len(features))
+	}
+	found := map[string]struct{}{}
+
+	checked = slices.Clone(watts)
+	for i, w := range checked {
+		switch {
+		case math.IsNaN(w) || math.IsInf(w, 0):
+			found[ReasonNotFinite] = struct{}{}
+		case w < s.IdleWatts:
+			found[ReasonBelowIdle] = struct{}{}
+			checked[i] = s.IdleWatts
+		case w > s.MaxWatts:
+			found[ReasonAboveMax] = struct{}{}
+			checked[i] = s.MaxWatts
+		}
+	}
+	if _, ok := found[ReasonNotFinite]; !ok && s.Monotonic {
+		if makeMonotonic(features, checked) {
+			found[ReasonNotMonotonic] = struct{}{}
+		}
+	}
+
+	reasons = slices.Sorted(maps.Keys(found))
+	_, notFinite := found[ReasonNotFinite]
+	if notFinite || (len(reasons) > 0 && !s.Clamp) {
+		return nil, reasons, fmt.Errorf("prediction rejected: %s", strings.Join(reasons, ","))
+	}
+	return checked, reasons, nil
+}
+
+// makeMonotonic replaces each watt with the max watt at lower or equal cpu_usage among features with the same other values.
+// It returns true if any watt is replaced. Features without cpu_usage are ignored.
+func makeMonotonic(features []Features, watts []float64) (changed bool) {
+	groups := map[string][]int{}
+	for i, f := range features {
+		if _, ok := f[FeatureCPUUsage]; !ok {
+			continue
+		}
+		env := maps.Clone(f)
+		delete(env, FeatureCPUUsage)
+		groups[env.String()] = append(groups[env.String()], i)
+	}
+	for _, idx := range groups {
+		sort.SliceStable(idx, func(a, b int) bool { return features[idx[a]][FeatureCPUUsage] < features[idx[b]][FeatureCPUUsage] })
+		for k := 1; k < len(idx); k++ {
+			if prev := watts[idx[k-1]]; watts[idx[k]] < prev {
+				watts[idx[k]] = prev
+				changed = true
+			}
+		}
+	}
+	return changed
+}
@@ -0,0 +1,58 @@
+package predictor
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestSanityCheck_Check(t *testing.T) {
+	f := func(cpu, temp float64) Features { return Features{FeatureCPUUsage: cpu, FeatureInletTemp: temp} }
+	tests := []struct {
+		name        string
+		check       SanityCheck
+		features    []Features
+		watts       []float64
+		want        []float64
+		wantReasons []string
+		wantErr     bool
+	}{
+		{"ok", DefaultSanityCheck(), []Features{f(10, 20), f(20, 20)}, []float64{100, 110}, []float64{100, 110}, []string{}, false},
+		{"negative", DefaultSanityCheck(), []Features{f(10, 20)}, []float64{-1}, nil, []string{ReasonBelowIdle}, true},
+		{"nan", DefaultSanityCheck(), []Features{f(10, 20)}, []float64{math.NaN()}, nil, []string{ReasonNotFinite}, true},
+		{"inf_clamp", SanityCheck{MaxWatts: 500, Clamp: true}, []Features{f(10, 20)}, []float64{math.Inf(1)}, nil, []string{ReasonNotFinite}, true},
+		{"clamp_bounds",
+			SanityCheck{IdleWatts: 80, MaxWatts: 500, Clamp: true},
+			[]Features{f(0, 20), f(10, 20), f(100, 20)}, []float64{50, 200, 900},
+			[]float64{80, 200, 500}, []string{ReasonAboveMax, ReasonBelowIdle}, false},
+		{"not_monotonic",
+			SanityCheck{MaxWatts: math.Inf(1), Monotonic: true},
+			[]Features{f(20, 20), f(10, 20)}, []float64{100, 110},
+			nil, []string{ReasonNotMonotonic}, true},
+		{"monotonic_clamp",
+			SanityCheck{MaxWatts: math.Inf(1), Monotonic: true, Clamp: true},
+			[]Features{f(20, 20), f(10, 20), f(30, 20)}, []float64{100, 110, 105},
+			[]float64{110, 110, 110}, []string{ReasonNotMonotonic}, false},
+		{"monotonic_other_env",
+			SanityCheck{MaxWatts: math.Inf(1), Monotonic: true},
+			[]Features{f(10, 20), f(20, 30)}, []float64{110, 100},
+			[]float64{110, 100}, []string{}, false},
+		{"length_mismatch", DefaultSanityCheck(), []Features{f(10, 20)}, []float64{1, 2}, nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reasons, err := tt.check.Check(tt.features, tt.watts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check() got = %v, want %v", got, tt.want)
+			}
+			if len(reasons) != 0 || len(tt.wantReasons) != 0 {
+				if !reflect.DeepEqual(reasons, tt.wantReasons) {
+					t.Errorf("Check() reasons = %v, want %v", reasons, tt.wantReasons)
+				}
+			}
+		})
+	}
+}
@@ -64,7 +64,7 @@ func main() {
 	}
 	requestEditorFns = append(requestEditorFns, util.WithCurlLogger(lg.With("func", "WithCurlLogger(v2inferenceprotocol.PowerConsumptionPredictor.Predict)")))
 
-	c := v2inferenceprotocol.NewPowerConsumptionPredictor(address, model, modelVersion, true, timeout, requestEditorFns...)
+	c := v2inferenceprotocol.NewPowerConsumptionPredictor(address, model, modelVersion, v2inferenceprotocol.DefaultSchema(v2inferenceprotocol.InferPowerConsumptionRequestInputName), true, timeout, requestEditorFns...)
 
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	v, err := c.Predict(ctx, cpuUsage, inletTemp, deltaP)
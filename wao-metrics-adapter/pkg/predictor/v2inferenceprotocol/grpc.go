@@ -0,0 +1,330 @@
+package v2inferenceprotocol
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/url"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"k8s.io/utils/lru"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor/v2inferenceprotocol/inference"
+)
+
+const (
+	// SchemeGRPC is the address scheme of plaintext gRPC.
+	SchemeGRPC = "grpc"
+	// SchemeGRPCS is the address scheme of gRPC over TLS.
+	SchemeGRPCS = "grpcs"
+)
+
+type grpcConnKey struct {
+	target             string
+	tls                bool
+	insecureSkipVerify bool
+}
+
+const (
+	// maxGRPCConns is the max number of gRPC connections kept open.
+	maxGRPCConns = 256
+	// grpcConnCloseDelay is the time an evicted connection is kept open for requests in flight.
+	grpcConnCloseDelay = time.Minute
+)
+
+// grpcConns holds a *grpc.ClientConn for each grpcConnKey, up to maxGRPCConns.
+// Predictors are created for each prediction, so connections are shared by predictors of the same endpoint.
+// The least recently used connection is closed after grpcConnCloseDelay.
+var grpcConns = lru.NewWithEvictionFunc(maxGRPCConns, func(_ lru.Key, v any) {
+	conn := v.(*grpc.ClientConn)
+	time.AfterFunc(grpcConnCloseDelay, func() { conn.Close() })
+})
+
+// grpcConnsMu serializes creating connections so that a connection is created only once for each key.
+var grpcConnsMu sync.Mutex
+
+func grpcConn(address string, insecureSkipVerify bool) (*grpc.ClientConn, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	var key grpcConnKey
+	switch u.Scheme {
+	case SchemeGRPC:
+		key = grpcConnKey{target: u.Host}
+	case SchemeGRPCS:
+		key = grpcConnKey{target: u.Host, tls: true, insecureSkipVerify: insecureSkipVerify}
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q (must be %s or %s)", u.Scheme, SchemeGRPC, SchemeGRPCS)
+	}
+	if v, ok := grpcConns.Get(key); ok {
+		return v.(*grpc.ClientConn), nil
+	}
+	grpcConnsMu.Lock()
+	defer grpcConnsMu.Unlock()
+	if v, ok := grpcConns.Get(key); ok {
+		return v.(*grpc.ClientConn), nil
+	}
+
+	creds := insecure.NewCredentials()
+	if key.tls {
+		creds = credentials.NewTLS(&tls.Config{InsecureSkipVerify: key.insecureSkipVerify})
+	}
+	conn, err := grpc.NewClient(key.target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create gRPC client: %w", err)
+	}
+	grpcConns.Add(key, conn)
+	return conn, nil
+}
+
+// grpcModel calls ModelMetadata and ModelInfer of GRPCInferenceService with the same input and output semantics as the REST API.
+type grpcModel struct {
+	// address contains scheme, host and port.
+	// E.g., "grpc://10.0.0.1:8001"
+	address string
+	// model name.
+	modelName string
+	// model version.
+	modelVersion string
+	// schema of the model.
+	schema Schema
+
+	client  inference.GRPCInferenceServiceClient
+	timeout time.Duration
+
+	basicAuthUsername string
+	basicAuthPassword string
+}
+
+func newGRPCModel(address, modelName, modelVersion string, schema Schema, insecureSkipVerify bool, timeout time.Duration, basicAuthUsername, basicAuthPassword string) (*grpcModel, error) {
+	conn, err := grpcConn(address, insecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	return &grpcModel{
+		address:           address,
+		modelName:         modelName,
+		modelVersion:      modelVersion,
+		schema:            schema,
+		client:            inference.NewGRPCInferenceServiceClient(conn),
+		timeout:           timeout,
+		basicAuthUsername: basicAuthUsername,
+		basicAuthPassword: basicAuthPassword,
+	}, nil
+}
+
+// Endpoint returns the model URL.
+// E.g., grpc://hogefuga:12345/v2/models/piyo/versions/v0.1.0
+func (m *grpcModel) Endpoint() (string, error) {
+	if m.modelVersion == "" {
+		return url.JoinPath(m.address, "v2/models", m.modelName)
+	}
+	return url.JoinPath(m.address, "v2/models", m.modelName, "versions", m.modelVersion)
+}
+
+// withBasicAuth returns the context with basic auth metadata if the credentials are set.
+func (m *grpcModel) withBasicAuth(ctx context.Context) context.Context {
+	if m.basicAuthUsername != "" || m.basicAuthPassword != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte(m.basicAuthUsername + ":" + m.basicAuthPassword))
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Basic "+auth)
+	}
+	return ctx
+}
+
+// ValidateSchema validates the schema against ModelMetadata once for each endpoint, or once per backoff interval while it fails.
+func (m *grpcModel) ValidateSchema(ctx context.Context) error {
+	ep, err := m.Endpoint()
+	if err != nil {
+		return fmt.Errorf("unable to get endpoint URL: %w", err)
+	}
+	return validateOnce(ep, m.schema, func() (*modelMetadata, error) {
+		ctx, cancel := context.WithTimeout(m.withBasicAuth(ctx), m.timeout)
+		defer cancel()
+		resp, err := m.client.ModelMetadata(ctx, &inference.ModelMetadataRequest{Name: m.modelName, Version: m.modelVersion})
+		if err != nil {
+			return nil, fmt.Errorf("unable to call ModelMetadata: %w", err)
+		}
+		md := &modelMetadata{}
+		for _, t := range resp.GetInputs() {
+			md.Inputs = append(md.Inputs, tensorMetadata{Name: t.GetName(), Datatype: t.GetDatatype(), Shape: t.GetShape()})
+		}
+		for _, t := range resp.GetOutputs() {
+			md.Outputs = append(md.Outputs, tensorMetadata{Name: t.GetName(), Datatype: t.GetDatatype(), Shape: t.GetShape()})
+		}
+		return md, nil
+	})
+}
+
+// infer sends inputs as rows of the schema's input tensor and returns a value for each row.
+// The schema is validated against the model metadata before the first request.
+func (m *grpcModel) infer(ctx context.Context, inputs []predictor.Features) ([]float64, error) {
+	if err := m.ValidateSchema(ctx); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(m.withBasicAuth(ctx), m.timeout)
+	defer cancel()
+
+	contents := &inference.InferTensorContents{}
+	for _, in := range inputs {
+		row, err := m.schema.row(in)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range row {
+			if m.schema.InputDatatype == DatatypeFP64 {
+				contents.Fp64Contents = append(contents.Fp64Contents, v)
+			} else {
+				contents.Fp32Contents = append(contents.Fp32Contents, float32(v))
+			}
+		}
+	}
+	resp, err := m.client.ModelInfer(ctx, &inference.ModelInferRequest{
+		ModelName:    m.modelName,
+		ModelVersion: m.modelVersion,
+		Inputs: []*inference.ModelInferRequest_InferInputTensor{
+			{
+				Name:     m.schema.InputName,
+				Datatype: m.schema.InputDatatype,
+				Shape:    []int64{int64(len(inputs)), int64(len(m.schema.Features))},
+				Contents: contents,
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to call ModelInfer: %w", err)
+	}
+
+	outputs := make([]outputTensor, len(resp.GetOutputs()))
+	for i, o := range resp.GetOutputs() {
+		outputs[i] = outputTensor{name: o.GetName(), shape: o.GetShape()}
+		if len(resp.GetRawOutputContents()) > i {
+			outputs[i].values, err = decodeRawContents(o.GetDatatype(), resp.GetRawOutputContents()[i])
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			outputs[i].values = tensorContents(o.GetContents())
+		}
+	}
+	return m.schema.predictions(outputs, len(inputs))
+}
+
+// tensorContents returns numeric contents as float64.
+func tensorContents(c *inference.InferTensorContents) []float64 {
+	var vs []float64
+	for _, v := range c.GetFp64Contents() {
+		vs = append(vs, v)
+	}
+	for _, v := range c.GetFp32Contents() {
+		vs = append(vs, float64(v))
+	}
+	for _, v := range c.GetInt64Contents() {
+		vs = append(vs, float64(v))
+	}
+	for _, v := range c.GetIntContents() {
+		vs = append(vs, float64(v))
+	}
+	return vs
+}
+
+// decodeRawContents decodes raw little-endian contents of the datatype as float64.
+func decodeRawContents(datatype string, raw []byte) ([]float64, error) {
+	var size int
+	var decode func(b []byte) float64
+	switch datatype {
+	case "FP64":
+		size, decode = 8, func(b []byte) float64 { return math.Float64frombits(binary.LittleEndian.Uint64(b)) }
+	case "FP32":
+		size, decode = 4, func(b []byte) float64 { return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))) }
+	case "INT64":
+		size, decode = 8, func(b []byte) float64 { return float64(int64(binary.LittleEndian.Uint64(b))) }
+	case "INT32":
+		size, decode = 4, func(b []byte) float64 { return float64(int32(binary.LittleEndian.Uint32(b))) }
+	default:
+		return nil, fmt.Errorf("unsupported output datatype %q", datatype)
+	}
+	if len(raw)%size != 0 {
+		return nil, fmt.Errorf("invalid raw contents of %d bytes for %s", len(raw), datatype)
+	}
+	vs := make([]float64, len(raw)/size)
+	for i := range vs {
+		vs[i] = decode(raw[i*size:])
+	}
+	return vs, nil
+}
+
+// GRPCPowerConsumptionPredictor is PowerConsumptionPredictor over gRPC.
+type GRPCPowerConsumptionPredictor struct {
+	*grpcModel
+}
+
+var _ predictor.BatchPowerConsumptionPredictor = (*GRPCPowerConsumptionPredictor)(nil)
+var _ predictor.FeaturePowerConsumptionPredictor = (*GRPCPowerConsumptionPredictor)(nil)
+var _ predictor.SchemaValidator = (*GRPCPowerConsumptionPredictor)(nil)
+
+// NewGRPCPowerConsumptionPredictor inits the predictor. address is "grpc://{host}:{port}" or "grpcs://{host}:{port}".
+// Connections are reused for each address.
+func NewGRPCPowerConsumptionPredictor(address, modelName, modelVersion string, schema Schema, insecureSkipVerify bool, timeout time.Duration, basicAuthUsername, basicAuthPassword string) (*GRPCPowerConsumptionPredictor, error) {
+	m, err := newGRPCModel(address, modelName, modelVersion, schema, insecureSkipVerify, timeout, basicAuthUsername, basicAuthPassword)
+	if err != nil {
+		return nil, err
+	}
+	return &GRPCPowerConsumptionPredictor{m}, nil
+}
+
+func (p *GRPCPowerConsumptionPredictor) Predict(ctx context.Context, cpuUsage, inletTemp, deltaP float64) (watt float64, err error) {
+	watts, err := p.PredictBatch(ctx, []predictor.PowerConsumptionInput{{CPUUsage: cpuUsage, InletTemp: inletTemp, DeltaP: deltaP}})
+	if err != nil {
+		return 0.0, err
+	}
+	return watts[0], nil
+}
+
+func (p *GRPCPowerConsumptionPredictor) PredictBatch(ctx context.Context, inputs []predictor.PowerConsumptionInput) (watts []float64, err error) {
+	features := make([]predictor.Features, len(inputs))
+	for i, in := range inputs {
+		features[i] = in.Features()
+	}
+	return p.PredictFeatures(ctx, features)
+}
+
+func (p *GRPCPowerConsumptionPredictor) PredictFeatures(ctx context.Context, features []predictor.Features) (watts []float64, err error) {
+	if len(features) == 0 {
+		return nil, nil
+	}
+	return p.infer(ctx, features)
+}
+
+// GRPCResponseTimePredictor is ResponseTimePredictor over gRPC.
+type GRPCResponseTimePredictor struct {
+	*grpcModel
+}
+
+var _ predictor.ResponseTimePredictor = (*GRPCResponseTimePredictor)(nil)
+
+// NewGRPCResponseTimePredictor inits the predictor. See NewGRPCPowerConsumptionPredictor.
+func NewGRPCResponseTimePredictor(address, modelName, modelVersion string, schema Schema, insecureSkipVerify bool, timeout time.Duration, basicAuthUsername, basicAuthPassword string) (*GRPCResponseTimePredictor, error) {
+	m, err := newGRPCModel(address, modelName, modelVersion, schema, insecureSkipVerify, timeout, basicAuthUsername, basicAuthPassword)
+	if err != nil {
+		return nil, err
+	}
+	return &GRPCResponseTimePredictor{m}, nil
+}
+
+func (p *GRPCResponseTimePredictor) Predict(ctx context.Context, cpuUsage, inletTemp, deltaP float64) (milliseconds float64, err error) {
+	ms, err := p.infer(ctx, []predictor.Features{predictor.PowerConsumptionInput{CPUUsage: cpuUsage, InletTemp: inletTemp, DeltaP: deltaP}.Features()})
+	if err != nil {
+		return 0.0, err
+	}
+	return ms[0], nil
+}
@@ -0,0 +1,120 @@
+package v2inferenceprotocol
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor/v2inferenceprotocol/inference"
+)
+
+type testInferenceServer struct {
+	inference.UnimplementedGRPCInferenceServiceServer
+}
+
+// ModelMetadata returns the metadata of a FP32 [-1,3] input named "predict-prob" and a [-1,1] output.
+func (s *testInferenceServer) ModelMetadata(ctx context.Context, req *inference.ModelMetadataRequest) (*inference.ModelMetadataResponse, error) {
+	if md, _ := metadata.FromIncomingContext(ctx); !slices.Equal(md.Get("authorization"), []string{"Basic dXNlcjpwYXNz"}) { // user:pass
+		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+	}
+	return &inference.ModelMetadataResponse{
+		Name:    req.GetName(),
+		Inputs:  []*inference.ModelMetadataResponse_TensorMetadata{{Name: "predict-prob", Datatype: "FP32", Shape: []int64{-1, 3}}},
+		Outputs: []*inference.ModelMetadataResponse_TensorMetadata{{Name: "predict", Datatype: "FP64", Shape: []int64{-1, 1}}},
+	}, nil
+}
+
+// ModelInfer returns cpuUsage*2 for each row, as FP32 contents for model "power" and as raw FP64 for model "latency".
+func (s *testInferenceServer) ModelInfer(ctx context.Context, req *inference.ModelInferRequest) (*inference.ModelInferResponse, error) {
+	if md, _ := metadata.FromIncomingContext(ctx); !slices.Equal(md.Get("authorization"), []string{"Basic dXNlcjpwYXNz"}) { // user:pass
+		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+	}
+	in := req.GetInputs()[0]
+	if in.GetName() != "predict-prob" || in.GetDatatype() != "FP32" || len(in.GetShape()) != 2 || in.GetShape()[1] != 3 {
+		return nil, status.Errorf(codes.InvalidArgument, "unexpected input %v", in)
+	}
+	data := in.GetContents().GetFp32Contents()
+	var out []float64
+	for i := range in.GetShape()[0] {
+		out = append(out, float64(data[i*3])*2)
+	}
+
+	switch req.GetModelName() {
+	case "power":
+		var fp32 []float32
+		for _, v := range out {
+			fp32 = append(fp32, float32(v))
+		}
+		return &inference.ModelInferResponse{Outputs: []*inference.ModelInferResponse_InferOutputTensor{
+			{Name: "predict", Datatype: "FP32", Shape: []int64{int64(len(out)), 1}, Contents: &inference.InferTensorContents{Fp32Contents: fp32}},
+		}}, nil
+	case "latency":
+		var raw []byte
+		for _, v := range out {
+			raw = binary.LittleEndian.AppendUint64(raw, math.Float64bits(v))
+		}
+		return &inference.ModelInferResponse{
+			Outputs:           []*inference.ModelInferResponse_InferOutputTensor{{Name: "predict", Datatype: "FP64", Shape: []int64{int64(len(out)), 1}}},
+			RawOutputContents: [][]byte{raw},
+		}, nil
+	default:
+		return nil, status.Errorf(codes.NotFound, "model %s not found", req.GetModelName())
+	}
+}
+
+func TestGRPCPredictor(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	inference.RegisterGRPCInferenceServiceServer(srv, &testInferenceServer{})
+	go srv.Serve(lis)
+	defer srv.Stop()
+	address := "grpc://" + lis.Addr().String()
+	schema := DefaultSchema("predict-prob")
+
+	pc, err := NewGRPCPowerConsumptionPredictor(address, "power", "", schema, false, time.Second, "user", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	watts, err := pc.PredictBatch(context.Background(), []predictor.PowerConsumptionInput{{CPUUsage: 10}, {CPUUsage: 20}})
+	if err != nil || !slices.Equal(watts, []float64{20, 40}) {
+		t.Errorf("PredictBatch() = %v, %v, want [20 40]", watts, err)
+	}
+
+	rt, err := NewGRPCResponseTimePredictor(address, "latency", "v1", schema, false, time.Second, "user", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ms, err := rt.Predict(context.Background(), 5, 25, 7.5); err != nil || ms != 10 {
+		t.Errorf("Predict() = %v, %v, want 10", ms, err)
+	}
+	if c1, _ := grpcConn(address, false); c1 == nil {
+		t.Fatal("connection is not cached")
+	} else if c2, _ := grpcConn(address, false); c1 != c2 {
+		t.Error("connection is not reused")
+	}
+
+	unauthorized, err := NewGRPCPowerConsumptionPredictor(address, "power", "", schema, false, time.Second, "user", "wrong")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unauthorized.Predict(context.Background(), 10, 25, 7.5); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Predict() error = %v, want Unauthenticated", err)
+	}
+
+	if _, err := NewGRPCPowerConsumptionPredictor("http://"+lis.Addr().String(), "power", "", schema, false, time.Second, "", ""); err == nil {
+		t.Error("NewGRPCPowerConsumptionPredictor() with http scheme got no error")
+	}
+}
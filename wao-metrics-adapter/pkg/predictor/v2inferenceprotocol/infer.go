@@ -0,0 +1,197 @@
+package v2inferenceprotocol
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/util"
+)
+
+// endpoint constructs the infer API endpoint.
+//
+// There is 2 types of URL.
+//   - With version http://hogefuga:12345/v2/models/piyo/versions/v0.1.0/infer
+//   - Without version http://hogefuga:12345/v2/models/piyo/infer
+func endpoint(address, modelName, modelVersion string) (string, error) {
+	if modelVersion == "" {
+		return url.JoinPath(address, "v2/models", modelName, "infer")
+	} else {
+		return url.JoinPath(address, "v2/models", modelName, "versions", modelVersion, "infer")
+	}
+}
+
+// metadataEndpoint constructs the model metadata API endpoint, i.e., endpoint without "/infer".
+func metadataEndpoint(address, modelName, modelVersion string) (string, error) {
+	if modelVersion == "" {
+		return url.JoinPath(address, "v2/models", modelName)
+	} else {
+		return url.JoinPath(address, "v2/models", modelName, "versions", modelVersion)
+	}
+}
+
+// infer sends inputs to the infer API endpoint as rows of the schema's input tensor and returns a value for each row.
+func infer(ctx context.Context, client *http.Client, editorFns []util.RequestEditorFn, url string, schema Schema, inputs []predictor.Features) ([]float64, error) {
+	reqBody, err := newInferRequest(schema, inputs)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal the request body=%+v err=%w", body, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create HTTP request: %w", err)
+	}
+	for i, f := range editorFns {
+		if err := f(ctx, req); err != nil {
+			return nil, fmt.Errorf("editorFns[%d] got error: %w", i, err)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to send HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var apiResp inferResponse
+		if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+			return nil, fmt.Errorf("could not decode resp: %w", err)
+		}
+		outputs := make([]outputTensor, len(apiResp.Outputs))
+		for i, o := range apiResp.Outputs {
+			outputs[i] = outputTensor{name: o.Name, shape: o.Shape, values: o.Data}
+		}
+		return schema.predictions(outputs, len(inputs))
+	default:
+		return nil, fmt.Errorf("HTTP status=%s", resp.Status)
+	}
+}
+
+// getModelMetadata gets the model metadata from the model metadata API endpoint.
+func getModelMetadata(ctx context.Context, client *http.Client, editorFns []util.RequestEditorFn, url string) (*modelMetadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create HTTP request: %w", err)
+	}
+	for i, f := range editorFns {
+		if err := f(ctx, req); err != nil {
+			return nil, fmt.Errorf("editorFns[%d] got error: %w", i, err)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to send HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var md modelMetadata
+		if err := json.NewDecoder(resp.Body).Decode(&md); err != nil {
+			return nil, fmt.Errorf("could not decode resp: %w", err)
+		}
+		return &md, nil
+	default:
+		return nil, fmt.Errorf("HTTP status=%s", resp.Status)
+	}
+}
+
+// inferRequest holds a request.
+//
+// E.g.,
+//
+//	{
+//	  "inputs": [
+//	    {
+//	      "name": "predict-prob",
+//	      "shape": [ 1, 3 ],
+//	      "datatype": "FP32",
+//	      "data": [ [10, 22, 0.2 ] ]
+//	    }
+//	  ]
+//	}
+type inferRequest struct {
+	Inputs []inferRequestInput `json:"inputs"`
+}
+
+type inferRequestInput struct {
+	Name     string `json:"name"`
+	Shape    []int  `json:"shape"`
+	Datatype string `json:"datatype"`
+	// Data is [][]float32 for FP32 or [][]float64 for FP64.
+	Data any `json:"data"`
+}
+
+// newInferRequest returns a request with a [N, len(schema.Features)] tensor, where each row is the features of an input.
+func newInferRequest(schema Schema, inputs []predictor.Features) (*inferRequest, error) {
+	rows := make([][]float64, len(inputs))
+	for i, in := range inputs {
+		row, err := schema.row(in)
+		if err != nil {
+			return nil, err
+		}
+		rows[i] = row
+	}
+	var data any = rows
+	if schema.InputDatatype != DatatypeFP64 {
+		fp32 := make([][]float32, len(rows))
+		for i, row := range rows {
+			for _, v := range row {
+				fp32[i] = append(fp32[i], float32(v))
+			}
+		}
+		data = fp32
+	}
+	return &inferRequest{
+		Inputs: []inferRequestInput{
+			{
+				Name:     schema.InputName,
+				Shape:    []int{len(inputs), len(schema.Features)},
+				Datatype: schema.InputDatatype,
+				Data:     data,
+			},
+		},
+	}, nil
+}
+
+// inferResponse holds a response.
+// Ignore values except outputs[*].name, outputs[*].shape and outputs[*].data[]
+//
+// E.g.,
+//
+//	{
+//	  "model_name": "model1",
+//	  "model_version": "v0.1.0",
+//	  "id": "0dc429d2-bd02-404b-b624-a0fa628e451e",
+//	  "parameters": {
+//	    "content_type": null,
+//	    "headers": null
+//	  },
+//	  "outputs": [
+//	    {
+//	      "name": "predict",
+//	      "shape": [ 1, 1 ],
+//	      "datatype": "FP64",
+//	      "parameters": null,
+//	      "data": [ 94.76267448501928 ]
+//	    }
+//	  ]
+//	}
+type inferResponse struct {
+	Outputs []inferResponseOutput `json:"outputs"`
+}
+
+type inferResponseOutput struct {
+	Name  string    `json:"name"`
+	Shape []int64   `json:"shape"`
+	Data  []float64 `json:"data"`
+}
@@ -0,0 +1,122 @@
+package v2inferenceprotocol
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
+)
+
+// testInferRequest decodes inferRequest with data of any datatype.
+type testInferRequest struct {
+	Inputs []struct {
+		Name     string      `json:"name"`
+		Shape    []int       `json:"shape"`
+		Datatype string      `json:"datatype"`
+		Data     [][]float64 `json:"data"`
+	} `json:"inputs"`
+}
+
+const testModelMetadata = `{
+  "inputs": [{"name": "predict-prob", "datatype": "FP32", "shape": [-1, 3]}],
+  "outputs": [{"name": "predict", "datatype": "FP64", "shape": [-1, 1]}]
+}`
+
+func TestPredict(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			switch r.URL.Path {
+			case "/v2/models/power/versions/v1", "/v2/models/latency", "/v2/models/empty":
+				w.Write([]byte(testModelMetadata))
+			default:
+				http.NotFound(w, r)
+			}
+			return
+		}
+		var req testInferRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(req.Inputs) != 1 || !slices.Equal(req.Inputs[0].Shape, []int{1, 3}) || !slices.Equal(req.Inputs[0].Data[0], []float64{10, 22, 0.5}) {
+			http.Error(w, "unexpected inputs", http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/models/power/versions/v1/infer":
+			w.Write([]byte(`{"outputs": [{"name": "predict", "data": [94.5]}]}`))
+		case "/v2/models/latency/infer":
+			w.Write([]byte(`{"outputs": [{"name": "predict", "data": [12.5]}]}`))
+		case "/v2/models/empty/infer":
+			w.Write([]byte(`{"outputs": []}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	schema := DefaultSchema("predict-prob")
+	tests := []struct {
+		name    string
+		predict func(ctx context.Context, cpuUsage, inletTemp, deltaP float64) (float64, error)
+		want    float64
+		wantErr bool
+	}{
+		{name: "power_consumption", predict: NewPowerConsumptionPredictor(srv.URL, "power", "v1", schema, false, time.Second).Predict, want: 94.5},
+		{name: "response_time", predict: NewResponseTimePredictor(srv.URL, "latency", "", schema, false, time.Second).Predict, want: 12.5},
+		{name: "empty_outputs", predict: NewResponseTimePredictor(srv.URL, "empty", "", schema, false, time.Second).Predict, wantErr: true},
+		{name: "not_found", predict: NewResponseTimePredictor(srv.URL, "unknown", "", schema, false, time.Second).Predict, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.predict(context.Background(), 10, 22, 0.5)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Predict() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Predict() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPowerConsumptionPredictor_PredictBatch(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(testModelMetadata))
+			return
+		}
+		requests++
+		var req testInferRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		in := req.Inputs[0]
+		if in.Shape[0] != len(in.Data) {
+			http.Error(w, "shape mismatch", http.StatusBadRequest)
+			return
+		}
+		resp := inferResponse{Outputs: make([]inferResponseOutput, 1)}
+		for _, row := range in.Data {
+			resp.Outputs[0].Data = append(resp.Outputs[0].Data, row[0]*2)
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	p := NewPowerConsumptionPredictor(srv.URL, "power", "", DefaultSchema("predict-prob"), false, time.Second)
+	got, err := p.PredictBatch(context.Background(), []predictor.PowerConsumptionInput{{CPUUsage: 1}, {CPUUsage: 2}, {CPUUsage: 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, []float64{2, 4, 6}) || requests != 1 {
+		t.Errorf("PredictBatch() = %v with %d requests, want [2 4 6] with 1 request", got, requests)
+	}
+}
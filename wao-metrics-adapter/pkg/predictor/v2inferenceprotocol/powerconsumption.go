@@ -1,13 +1,10 @@
 package v2inferenceprotocol
 
 import (
-	"bytes"
 	"context"
 	"crypto/tls"
-	"encoding/json"
 	"fmt"
 	"net/http"
-	"net/url"
 	"time"
 
 	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
@@ -22,18 +19,23 @@ type PowerConsumptionPredictor struct {
 	modelName string
 	// model version.
 	modelVersion string
+	// schema of the model.
+	schema Schema
 
 	client    *http.Client
 	editorFns []util.RequestEditorFn
 }
 
-var _ predictor.PowerConsumptionPredictor = (*PowerConsumptionPredictor)(nil)
+var _ predictor.BatchPowerConsumptionPredictor = (*PowerConsumptionPredictor)(nil)
+var _ predictor.FeaturePowerConsumptionPredictor = (*PowerConsumptionPredictor)(nil)
+var _ predictor.SchemaValidator = (*PowerConsumptionPredictor)(nil)
 
-func NewPowerConsumptionPredictor(address, modelName, modelVersion string, insecureSkipVerify bool, timeout time.Duration, editorFns ...util.RequestEditorFn) *PowerConsumptionPredictor {
+func NewPowerConsumptionPredictor(address, modelName, modelVersion string, schema Schema, insecureSkipVerify bool, timeout time.Duration, editorFns ...util.RequestEditorFn) *PowerConsumptionPredictor {
 	return &PowerConsumptionPredictor{
 		address:      address,
 		modelName:    modelName,
 		modelVersion: modelVersion,
+		schema:       schema,
 		client: &http.Client{
 			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: insecureSkipVerify}},
 			Timeout:   timeout,
@@ -48,129 +50,54 @@ func NewPowerConsumptionPredictor(address, modelName, modelVersion string, insec
 //   - With version http://hogefuga:12345/v2/models/piyo/versions/v0.1.0/infer
 //   - Without version http://hogefuga:12345/v2/models/piyo/infer
 func (p *PowerConsumptionPredictor) Endpoint() (string, error) {
-	if p.modelVersion == "" {
-		return url.JoinPath(p.address, "v2/models", p.modelName, "infer")
-	} else {
-		return url.JoinPath(p.address, "v2/models", p.modelName, "versions", p.modelVersion, "infer")
-	}
+	return endpoint(p.address, p.modelName, p.modelVersion)
 }
 
 func (p *PowerConsumptionPredictor) Predict(ctx context.Context, cpuUsage, inletTemp, deltaP float64) (watt float64, err error) {
-	url, err := p.Endpoint()
+	watts, err := p.PredictBatch(ctx, []predictor.PowerConsumptionInput{{CPUUsage: cpuUsage, InletTemp: inletTemp, DeltaP: deltaP}})
 	if err != nil {
-		return 0.0, fmt.Errorf("unable to get endpoint URL: %w", err)
+		return 0.0, err
 	}
+	return watts[0], nil
+}
 
-	body, err := json.Marshal(newInferPowerConsumptionRequest(cpuUsage, inletTemp, deltaP))
-	if err != nil {
-		return 0.0, fmt.Errorf("unable to marshal the request body=%+v err=%w", body, err)
+// PredictBatch is PredictFeatures with the 3 inputs.
+func (p *PowerConsumptionPredictor) PredictBatch(ctx context.Context, inputs []predictor.PowerConsumptionInput) (watts []float64, err error) {
+	features := make([]predictor.Features, len(inputs))
+	for i, in := range inputs {
+		features[i] = in.Features()
 	}
+	return p.PredictFeatures(ctx, features)
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(body))
-	if err != nil {
-		return 0.0, fmt.Errorf("unable to create HTTP request: %w", err)
+// PredictFeatures sends features as rows of a [N, len(schema.Features)] tensor in a single request.
+// The schema is validated against the model metadata before the first request.
+func (p *PowerConsumptionPredictor) PredictFeatures(ctx context.Context, features []predictor.Features) (watts []float64, err error) {
+	if len(features) == 0 {
+		return nil, nil
 	}
-	for i, f := range p.editorFns {
-		if err := f(ctx, req); err != nil {
-			return 0.0, fmt.Errorf("editorFns[%d] got error: %w", i, err)
-		}
-	}
-
-	resp, err := p.client.Do(req)
+	url, err := p.Endpoint()
 	if err != nil {
-		return 0.0, fmt.Errorf("unable to send HTTP request: %w", err)
+		return nil, fmt.Errorf("unable to get endpoint URL: %w", err)
 	}
-	switch resp.StatusCode {
-	case http.StatusOK:
-		var apiResp inferPowerConsumptionResponse
-		if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
-			return 0.0, fmt.Errorf("could not decode resp: %w", err)
-		}
-		if len(apiResp.Outputs) == 0 || len(apiResp.Outputs[0].Data) == 0 {
-			return 0.0, fmt.Errorf("invalid response apiResp=%+v", apiResp)
-		}
-		return apiResp.Outputs[0].Data[0], nil
-	default:
-		return 0.0, fmt.Errorf("HTTP status=%s", resp.Status)
+	if err := p.ValidateSchema(ctx); err != nil {
+		return nil, err
 	}
+	return infer(ctx, p.client, p.editorFns, url, p.schema, features)
 }
 
-// inferPowerConsumptionRequest holds a request.
-//
-// E.g.,
-//
-//	{
-//	  "inputs": [
-//	    {
-//	      "name": "predict-prob",
-//	      "shape": [ 1, 3 ],
-//	      "datatype": "FP32",
-//	      "data": [ [10, 22, 0.2 ] ]
-//	    }
-//	  ]
-//	}
-type inferPowerConsumptionRequest struct {
-	Inputs []struct {
-		Name     string      `json:"name"`
-		Shape    []int       `json:"shape"`
-		Datatype string      `json:"datatype"`
-		Data     [][]float32 `json:"data"`
-	} `json:"inputs"`
+// ValidateSchema validates the schema against the model metadata once for each endpoint, or once per backoff interval while it fails.
+func (p *PowerConsumptionPredictor) ValidateSchema(ctx context.Context) error {
+	url, err := metadataEndpoint(p.address, p.modelName, p.modelVersion)
+	if err != nil {
+		return fmt.Errorf("unable to get metadata endpoint URL: %w", err)
+	}
+	return validateOnce(url, p.schema, func() (*modelMetadata, error) {
+		return getModelMetadata(ctx, p.client, p.editorFns, url)
+	})
 }
 
 var (
+	// InferPowerConsumptionRequestInputName is the input name of DefaultSchema used by the CLI and when ModelSchema is not specified.
 	InferPowerConsumptionRequestInputName = "predict-prob"
 )
-
-func newInferPowerConsumptionRequest(cpuUsage, ambientTemp, staticPressureDiff float64) *inferPowerConsumptionRequest {
-	var (
-		name     = InferPowerConsumptionRequestInputName
-		datatype = "FP32"
-		shapeX   = 1
-		shapeY   = 3
-	)
-	return &inferPowerConsumptionRequest{
-		Inputs: []struct {
-			Name     string      `json:"name"`
-			Shape    []int       `json:"shape"`
-			Datatype string      `json:"datatype"`
-			Data     [][]float32 `json:"data"`
-		}{
-			{
-				Name:     name,
-				Shape:    []int{shapeX, shapeY},
-				Datatype: datatype,
-				Data:     [][]float32{{float32(cpuUsage), float32(ambientTemp), float32(staticPressureDiff)}},
-			},
-		},
-	}
-}
-
-// inferPowerConsumptionResponse holds a response.
-// Ignore values except outputs[*].data[]
-//
-// E.g.,
-//
-//	{
-//	  "model_name": "model1",
-//	  "model_version": "v0.1.0",
-//	  "id": "0dc429d2-bd02-404b-b624-a0fa628e451e",
-//	  "parameters": {
-//	    "content_type": null,
-//	    "headers": null
-//	  },
-//	  "outputs": [
-//	    {
-//	      "name": "predict",
-//	      "shape": [ 1, 1 ],
-//	      "datatype": "FP64",
-//	      "parameters": null,
-//	      "data": [ 94.76267448501928 ]
-//	    }
-//	  ]
-//	}
-type inferPowerConsumptionResponse struct {
-	Outputs []struct {
-		Data []float64 `json:"data"`
-	} `json:"outputs"`
-}
@@ -0,0 +1,81 @@
+package v2inferenceprotocol
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/util"
+)
+
+// ResponseTimePredictor predicts response time with a model that takes the same inputs as PowerConsumptionPredictor
+// and outputs milliseconds.
+type ResponseTimePredictor struct {
+	// address contains scheme, host and port.
+	// E.g., "http://10.0.0.1:8080"
+	address string
+	// model name.
+	modelName string
+	// model version.
+	modelVersion string
+	// schema of the model.
+	schema Schema
+
+	client    *http.Client
+	editorFns []util.RequestEditorFn
+}
+
+var _ predictor.ResponseTimePredictor = (*ResponseTimePredictor)(nil)
+
+func NewResponseTimePredictor(address, modelName, modelVersion string, schema Schema, insecureSkipVerify bool, timeout time.Duration, editorFns ...util.RequestEditorFn) *ResponseTimePredictor {
+	return &ResponseTimePredictor{
+		address:      address,
+		modelName:    modelName,
+		modelVersion: modelVersion,
+		schema:       schema,
+		client: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: insecureSkipVerify}},
+			Timeout:   timeout,
+		},
+		editorFns: editorFns,
+	}
+}
+
+// Endpoint constructs the API endpoint. See PowerConsumptionPredictor.Endpoint.
+func (p *ResponseTimePredictor) Endpoint() (string, error) {
+	return endpoint(p.address, p.modelName, p.modelVersion)
+}
+
+func (p *ResponseTimePredictor) Predict(ctx context.Context, cpuUsage, inletTemp, deltaP float64) (milliseconds float64, err error) {
+	url, err := p.Endpoint()
+	if err != nil {
+		return 0.0, fmt.Errorf("unable to get endpoint URL: %w", err)
+	}
+	if err := p.ValidateSchema(ctx); err != nil {
+		return 0.0, err
+	}
+	ms, err := infer(ctx, p.client, p.editorFns, url, p.schema, []predictor.Features{predictor.PowerConsumptionInput{CPUUsage: cpuUsage, InletTemp: inletTemp, DeltaP: deltaP}.Features()})
+	if err != nil {
+		return 0.0, err
+	}
+	return ms[0], nil
+}
+
+// ValidateSchema validates the schema against the model metadata once for each endpoint, or once per backoff interval while it fails.
+func (p *ResponseTimePredictor) ValidateSchema(ctx context.Context) error {
+	url, err := metadataEndpoint(p.address, p.modelName, p.modelVersion)
+	if err != nil {
+		return fmt.Errorf("unable to get metadata endpoint URL: %w", err)
+	}
+	return validateOnce(url, p.schema, func() (*modelMetadata, error) {
+		return getModelMetadata(ctx, p.client, p.editorFns, url)
+	})
+}
+
+var (
+	// InferResponseTimeRequestInputName is the input name of DefaultSchema used by the CLI and when ModelSchema is not specified.
+	InferResponseTimeRequestInputName = "predict-prob"
+)
@@ -0,0 +1,261 @@
+package v2inferenceprotocol
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"k8s.io/utils/lru"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
+)
+
+const (
+	DatatypeFP32 = "FP32"
+	DatatypeFP64 = "FP64"
+)
+
+// Schema describes the input and output tensors of a model.
+//
+// Each input is sent as a row of a [N, len(Features)] tensor, where each column is Feature.Metric*Feature.Scale+Feature.Offset.
+// The prediction of each row is OutputTensor[row][OutputIndex]*OutputScale+OutputOffset.
+type Schema struct {
+	InputName     string
+	InputDatatype string
+	Features      []Feature
+	// OutputName selects the output tensor. Empty means the first output.
+	OutputName   string
+	OutputIndex  int
+	OutputScale  float64
+	OutputOffset float64
+	// CheckMetadata validates the schema against the model metadata before the first request.
+	// DefaultSchema does not set it, as servers configured without ModelSchema may not serve metadata.
+	CheckMetadata bool
+}
+
+type Feature struct {
+	// Metric is a feature name of predictor.Features, e.g., predictor.FeatureCPUUsage.
+	Metric string
+	Scale  float64
+	Offset float64
+}
+
+// DefaultSchema returns the schema used before ModelSchema was configurable,
+// i.e., a FP32 [N,3] tensor named inputName with cpu_usage, inlet_temp and delta_p, and the first column of the first output.
+func DefaultSchema(inputName string) Schema {
+	return Schema{
+		InputName:     inputName,
+		InputDatatype: DatatypeFP32,
+		Features: []Feature{
+			{Metric: predictor.FeatureCPUUsage, Scale: 1},
+			{Metric: predictor.FeatureInletTemp, Scale: 1},
+			{Metric: predictor.FeatureDeltaP, Scale: 1},
+		},
+		OutputScale: 1,
+	}
+}
+
+// Validate checks the schema itself. See validateMetadata for checks against the model.
+func (s Schema) Validate() error {
+	if s.InputName == "" {
+		return fmt.Errorf("input name is empty")
+	}
+	if s.InputDatatype != DatatypeFP32 && s.InputDatatype != DatatypeFP64 {
+		return fmt.Errorf("unsupported input datatype %q (must be %s or %s)", s.InputDatatype, DatatypeFP32, DatatypeFP64)
+	}
+	if len(s.Features) == 0 {
+		return fmt.Errorf("no features")
+	}
+	for i, f := range s.Features {
+		if f.Metric == "" {
+			return fmt.Errorf("features[%d] has empty metric", i)
+		}
+	}
+	if s.OutputIndex < 0 {
+		return fmt.Errorf("output index %d is negative", s.OutputIndex)
+	}
+	return nil
+}
+
+// row returns the normalized values of the schema's features. Features not in the schema are ignored.
+func (s Schema) row(features predictor.Features) ([]float64, error) {
+	row := make([]float64, len(s.Features))
+	for i, f := range s.Features {
+		v, ok := features[f.Metric]
+		if !ok {
+			return nil, fmt.Errorf("feature %q is not supplied", f.Metric)
+		}
+		row[i] = v*f.Scale + f.Offset
+	}
+	return row, nil
+}
+
+// outputTensor is an output tensor decoded from a REST or gRPC response.
+type outputTensor struct {
+	name   string
+	shape  []int64
+	values []float64
+}
+
+// predictions selects the output tensor and returns a value for each of n rows.
+func (s Schema) predictions(outputs []outputTensor, n int) ([]float64, error) {
+	if len(outputs) == 0 {
+		return nil, fmt.Errorf("invalid response: no outputs")
+	}
+	out := outputs[0]
+	if s.OutputName != "" {
+		i := slices.IndexFunc(outputs, func(o outputTensor) bool { return o.name == s.OutputName })
+		if i < 0 {
+			return nil, fmt.Errorf("invalid response: no output named %q", s.OutputName)
+		}
+		out = outputs[i]
+	}
+
+	// columns of the output, i.e., [N] has 1 column and [N,K] has K columns.
+	cols := 1
+	switch {
+	case len(out.shape) >= 2:
+		cols = int(out.shape[len(out.shape)-1])
+	case len(out.shape) == 0 && n > 0 && len(out.values) >= n:
+		cols = len(out.values) / n
+	}
+	if s.OutputIndex >= cols {
+		return nil, fmt.Errorf("invalid response: output index %d is out of %d columns", s.OutputIndex, cols)
+	}
+	if len(out.values) < n*cols {
+		return nil, fmt.Errorf("invalid response: got %d values for %d inputs", len(out.values), n)
+	}
+	vs := make([]float64, n)
+	for i := range vs {
+		vs[i] = out.values[i*cols+s.OutputIndex]*s.OutputScale + s.OutputOffset
+	}
+	return vs, nil
+}
+
+// modelMetadata holds a model metadata response, i.e., GET /v2/models/{name}[/versions/{version}].
+//
+// E.g.,
+//
+//	{
+//	  "name": "model1",
+//	  "versions": [ "v0.1.0" ],
+//	  "platform": "sklearn",
+//	  "inputs": [ { "name": "predict-prob", "datatype": "FP32", "shape": [ -1, 3 ] } ],
+//	  "outputs": [ { "name": "predict", "datatype": "FP64", "shape": [ -1, 1 ] } ]
+//	}
+type modelMetadata struct {
+	Inputs  []tensorMetadata `json:"inputs"`
+	Outputs []tensorMetadata `json:"outputs"`
+}
+
+type tensorMetadata struct {
+	Name     string  `json:"name"`
+	Datatype string  `json:"datatype"`
+	Shape    []int64 `json:"shape"`
+}
+
+// validateMetadata checks the schema against the model metadata. Dimensions of -1 are variable and always match.
+func (s Schema) validateMetadata(md *modelMetadata) error {
+	i := slices.IndexFunc(md.Inputs, func(t tensorMetadata) bool { return t.Name == s.InputName })
+	if i < 0 {
+		return fmt.Errorf("model has no input named %q", s.InputName)
+	}
+	in := md.Inputs[i]
+	if in.Datatype != "" && in.Datatype != s.InputDatatype {
+		return fmt.Errorf("input %q has datatype %s, but schema has %s", in.Name, in.Datatype, s.InputDatatype)
+	}
+	if d := lastDim(in.Shape); d >= 0 && d != int64(len(s.Features)) {
+		return fmt.Errorf("input %q has %d columns, but schema has %d features", in.Name, d, len(s.Features))
+	}
+
+	if len(md.Outputs) == 0 {
+		// Some servers do not report outputs.
+		return nil
+	}
+	out := md.Outputs[0]
+	if s.OutputName != "" {
+		i := slices.IndexFunc(md.Outputs, func(t tensorMetadata) bool { return t.Name == s.OutputName })
+		if i < 0 {
+			return fmt.Errorf("model has no output named %q", s.OutputName)
+		}
+		out = md.Outputs[i]
+	}
+	if len(out.Shape) >= 2 {
+		if d := lastDim(out.Shape); d >= 0 && int64(s.OutputIndex) >= d {
+			return fmt.Errorf("output %q has %d columns, but schema has output index %d", out.Name, d, s.OutputIndex)
+		}
+	} else if s.OutputIndex > 0 {
+		return fmt.Errorf("output %q has shape %v, but schema has output index %d", out.Name, out.Shape, s.OutputIndex)
+	}
+	return nil
+}
+
+// lastDim returns the last dimension of the shape, or -1 if unknown.
+func lastDim(shape []int64) int64 {
+	if len(shape) == 0 {
+		return -1
+	}
+	return shape[len(shape)-1]
+}
+
+const (
+	// MetadataRetryInitialInterval is the interval before the metadata of a model that failed validation is fetched again.
+	// It doubles on each failure up to MetadataRetryMaxInterval.
+	MetadataRetryInitialInterval = 10 * time.Second
+	MetadataRetryMaxInterval     = 5 * time.Minute
+)
+
+// validationResult is the result of validateOnce. A failed result is returned until retryAt.
+type validationResult struct {
+	err     error
+	retryAt time.Time
+	backoff time.Duration
+}
+
+// maxValidatedSchemas is the max number of endpoints and schemas validateOnce keeps results for.
+const maxValidatedSchemas = 1024
+
+// validatedSchemas holds a *validationResult for each model endpoint and schema, up to maxValidatedSchemas.
+// Predictors are created for each prediction, so the metadata is fetched only once for each endpoint and schema,
+// or once per backoff interval while the validation fails.
+var validatedSchemas = lru.New(maxValidatedSchemas)
+
+// validateOnce validates the schema against the metadata returned by getMetadata, unless it has been validated for the endpoint.
+// It does nothing if s.CheckMetadata is false.
+func validateOnce(endpoint string, s Schema, getMetadata func() (*modelMetadata, error)) error {
+	if !s.CheckMetadata {
+		return nil
+	}
+	key := fmt.Sprintf("%s %+v", endpoint, s)
+	var backoff time.Duration
+	if v, ok := validatedSchemas.Get(key); ok {
+		r := v.(*validationResult)
+		if r.err == nil || time.Now().Before(r.retryAt) {
+			return r.err
+		}
+		backoff = r.backoff
+	}
+
+	err := validate(s, getMetadata)
+	r := &validationResult{err: err}
+	if err != nil {
+		r.backoff = min(max(2*backoff, MetadataRetryInitialInterval), MetadataRetryMaxInterval)
+		r.retryAt = time.Now().Add(r.backoff)
+	}
+	validatedSchemas.Add(key, r)
+	return err
+}
+
+func validate(s Schema, getMetadata func() (*modelMetadata, error)) error {
+	if err := s.Validate(); err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+	md, err := getMetadata()
+	if err != nil {
+		return fmt.Errorf("unable to get model metadata: %w", err)
+	}
+	if err := s.validateMetadata(md); err != nil {
+		return fmt.Errorf("schema does not match the model: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package v2inferenceprotocol
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
+)
+
+func TestSchema_PredictBatch(t *testing.T) {
+	var metadataRequests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			metadataRequests++
+			w.Write([]byte(`{
+  "inputs": [{"name": "x", "datatype": "FP64", "shape": [-1, 2]}],
+  "outputs": [{"name": "proba", "shape": [-1, 1]}, {"name": "y", "datatype": "FP64", "shape": [-1, 2]}]
+}`))
+			return
+		}
+		var req testInferRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		in := req.Inputs[0]
+		if in.Name != "x" || in.Datatype != "FP64" || !slices.Equal(in.Shape, []int{len(in.Data), 2}) {
+			http.Error(w, "unexpected input", http.StatusBadRequest)
+			return
+		}
+		// y[i] = [0, x[i][0]+x[i][1]]
+		resp := inferResponse{Outputs: []inferResponseOutput{{Name: "proba", Shape: []int64{int64(len(in.Data)), 1}}, {Name: "y", Shape: []int64{int64(len(in.Data)), 2}}}}
+		for _, row := range in.Data {
+			resp.Outputs[0].Data = append(resp.Outputs[0].Data, -1)
+			resp.Outputs[1].Data = append(resp.Outputs[1].Data, 0, row[0]+row[1])
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	schema := Schema{
+		InputName:     "x",
+		InputDatatype: DatatypeFP64,
+		Features: []Feature{
+			{Metric: predictor.FeatureDeltaP, Scale: 10},
+			{Metric: predictor.FeatureCPUUsage, Scale: 0.01, Offset: 1},
+		},
+		OutputName:    "y",
+		OutputIndex:   1,
+		OutputScale:   100,
+		OutputOffset:  5,
+		CheckMetadata: true,
+	}
+	p := NewPowerConsumptionPredictor(srv.URL, "custom", "", schema, false, time.Second)
+	for range 2 {
+		// (deltaP*10 + cpuUsage*0.01+1) * 100 + 5
+		got, err := p.PredictBatch(context.Background(), []predictor.PowerConsumptionInput{{CPUUsage: 50, DeltaP: 0.5}, {CPUUsage: 100, DeltaP: 1}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, []float64{655, 1205}) {
+			t.Errorf("PredictBatch() = %v, want [655 1205]", got)
+		}
+	}
+	if metadataRequests != 1 {
+		t.Errorf("got %d metadata requests, want 1", metadataRequests)
+	}
+}
+
+func TestValidateOnce(t *testing.T) {
+	var metadataRequests int
+	getMetadata := func() (*modelMetadata, error) {
+		metadataRequests++
+		return nil, fmt.Errorf("not found")
+	}
+
+	// DefaultSchema does not check the metadata
+	if err := validateOnce("http://example.com/v2/models/default", DefaultSchema("predict-prob"), getMetadata); err != nil {
+		t.Errorf("validateOnce() of DefaultSchema error = %v", err)
+	}
+	if metadataRequests != 0 {
+		t.Errorf("got %d metadata requests of DefaultSchema, want 0", metadataRequests)
+	}
+
+	// a failed result is cached until the backoff expires
+	s := DefaultSchema("predict-prob")
+	s.CheckMetadata = true
+	for range 3 {
+		if err := validateOnce("http://example.com/v2/models/failed", s, getMetadata); err == nil {
+			t.Error("validateOnce() error = nil")
+		}
+	}
+	if metadataRequests != 1 {
+		t.Errorf("got %d metadata requests, want 1", metadataRequests)
+	}
+}
+
+func TestSchema_validateMetadata(t *testing.T) {
+	md := &modelMetadata{
+		Inputs:  []tensorMetadata{{Name: "predict-prob", Datatype: "FP32", Shape: []int64{-1, 3}}},
+		Outputs: []tensorMetadata{{Name: "predict", Datatype: "FP64", Shape: []int64{-1, 2}}},
+	}
+	tests := []struct {
+		name    string
+		modify  func(s *Schema)
+		wantErr bool
+	}{
+		{name: "default", modify: func(s *Schema) {}},
+		{name: "output_index", modify: func(s *Schema) { s.OutputName = "predict"; s.OutputIndex = 1 }},
+		{name: "wrong_input_name", modify: func(s *Schema) { s.InputName = "x" }, wantErr: true},
+		{name: "wrong_datatype", modify: func(s *Schema) { s.InputDatatype = DatatypeFP64 }, wantErr: true},
+		{name: "wrong_features", modify: func(s *Schema) { s.Features = s.Features[:2] }, wantErr: true},
+		{name: "wrong_output_name", modify: func(s *Schema) { s.OutputName = "y" }, wantErr: true},
+		{name: "output_index_out_of_range", modify: func(s *Schema) { s.OutputIndex = 2 }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := DefaultSchema("predict-prob")
+			tt.modify(&s)
+			if err := s.validateMetadata(md); (err != nil) != tt.wantErr {
+				t.Errorf("validateMetadata() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package provider
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"math"
 	"net/http"
 	"time"
@@ -17,7 +18,6 @@ import (
 	"k8s.io/client-go/dynamic"
 	"k8s.io/metrics/pkg/apis/custom_metrics"
 	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
-	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider/defaults"
 	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider/helpers"
 
 	waometrics "github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
@@ -28,25 +28,37 @@ var (
 )
 
 type Provider struct {
-	defaults.DefaultCustomMetricsProvider
 	// defaults.DefaultExternalMetricsProvider
 
 	client dynamic.Interface
 	mapper apimeta.RESTMapper
 
 	metricsStore *waometrics.Store
+
+	opts Options
+}
+
+type Options struct {
+	// PartialList makes GetMetricBySelector skip objects that have no fresh (or no matching) data
+	// instead of failing the whole list.
+	PartialList bool
 }
 
+const (
+	DefaultPartialList = true
+)
+
 var (
 	_ provider.CustomMetricsProvider = (*Provider)(nil)
 	// _ provider.ExternalMetricsProvider = (*Provider)(nil)
 )
 
-func New(client dynamic.Interface, mapper apimeta.RESTMapper, metricStore *waometrics.Store) *Provider {
+func New(client dynamic.Interface, mapper apimeta.RESTMapper, metricStore *waometrics.Store, opts Options) *Provider {
 	return &Provider{
 		client:       client,
 		mapper:       mapper,
 		metricsStore: metricStore,
+		opts:         opts,
 	}
 }
 
@@ -90,22 +102,31 @@ func fixedScale(r float64, precision int32) (n int64, scale int32) {
 	return
 }
 
-func metricValueMilli(objRef custom_metrics.ObjectReference, t time.Time, key string, value int64) *custom_metrics.MetricValue {
+// metricIdentifier constructs a MetricIdentifier. Labels are exposed as the selector of the metric.
+func metricIdentifier(key string, metricLabels map[string]string) custom_metrics.MetricIdentifier {
+	id := custom_metrics.MetricIdentifier{Name: key}
+	if len(metricLabels) > 0 {
+		id.Selector = &metav1.LabelSelector{MatchLabels: metricLabels}
+	}
+	return id
+}
+
+func metricValueMilli(objRef custom_metrics.ObjectReference, t time.Time, key string, metricLabels map[string]string, value int64) *custom_metrics.MetricValue {
 	var window int64 = 0
 	return &custom_metrics.MetricValue{
 		DescribedObject: objRef,
-		Metric:          custom_metrics.MetricIdentifier{Name: key},
+		Metric:          metricIdentifier(key, metricLabels),
 		Timestamp:       metav1.Time{Time: t},
 		WindowSeconds:   &window,
 		Value:           *resource.NewMilliQuantity(value, resource.DecimalSI),
 	}
 }
 
-func metricValueScale(objRef custom_metrics.ObjectReference, t time.Time, key string, value int64, scale int32) *custom_metrics.MetricValue {
+func metricValueScale(objRef custom_metrics.ObjectReference, t time.Time, key string, metricLabels map[string]string, value int64, scale int32) *custom_metrics.MetricValue {
 	var window int64 = 0
 	return &custom_metrics.MetricValue{
 		DescribedObject: objRef,
-		Metric:          custom_metrics.MetricIdentifier{Name: key},
+		Metric:          metricIdentifier(key, metricLabels),
 		Timestamp:       metav1.Time{Time: t},
 		WindowSeconds:   &window,
 		Value:           *resource.NewScaledQuantity(value, resource.Scale(scale)),
@@ -113,8 +134,9 @@ func metricValueScale(objRef custom_metrics.ObjectReference, t time.Time, key st
 }
 
 // metricFor constructs a result for a single metric value.
+// Values whose labels do not match metricSelector are treated as not found.
 // The `error` return value is ensured to be in the metav1.Status format.
-func (p *Provider) metricFor(namespace, name string, info provider.CustomMetricInfo) (*custom_metrics.MetricValue, error) {
+func (p *Provider) metricFor(namespace, name string, info provider.CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValue, error) {
 	// get value
 	info, err := p.validateResource(namespace, name, info)
 	if err != nil {
@@ -126,18 +148,16 @@ func (p *Provider) metricFor(namespace, name string, info provider.CustomMetricI
 		return nil, provider.NewMetricNotFoundForError(info.GroupResource, info.Metric, types.NamespacedName{Namespace: namespace, Name: name}.String())
 	}
 
-	// check timestamp
-	switch info.Metric {
-	case waometrics.ValueInletTemperature:
-		if m.InletTempTimestamp.Add(MetricTTL).Before(time.Now()) {
-			return nil, newMetricExpiredForError(info.GroupResource, info.Metric, types.NamespacedName{Namespace: namespace, Name: name}.String())
-		}
-	case waometrics.ValueDeltaPressure:
-		if m.DeltaPressureTimestamp.Add(MetricTTL).Before(time.Now()) {
-			return nil, newMetricExpiredForError(info.GroupResource, info.Metric, types.NamespacedName{Namespace: namespace, Name: name}.String())
-		}
-	default:
-		return nil, apierr.NewInternalError(fmt.Errorf("metric not supported: name=%s", info.Metric))
+	// check timestamp and labels
+	value, timestamp, metricLabels, ok := m.Value(waometrics.ValueType(info.Metric))
+	if !ok {
+		return nil, provider.NewMetricNotFoundForError(info.GroupResource, info.Metric, types.NamespacedName{Namespace: namespace, Name: name}.String())
+	}
+	if timestamp.Add(MetricTTL).Before(time.Now()) {
+		return nil, newMetricExpiredForError(info.GroupResource, info.Metric, types.NamespacedName{Namespace: namespace, Name: name}.String())
+	}
+	if metricSelector != nil && !metricSelector.Matches(labels.Set(metricLabels)) {
+		return nil, newMetricNotMatchedForError(info.GroupResource, info.Metric, types.NamespacedName{Namespace: namespace, Name: name}.String(), metricSelector)
 	}
 
 	// construct result
@@ -145,39 +165,61 @@ func (p *Provider) metricFor(namespace, name string, info provider.CustomMetricI
 	if err != nil {
 		return nil, apierr.NewInternalError(err)
 	}
-	switch info.Metric {
-	case waometrics.ValueInletTemperature:
-		v, s := fixedScale(m.InletTemp, 6)
-		return metricValueScale(objRef, time.Now(), info.Metric, v, s), nil
-	case waometrics.ValueDeltaPressure:
-		v, s := fixedScale(m.DeltaPressure, 6)
-		return metricValueScale(objRef, time.Now(), info.Metric, v, s), nil
-	default:
-		return nil, apierr.NewInternalError(fmt.Errorf("metric not supported: name=%s", info.Metric))
-	}
+	v, s := fixedScale(value, 6)
+	return metricValueScale(objRef, time.Now(), info.Metric, metricLabels, v, s), nil
+}
+
+// ListAllMetrics implements CustomMetricsProvider interface.
+// It returns GroupResource and metric pairs that currently have fresh data in the Store.
+func (p *Provider) ListAllMetrics() []provider.CustomMetricInfo {
+	seen := map[provider.CustomMetricInfo]struct{}{}
+	var infos []provider.CustomMetricInfo
+	p.metricsStore.Range(func(namespace string, gr schema.GroupResource, _ string, m waometrics.MetricData) bool {
+		for _, vt := range waometrics.ValueTypes {
+			_, timestamp, _, ok := m.Value(vt)
+			if !ok || timestamp.Add(MetricTTL).Before(time.Now()) {
+				continue
+			}
+			info := provider.CustomMetricInfo{GroupResource: gr, Namespaced: namespace != "", Metric: string(vt)}
+			if _, ok := seen[info]; ok {
+				continue
+			}
+			seen[info] = struct{}{}
+			infos = append(infos, info)
+		}
+		return true
+	})
+	return infos
 }
 
 // GetMetricByName implements CustomMetricsProvider interface.
 // The `error` return value is ensured to be in the metav1.Status format.
-func (p *Provider) GetMetricByName(ctx context.Context, name types.NamespacedName, info provider.CustomMetricInfo, _ labels.Selector) (*custom_metrics.MetricValue, error) {
-	return p.metricFor(name.Namespace, name.Name, info)
+func (p *Provider) GetMetricByName(ctx context.Context, name types.NamespacedName, info provider.CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValue, error) {
+	return p.metricFor(name.Namespace, name.Name, info, metricSelector)
 }
 
 // GetMetricBySelector implements CustomMetricsProvider interface.
+// If Options.PartialList is set, objects without fresh data are skipped instead of failing the whole list.
 // The `error` return value is ensured to be in the metav1.Status format.
-func (p *Provider) GetMetricBySelector(ctx context.Context, namespace string, selector labels.Selector, info provider.CustomMetricInfo, _ labels.Selector) (*custom_metrics.MetricValueList, error) {
+func (p *Provider) GetMetricBySelector(ctx context.Context, namespace string, selector labels.Selector, info provider.CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValueList, error) {
+	lg := slog.With("func", "Provider.GetMetricBySelector", "namespace", namespace, "selector", selector, "metric", info.Metric, "metricSelector", metricSelector)
+
 	names, err := helpers.ListObjectNames(p.mapper, p.client, namespace, selector, info)
 	if err != nil {
 		return nil, apierr.NewInternalError(fmt.Errorf("failed to list objects: %w", err))
 	}
 
-	res := make([]custom_metrics.MetricValue, len(names))
-	for i, name := range names {
-		value, err := p.metricFor(namespace, name, info)
+	res := make([]custom_metrics.MetricValue, 0, len(names))
+	for _, name := range names {
+		value, err := p.metricFor(namespace, name, info, metricSelector)
 		if err != nil {
+			if p.opts.PartialList && apierr.IsNotFound(err) {
+				lg.Debug("skip object as no data available", "name", name, "err", err)
+				continue
+			}
 			return nil, err
 		}
-		res[i] = *value
+		res = append(res, *value)
 	}
 
 	return &custom_metrics.MetricValueList{
@@ -194,3 +236,13 @@ func newMetricExpiredForError(resource schema.GroupResource, metricName string,
 		Message: fmt.Sprintf("metric %s for %s %s is expired", metricName, resource.String(), resourceName),
 	}}
 }
+
+// newMetricNotMatchedForError returns a StatusError, specialized for the case where a metric does not match the metric selector.
+func newMetricNotMatchedForError(resource schema.GroupResource, metricName string, resourceName string, metricSelector labels.Selector) *apierr.StatusError {
+	return &apierr.StatusError{ErrStatus: metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    int32(http.StatusNotFound),
+		Reason:  metav1.StatusReasonNotFound,
+		Message: fmt.Sprintf("metric %s for %s %s does not match selector %q", metricName, resource.String(), resourceName, metricSelector.String()),
+	}}
+}
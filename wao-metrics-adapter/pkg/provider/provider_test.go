@@ -0,0 +1,162 @@
+package provider
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+
+	waometrics "github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+)
+
+func testMapper() apimeta.RESTMapper {
+	m := apimeta.NewDefaultRESTMapper([]schema.GroupVersion{{Group: "", Version: "v1"}})
+	m.Add(schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Node"}, apimeta.RESTScopeRoot)
+	return m
+}
+
+func testStore() *waometrics.Store {
+	s := &waometrics.Store{}
+	s.Set(waometrics.StoreKeyForNode("node-0"), waometrics.MetricData{
+		InletTemp:              20.5,
+		InletTempTimestamp:     time.Now(),
+		InletTempLabels:        map[string]string{waometrics.LabelSource: "redfish"},
+		DeltaPressure:          7.5,
+		DeltaPressureTimestamp: time.Now(),
+		DeltaPressureLabels:    map[string]string{waometrics.LabelSource: "dpapi", waometrics.LabelSensor: "101037B"},
+	})
+	s.Set(waometrics.StoreKeyForNode("node-1"), waometrics.MetricData{
+		InletTemp:              21.5,
+		InletTempTimestamp:     time.Now().Add(-2 * MetricTTL), // expired
+		InletTempLabels:        map[string]string{waometrics.LabelSource: "redfish"},
+		DeltaPressure:          8.5,
+		DeltaPressureTimestamp: time.Now(),
+		DeltaPressureLabels:    map[string]string{waometrics.LabelSource: "fake"},
+	})
+	return s
+}
+
+func TestProvider_GetMetricByName(t *testing.T) {
+	tests := []struct {
+		name           string
+		node           string
+		metric         string
+		metricSelector string
+		want           float64
+		wantErr        bool
+	}{
+		{"inlet_temp", "node-0", waometrics.ValueInletTemperature, "", 20.5, false},
+		{"delta_p", "node-0", waometrics.ValueDeltaPressure, "", 7.5, false},
+		{"match", "node-0", waometrics.ValueInletTemperature, "source=redfish", 20.5, false},
+		{"match_multi", "node-0", waometrics.ValueDeltaPressure, "source=dpapi,sensor=101037B", 7.5, false},
+		{"not_match", "node-0", waometrics.ValueInletTemperature, "source=dpapi", 0, true},
+		{"expired", "node-1", waometrics.ValueInletTemperature, "", 0, true},
+		{"not_found", "node-2", waometrics.ValueInletTemperature, "", 0, true},
+		{"unknown_metric", "node-0", "unknown", "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(nil, testMapper(), testStore(), Options{})
+			sel, err := labels.Parse(tt.metricSelector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			info := provider.CustomMetricInfo{GroupResource: schema.GroupResource{Resource: "nodes"}, Metric: tt.metric}
+			got, err := p.GetMetricByName(context.Background(), types.NamespacedName{Name: tt.node}, info, sel)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetMetricByName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if v := got.Value.AsApproximateFloat64(); v != tt.want {
+				t.Errorf("GetMetricByName() = %v, want %v", v, tt.want)
+			}
+		})
+	}
+}
+
+func TestProvider_ListAllMetrics(t *testing.T) {
+	p := New(nil, testMapper(), testStore(), Options{})
+	got := p.ListAllMetrics()
+	sort.Slice(got, func(i, j int) bool { return got[i].Metric < got[j].Metric })
+	want := []provider.CustomMetricInfo{
+		{GroupResource: schema.GroupResource{Resource: "nodes"}, Metric: waometrics.ValueDeltaPressure},
+		{GroupResource: schema.GroupResource{Resource: "nodes"}, Metric: waometrics.ValueInletTemperature},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListAllMetrics() = %v, want %v", got, want)
+	}
+
+	p = New(nil, testMapper(), &waometrics.Store{}, Options{})
+	if got := p.ListAllMetrics(); len(got) != 0 {
+		t.Errorf("ListAllMetrics() = %v, want empty", got)
+	}
+}
+
+func TestProvider_GetMetricBySelector(t *testing.T) {
+	scheme := runtime.NewScheme()
+	corev1.AddToScheme(scheme)
+	var nodes []runtime.Object
+	for _, name := range []string{"node-0", "node-1", "node-2"} { // node-2 is not in the Store
+		nodes = append(nodes, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"rack": "r0"}}})
+	}
+	client := dynamicfake.NewSimpleDynamicClient(scheme, nodes...)
+
+	tests := []struct {
+		name           string
+		metric         string
+		selector       string
+		metricSelector string
+		partialList    bool
+		want           []string
+		wantErr        bool
+	}{
+		{"partial_missing_and_expired", waometrics.ValueInletTemperature, "", "", true, []string{"node-0"}, false},
+		{"partial_missing", waometrics.ValueDeltaPressure, "rack=r0", "", true, []string{"node-0", "node-1"}, false},
+		{"partial_not_match", waometrics.ValueDeltaPressure, "", "source=fake", true, []string{"node-1"}, false},
+		{"partial_none", waometrics.ValueInletTemperature, "", "source=dpapi", true, []string{}, false},
+		{"no_nodes", waometrics.ValueInletTemperature, "rack=r1", "", true, []string{}, false},
+		{"not_partial", waometrics.ValueDeltaPressure, "", "", false, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(client, testMapper(), testStore(), Options{PartialList: tt.partialList})
+			sel, err := labels.Parse(tt.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			metricSel, err := labels.Parse(tt.metricSelector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			info := provider.CustomMetricInfo{GroupResource: schema.GroupResource{Resource: "nodes"}, Metric: tt.metric}
+			got, err := p.GetMetricBySelector(context.Background(), "", sel, info, metricSel)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetMetricBySelector() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			names := []string{}
+			for _, v := range got.Items {
+				names = append(names, v.DescribedObject.Name)
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("GetMetricBySelector() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
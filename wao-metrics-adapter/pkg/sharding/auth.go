@@ -0,0 +1,140 @@
+package sharding
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// DefaultServiceAccountTokenFile is the path of the ServiceAccount token mounted in the pod.
+	DefaultServiceAccountTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+	// peerAuthCacheTTL is the duration a reviewed peer token is trusted without another TokenReview.
+	peerAuthCacheTTL = time.Minute
+)
+
+// PeerAuthenticator authenticates requests between replicas with ServiceAccount tokens.
+//
+// Requests carry the token of the sender as a bearer token, and the receiver verifies it with a TokenReview.
+// Only peers authenticated as the same user as this replica (i.e., running with the same ServiceAccount) are allowed.
+type PeerAuthenticator struct {
+	client    kubernetes.Interface
+	tokenFile string
+
+	mu sync.Mutex
+	// user is the username of this replica, resolved on the first authentication.
+	user string
+	// reviewed holds the expiry of each accepted token by its hash.
+	reviewed map[[sha256.Size]byte]time.Time
+}
+
+// NewPeerAuthenticator inits the authenticator. tokenFile defaults to DefaultServiceAccountTokenFile.
+func NewPeerAuthenticator(client kubernetes.Interface, tokenFile string) *PeerAuthenticator {
+	if tokenFile == "" {
+		tokenFile = DefaultServiceAccountTokenFile
+	}
+	return &PeerAuthenticator{
+		client:    client,
+		tokenFile: tokenFile,
+		reviewed:  map[[sha256.Size]byte]time.Time{},
+	}
+}
+
+// Token returns the token of this replica. It is read on each call as kubelet rotates it.
+func (a *PeerAuthenticator) Token() (string, error) {
+	b, err := os.ReadFile(a.tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("unable to read token: %w", err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// Authenticate returns nil if the token belongs to the same user as this replica.
+// Thread-safe.
+func (a *PeerAuthenticator) Authenticate(ctx context.Context, token string) error {
+	if token == "" {
+		return fmt.Errorf("no token")
+	}
+	h := sha256.Sum256([]byte(token))
+	now := time.Now()
+
+	a.mu.Lock()
+	expiry, ok := a.reviewed[h]
+	user := a.user
+	a.mu.Unlock()
+	if ok && now.Before(expiry) {
+		return nil
+	}
+
+	if user == "" {
+		own, err := a.Token()
+		if err != nil {
+			return err
+		}
+		if user, err = a.review(ctx, own); err != nil {
+			return fmt.Errorf("unable to review own token: %w", err)
+		}
+		a.mu.Lock()
+		a.user = user
+		a.mu.Unlock()
+	}
+	peer, err := a.review(ctx, token)
+	if err != nil {
+		return err
+	}
+	if peer != user {
+		return fmt.Errorf("user %q is not allowed", peer)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for k, expiry := range a.reviewed {
+		if !now.Before(expiry) {
+			delete(a.reviewed, k)
+		}
+	}
+	a.reviewed[h] = now.Add(peerAuthCacheTTL)
+	return nil
+}
+
+// review returns the username of the token.
+func (a *PeerAuthenticator) review(ctx context.Context, token string) (string, error) {
+	tr, err := a.client.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("unable to create TokenReview: %w", err)
+	}
+	if !tr.Status.Authenticated {
+		return "", fmt.Errorf("token is not authenticated: %s", tr.Status.Error)
+	}
+	return tr.Status.User.Username, nil
+}
+
+// Wrap returns a http.Handler that calls h only for authenticated peers.
+func (a *PeerAuthenticator) Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if err := a.Authenticate(r.Context(), token); err != nil {
+			slog.Warn("unable to authenticate peer", "func", "PeerAuthenticator.Wrap", "remoteAddr", r.RemoteAddr, "err", err)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
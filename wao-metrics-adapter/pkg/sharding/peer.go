@@ -0,0 +1,114 @@
+package sharding
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+)
+
+// PeerStorePath is the path of the peer endpoint that serves the metrics.Store.
+const PeerStorePath = "/peer/v1/store"
+
+const DefaultPeerSyncInterval = 5 * time.Second
+
+// NewPeerHandler returns a http.Handler that serves the entries of the given Store as JSON to peers authenticated by auth.
+func NewPeerHandler(store *metrics.Store, auth *PeerAuthenticator) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+PeerStorePath, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(store.Entries()); err != nil {
+			slog.Error("unable to encode store entries", "func", "PeerHandler", "err", err)
+		}
+	})
+	return auth.Wrap(mux)
+}
+
+// PeerSyncer periodically pulls values collected by peers and merges them into the local Store,
+// so that any replica can answer custom metrics queries.
+type PeerSyncer struct {
+	sharder  *Sharder
+	store    *metrics.Store
+	interval time.Duration
+	auth     *PeerAuthenticator
+
+	client *http.Client
+}
+
+func NewPeerSyncer(sharder *Sharder, store *metrics.Store, interval time.Duration, auth *PeerAuthenticator) *PeerSyncer {
+	return &PeerSyncer{
+		sharder:  sharder,
+		store:    store,
+		interval: interval,
+		auth:     auth,
+		client:   &http.Client{Timeout: interval},
+	}
+}
+
+// Run syncs periodically until ctx is done.
+func (p *PeerSyncer) Run(ctx context.Context) {
+	lg := slog.With("func", "PeerSyncer.Run")
+
+	t := time.NewTicker(p.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			lg.Info("stopped")
+			return
+		case <-t.C:
+			p.Sync(ctx)
+		}
+	}
+}
+
+// Sync pulls values from the current peers once.
+func (p *PeerSyncer) Sync(ctx context.Context) {
+	lg := slog.With("func", "PeerSyncer.Sync")
+
+	for _, peer := range p.sharder.Peers() {
+		if err := p.pull(ctx, peer); err != nil {
+			lg.Error("unable to pull from peer", "peer", peer.Identity, "address", peer.Address, "err", err)
+		}
+	}
+}
+
+func (p *PeerSyncer) pull(ctx context.Context, peer Member) error {
+	if peer.Address == "" {
+		return fmt.Errorf("peer address is empty")
+	}
+	u, err := url.JoinPath(peer.Address, PeerStorePath)
+	if err != nil {
+		return fmt.Errorf("could not build URL: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("unable to create HTTP request: %w", err)
+	}
+	token, err := p.auth.Token()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to send HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var entries []metrics.StoreEntry
+		if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+			return fmt.Errorf("could not decode resp: %w", err)
+		}
+		p.store.Merge(entries)
+		return nil
+	default:
+		return fmt.Errorf("HTTP status=%s", resp.Status)
+	}
+}
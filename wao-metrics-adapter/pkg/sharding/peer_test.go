@@ -0,0 +1,131 @@
+package sharding
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/metrics"
+)
+
+// newTestAuthenticator returns a PeerAuthenticator whose own token is token-self,
+// and a TokenReview reactor that authenticates token-self and token-peer as the adapter and token-other as another user.
+func newTestAuthenticator(t *testing.T, client *fake.Clientset) *PeerAuthenticator {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("token-self\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		tr := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview).DeepCopy()
+		switch tr.Spec.Token {
+		case "token-self", "token-peer":
+			tr.Status = authenticationv1.TokenReviewStatus{Authenticated: true, User: authenticationv1.UserInfo{Username: "system:serviceaccount:custom-metrics:wao-metrics-adapter"}}
+		case "token-other":
+			tr.Status = authenticationv1.TokenReviewStatus{Authenticated: true, User: authenticationv1.UserInfo{Username: "system:serviceaccount:default:default"}}
+		default:
+			tr.Status = authenticationv1.TokenReviewStatus{Error: "invalid token"}
+		}
+		return true, tr, nil
+	})
+	return NewPeerAuthenticator(client, tokenFile)
+}
+
+func countTokenReviews(client *fake.Clientset) int {
+	n := 0
+	for _, a := range client.Actions() {
+		if a.GetVerb() == "create" && a.GetResource().Resource == "tokenreviews" {
+			n++
+		}
+	}
+	return n
+}
+
+func TestPeerHandler(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	store := &metrics.Store{}
+	srv := httptest.NewServer(NewPeerHandler(store, newTestAuthenticator(t, client)))
+	defer srv.Close()
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"no_token", "", http.StatusUnauthorized},
+		{"invalid_token", "token-invalid", http.StatusUnauthorized},
+		{"other_user", "token-other", http.StatusUnauthorized},
+		{"peer", "token-peer", http.StatusOK},
+		{"peer_cached", "token-peer", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+PeerStorePath, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+	// token-self once, then token-invalid, token-other and token-peer, and the second token-peer is cached
+	if n := countTokenReviews(client); n != 4 {
+		t.Errorf("got %d TokenReviews, want 4", n)
+	}
+}
+
+func TestPeerSyncer_Sync(t *testing.T) {
+	ctx := context.Background()
+	nodeKey := metrics.StoreKeyForNode("node-0")
+	now := time.Now().Truncate(time.Second)
+
+	// the peer has a newer inlet temperature, and this replica has a newer delta pressure
+	peerStore := &metrics.Store{}
+	peerStore.Set(nodeKey, metrics.MetricData{}.
+		WithValue(metrics.ValueInletTemperature, 25, now, nil).
+		WithValue(metrics.ValueDeltaPressure, 1, now.Add(-time.Minute), nil))
+	localStore := &metrics.Store{}
+	localStore.Set(nodeKey, metrics.MetricData{}.
+		WithValue(metrics.ValueInletTemperature, 20, now.Add(-time.Minute), nil).
+		WithValue(metrics.ValueDeltaPressure, 2, now, nil))
+
+	client := fake.NewSimpleClientset()
+	auth := newTestAuthenticator(t, client)
+	srv := httptest.NewServer(NewPeerHandler(peerStore, auth))
+	defer srv.Close()
+	if _, err := client.CoordinationV1().Leases("wao-system").Create(ctx, testLease("g", "adapter-1", srv.URL, time.Now()), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	sharder := NewSharder(client, Options{Namespace: "wao-system", Group: "g", Identity: "adapter-0"})
+	if err := sharder.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	NewPeerSyncer(sharder, localStore, time.Second, auth).Sync(ctx)
+
+	m, ok := localStore.Get(nodeKey)
+	if !ok {
+		t.Fatal("no data of node-0")
+	}
+	if v, _, _, _ := m.Value(metrics.ValueInletTemperature); v != 25 {
+		t.Errorf("inlet temperature = %v, want 25 from the peer", v)
+	}
+	if v, _, _, _ := m.Value(metrics.ValueDeltaPressure); v != 2 {
+		t.Errorf("delta pressure = %v, want the newer local 2", v)
+	}
+}
@@ -0,0 +1,65 @@
+package sharding
+
+import (
+	"hash/fnv"
+	"slices"
+	"sort"
+	"strconv"
+)
+
+// DefaultVirtualNodes is the number of points each member has on the Ring.
+// More points give a more even distribution.
+const DefaultVirtualNodes = 128
+
+// Ring is a consistent hash ring.
+// Adding or removing a member only moves keys from/to that member.
+// Ring is immutable, so it is safe for concurrent use.
+type Ring struct {
+	points []uint64
+	owners map[uint64]string
+}
+
+// NewRing builds a Ring from the given members.
+func NewRing(members []string, virtualNodes int) *Ring {
+	r := &Ring{owners: map[uint64]string{}}
+	members = slices.Clone(members)
+	slices.Sort(members) // for deterministic collision handling
+	for _, m := range members {
+		for i := 0; i < virtualNodes; i++ {
+			p := hash(m + "#" + strconv.Itoa(i))
+			if _, ok := r.owners[p]; ok {
+				continue
+			}
+			r.owners[p] = m
+			r.points = append(r.points, p)
+		}
+	}
+	slices.Sort(r.points)
+	return r
+}
+
+// Owner returns the member that owns the given key, or "" if the Ring is empty.
+func (r *Ring) Owner(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	h := hash(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]]
+}
+
+// hash returns FNV-1a with the MurmurHash3 finalizer, as FNV alone spreads similar short strings poorly.
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
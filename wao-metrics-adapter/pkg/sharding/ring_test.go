@@ -0,0 +1,45 @@
+package sharding
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRing_Owner(t *testing.T) {
+	if got := NewRing(nil, DefaultVirtualNodes).Owner("wao-system/node-0"); got != "" {
+		t.Errorf("Owner() on empty ring = %q, want empty", got)
+	}
+
+	members := []string{"adapter-0", "adapter-1", "adapter-2"}
+	r := NewRing(members, DefaultVirtualNodes)
+
+	// deterministic regardless of member order
+	r2 := NewRing([]string{"adapter-2", "adapter-0", "adapter-1"}, DefaultVirtualNodes)
+	keys := make([]string, 3000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("wao-system/node-%d", i)
+	}
+	counts := map[string]int{}
+	for _, k := range keys {
+		o := r.Owner(k)
+		if o2 := r2.Owner(k); o != o2 {
+			t.Fatalf("Owner(%q) = %q and %q, want same", k, o, o2)
+		}
+		counts[o]++
+	}
+
+	// roughly even distribution
+	for _, m := range members {
+		if c := counts[m]; c < len(keys)/len(members)/2 {
+			t.Errorf("member %s owns %d keys, too few (%v)", m, c, counts)
+		}
+	}
+
+	// removing a member moves only its keys
+	r3 := NewRing([]string{"adapter-0", "adapter-1"}, DefaultVirtualNodes)
+	for _, k := range keys {
+		if o := r.Owner(k); o != "adapter-2" && r3.Owner(k) != o {
+			t.Errorf("Owner(%q) moved from %q to %q", k, o, r3.Owner(k))
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// LabelShardGroup is set on Leases of all replicas in the same group.
+	LabelShardGroup = "waok8s.github.io/shard-group"
+	// AnnotationPeerAddress holds the address of the peer endpoint of the replica. E.g., "http://10.0.0.1:8081"
+	AnnotationPeerAddress = "waok8s.github.io/peer-address"
+
+	DefaultLeaseDuration = 30 * time.Second
+	DefaultRenewInterval = 10 * time.Second
+)
+
+// Member is a replica in the shard group.
+type Member struct {
+	Identity string
+	Address  string
+}
+
+type Options struct {
+	// Namespace is the namespace to create Leases in.
+	Namespace string
+	// Group is the name of the shard group. Replicas with the same Group share objects.
+	Group string
+	// Identity is the unique name of this replica. Typically the pod name.
+	Identity string
+	// Address is the peer endpoint address of this replica.
+	Address string
+
+	LeaseDuration time.Duration
+	RenewInterval time.Duration
+}
+
+// Sharder assigns objects to replicas in the shard group.
+//
+// Each replica holds its own Lease and renews it periodically.
+// Replicas whose Leases are not expired are members, and objects are assigned to members with a consistent hash Ring.
+type Sharder struct {
+	client kubernetes.Interface
+	opts   Options
+
+	mu      sync.RWMutex
+	ring    *Ring
+	members []Member
+
+	changeCh chan struct{}
+}
+
+func NewSharder(client kubernetes.Interface, opts Options) *Sharder {
+	if opts.LeaseDuration == 0 {
+		opts.LeaseDuration = DefaultLeaseDuration
+	}
+	if opts.RenewInterval == 0 {
+		opts.RenewInterval = DefaultRenewInterval
+	}
+	return &Sharder{
+		client:   client,
+		opts:     opts,
+		ring:     NewRing(nil, DefaultVirtualNodes),
+		changeCh: make(chan struct{}, 1),
+	}
+}
+
+func (s *Sharder) leaseName() string { return fmt.Sprintf("%s-%s", s.opts.Group, s.opts.Identity) }
+
+// Owns returns true if this replica owns the given key.
+// Thread-safe.
+func (s *Sharder) Owns(key string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ring.Owner(key) == s.opts.Identity
+}
+
+// Peers returns the current members except this replica.
+// Thread-safe.
+func (s *Sharder) Peers() []Member {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var peers []Member
+	for _, m := range s.members {
+		if m.Identity != s.opts.Identity {
+			peers = append(peers, m)
+		}
+	}
+	return peers
+}
+
+// Changes returns a channel that receives a value when the members have changed.
+func (s *Sharder) Changes() <-chan struct{} { return s.changeCh }
+
+// Sync renews the Lease of this replica and refreshes the members.
+func (s *Sharder) Sync(ctx context.Context) error {
+	if err := s.renew(ctx); err != nil {
+		return fmt.Errorf("unable to renew Lease: %w", err)
+	}
+	if err := s.refresh(ctx); err != nil {
+		return fmt.Errorf("unable to refresh members: %w", err)
+	}
+	return nil
+}
+
+// Run calls Sync periodically until ctx is done, then releases the Lease of this replica
+// so that other replicas take over its objects immediately.
+func (s *Sharder) Run(ctx context.Context) {
+	lg := slog.With("func", "Sharder.Run", "identity", s.opts.Identity)
+
+	t := time.NewTicker(s.opts.RenewInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			ctx2, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := s.client.CoordinationV1().Leases(s.opts.Namespace).Delete(ctx2, s.leaseName(), metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+				lg.Error("unable to release Lease", "err", err)
+			}
+			cancel()
+			lg.Info("stopped")
+			return
+		case <-t.C:
+			if err := s.Sync(ctx); err != nil {
+				lg.Error("unable to sync", "err", err)
+			}
+		}
+	}
+}
+
+func (s *Sharder) renew(ctx context.Context) error {
+	leases := s.client.CoordinationV1().Leases(s.opts.Namespace)
+	now := metav1.NewMicroTime(time.Now())
+
+	lease, err := leases.Get(ctx, s.leaseName(), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err := leases.Create(ctx, &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        s.leaseName(),
+				Namespace:   s.opts.Namespace,
+				Labels:      map[string]string{LabelShardGroup: s.opts.Group},
+				Annotations: map[string]string{AnnotationPeerAddress: s.opts.Address},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       ptr.To(s.opts.Identity),
+				LeaseDurationSeconds: ptr.To(int32(s.opts.LeaseDuration.Seconds())),
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if lease.Annotations == nil {
+		lease.Annotations = map[string]string{}
+	}
+	lease.Annotations[AnnotationPeerAddress] = s.opts.Address
+	lease.Spec.HolderIdentity = ptr.To(s.opts.Identity)
+	lease.Spec.LeaseDurationSeconds = ptr.To(int32(s.opts.LeaseDuration.Seconds()))
+	lease.Spec.RenewTime = &now
+	_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	return err
+}
+
+func (s *Sharder) refresh(ctx context.Context) error {
+	lg := slog.With("func", "Sharder.refresh", "identity", s.opts.Identity)
+
+	leases, err := s.client.CoordinationV1().Leases(s.opts.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", LabelShardGroup, s.opts.Group),
+	})
+	if err != nil {
+		return err
+	}
+
+	var members []Member
+	for _, l := range leases.Items {
+		if l.Spec.HolderIdentity == nil || l.Spec.RenewTime == nil || l.Spec.LeaseDurationSeconds == nil {
+			continue
+		}
+		expiry := l.Spec.RenewTime.Add(time.Duration(*l.Spec.LeaseDurationSeconds) * time.Second)
+		if expiry.Before(time.Now()) {
+			continue
+		}
+		members = append(members, Member{Identity: *l.Spec.HolderIdentity, Address: l.Annotations[AnnotationPeerAddress]})
+	}
+	slices.SortFunc(members, func(a, b Member) int { return strings.Compare(a.Identity, b.Identity) })
+
+	s.mu.Lock()
+	changed := !slices.Equal(s.members, members)
+	if changed {
+		identities := make([]string, len(members))
+		for i, m := range members {
+			identities[i] = m.Identity
+		}
+		s.members = members
+		s.ring = NewRing(identities, DefaultVirtualNodes)
+	}
+	s.mu.Unlock()
+
+	if changed {
+		lg.Info("members changed", "members", members)
+		select {
+		case s.changeCh <- struct{}{}:
+		default: // a notification is already pending
+		}
+	}
+	return nil
+}
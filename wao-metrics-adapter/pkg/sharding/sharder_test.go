@@ -0,0 +1,103 @@
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+// testLease returns a Lease of a replica renewed at renewTime.
+func testLease(group, identity, address string, renewTime time.Time) *coordinationv1.Lease {
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-%s", group, identity),
+			Namespace:   "wao-system",
+			Labels:      map[string]string{LabelShardGroup: group},
+			Annotations: map[string]string{AnnotationPeerAddress: address},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       ptr.To(identity),
+			LeaseDurationSeconds: ptr.To(int32(30)),
+			RenewTime:            ptr.To(metav1.NewMicroTime(renewTime)),
+		},
+	}
+}
+
+func TestSharder_Sync(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset(
+		testLease("g", "adapter-1", "http://10.0.0.1:8081", time.Now()),
+		testLease("g", "adapter-2", "http://10.0.0.2:8081", time.Now().Add(-time.Hour)), // expired
+		testLease("other", "adapter-3", "http://10.0.0.3:8081", time.Now()),             // other group
+	)
+	s := NewSharder(client, Options{Namespace: "wao-system", Group: "g", Identity: "adapter-0", Address: "http://10.0.0.0:8081"})
+
+	// no members before Sync
+	if len(s.Peers()) != 0 || s.Owns("wao-system/node-0") {
+		t.Fatalf("got peers %v before Sync", s.Peers())
+	}
+
+	if err := s.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CoordinationV1().Leases("wao-system").Get(ctx, "g-adapter-0", metav1.GetOptions{}); err != nil {
+		t.Errorf("Lease of this replica is not created: %v", err)
+	}
+	if got, want := s.Peers(), []Member{{Identity: "adapter-1", Address: "http://10.0.0.1:8081"}}; !slices.Equal(got, want) {
+		t.Errorf("Peers() = %v, want %v", got, want)
+	}
+	select {
+	case <-s.Changes():
+	default:
+		t.Error("no change notified")
+	}
+
+	// ownership matches the ring of the live members
+	ring := NewRing([]string{"adapter-0", "adapter-1"}, DefaultVirtualNodes)
+	owned := 0
+	for i := range 100 {
+		k := fmt.Sprintf("wao-system/node-%d", i)
+		if got, want := s.Owns(k), ring.Owner(k) == "adapter-0"; got != want {
+			t.Errorf("Owns(%q) = %v, want %v", k, got, want)
+		}
+		if s.Owns(k) {
+			owned++
+		}
+	}
+	if owned == 0 || owned == 100 {
+		t.Errorf("owns %d of 100 keys, want a subset", owned)
+	}
+
+	// renewing without member changes does not notify
+	if err := s.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-s.Changes():
+		t.Error("change notified without member changes")
+	default:
+	}
+
+	// adapter-1 leaves, so this replica owns everything
+	if err := client.CoordinationV1().Leases("wao-system").Delete(ctx, "g-adapter-1", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Peers()) != 0 {
+		t.Errorf("Peers() = %v, want none", s.Peers())
+	}
+	for i := range 100 {
+		if k := fmt.Sprintf("wao-system/node-%d", i); !s.Owns(k) {
+			t.Errorf("Owns(%q) = false with a single member", k)
+		}
+	}
+}
@@ -3,7 +3,9 @@ package minimizepower
 import (
 	"context"
 	"fmt"
+	"maps"
 	"math"
+	"strings"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -32,6 +34,8 @@ type MinimizePower struct {
 
 	metricsclient   *waoclient.CachedMetricsClient
 	predictorclient *waoclient.CachedPredictorClient
+	// powercurveclient is nil unless args.PowerCurve is set.
+	powercurveclient *waoclient.PowerCurveClient
 
 	args *MinimizePowerArgs
 
@@ -114,14 +118,51 @@ func New(_ context.Context, obj runtime.Object, fh framework.Handle) (framework.
 		return nil, err
 	}
 
-	return &MinimizePower{
+	pl := &MinimizePower{
 		snapshotSharedLister: fh.SnapshotSharedLister(),
 		ctrlclient:           c,
-		metricsclient:        waoclient.NewCachedMetricsClient(mc, cmc, args.MetricsCacheTTL.Duration),
-		predictorclient:      waoclient.NewCachedPredictorClient(fh.ClientSet(), args.PredictorCacheTTL.Duration),
+		metricsclient:        waoclient.NewCachedMetricsClient(mc, cmc, args.MetricsCacheTTL.Duration, args.MetricsCacheSize),
+		predictorclient:      waoclient.NewCachedPredictorClient(fh.ClientSet(), args.PredictorCacheTTL.Duration, args.PredictorCacheSize),
 		args:                 &args,
 		startTime:            map[string]time.Time{},
-	}, nil
+	}
+	pl.predictorclient.Quantization = args.FeatureQuantization
+	pl.predictorclient.BatchWindow = args.PredictionBatchWindow.Duration
+
+	// init power curve client
+	if args.PowerCurve {
+		pl.powercurveclient = waoclient.NewPowerCurveClient(c, pl.metricsclient, pl.predictorclient, waoclient.PowerCurveOptions{
+			Points:          args.PowerCurvePoints,
+			RefreshInterval: args.PowerCurveRefreshInterval.Duration,
+			DriftThreshold:  args.PowerCurveDriftThreshold,
+			DriftFloor:      args.PowerCurveDriftFloor,
+			CPUUsagePercent: args.CPUUsageFormat == CPUUsageFormatPercent,
+			ExtraFeatures:   args.ExtraFeatures,
+		})
+		go pl.powercurveclient.Run(context.TODO()) // NOTE: this context needs live until the scheduler stops
+	}
+
+	// init calibrator
+	if args.Calibration {
+		opts := waoclient.CalibrationOptions{
+			Interval:         args.CalibrationInterval.Duration,
+			ForgettingFactor: args.CalibrationForgettingFactor,
+			MinScale:         args.CalibrationMinScale,
+			MaxScale:         args.CalibrationMaxScale,
+			MaxOffset:        args.CalibrationMaxOffset,
+			MaxAge:           args.CalibrationMaxAge.Duration,
+			CPUUsagePercent:  args.CPUUsageFormat == CPUUsageFormatPercent,
+			ExtraFeatures:    args.ExtraFeatures,
+		}
+		if args.CalibrationStateConfigMap != "" {
+			ns, name, _ := strings.Cut(args.CalibrationStateConfigMap, "/")
+			opts.Backend = &waometrics.ConfigMapSnapshotBackend{Client: fh.ClientSet(), Namespace: ns, Name: name}
+		}
+		pl.predictorclient.Calibrator = waoclient.NewCalibrator(c, pl.metricsclient, pl.predictorclient, opts)
+		go pl.predictorclient.Calibrator.Run(context.TODO()) // NOTE: this context needs live until the scheduler stops
+	}
+
+	return pl, nil
 }
 
 // Name returns name of the plugin. It is used in logs, etc.
@@ -249,6 +290,30 @@ func (pl *MinimizePower) Score(ctx context.Context, state *framework.CycleState,
 	}
 	klog.InfoS("MinimizePower.Score metrics", "pod", pod.Name, "node", nodeName, "inlet_temp", inletTemp.Value.AsApproximateFloat64(), "delta_p", deltaP.Value.AsApproximateFloat64())
 
+	// get extra features
+	extraFeatures, err := pl.metricsclient.GetNodeFeatures(ctx, node, pl.args.ExtraFeatures)
+	if err != nil {
+		klog.ErrorS(err, "MinimizePower.Score GetNodeFeatures score=ScoreError as error occurred", "pod", pod.Name, "node", nodeName)
+		return ScoreError, nil
+	}
+	if len(extraFeatures) > 0 {
+		klog.InfoS("MinimizePower.Score extra features", "pod", pod.Name, "node", nodeName, "features", extraFeatures)
+	}
+	beforeFeatures := predictor.PowerConsumptionInput{CPUUsage: beforeUsage, InletTemp: inletTemp.Value.AsApproximateFloat64(), DeltaP: deltaP.Value.AsApproximateFloat64()}.Features()
+	afterFeatures := predictor.PowerConsumptionInput{CPUUsage: afterUsage, InletTemp: inletTemp.Value.AsApproximateFloat64(), DeltaP: deltaP.Value.AsApproximateFloat64()}.Features()
+	maps.Copy(beforeFeatures, extraFeatures)
+	maps.Copy(afterFeatures, extraFeatures)
+
+	// interpolate the power curve if available (otherwise it is refreshed in background and the predictor is used)
+	if pl.powercurveclient != nil {
+		beforeWatt, ok1 := pl.powercurveclient.PredictPowerConsumption(nodeName, beforeFeatures)
+		afterWatt, ok2 := pl.powercurveclient.PredictPowerConsumption(nodeName, afterFeatures)
+		if ok1 && ok2 {
+			klog.InfoS("MinimizePower.Score prediction (power curve)", "pod", pod.Name, "node", nodeName, "watt_before", beforeWatt, "watt_after", afterWatt)
+			return podPowerConsumption(pod, nodeName, beforeWatt, afterWatt), nil
+		}
+	}
+
 	// get NodeConfig
 	var nc *waov1beta1.NodeConfig
 	var ncs waov1beta1.NodeConfigList
@@ -268,44 +333,26 @@ func (pl *MinimizePower) Score(ctx context.Context, state *framework.CycleState,
 		return ScoreError, nil
 	}
 
-	// init predictor endpoint
-	var ep *waov1beta1.EndpointTerm
-	if nc.Spec.Predictor.PowerConsumption != nil {
-		ep = nc.Spec.Predictor.PowerConsumption
-	} else {
-		ep = &waov1beta1.EndpointTerm{}
-	}
-
-	if nc.Spec.Predictor.PowerConsumptionEndpointProvider != nil {
-		ep2, err := pl.predictorclient.GetPredictorEndpoint(ctx, nc.Namespace, nc.Spec.Predictor.PowerConsumptionEndpointProvider, predictor.TypePowerConsumption)
-		if err != nil {
-			klog.ErrorS(err, "MinimizePower.Score GetPredictorEndpoint score=ScoreError as error occurred", "pod", pod.Name, "node", nodeName)
-			return ScoreError, nil
-		}
-		ep.Type = ep2.Type
-		ep.Endpoint = ep2.Endpoint
-	}
-
-	// do predict
-	beforeWatt, err := pl.predictorclient.PredictPowerConsumption(ctx, nc.Namespace, ep, beforeUsage, inletTemp.Value.AsApproximateFloat64(), deltaP.Value.AsApproximateFloat64())
+	// do predict (before and after in a single request), falling back to PowerConsumptionFallbacks
+	watts, err := pl.predictorclient.PredictNodePowerConsumptionFeaturesBatch(ctx, nc, []predictor.Features{beforeFeatures, afterFeatures})
 	if err != nil {
-		klog.ErrorS(err, "MinimizePower.Score PredictPowerConsumption(before) score=ScoreError as error occurred", "pod", pod.Name, "node", nodeName)
-		return ScoreError, nil
-	}
-	afterWatt, err := pl.predictorclient.PredictPowerConsumption(ctx, nc.Namespace, ep, afterUsage, inletTemp.Value.AsApproximateFloat64(), deltaP.Value.AsApproximateFloat64())
-	if err != nil {
-		klog.ErrorS(err, "MinimizePower.Score PredictPowerConsumption(after) score=ScoreError as error occurred", "pod", pod.Name, "node", nodeName)
+		klog.ErrorS(err, "MinimizePower.Score PredictNodePowerConsumptionFeaturesBatch score=ScoreError as error occurred", "pod", pod.Name, "node", nodeName)
 		return ScoreError, nil
 	}
+	beforeWatt, afterWatt := watts[0], watts[1]
 	klog.InfoS("MinimizePower.Score prediction", "pod", pod.Name, "node", nodeName, "watt_before", beforeWatt, "watt_after", afterWatt)
 
-	podPowerConsumption := int64(afterWatt - beforeWatt)
-	if podPowerConsumption < 0 {
+	return podPowerConsumption(pod, nodeName, beforeWatt, afterWatt), nil
+}
+
+// podPowerConsumption returns how many watts will be increased by the pod, rounding negative values to 0.
+func podPowerConsumption(pod *corev1.Pod, nodeName string, beforeWatt, afterWatt float64) int64 {
+	watt := int64(afterWatt - beforeWatt)
+	if watt < 0 {
 		klog.InfoS("MinimizePower.Score round negative scores to 0", "pod", pod.Name, "node", nodeName, "watt", afterWatt-beforeWatt)
-		podPowerConsumption = 0
+		watt = 0
 	}
-
-	return podPowerConsumption, nil
+	return watt
 }
 
 func (pl *MinimizePower) NormalizeScore(_ context.Context, _ *framework.CycleState, pod *corev1.Pod, scores framework.NodeScoreList) *framework.Status {
@@ -2,10 +2,14 @@ package minimizepower
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+
+	waoclient "github.com/waok8s/waok8s/wao-metrics-adapter/pkg/client"
+	"github.com/waok8s/waok8s/wao-metrics-adapter/pkg/predictor"
 )
 
 // TODO: use code-generator to generate DeepCopy functions
@@ -14,8 +18,22 @@ import (
 const (
 	DefaultMetricsCacheTTL            = 30 * time.Second
 	DefaultPredictorCacheTTL          = 30 * time.Minute
+	DefaultMetricsCacheSize           = waoclient.DefaultMetricsCacheSize
+	DefaultPredictorCacheSize         = waoclient.DefaultPredictorCacheSize
 	DefaultPodUsageAssumption float64 = 0.5
 	DefaultCPUUsageFormat             = CPUUsageFormatRaw
+
+	DefaultPowerCurvePoints          = 11
+	DefaultPowerCurveRefreshInterval = 5 * time.Minute
+	DefaultPowerCurveDriftThreshold  = 0.05
+	DefaultPowerCurveDriftFloor      = waoclient.DefaultPowerCurveDriftFloor
+
+	DefaultCalibrationInterval         = waoclient.DefaultCalibrationInterval
+	DefaultCalibrationForgettingFactor = waoclient.DefaultCalibrationForgettingFactor
+	DefaultCalibrationMinScale         = waoclient.DefaultCalibrationMinScale
+	DefaultCalibrationMaxScale         = waoclient.DefaultCalibrationMaxScale
+	DefaultCalibrationMaxOffset        = waoclient.DefaultCalibrationMaxOffset
+	DefaultCalibrationMaxAge           = waoclient.DefaultCalibrationMaxAge
 )
 
 const (
@@ -31,9 +49,43 @@ type MinimizePowerArgs struct {
 	MetricsCacheTTL   metav1.Duration `json:"metricsCacheTTL,omitempty"`
 	PredictorCacheTTL metav1.Duration `json:"predictorCacheTTL,omitempty"`
 
+	MetricsCacheSize   int `json:"metricsCacheSize,omitempty"`
+	PredictorCacheSize int `json:"predictorCacheSize,omitempty"`
+
+	// FeatureQuantization is the step to round each feature to before predicting, e.g., {"cpu_usage": 0.1}.
+	// Nil means waoclient.DefaultQuantization, and a step of 0 disables quantization of the feature.
+	FeatureQuantization map[string]float64 `json:"featureQuantization,omitempty"`
+
+	// PredictionBatchWindow is the time to wait for concurrent power consumption predictions to the same endpoint
+	// to coalesce into a single request. Zero disables coalescing.
+	PredictionBatchWindow metav1.Duration `json:"predictionBatchWindow,omitempty"`
+
 	PodUsageAssumption float64 `json:"podUsageAssumption,omitempty"`
 
 	CPUUsageFormat string `json:"cpuUsageFormat,omitempty"`
+
+	// ExtraFeatures are features supplied to the predictor in addition to cpu_usage, inlet_temp and delta_p.
+	// "memory_usage" is from metrics-server, and other names are custom metrics of the node.
+	ExtraFeatures []string `json:"extraFeatures,omitempty"`
+
+	// PowerCurve enables scoring by interpolating per-node power curves, which are predicted in background.
+	PowerCurve                bool            `json:"powerCurve,omitempty"`
+	PowerCurvePoints          int             `json:"powerCurvePoints,omitempty"`
+	PowerCurveRefreshInterval metav1.Duration `json:"powerCurveRefreshInterval,omitempty"`
+	PowerCurveDriftThreshold  float64         `json:"powerCurveDriftThreshold,omitempty"`
+	PowerCurveDriftFloor      float64         `json:"powerCurveDriftFloor,omitempty"`
+
+	// Calibration enables correcting predictions of each node with a fit between predicted and measured power consumption.
+	Calibration                 bool            `json:"calibration,omitempty"`
+	CalibrationInterval         metav1.Duration `json:"calibrationInterval,omitempty"`
+	CalibrationForgettingFactor float64         `json:"calibrationForgettingFactor,omitempty"`
+	CalibrationMinScale         float64         `json:"calibrationMinScale,omitempty"`
+	CalibrationMaxScale         float64         `json:"calibrationMaxScale,omitempty"`
+	CalibrationMaxOffset        float64         `json:"calibrationMaxOffset,omitempty"`
+	CalibrationMaxAge           metav1.Duration `json:"calibrationMaxAge,omitempty"`
+	// CalibrationStateConfigMap is the ConfigMap in "namespace/name" format to persist calibration across restarts.
+	// Empty disables persistence.
+	CalibrationStateConfigMap string `json:"calibrationStateConfigMap,omitempty"`
 }
 
 func (args *MinimizePowerArgs) Default() {
@@ -46,6 +98,18 @@ func (args *MinimizePowerArgs) Default() {
 		args.PredictorCacheTTL = metav1.Duration{Duration: DefaultPredictorCacheTTL}
 	}
 
+	if args.MetricsCacheSize == 0 {
+		args.MetricsCacheSize = DefaultMetricsCacheSize
+	}
+
+	if args.PredictorCacheSize == 0 {
+		args.PredictorCacheSize = DefaultPredictorCacheSize
+	}
+
+	if args.FeatureQuantization == nil {
+		args.FeatureQuantization = waoclient.DefaultQuantization()
+	}
+
 	if args.PodUsageAssumption == 0.0 {
 		args.PodUsageAssumption = DefaultPodUsageAssumption
 	}
@@ -54,10 +118,68 @@ func (args *MinimizePowerArgs) Default() {
 		args.CPUUsageFormat = DefaultCPUUsageFormat
 	}
 
+	if args.PowerCurvePoints == 0 {
+		args.PowerCurvePoints = DefaultPowerCurvePoints
+	}
+
+	if args.PowerCurveRefreshInterval.Duration == 0 {
+		args.PowerCurveRefreshInterval = metav1.Duration{Duration: DefaultPowerCurveRefreshInterval}
+	}
+
+	if args.PowerCurveDriftThreshold == 0.0 {
+		args.PowerCurveDriftThreshold = DefaultPowerCurveDriftThreshold
+	}
+
+	if args.PowerCurveDriftFloor == 0.0 {
+		args.PowerCurveDriftFloor = DefaultPowerCurveDriftFloor
+	}
+
+	if args.CalibrationInterval.Duration == 0 {
+		args.CalibrationInterval = metav1.Duration{Duration: DefaultCalibrationInterval}
+	}
+
+	if args.CalibrationForgettingFactor == 0.0 {
+		args.CalibrationForgettingFactor = DefaultCalibrationForgettingFactor
+	}
+
+	if args.CalibrationMinScale == 0.0 {
+		args.CalibrationMinScale = DefaultCalibrationMinScale
+	}
+
+	if args.CalibrationMaxScale == 0.0 {
+		args.CalibrationMaxScale = DefaultCalibrationMaxScale
+	}
+
+	if args.CalibrationMaxOffset == 0.0 {
+		args.CalibrationMaxOffset = DefaultCalibrationMaxOffset
+	}
+
+	if args.CalibrationMaxAge.Duration == 0 {
+		args.CalibrationMaxAge = metav1.Duration{Duration: DefaultCalibrationMaxAge}
+	}
+
 }
 
 func (args *MinimizePowerArgs) Validate() error {
 
+	if args.MetricsCacheSize <= 0 {
+		return fmt.Errorf("metricsCacheSize must be positive")
+	}
+
+	if args.PredictorCacheSize <= 0 {
+		return fmt.Errorf("predictorCacheSize must be positive")
+	}
+
+	for k, v := range args.FeatureQuantization {
+		if v < 0.0 {
+			return fmt.Errorf("featureQuantization of %s must not be negative", k)
+		}
+	}
+
+	if args.PredictionBatchWindow.Duration < 0 {
+		return fmt.Errorf("predictionBatchWindow must not be negative")
+	}
+
 	if args.PodUsageAssumption < 0.0 || args.PodUsageAssumption > 1.0 {
 		return fmt.Errorf("podUsageAssumption must be between 0.0 and 1.0")
 	}
@@ -66,6 +188,52 @@ func (args *MinimizePowerArgs) Validate() error {
 		return fmt.Errorf("cpuUsageFormat must be either `Raw` or `Percent`")
 	}
 
+	if err := predictor.ValidateExtraFeatures(args.ExtraFeatures); err != nil {
+		return fmt.Errorf("extraFeatures: %w", err)
+	}
+
+	if args.PowerCurvePoints < 2 {
+		return fmt.Errorf("powerCurvePoints must be at least 2")
+	}
+
+	if args.PowerCurveRefreshInterval.Duration < 0 {
+		return fmt.Errorf("powerCurveRefreshInterval must be positive")
+	}
+
+	if args.PowerCurveDriftThreshold < 0.0 {
+		return fmt.Errorf("powerCurveDriftThreshold must be positive")
+	}
+
+	if args.PowerCurveDriftFloor < 0.0 {
+		return fmt.Errorf("powerCurveDriftFloor must be positive")
+	}
+
+	if args.CalibrationInterval.Duration < 0 {
+		return fmt.Errorf("calibrationInterval must be positive")
+	}
+
+	if args.CalibrationForgettingFactor <= 0.0 || args.CalibrationForgettingFactor > 1.0 {
+		return fmt.Errorf("calibrationForgettingFactor must be in (0.0, 1.0]")
+	}
+
+	if args.CalibrationMinScale <= 0.0 || args.CalibrationMinScale > 1.0 || args.CalibrationMaxScale < 1.0 {
+		return fmt.Errorf("calibrationMinScale must be in (0.0, 1.0] and calibrationMaxScale must be at least 1.0")
+	}
+
+	if args.CalibrationMaxOffset < 0.0 {
+		return fmt.Errorf("calibrationMaxOffset must be positive")
+	}
+
+	if args.CalibrationMaxAge.Duration < 0 {
+		return fmt.Errorf("calibrationMaxAge must be positive")
+	}
+
+	if args.CalibrationStateConfigMap != "" {
+		if ns, name, ok := strings.Cut(args.CalibrationStateConfigMap, "/"); !ok || ns == "" || name == "" {
+			return fmt.Errorf("calibrationStateConfigMap must be in namespace/name format")
+		}
+	}
+
 	return nil
 }
 
@@ -74,6 +242,22 @@ func (in *MinimizePowerArgs) DeepCopyInto(out *MinimizePowerArgs) {
 	out.TypeMeta = in.TypeMeta
 	out.MetricsCacheTTL = in.MetricsCacheTTL
 	out.PredictorCacheTTL = in.PredictorCacheTTL
+	out.PredictionBatchWindow = in.PredictionBatchWindow
+	out.PowerCurveRefreshInterval = in.PowerCurveRefreshInterval
+	out.CalibrationInterval = in.CalibrationInterval
+	out.CalibrationMaxAge = in.CalibrationMaxAge
+	if in.ExtraFeatures != nil {
+		in, out := &in.ExtraFeatures, &out.ExtraFeatures
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.FeatureQuantization != nil {
+		in, out := &in.FeatureQuantization, &out.FeatureQuantization
+		*out = make(map[string]float64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 func (in *MinimizePowerArgs) DeepCopy() *MinimizePowerArgs {